@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/elves/elvish/store/storedefs"
 )
 
@@ -9,7 +11,7 @@ const (
 	ServiceName = "Daemon"
 
 	// Version is the API version. It should be bumped any time the API changes.
-	Version = -97
+	Version = -92
 )
 
 // Basic requests.
@@ -79,6 +81,31 @@ type PrevCmdResponse struct {
 	Text string
 }
 
+type PrevCmdsRequest struct {
+	Upto int
+	N    int
+}
+
+type PrevCmdsResponse struct {
+	Cmds []storedefs.Cmd
+}
+
+type SetCmdInfoRequest struct {
+	Seq  int
+	Info storedefs.CmdInfo
+}
+
+type SetCmdInfoResponse struct{}
+
+type CmdsWithInfoRequest struct {
+	From int
+	Upto int
+}
+
+type CmdsWithInfoResponse struct {
+	Cmds []storedefs.Cmd
+}
+
 // Dir requests.
 
 type AddDirRequest struct {
@@ -118,3 +145,53 @@ type DelSharedVarRequest struct {
 }
 
 type DelSharedVarResponse struct{}
+
+// EnvFile requests.
+
+type EnvFileHashRequest struct {
+	Path string
+}
+
+type EnvFileHashResponse struct {
+	Hash string
+}
+
+type AllowEnvFileRequest struct {
+	Path string
+	Hash string
+}
+
+type AllowEnvFileResponse struct{}
+
+type DisallowEnvFileRequest struct {
+	Path string
+}
+
+type DisallowEnvFileResponse struct{}
+
+// Completion cache requests.
+
+type CachedCompletionRequest struct {
+	Key string
+}
+
+type CachedCompletionResponse struct {
+	Values []string
+	Found  bool
+}
+
+type CacheCompletionRequest struct {
+	Key    string
+	Values []string
+	TTL    time.Duration
+}
+
+type CacheCompletionResponse struct{}
+
+// Metrics requests.
+
+type ReportEvalRequest struct {
+	Duration time.Duration
+}
+
+type ReportEvalResponse struct{}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// Maximum number of entries in the completion cache. When the cache is full,
+// expired entries are dropped first, and then the entries that expire soonest.
+const maxCacheEntries = 1024
+
+// completionCache holds the outputs of expensive completers, shared by all
+// sessions talking to the daemon. It lives in memory and is lost when the
+// daemon exits. All methods are safe for concurrent use.
+type completionCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	values []string
+	expiry time.Time
+}
+
+func newCompletionCache() *completionCache {
+	return &completionCache{entries: map[string]cacheEntry{}}
+}
+
+// get returns the values cached under key, and whether they are present and
+// not yet expired at the time now.
+func (c *completionCache) get(key string, now time.Time) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expiry) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.values, true
+}
+
+// set caches values under key until ttl has passed since now. A non-positive
+// ttl removes the entry.
+func (c *completionCache) set(key string, values []string, ttl time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl <= 0 {
+		delete(c.entries, key)
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{values, now.Add(ttl)}
+}
+
+// evict makes room for one more entry. It must be called with c.mu held.
+func (c *completionCache) evict(now time.Time) {
+	var soonestKey string
+	var soonest time.Time
+	for key, e := range c.entries {
+		if !now.Before(e.expiry) {
+			delete(c.entries, key)
+		} else if soonestKey == "" || e.expiry.Before(soonest) {
+			soonestKey, soonest = key, e.expiry
+		}
+	}
+	if len(c.entries) >= maxCacheEntries {
+		delete(c.entries, soonestKey)
+	}
+}
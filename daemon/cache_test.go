@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCompletionCache(t *testing.T) {
+	c := newCompletionCache()
+	now := time.Unix(1000, 0)
+
+	if _, ok := c.get("k", now); ok {
+		t.Errorf("get on empty cache found an entry")
+	}
+
+	c.set("k", []string{"a", "b"}, time.Minute, now)
+	values, ok := c.get("k", now.Add(30*time.Second))
+	if !ok || !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Errorf("get before expiry -> %v, %v, want [a b], true", values, ok)
+	}
+	if _, ok := c.get("k", now.Add(time.Minute)); ok {
+		t.Errorf("get after expiry found an entry")
+	}
+	if len(c.entries) != 0 {
+		t.Errorf("expired entry not removed")
+	}
+
+	c.set("k", []string{"a"}, time.Minute, now)
+	c.set("k", nil, 0, now)
+	if _, ok := c.get("k", now); ok {
+		t.Errorf("set with zero TTL did not remove the entry")
+	}
+}
+
+func TestCompletionCache_Evict(t *testing.T) {
+	c := newCompletionCache()
+	now := time.Unix(1000, 0)
+	for i := 0; i < maxCacheEntries; i++ {
+		c.set(strconv.Itoa(i), nil, time.Duration(i+1)*time.Second, now)
+	}
+	c.set("new", nil, time.Hour, now)
+	if len(c.entries) != maxCacheEntries {
+		t.Errorf("cache has %d entries, want %d", len(c.entries), maxCacheEntries)
+	}
+	if _, ok := c.get("0", now); ok {
+		t.Errorf("entry expiring soonest not evicted")
+	}
+	if _, ok := c.get("new", now); !ok {
+		t.Errorf("new entry not added")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/rpc"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/store/storedefs"
 )
@@ -20,7 +21,9 @@ var (
 
 // Client is a client to the Elvish daemon. A nil *Client is safe to use.
 type Client struct {
-	sockPath  string
+	sockPath string
+	// Guards rpcClient, since requests may be made from several goroutines.
+	mutex     sync.Mutex
 	rpcClient *rpc.Client
 	waits     sync.WaitGroup
 }
@@ -30,7 +33,7 @@ var _ storedefs.Store = (*Client)(nil)
 // NewClient creates a new Client instance that talks to the socket. Connection
 // creation is deferred to the first request.
 func NewClient(sockPath string) *Client {
-	return &Client{sockPath, nil, sync.WaitGroup{}}
+	return &Client{sockPath: sockPath}
 }
 
 // SockPath returns the socket path that the Client talks to. If the client is
@@ -45,11 +48,16 @@ func (c *Client) SockPath() string {
 // ResetConn resets the current connection. A new connection will be established
 // the next time a request is made. If the client is nil, it does nothing.
 func (c *Client) ResetConn() error {
-	if c == nil || c.rpcClient == nil {
+	if c == nil {
 		return nil
 	}
+	c.mutex.Lock()
 	rc := c.rpcClient
 	c.rpcClient = nil
+	c.mutex.Unlock()
+	if rc == nil {
+		return nil
+	}
 	return rc.Close()
 }
 
@@ -71,18 +79,19 @@ func (c *Client) call(f string, req, res interface{}) error {
 	defer c.waits.Done()
 
 	for attempt := 0; attempt < retriesOnShutdown; attempt++ {
-		if c.rpcClient == nil {
-			conn, err := dial(c.sockPath)
-			if err != nil {
-				return err
-			}
-			c.rpcClient = rpc.NewClient(conn)
+		rc, err := c.conn()
+		if err != nil {
+			return err
 		}
 
-		err := c.rpcClient.Call(ServiceName+"."+f, req, res)
+		err = rc.Call(ServiceName+"."+f, req, res)
 		if err == rpc.ErrShutdown {
 			// Clear rpcClient so as to reconnect next time
-			c.rpcClient = nil
+			c.mutex.Lock()
+			if c.rpcClient == rc {
+				c.rpcClient = nil
+			}
+			c.mutex.Unlock()
 			continue
 		} else {
 			return err
@@ -91,6 +100,20 @@ func (c *Client) call(f string, req, res interface{}) error {
 	return ErrDaemonUnreachable
 }
 
+// conn returns the current connection, connecting first if there is none.
+func (c *Client) conn() (*rpc.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.rpcClient == nil {
+		conn, err := dial(c.sockPath)
+		if err != nil {
+			return nil, err
+		}
+		c.rpcClient = rpc.NewClient(conn)
+	}
+	return c.rpcClient, nil
+}
+
 // Convenience methods for RPC methods. These are quite repetitive; when the
 // number of RPC calls grow above some threshold, a code generator should be
 // written to generate them.
@@ -151,6 +174,26 @@ func (c *Client) PrevCmd(upto int, prefix string) (int, string, error) {
 	return res.Seq, res.Text, err
 }
 
+func (c *Client) PrevCmds(upto, n int) ([]storedefs.Cmd, error) {
+	req := &PrevCmdsRequest{upto, n}
+	res := &PrevCmdsResponse{}
+	err := c.call("PrevCmds", req, res)
+	return res.Cmds, err
+}
+
+func (c *Client) SetCmdInfo(seq int, info storedefs.CmdInfo) error {
+	req := &SetCmdInfoRequest{seq, info}
+	res := &SetCmdInfoResponse{}
+	return c.call("SetCmdInfo", req, res)
+}
+
+func (c *Client) CmdsWithInfo(from, upto int) ([]storedefs.Cmd, error) {
+	req := &CmdsWithInfoRequest{from, upto}
+	res := &CmdsWithInfoResponse{}
+	err := c.call("CmdsWithInfo", req, res)
+	return res.Cmds, err
+}
+
 func (c *Client) AddDir(dir string, incFactor float64) error {
 	req := &AddDirRequest{dir, incFactor}
 	res := &AddDirResponse{}
@@ -183,3 +226,57 @@ func (c *Client) DelSharedVar(name string) error {
 	res := &DelSharedVarResponse{}
 	return c.call("DelSharedVar", req, res)
 }
+
+func (c *Client) EnvFileHash(path string) (string, error) {
+	req := &EnvFileHashRequest{path}
+	res := &EnvFileHashResponse{}
+	err := c.call("EnvFileHash", req, res)
+	return res.Hash, err
+}
+
+func (c *Client) AllowEnvFile(path, hash string) error {
+	req := &AllowEnvFileRequest{path, hash}
+	res := &AllowEnvFileResponse{}
+	return c.call("AllowEnvFile", req, res)
+}
+
+func (c *Client) DisallowEnvFile(path string) error {
+	req := &DisallowEnvFileRequest{path}
+	res := &DisallowEnvFileResponse{}
+	return c.call("DisallowEnvFile", req, res)
+}
+
+func (c *Client) CachedCompletion(key string) ([]string, bool, error) {
+	req := &CachedCompletionRequest{key}
+	res := &CachedCompletionResponse{}
+	err := c.call("CachedCompletion", req, res)
+	return res.Values, res.Found, err
+}
+
+func (c *Client) CacheCompletion(key string, values []string, ttl time.Duration) error {
+	req := &CacheCompletionRequest{key, values, ttl}
+	res := &CacheCompletionResponse{}
+	return c.call("CacheCompletion", req, res)
+}
+
+// ReportEval reports the duration of an evaluation without waiting for the
+// daemon. If done is not nil, it is called with the error of the request, or
+// nil, from another goroutine.
+func (c *Client) ReportEval(d time.Duration, done func(error)) {
+	if c == nil {
+		if done != nil {
+			done(ErrClientNotInitialized)
+		}
+		return
+	}
+	c.waits.Add(1)
+	go func() {
+		defer c.waits.Done()
+		req := &ReportEvalRequest{d}
+		res := &ReportEvalResponse{}
+		err := c.call("ReportEval", req, res)
+		if done != nil {
+			done(err)
+		}
+	}()
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -11,7 +12,7 @@ func TestDaemon(t *testing.T) {
 	util.InTempDir(func(string) {
 		serverDone := make(chan struct{})
 		go func() {
-			Serve("sock", "db")
+			Serve("sock", "db", "")
 			close(serverDone)
 		}()
 
@@ -30,6 +31,20 @@ func TestDaemon(t *testing.T) {
 		if err != nil {
 			t.Errorf("client.AddCmd -> error %v", err)
 		}
+		err = client.CacheCompletion("key", []string{"a", "b"}, time.Minute)
+		if err != nil {
+			t.Errorf("client.CacheCompletion -> error %v", err)
+		}
+		values, found, err := client.CachedCompletion("key")
+		if !found || !reflect.DeepEqual(values, []string{"a", "b"}) || err != nil {
+			t.Errorf("client.CachedCompletion -> %v, %v, %v, want [a b], true, nil",
+				values, found, err)
+		}
+		reported := make(chan error, 1)
+		client.ReportEval(time.Second, func(err error) { reported <- err })
+		if err := <-reported; err != nil {
+			t.Errorf("client.ReportEval -> error %v", err)
+		}
 		client.Close()
 		// Wait for server to quit before returning
 		<-serverDone
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/store/storedefs"
+)
+
+// Upper bounds of histogram buckets, in seconds.
+var (
+	queryBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+	evalBuckets  = []float64{.001, .01, .1, .5, 1, 5, 10, 30, 60, 300}
+)
+
+// metrics collects runtime metrics of the daemon. All methods are safe for
+// concurrent use, and a nil *metrics silently discards all observations.
+type metrics struct {
+	mu       sync.Mutex
+	sessions int
+	queries  map[string]*histogram
+	evals    *histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{queries: map[string]*histogram{}, evals: newHistogram(evalBuckets)}
+}
+
+// observeQuery records the latency of the RPC method, measured from begin. It
+// is meant to be deferred.
+func (m *metrics) observeQuery(method string, begin time.Time) {
+	if m == nil {
+		return
+	}
+	d := time.Since(begin)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.queries[method]
+	if !ok {
+		h = newHistogram(queryBuckets)
+		m.queries[method] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (m *metrics) observeEval(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evals.observe(d.Seconds())
+}
+
+func (m *metrics) addSessions(delta int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions += delta
+}
+
+// writeTo writes all metrics to w in the Prometheus text exposition format.
+func (m *metrics) writeTo(w io.Writer, st storedefs.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if st != nil {
+		if seq, err := st.NextCmdSeq(); err == nil {
+			fmt.Fprintln(w, "# HELP elvish_daemon_history_size Number of commands in the history.")
+			fmt.Fprintln(w, "# TYPE elvish_daemon_history_size gauge")
+			// Command sequence numbers start from 1.
+			fmt.Fprintln(w, "elvish_daemon_history_size", seq-1)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP elvish_daemon_sessions Number of connected sessions.")
+	fmt.Fprintln(w, "# TYPE elvish_daemon_sessions gauge")
+	fmt.Fprintln(w, "elvish_daemon_sessions", m.sessions)
+
+	fmt.Fprintln(w, "# HELP elvish_daemon_query_duration_seconds Latency of RPC queries.")
+	fmt.Fprintln(w, "# TYPE elvish_daemon_query_duration_seconds histogram")
+	methods := make([]string, 0, len(m.queries))
+	for method := range m.queries {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		m.queries[method].writeTo(w, "elvish_daemon_query_duration_seconds",
+			fmt.Sprintf("method=%q", method))
+	}
+
+	fmt.Fprintln(w, "# HELP elvish_shell_eval_duration_seconds Duration of interactive evaluations, as reported by shells.")
+	fmt.Fprintln(w, "# TYPE elvish_shell_eval_duration_seconds histogram")
+	m.evals.writeTo(w, "elvish_shell_eval_duration_seconds", "")
+}
+
+// histogram is a cumulative histogram in the Prometheus sense.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) writeTo(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%g\"} %d\n", name, labels, sep, bound, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// serveMetrics serves metrics over HTTP on addr at /metrics. It returns the
+// listener, which should be closed to stop serving.
+func serveMetrics(addr string, m *metrics, st storedefs.Store) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.writeTo(w, st)
+	})
+	go func() {
+		err := http.Serve(listener, mux)
+		logger.Println("metrics server exited:", err)
+	}()
+	return listener, nil
+}
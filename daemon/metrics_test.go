@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+	m.addSessions(2)
+	m.addSessions(-1)
+	m.observeEval(2 * time.Second)
+	m.observeQuery("AddCmd", time.Now())
+
+	var buf bytes.Buffer
+	m.writeTo(&buf, nil)
+	out := buf.String()
+	for _, want := range []string{
+		"elvish_daemon_sessions 1\n",
+		`elvish_daemon_query_duration_seconds_bucket{method="AddCmd",le="+Inf"} 1` + "\n",
+		`elvish_daemon_query_duration_seconds_count{method="AddCmd"} 1` + "\n",
+		`elvish_shell_eval_duration_seconds_bucket{le="1"} 0` + "\n",
+		`elvish_shell_eval_duration_seconds_bucket{le="5"} 1` + "\n",
+		"elvish_shell_eval_duration_seconds_sum 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output does not contain %q; output is:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "history_size") {
+		t.Errorf("history size reported without a store")
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *metrics
+	m.addSessions(1)
+	m.observeEval(time.Second)
+	m.observeQuery("Cmd", time.Now())
+}
@@ -1,20 +1,23 @@
 package daemon
 
 import (
+	"net"
 	"net/rpc"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/elves/elvish/store"
 	"github.com/elves/elvish/store/storedefs"
 )
 
 // Serve runs the daemon service, listening on the socket specified by sockpath
-// and serving data from dbpath. It quits upon receiving SIGTERM, SIGINT or when
-// all active clients have disconnected.
-func Serve(sockpath, dbpath string) {
+// and serving data from dbpath. If metricsAddr is not empty, runtime metrics
+// are also served over HTTP on that address. It quits upon receiving SIGTERM,
+// SIGINT or when all active clients have disconnected.
+func Serve(sockpath, dbpath, metricsAddr string) {
 	logger.Println("pid is", syscall.Getpid())
 	logger.Println("going to listen", sockpath)
 	listener, err := listen(sockpath)
@@ -30,6 +33,23 @@ func Serve(sockpath, dbpath string) {
 		logger.Printf("serving anyway")
 	}
 
+	var m *metrics
+	var metricsListener net.Listener
+	if metricsAddr != "" {
+		m = newMetrics()
+		var metricsStore storedefs.Store
+		if err == nil {
+			metricsStore = st
+		}
+		var metricsErr error
+		metricsListener, metricsErr = serveMetrics(metricsAddr, m, metricsStore)
+		if metricsErr != nil {
+			logger.Printf("failed to serve metrics on %s: %v", metricsAddr, metricsErr)
+		} else {
+			logger.Println("serving metrics on", metricsAddr)
+		}
+	}
+
 	quitSignals := make(chan os.Signal)
 	quitChan := make(chan struct{})
 	signal.Notify(quitSignals, syscall.SIGTERM, syscall.SIGINT)
@@ -52,10 +72,16 @@ func Serve(sockpath, dbpath string) {
 		if err != nil {
 			logger.Printf("failed to close listener: %v", err)
 		}
+		if metricsListener != nil {
+			err = metricsListener.Close()
+			if err != nil {
+				logger.Printf("failed to close metrics listener: %v", err)
+			}
+		}
 		logger.Println("listener closed, waiting to exit")
 	}()
 
-	service := &Service{st, err}
+	service := &Service{st, err, m, newCompletionCache()}
 	rpc.RegisterName(ServiceName, service)
 
 	logger.Println("starting to serve RPC calls")
@@ -82,7 +108,9 @@ func Serve(sockpath, dbpath string) {
 			activeClient.Add(1)
 		}
 		go func() {
+			m.addSessions(1)
 			rpc.DefaultServer.ServeConn(conn)
+			m.addSessions(-1)
 			activeClient.Done()
 		}()
 	}
@@ -93,8 +121,10 @@ func Serve(sockpath, dbpath string) {
 // Service provides the daemon RPC service. It is suitable as a service for
 // net/rpc.
 type Service struct {
-	store storedefs.Store
-	err   error
+	store   storedefs.Store
+	err     error
+	metrics *metrics
+	cache   *completionCache
 }
 
 // Implementations of RPC methods.
@@ -118,6 +148,7 @@ func (s *Service) NextCmdSeq(req *NextCmdSeqRequest, res *NextCmdSeqResponse) er
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("NextCmdSeq", time.Now())
 	seq, err := s.store.NextCmdSeq()
 	res.Seq = seq
 	return err
@@ -127,6 +158,7 @@ func (s *Service) AddCmd(req *AddCmdRequest, res *AddCmdResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("AddCmd", time.Now())
 	seq, err := s.store.AddCmd(req.Text)
 	res.Seq = seq
 	return err
@@ -136,6 +168,7 @@ func (s *Service) Cmd(req *CmdRequest, res *CmdResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("Cmd", time.Now())
 	text, err := s.store.Cmd(req.Seq)
 	res.Text = text
 	return err
@@ -145,6 +178,7 @@ func (s *Service) Cmds(req *CmdsRequest, res *CmdsResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("Cmds", time.Now())
 	cmds, err := s.store.Cmds(req.From, req.Upto)
 	res.Cmds = cmds
 	return err
@@ -154,6 +188,7 @@ func (s *Service) NextCmd(req *NextCmdRequest, res *NextCmdResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("NextCmd", time.Now())
 	seq, text, err := s.store.NextCmd(req.From, req.Prefix)
 	res.Seq, res.Text = seq, text
 	return err
@@ -163,15 +198,45 @@ func (s *Service) PrevCmd(req *PrevCmdRequest, res *PrevCmdResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("PrevCmd", time.Now())
 	seq, text, err := s.store.PrevCmd(req.Upto, req.Prefix)
 	res.Seq, res.Text = seq, text
 	return err
 }
 
+func (s *Service) PrevCmds(req *PrevCmdsRequest, res *PrevCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("PrevCmds", time.Now())
+	cmds, err := s.store.PrevCmds(req.Upto, req.N)
+	res.Cmds = cmds
+	return err
+}
+
+func (s *Service) SetCmdInfo(req *SetCmdInfoRequest, res *SetCmdInfoResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("SetCmdInfo", time.Now())
+	return s.store.SetCmdInfo(req.Seq, req.Info)
+}
+
+func (s *Service) CmdsWithInfo(req *CmdsWithInfoRequest, res *CmdsWithInfoResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("CmdsWithInfo", time.Now())
+	cmds, err := s.store.CmdsWithInfo(req.From, req.Upto)
+	res.Cmds = cmds
+	return err
+}
+
 func (s *Service) AddDir(req *AddDirRequest, res *AddDirResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("AddDir", time.Now())
 	return s.store.AddDir(req.Dir, req.IncFactor)
 }
 
@@ -179,6 +244,7 @@ func (s *Service) Dirs(req *DirsRequest, res *DirsResponse) error {
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("Dirs", time.Now())
 	dirs, err := s.store.Dirs(req.Blacklist)
 	res.Dirs = dirs
 	return err
@@ -188,6 +254,7 @@ func (s *Service) SharedVar(req *SharedVarRequest, res *SharedVarResponse) error
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("SharedVar", time.Now())
 	value, err := s.store.SharedVar(req.Name)
 	res.Value = value
 	return err
@@ -197,6 +264,7 @@ func (s *Service) SetSharedVar(req *SetSharedVarRequest, res *SetSharedVarRespon
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("SetSharedVar", time.Now())
 	return s.store.SetSharedVar(req.Name, req.Value)
 }
 
@@ -204,5 +272,53 @@ func (s *Service) DelSharedVar(req *DelSharedVarRequest, res *DelSharedVarRespon
 	if s.err != nil {
 		return s.err
 	}
+	defer s.metrics.observeQuery("DelSharedVar", time.Now())
 	return s.store.DelSharedVar(req.Name)
 }
+
+func (s *Service) EnvFileHash(req *EnvFileHashRequest, res *EnvFileHashResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("EnvFileHash", time.Now())
+	hash, err := s.store.EnvFileHash(req.Path)
+	res.Hash = hash
+	return err
+}
+
+func (s *Service) AllowEnvFile(req *AllowEnvFileRequest, res *AllowEnvFileResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("AllowEnvFile", time.Now())
+	return s.store.AllowEnvFile(req.Path, req.Hash)
+}
+
+func (s *Service) DisallowEnvFile(req *DisallowEnvFileRequest, res *DisallowEnvFileResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	defer s.metrics.observeQuery("DisallowEnvFile", time.Now())
+	return s.store.DisallowEnvFile(req.Path)
+}
+
+// CachedCompletion looks up the completion cache. The cache is kept in memory,
+// so it works even if the storage failed to open.
+func (s *Service) CachedCompletion(req *CachedCompletionRequest, res *CachedCompletionResponse) error {
+	defer s.metrics.observeQuery("CachedCompletion", time.Now())
+	res.Values, res.Found = s.cache.get(req.Key, time.Now())
+	return nil
+}
+
+// CacheCompletion stores values in the completion cache.
+func (s *Service) CacheCompletion(req *CacheCompletionRequest, res *CacheCompletionResponse) error {
+	defer s.metrics.observeQuery("CacheCompletion", time.Now())
+	s.cache.set(req.Key, req.Values, req.TTL, time.Now())
+	return nil
+}
+
+// ReportEval records the duration of an evaluation done by a shell session.
+func (s *Service) ReportEval(req *ReportEvalRequest, res *ReportEvalResponse) error {
+	s.metrics.observeEval(req.Duration)
+	return nil
+}
@@ -155,7 +155,10 @@ func installModules(builtin eval.Ns, ed *Editor) {
 		&eval.BuiltinFn{"edit:complete-getopt", complGetopt},
 		&eval.BuiltinFn{"edit:complex-candidate", outputComplexCandidate},
 		&eval.BuiltinFn{"edit:insert-at-dot", InsertAtDot},
+		&eval.BuiltinFn{"edit:reload-rc", reloadRC},
 		&eval.BuiltinFn{"edit:replace-input", ReplaceInput},
+		&eval.BuiltinFn{"edit:rprompt-duration", rpromptDuration},
+		&eval.BuiltinFn{"edit:rprompt-status", rpromptStatus},
 		&eval.BuiltinFn{"edit:styled", styled},
 		&eval.BuiltinFn{"edit:key", ui.KeyBuiltin},
 		&eval.BuiltinFn{"edit:wordify", Wordify},
@@ -172,6 +175,9 @@ func installModules(builtin eval.Ns, ed *Editor) {
 		}
 	}
 
+	submods[modeCompletion]["cached"+eval.FnSuffix] = vartypes.NewRo(
+		&eval.BuiltinFn{"edit:completion:cached", completionCached})
+
 	// Add $edit:{mode}:binding variables.
 	for mode, bindingVar := range ed.bindings {
 		submod, ok := submods[mode]
@@ -169,7 +169,7 @@ func complFilename(words []string, ev *eval.Evaler, rawCands chan<- rawCandidate
 	if len(words) < 1 {
 		return ErrTooFewArguments
 	}
-	return complFilenameInner(words[len(words)-1], false, rawCands)
+	return complFilenameInner(ev.Mounts, words[len(words)-1], false, rawCands)
 }
 
 func complSudo(words []string, ev *eval.Evaler, rawCands chan<- rawCandidate) error {
@@ -9,12 +9,27 @@ import (
 	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
 	"github.com/xiaq/persistent/hash"
 )
 
 type candidate struct {
 	code string    // This is what will be substituted on the command line.
 	menu ui.Styled // This is what is displayed in the completion menu.
+	// Whether the menu text is a path, which can be abbreviated in the middle
+	// when it is too wide. The full text is then shown below the menu when the
+	// candidate is selected.
+	path bool
+}
+
+// display returns the text to show in the completion menu when the column is
+// of the given width. Paths that are too wide are abbreviated in the middle;
+// other texts are left to be trimmed.
+func (c *candidate) display(width int) string {
+	if c.path {
+		return util.AbbrPathWcwidth(c.menu.Text, width)
+	}
+	return c.menu.Text
 }
 
 // rawCandidate is what can be converted to a candidate.
@@ -66,13 +81,14 @@ type complexCandidate struct {
 	codeSuffix    string    // Appended to the code.
 	displaySuffix string    // Appended to the display.
 	style         ui.Styles // Used in the menu.
+	path          bool      // Whether the stem is a path.
 }
 
 func (c *complexCandidate) Kind() string { return "map" }
 
 func (c *complexCandidate) Equal(a interface{}) bool {
 	rhs, ok := a.(*complexCandidate)
-	return ok && c.stem == rhs.stem && c.codeSuffix == rhs.codeSuffix && c.displaySuffix == rhs.displaySuffix && c.style.Eq(rhs.style)
+	return ok && c.stem == rhs.stem && c.codeSuffix == rhs.codeSuffix && c.displaySuffix == rhs.displaySuffix && c.style.Eq(rhs.style) && c.path == rhs.path
 }
 
 func (c *complexCandidate) Hash() uint32 {
@@ -98,6 +114,7 @@ func (c *complexCandidate) cook(q parse.PrimaryType) *candidate {
 	return &candidate{
 		code: quoted + c.codeSuffix,
 		menu: ui.Styled{c.stem + c.displaySuffix, c.style},
+		path: c.path,
 	}
 }
 
@@ -108,8 +125,8 @@ func outputComplexCandidate(ec *eval.Frame,
 	var style string
 	c := &complexCandidate{}
 
-	eval.ScanArgs(args, &c.stem)
-	eval.ScanOpts(opts,
+	ec.ScanArgs(args, &c.stem)
+	ec.ScanOpts(opts,
 		eval.OptToScan{"code-suffix", &c.codeSuffix, types.String("")},
 		eval.OptToScan{"display-suffix", &c.displaySuffix, types.String("")},
 		eval.OptToScan{"style", &style, types.String("")},
@@ -12,7 +12,7 @@ import (
 
 func complGetopt(ec *eval.Frame, a []types.Value, o map[string]types.Value) {
 	var elemsv, optsv, argsv types.IteratorValue
-	eval.ScanArgs(a, &elemsv, &optsv, &argsv)
+	ec.ScanArgs(a, &elemsv, &optsv, &argsv)
 	eval.TakeNoOpt(o)
 
 	var (
@@ -2,7 +2,6 @@ package edit
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +9,7 @@ import (
 	"github.com/elves/elvish/edit/lscolors"
 	"github.com/elves/elvish/edit/ui"
 	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/glob"
 	"github.com/elves/elvish/parse"
 )
 
@@ -71,15 +71,18 @@ func (ctx *argComplContext) generate(ev *eval.Evaler, ch chan<- rawCandidate) er
 	return completeArg(ctx.words, ev, ch)
 }
 
+// complFilenameInner completes filenames by listing a directory of fs, which
+// may have virtual file systems mounted on it.
+//
 // TODO: getStyle does redundant stats.
-func complFilenameInner(head string, executableOnly bool, rawCands chan<- rawCandidate) error {
+func complFilenameInner(fs glob.FS, head string, executableOnly bool, rawCands chan<- rawCandidate) error {
 	dir, fileprefix := filepath.Split(head)
 	dirToRead := dir
 	if dirToRead == "" {
 		dirToRead = "."
 	}
 
-	infos, err := ioutil.ReadDir(dirToRead)
+	infos, err := fs.ReadDir(dirToRead)
 	if err != nil {
 		return fmt.Errorf("cannot list directory %s: %v", dirToRead, err)
 	}
@@ -106,7 +109,7 @@ func complFilenameInner(head string, executableOnly bool, rawCands chan<- rawCan
 		if info.IsDir() {
 			suffix = string(filepath.Separator)
 		} else if info.Mode()&os.ModeSymlink != 0 {
-			stat, err := os.Stat(full)
+			stat, err := fs.Stat(full)
 			if err == nil && stat.IsDir() {
 				// Symlink to directory.
 				suffix = string(filepath.Separator)
@@ -116,12 +119,41 @@ func complFilenameInner(head string, executableOnly bool, rawCands chan<- rawCan
 		rawCands <- &complexCandidate{
 			stem: full, codeSuffix: suffix,
 			style: ui.StylesFromString(lsColor.GetStyle(full)),
+			path:  true,
 		}
 	}
 
 	return nil
 }
 
+// CompleteFilename returns the paths that complete seed as a file name, with
+// a trailing slash for directories. It is used by builtins that complete file
+// names outside the editor, like parse-args and args-completer.
+func (ed *Editor) CompleteFilename(seed string) []string {
+	return completeFilenames(ed.evaler.Mounts, seed)
+}
+
+func completeFilenames(fs glob.FS, seed string) []string {
+	rawCands := make(chan rawCandidate)
+	go func() {
+		complFilenameInner(fs, seed, false, rawCands)
+		close(rawCands)
+	}()
+	var paths []string
+	for c := range rawCands {
+		c := c.(*complexCandidate)
+		if !strings.HasPrefix(c.stem, seed) {
+			continue
+		}
+		if c.codeSuffix == string(filepath.Separator) {
+			paths = append(paths, c.stem+c.codeSuffix)
+		} else {
+			paths = append(paths, c.stem)
+		}
+	}
+	return paths
+}
+
 func dotfile(fname string) bool {
 	return strings.HasPrefix(fname, ".")
 }
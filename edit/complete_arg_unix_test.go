@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/elves/elvish/edit/ui"
+	"github.com/elves/elvish/glob"
 	"github.com/elves/elvish/util"
 )
 
@@ -28,20 +29,20 @@ var complFilenameInnerTests = []struct {
 	// Files have suffix " " and directories "/". Styles are set according to
 	// the LS_COLORS variable, which are set in the beginning of the test.
 	{"haha", false, rawCandidates{
-		&complexCandidate{stem: "Documents", codeSuffix: "/", style: dirStyle},
-		&complexCandidate{stem: "bar", codeSuffix: " ", style: fileStyle},
-		&complexCandidate{stem: "elvish", codeSuffix: " ", style: exeStyle},
-		&complexCandidate{stem: "foo", codeSuffix: " ", style: fileStyle},
+		&complexCandidate{stem: "Documents", codeSuffix: "/", style: dirStyle, path: true},
+		&complexCandidate{stem: "bar", codeSuffix: " ", style: fileStyle, path: true},
+		&complexCandidate{stem: "elvish", codeSuffix: " ", style: exeStyle, path: true},
+		&complexCandidate{stem: "foo", codeSuffix: " ", style: fileStyle, path: true},
 	}},
 	// Only match executables and directories.
 	{"haha", true, rawCandidates{
-		&complexCandidate{stem: "Documents", codeSuffix: "/", style: dirStyle},
-		&complexCandidate{stem: "elvish", codeSuffix: " ", style: exeStyle},
+		&complexCandidate{stem: "Documents", codeSuffix: "/", style: dirStyle, path: true},
+		&complexCandidate{stem: "elvish", codeSuffix: " ", style: exeStyle, path: true},
 	}},
 	// Match hidden files and directories.
 	{".haha", false, rawCandidates{
-		&complexCandidate{stem: ".elvish", codeSuffix: "/", style: dirStyle},
-		&complexCandidate{stem: ".vimrc", codeSuffix: " ", style: fileStyle},
+		&complexCandidate{stem: ".elvish", codeSuffix: "/", style: dirStyle, path: true},
+		&complexCandidate{stem: ".vimrc", codeSuffix: " ", style: fileStyle, path: true},
 	}},
 }
 
@@ -65,7 +66,7 @@ func TestComplFilenameInner(t *testing.T) {
 			)
 			go func() {
 				defer close(gets)
-				err = complFilenameInner(test.head, test.executableOnly, gets)
+				err = complFilenameInner(glob.OSFS, test.head, test.executableOnly, gets)
 			}()
 			for v := range gets {
 				cands = append(cands, v)
@@ -101,3 +102,28 @@ func create(fname string, perm os.FileMode) {
 	}
 	f.Close()
 }
+
+func TestCompleteFilenames(t *testing.T) {
+	util.InTempDir(func(string) {
+		for _, name := range []string{"a1", "a2", ".a3", "b"} {
+			create(name, 0600)
+		}
+		mkdir("ad", 0700)
+		create("ad/x", 0600)
+
+		for _, test := range []struct {
+			seed string
+			want []string
+		}{
+			{"a", []string{"a1", "a2", "ad/"}},
+			{".a", []string{".a3"}},
+			{"ad/", []string{"ad/x"}},
+			{"c", nil},
+			{"nonexistent/", nil},
+		} {
+			if got := completeFilenames(glob.OSFS, test.seed); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("completeFilenames(%q) -> %v, want %v", test.seed, got, test.want)
+			}
+		}
+	})
+}
@@ -63,7 +63,7 @@ func (ctx *commandComplContext) generate(ev *eval.Evaler, ch chan<- rawCandidate
 
 func complFormHeadInner(head string, ev *eval.Evaler, rawCands chan<- rawCandidate) error {
 	if util.DontSearch(head) {
-		return complFilenameInner(head, true, rawCands)
+		return complFilenameInner(ev.Mounts, head, true, rawCands)
 	}
 
 	got := func(s string) {
@@ -79,7 +79,7 @@ func complFormHeadInner(head string, ev *eval.Evaler, rawCands chan<- rawCandida
 				got(eval.MakeVariableName(false, ns, varname[:len(varname)-len(eval.FnSuffix)]))
 			} else {
 				name := eval.MakeVariableName(false, ns, varname)
-				rawCands <- &complexCandidate{name, " = ", " = ", ui.Styles{}}
+				rawCands <- &complexCandidate{name, " = ", " = ", ui.Styles{}, false}
 			}
 		})
 	}
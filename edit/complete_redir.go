@@ -30,5 +30,5 @@ func findRedirComplContext(n parse.Node, ev pureEvaler) complContext {
 }
 
 func (ctx *redirComplContext) generate(ev *eval.Evaler, ch chan<- rawCandidate) error {
-	return complFilenameInner(ctx.seed, false, ch)
+	return complFilenameInner(ev.Mounts, ctx.seed, false, ch)
 }
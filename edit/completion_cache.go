@@ -0,0 +1,103 @@
+package edit
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+)
+
+// completionCached implements edit:completion:cached, which caches the outputs
+// of an expensive producer in the daemon, so that completers that run commands
+// like `kubectl get pods` stay fast and share results across sessions.
+//
+// The key can be any value; non-string keys are keyed by their kind and
+// representation, so a list like [kubectl get pods $context] works as expected
+// and does not collide with a string that looks like it. Byte outputs of
+// the producer are split into lines and cached as strings, like the outputs of
+// completers. If the producer outputs a value that is not a string, such as a
+// complex candidate, the outputs are passed through without being cached. When
+// the daemon is not available, the producer is always called.
+func completionCached(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		key      types.Value
+		ttl      float64
+		producer eval.Fn
+	)
+	ec.ScanArgs(args, &key, &ttl, &producer)
+	eval.TakeNoOpt(opts)
+
+	keyString := completionCacheKey(key)
+	daemon := ec.DaemonClient
+	out := ec.OutputChan()
+
+	if daemon != nil {
+		values, found, err := daemon.CachedCompletion(keyString)
+		if err != nil {
+			logger.Println("completion cache lookup error:", err)
+		} else if found {
+			for _, value := range values {
+				out <- types.String(value)
+			}
+			return
+		}
+	}
+
+	var outputs []types.Value
+	valuesCb := func(ch <-chan types.Value) {
+		for v := range ch {
+			outputs = append(outputs, v)
+		}
+	}
+	var lines []types.Value
+	bytesCb := func(r *os.File) {
+		buffered := bufio.NewReader(r)
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				lines = append(lines, types.String(strings.TrimSuffix(line, "\n")))
+			}
+			if err != nil {
+				if err != io.EOF {
+					logger.Println("error on reading:", err)
+				}
+				break
+			}
+		}
+	}
+	err := ec.PCaptureOutputInner(producer, eval.NoArgs, eval.NoOpts, valuesCb, bytesCb)
+	maybeThrow(err)
+	outputs = append(outputs, lines...)
+
+	cacheable := daemon != nil
+	values := make([]string, len(outputs))
+	for i, output := range outputs {
+		out <- output
+		if s, ok := output.(types.String); ok {
+			values[i] = string(s)
+		} else {
+			cacheable = false
+		}
+	}
+	if cacheable {
+		err := daemon.CacheCompletion(keyString, values,
+			time.Duration(ttl*float64(time.Second)))
+		if err != nil {
+			logger.Println("completion cache store error:", err)
+		}
+	}
+}
+
+// completionCacheKey returns the key used in the daemon for a key value. The
+// kind of the value is prepended, so that values of different kinds with the
+// same representation do not share a key.
+func completionCacheKey(key types.Value) string {
+	if s, ok := key.(types.String); ok {
+		return "string:" + string(s)
+	}
+	return key.Kind() + ":" + key.Repr(types.NoPretty)
+}
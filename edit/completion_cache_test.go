@@ -0,0 +1,52 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+func TestCompletionCached(t *testing.T) {
+	// Without a daemon, the producer is called every time.
+	eval.RunTests(t, []eval.Test{
+		eval.NewTest(`edit:completion:cached k 60 { put a b }`).
+			WantOutStrings("a", "b"),
+		eval.NewTest(`edit:completion:cached k 60 { echo "a\nb" }`).
+			WantOutStrings("a", "b"),
+		eval.NewTest(`n = 0
+		              f = { n = (+ $n 1); put $n }
+		              edit:completion:cached [k $n] 60 $f
+		              edit:completion:cached [k $n] 60 $f`).
+			WantOutStrings("1", "2"),
+		eval.NewTest(`edit:completion:cached k 60 { fail bad }`).WantAnyErr(),
+		eval.NewTest(`edit:completion:cached k 60`).WantAnyErr(),
+	}, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["edit:completion"+eval.NsSuffix] = vartypes.NewRo(eval.Ns{
+			"cached" + eval.FnSuffix: vartypes.NewRo(
+				&eval.BuiltinFn{"edit:completion:cached", completionCached}),
+		})
+		return ev
+	})
+}
+
+func TestCompletionCacheKey(t *testing.T) {
+	list := types.MakeList(types.String("a"), types.String("b"))
+	for _, test := range []struct {
+		a, b types.Value
+	}{
+		{types.String("[a b]"), list},
+		{types.String("a"), types.MakeList(types.String("a"))},
+	} {
+		if completionCacheKey(test.a) == completionCacheKey(test.b) {
+			t.Errorf("%s and %s have the same key %q",
+				test.a.Repr(types.NoPretty), test.b.Repr(types.NoPretty),
+				completionCacheKey(test.a))
+		}
+	}
+	if k1, k2 := completionCacheKey(list), completionCacheKey(list); k1 != k2 {
+		t.Errorf("keys of the same list differ: %q and %q", k1, k2)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/elves/elvish/edit/ui"
 	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/parse"
 	"github.com/elves/elvish/util"
 )
 
@@ -170,13 +171,22 @@ func (c *completion) next(cycle bool) {
 }
 
 func startCompletionInner(ed *Editor, acceptPrefix bool) {
-	node := findLeafNode(ed.chunk, ed.dot)
-	if node == nil {
-		return
+	var (
+		node      parse.Node
+		completer string
+		complSpec *complSpec
+		err       error
+	)
+	if ed.customPrompt != nil {
+		completer, complSpec = ed.customPrompt.complete(ed.buffer, ed.dot)
+	} else {
+		node = findLeafNode(ed.chunk, ed.dot)
+		if node == nil {
+			return
+		}
+		completer, complSpec, err = complete(node, ed.evaler)
 	}
 
-	completer, complSpec, err := complete(node, ed.evaler)
-
 	if err != nil {
 		ed.addTip("%v", err)
 		// We don't show the full stack trace. To make debugging still possible,
@@ -261,16 +271,42 @@ func (c *completion) maxWidth(lo, hi int) int {
 	return width
 }
 
+// ListRender renders the candidates. When the selected candidate gets
+// abbreviated, its full text is shown on an additional last line, for which
+// the candidates make room if they would use up all the lines.
 func (c *completion) ListRender(width, maxHeight int) *ui.Buffer {
+	b, abbreviated := c.listRender(width, maxHeight)
+	if !abbreviated {
+		return b
+	}
+	if len(b.Lines) >= maxHeight {
+		if maxHeight <= 2 {
+			return b
+		}
+		b, abbreviated = c.listRender(width, maxHeight-1)
+		if !abbreviated {
+			return b
+		}
+	}
+	detail := ui.NewBuffer(width)
+	detail.WriteString(util.TrimWcwidth(c.selectedCandidate().menu.Text, width),
+		styleForCompletion.String())
+	b.Extend(detail, false)
+	return b
+}
+
+// listRender renders the candidates, and returns whether the selected one
+// gets abbreviated.
+func (c *completion) listRender(width, maxHeight int) (*ui.Buffer, bool) {
 	b := ui.NewBuffer(width)
 	cands := c.filtered
 	if len(cands) == 0 {
 		b.WriteString(util.TrimWcwidth("(no result)", width), "")
-		return b
+		return b, false
 	}
 	if maxHeight <= 1 || width <= 2 {
 		b.WriteString(util.TrimWcwidth("(terminal too small)", width), "")
-		return b
+		return b, false
 	}
 
 	// Reserve the the rightmost row as margins.
@@ -309,6 +345,7 @@ func (c *completion) ListRender(width, maxHeight int) *ui.Buffer {
 	var i, j int
 	remainedWidth := width
 	trimmed := false
+	abbreviated := false
 	// Show the results in columns, until width is exceeded.
 	for i = first; i < len(cands); i += height {
 		// Determine the width of the column (without the margin)
@@ -331,10 +368,12 @@ func (c *completion) ListRender(width, maxHeight int) *ui.Buffer {
 			} else {
 				col.WriteSpaces(completionColMarginLeft, styleForCompletion.String())
 				s := ui.JoinStyles(styleForCompletion, cands[j].menu.Styles)
+				text := cands[j].display(colWidth)
 				if j == c.selected {
 					s = append(s, styleForSelectedCompletion.String())
+					abbreviated = text != cands[j].menu.Text
 				}
-				col.WriteString(util.ForceWcwidth(cands[j].menu.Text, colWidth), s.String())
+				col.WriteString(util.ForceWcwidth(text, colWidth), s.String())
 				col.WriteSpaces(completionColMarginRight, styleForCompletion.String())
 				if !trimmed {
 					c.lastShownInFull = j
@@ -360,7 +399,7 @@ func (c *completion) ListRender(width, maxHeight int) *ui.Buffer {
 		b.ExtendRight(col, 0)
 		remainedWidth = 0
 	}
-	return b
+	return b, abbreviated
 }
 
 func (c *completion) changeFilter(f string) {
@@ -0,0 +1,86 @@
+package edit
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/edit/ui"
+)
+
+func bufferTexts(b *ui.Buffer) []string {
+	var texts []string
+	for _, line := range b.Lines {
+		var buf bytes.Buffer
+		for _, cell := range line {
+			buf.WriteString(cell.Text)
+		}
+		texts = append(texts, buf.String())
+	}
+	return texts
+}
+
+var completionListRenderTests = []struct {
+	cands    []*candidate
+	selected int
+	want     []string
+}{
+	// Candidates that fit are shown in full.
+	{[]*candidate{
+		{code: "a/b", menu: ui.Unstyled("a/b"), path: true},
+	}, 0, []string{" a/b "}},
+	// Paths that are too wide are abbreviated in the middle, and the full path
+	// of the selected candidate is shown on the last line.
+	{[]*candidate{
+		{code: "src/elvish/edit/ui/a", menu: ui.Unstyled("src/elvish/edit/ui/a"), path: true},
+		{code: "src/elvish/edit/ui/b", menu: ui.Unstyled("src/elvish/edit/ui/b"), path: true},
+	}, 1, []string{
+		" src/…/edit/ui/a   ",
+		" src/…/edit/ui/b   ",
+		"src/elvish/edit/ui/b",
+	}},
+	// Other candidates are trimmed, and their full text is not shown.
+	{[]*candidate{
+		{code: "src/elvish/edit/ui/a", menu: ui.Unstyled("src/elvish/edit/ui/a")},
+	}, 0, []string{" src/elvish/edit/u "}},
+	// No line is reserved when the selected candidate is not abbreviated.
+	{[]*candidate{
+		{code: "src/elvish/edit/ui/a", menu: ui.Unstyled("src/elvish/edit/ui/a")},
+		{code: "b", menu: ui.Unstyled("b")},
+		{code: "c", menu: ui.Unstyled("c")},
+		{code: "d", menu: ui.Unstyled("d")},
+		{code: "e", menu: ui.Unstyled("e")},
+	}, 1, []string{
+		" src/elvish/edit/u ",
+		" b                 ",
+		" c                 ",
+		" d                 ",
+		" e                 ",
+	}},
+	// Otherwise, the candidates make room for the full text of the selected
+	// one when they would use up all the lines.
+	{[]*candidate{
+		{code: "src/elvish/edit/ui/a", menu: ui.Unstyled("src/elvish/edit/ui/a"), path: true},
+		{code: "b", menu: ui.Unstyled("b")},
+		{code: "c", menu: ui.Unstyled("c")},
+		{code: "d", menu: ui.Unstyled("d")},
+		{code: "e", menu: ui.Unstyled("e")},
+	}, 0, []string{
+		" src/…/edit/ui/a   ",
+		" b                 ",
+		" c                 ",
+		" d                 ",
+		"src/elvish/edit/ui/a",
+	}},
+}
+
+func TestCompletionListRender(t *testing.T) {
+	for _, test := range completionListRenderTests {
+		c := &completion{filtered: test.cands, selected: test.selected}
+		got := bufferTexts(c.ListRender(20, 5))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ListRender with %d candidates => %q, want %q",
+				len(test.cands), got, test.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package edit
+
+import (
+	"errors"
+
+	"github.com/elves/elvish/edit/ui"
+)
+
+// Reading values with a custom prompt, used by builtins that ask the user for
+// input.
+
+var errEditorActive = errors.New("cannot prompt while the editor is active")
+
+// customPrompt replaces the prompt and the completion of ReadLine.
+type customPrompt struct {
+	prompt     string
+	completeFn func(seed string) []string
+}
+
+// Prompt reads a line with the given prompt instead of the usual ones. The
+// input is not treated as code: it is neither highlighted nor completed as
+// code, and the readline hooks are not called. If complete is not nil, it
+// supplies the completion candidates.
+func (ed *Editor) Prompt(prompt string, complete func(seed string) []string) (string, error) {
+	if ed.Active() {
+		return "", errEditorActive
+	}
+	ed.customPrompt = &customPrompt{prompt, complete}
+	defer func() { ed.customPrompt = nil }()
+	return ed.ReadLine()
+}
+
+func (cp *customPrompt) content() []*ui.Styled {
+	s := ui.Unstyled(cp.prompt)
+	return []*ui.Styled{&s}
+}
+
+// complete finds the candidates for the text before the dot. It returns an
+// empty completer name when there is no completion function.
+func (cp *customPrompt) complete(buffer string, dot int) (string, *complSpec) {
+	if cp.completeFn == nil {
+		return "", nil
+	}
+	var candidates []*candidate
+	for _, s := range cp.completeFn(buffer[:dot]) {
+		candidates = append(candidates, noQuoteCandidate(s).cook(0))
+	}
+	return "prompt", &complSpec{0, dot, candidates}
+}
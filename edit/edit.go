@@ -45,12 +45,29 @@ type Editor struct {
 
 	historyFuser *history.Fuser
 	historyMutex sync.RWMutex
+	// The sequence number of the last line added to the command history, or 0
+	// if it was not added, and the working directory when it was read. Guarded
+	// by historyMutex.
+	lastCmdSeq int
+	lastCmdDir string
 
 	// notifyPort is a write-only port that turns data written to it into editor
 	// notifications.
 	notifyPort *eval.Port
 	// notifyRead is the read end of notifyPort.File.
 	notifyRead *os.File
+	// notifyCh is written to when a notification is added, so that ReadLine
+	// shows notifications from other goroutines, like those of background
+	// jobs, right away.
+	notifyCh chan struct{}
+
+	// customPrompt is set when reading a line for Prompt.
+	customPrompt *customPrompt
+
+	// How long the last command took and the error it caused, as reported by
+	// AfterCommand.
+	lastCmdDuration time.Duration
+	lastCmdErr      error
 
 	editorState
 }
@@ -63,6 +80,7 @@ type editorState struct {
 
 	notifications []string
 	tips          []string
+	watchLines    []string
 
 	buffer string
 	dot    int
@@ -105,6 +123,7 @@ func NewEditor(in *os.File, out *os.File, sigs chan os.Signal, ev *eval.Evaler)
 
 		bindings:  makeBindings(),
 		variables: makeVariables(),
+		notifyCh:  make(chan struct{}, 1),
 	}
 
 	notifyChan := make(chan types.Value)
@@ -173,6 +192,17 @@ func (ed *Editor) ActiveMutex() *sync.Mutex {
 	return &ed.activeMutex
 }
 
+// RestoreTerminal restores the terminal to the state before the Editor became
+// active. It does nothing if the Editor is not active.
+func (ed *Editor) RestoreTerminal() error {
+	ed.activeMutex.Lock()
+	defer ed.activeMutex.Unlock()
+	if !ed.active || ed.restoreTerminal == nil {
+		return nil
+	}
+	return ed.restoreTerminal()
+}
+
 func (ed *Editor) Evaler() *eval.Evaler {
 	return ed.evaler
 }
@@ -194,9 +224,31 @@ func (ed *Editor) Notify(format string, args ...interface{}) {
 	ed.notificationMutex.Lock()
 	defer ed.notificationMutex.Unlock()
 	ed.notifications = append(ed.notifications, fmt.Sprintf(format, args...))
+	select {
+	case ed.notifyCh <- struct{}{}:
+	default:
+	}
 }
 
 func (ed *Editor) refresh(fullRefresh bool, addErrorsToTips bool) error {
+	if ed.customPrompt != nil {
+		// The input to a custom prompt is not code.
+		ed.chunk, ed.parseErrorAtEnd = nil, false
+		ed.styling = &highlight.Styling{}
+	} else {
+		ed.parseAndHighlight(addErrorsToTips)
+	}
+
+	// Render onto a buffer.
+	termHeight, width := sys.GetWinsize(ed.out)
+	height := min(termHeight, ed.maxHeight(termHeight))
+	er := &editorRenderer{&ed.editorState, height, ed.maxListingHeight(termHeight), nil}
+	buf := ui.Render(er, width)
+	return ed.writer.CommitBuffer(er.bufNoti, buf, fullRefresh)
+}
+
+// parseAndHighlight parses, highlights and compiles the buffer.
+func (ed *Editor) parseAndHighlight(addErrorsToTips bool) {
 	src := ed.buffer
 	// Parse the current line
 	n, err := parse.Parse("[interactive]", src)
@@ -223,20 +275,13 @@ func (ed *Editor) refresh(fullRefresh bool, addErrorsToTips bool) error {
 		ctx := err.(*eval.CompilationError).Context
 		ed.styling.Add(ctx.Begin, ctx.End, styleForCompilerError.String())
 	}
-
-	// Render onto a buffer.
-	height, width := sys.GetWinsize(ed.out)
-	height = min(height, ed.maxHeight())
-	er := &editorRenderer{&ed.editorState, height, nil}
-	buf := ui.Render(er, width)
-	return ed.writer.CommitBuffer(er.bufNoti, buf, fullRefresh)
 }
 
 func atEnd(e error, n int) bool {
 	switch e := e.(type) {
 	case *eval.CompilationError:
 		return e.Context.Begin == n
-	case *parse.Error:
+	case *parse.MultiError:
 		for _, entry := range e.Entries {
 			if entry.Context.Begin != n {
 				return false
@@ -300,7 +345,9 @@ func (ed *Editor) finishReadLine() error {
 	line := ed.buffer
 	ed.editorState = editorState{}
 
-	callHooks(ed.evaler, ed.afterReadLine(), types.String(line))
+	if ed.customPrompt == nil {
+		callHooks(ed.evaler, ed.afterReadLine(), types.String(line))
+	}
 
 	return util.Errors(errRefresh, errRestore)
 }
@@ -329,31 +376,41 @@ func (ed *Editor) ReadLine() (string, error) {
 
 	fullRefresh := false
 
-	callHooks(ed.evaler, ed.beforeReadLine())
+	if ed.customPrompt == nil {
+		callHooks(ed.evaler, ed.beforeReadLine())
+		ed.updateWatches()
+	}
 
 	promptUpdater := prompt.NewUpdater(prompt.Prompt)
 	rpromptUpdater := prompt.NewUpdater(prompt.Rprompt)
 
 MainLoop:
 	for {
-		promptCh := promptUpdater.Update(ed)
-		rpromptCh := rpromptUpdater.Update(ed)
-		promptTimeout := prompt.MakeMaxWaitChan(ed)
-		rpromptTimeout := prompt.MakeMaxWaitChan(ed)
-
-		select {
-		case ed.promptContent = <-promptCh:
-			logger.Println("prompt fetched")
-		case <-promptTimeout:
-			logger.Println("stale prompt")
-			ed.promptContent = promptUpdater.Staled
-		}
-		select {
-		case ed.rpromptContent = <-rpromptCh:
-			logger.Println("rprompt fetched")
-		case <-rpromptTimeout:
-			logger.Println("stale rprompt")
-			ed.rpromptContent = rpromptUpdater.Staled
+		// Channels for late prompts; they stay nil with a custom prompt.
+		var promptCh, rpromptCh <-chan []*ui.Styled
+		if ed.customPrompt != nil {
+			ed.promptContent = ed.customPrompt.content()
+			ed.rpromptContent = nil
+		} else {
+			promptCh = promptUpdater.Update(ed)
+			rpromptCh = rpromptUpdater.Update(ed)
+			promptTimeout := prompt.MakeMaxWaitChan(ed)
+			rpromptTimeout := prompt.MakeMaxWaitChan(ed)
+
+			select {
+			case ed.promptContent = <-promptCh:
+				logger.Println("prompt fetched")
+			case <-promptTimeout:
+				logger.Println("stale prompt")
+				ed.promptContent = promptUpdater.Staled
+			}
+			select {
+			case ed.rpromptContent = <-rpromptCh:
+				logger.Println("rprompt fetched")
+			case <-rpromptTimeout:
+				logger.Println("stale rprompt")
+				ed.rpromptContent = rpromptUpdater.Staled
+			}
 		}
 
 	refresh:
@@ -374,11 +431,16 @@ MainLoop:
 			goto refresh
 		case m := <-isExternalCh:
 			ed.isExternal = m
+		case <-ed.notifyCh:
+			goto refresh
+		case <-ed.evaler.QueuedHooksReady():
+			// Signal hooks and event handlers can't wait until the line is
+			// committed.
+			ed.evaler.RunQueuedHooks()
+			goto refresh
 		case sig := <-ed.sigs:
 			// TODO(xiaq): Maybe support customizable handling of signals
 			switch sig {
-			case syscall.SIGHUP:
-				return "", io.EOF
 			case syscall.SIGINT:
 				// Start over
 				ed.editorState = editorState{
@@ -0,0 +1,107 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/edit/tty"
+	"github.com/elves/elvish/util"
+)
+
+// Editing the buffer in an external editor.
+
+var _ = registerBuiltins("", map[string]func(*Editor){
+	"edit-command-in-editor": editCommandInEditor,
+})
+
+var errNoEditor = errors.New("no editor; set $E:VISUAL or $E:EDITOR")
+
+func editCommandInEditor(ed *Editor) {
+	err := ed.editInExternalEditor()
+	if err != nil {
+		ed.Notify("%v", err)
+	}
+}
+
+// editInExternalEditor writes the buffer to a temporary file, and runs the
+// editor in $E:VISUAL or $E:EDITOR, falling back to vi, on it with the terminal
+// restored. When the editor exits successfully, the content of the file,
+// minus the final newline most editors add, becomes the new buffer. The buffer
+// is left untouched if the editor fails.
+func (ed *Editor) editInExternalEditor() error {
+	editor := externalEditor()
+	if len(editor) == 0 {
+		return errNoEditor
+	}
+
+	// The file is in a directory of its own, so that it can have a name that
+	// editors recognize as Elvish code.
+	dir, err := ioutil.TempDir("", "elvish-edit-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "edit.elv")
+	err = ioutil.WriteFile(name, []byte(ed.buffer), 0600)
+	if err != nil {
+		return err
+	}
+
+	errRun := ed.suspend(func() error {
+		cmd := exec.Command(editor[0], append(editor[1:], name)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = ed.in, ed.out, os.Stderr
+		ed.evaler.SyncExports()
+		return cmd.Run()
+	})
+	if errRun != nil {
+		return fmt.Errorf("editor: %v", errRun)
+	}
+
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	ed.buffer = strings.TrimSuffix(strings.TrimSuffix(string(content), "\n"), "\r")
+	ed.dot = len(ed.buffer)
+	return nil
+}
+
+// suspend gives the terminal back to other programs while f runs, and sets it
+// up for the editor again afterwards. It must be called while the editor is
+// reading a line.
+func (ed *Editor) suspend(f func() error) error {
+	ed.reader.Stop()
+	// Leave the current rendering alone, and start the program on a new line.
+	ed.out.WriteString("\n")
+	errRestore := ed.restoreTerminal()
+
+	err := f()
+
+	restoreTerminal, errSetup := tty.Setup(ed.in, ed.out)
+	if errSetup == nil {
+		ed.restoreTerminal = restoreTerminal
+	} else if restoreTerminal != nil {
+		restoreTerminal()
+	}
+	ed.writer.ResetCurrentBuffer()
+	ed.reader.Start()
+	return util.Errors(err, errRestore, errSetup)
+}
+
+// externalEditor returns the command line of the external editor.
+func externalEditor() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if s := os.Getenv(name); s != "" {
+			return strings.Fields(s)
+		}
+	}
+	if _, err := exec.LookPath("vi"); err == nil {
+		return []string{"vi"}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// +build !windows,!plan9
+
+package edit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/kr/pty"
+)
+
+type externalEditorTest struct {
+	editor   string
+	buffer   string
+	wantBuf  string
+	wantNoti bool
+}
+
+var externalEditorTests = []externalEditorTest{
+	// The final newline written by the editor is removed.
+	{"sed -i s/foo/bar/", "echo foo", "echo bar", false},
+	// Multi-line buffers survive.
+	{"sed -i s/a/b/", "echo a\necho a", "echo b\necho b", false},
+	// The buffer is kept when the editor fails.
+	{"false", "echo foo", "echo foo", true},
+}
+
+func TestEditCommandInEditor(t *testing.T) {
+	master, tty, err := pty.Open()
+	if err != nil {
+		panic(err)
+	}
+	defer master.Close()
+	defer tty.Close()
+
+	ev := eval.NewEvaler()
+	defer ev.Close()
+	ed := NewEditor(tty, tty, nil, ev)
+	defer ed.Close()
+
+	visual, hasVisual := os.LookupEnv("VISUAL")
+	defer func() {
+		if hasVisual {
+			os.Setenv("VISUAL", visual)
+		} else {
+			os.Unsetenv("VISUAL")
+		}
+	}()
+
+	// The file has a name that editors recognize as Elvish code.
+	dir, err := ioutil.TempDir("", "elvish-test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "editor")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\nbasename \"$1\" > \"$1\"\n"), 0700)
+	if err != nil {
+		panic(err)
+	}
+	tests := append(externalEditorTests,
+		externalEditorTest{script, "", "edit.elv", false})
+
+	for _, test := range tests {
+		os.Setenv("VISUAL", test.editor)
+		err := ed.startReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ed.reader.Start()
+		ed.buffer, ed.dot = test.buffer, 0
+		ed.notifications = nil
+
+		editCommandInEditor(ed)
+
+		if ed.buffer != test.wantBuf {
+			t.Errorf("editor %q changed buffer to %q, want %q",
+				test.editor, ed.buffer, test.wantBuf)
+		}
+		if !test.wantNoti && ed.dot != len(ed.buffer) {
+			t.Errorf("dot is %d, want end of buffer", ed.dot)
+		}
+		if hasNoti := len(ed.notifications) > 0; hasNoti != test.wantNoti {
+			t.Errorf("editor %q: notifications %q", test.editor, ed.notifications)
+		}
+		ed.reader.Stop()
+		ed.restoreTerminal()
+		ed.active = false
+	}
+}
@@ -22,7 +22,7 @@ func goodFormHead(head string, ed *Editor) bool {
 		return true
 	} else if util.DontSearch(head) {
 		// XXX don't stat twice
-		return util.IsExecutable(head) || isDir(head)
+		return util.FindExecutable("", head) != "" || isDir(head)
 	} else {
 		ev := ed.evaler
 		explode, ns, name := eval.ParseVariable(head)
@@ -32,7 +32,11 @@ func goodFormHead(head string, ed *Editor) bool {
 				if ev.Builtin[name+eval.FnSuffix] != nil || ev.Global[name+eval.FnSuffix] != nil {
 					return true
 				}
-			case "e":
+			case "builtin":
+				if eval.IsBuiltinSpecial[name] || ev.Builtin[name+eval.FnSuffix] != nil {
+					return true
+				}
+			case "e", "external":
 				if ed.isExternal[name] {
 					return true
 				}
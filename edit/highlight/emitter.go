@@ -52,13 +52,25 @@ func (e *Emitter) form(n *parse.Form) {
 					e.AddStyling(a.Begin(), a.End(), styleForSep[argText])
 				}
 			}
+		case "while":
+			if len(n.Args) >= 3 && n.Args[2].SourceText() == "else" {
+				a := n.Args[2]
+				e.AddStyling(a.Begin(), a.End(), styleForSep["else"])
+			}
 		case "for":
-			if len(n.Args) >= 1 && len(n.Args[0].Indexings) > 0 {
-				v := n.Args[0].Indexings[0].Head
-				e.AddStyling(v.Begin(), v.End(), styleForGoodVariable.String())
+			// There are two variables if the third argument is not a lambda.
+			nvars := 1
+			if len(n.Args) >= 3 && !isLambda(n.Args[2]) {
+				nvars = 2
 			}
-			if len(n.Args) >= 4 && n.Args[3].SourceText() == "else" {
-				a := n.Args[3]
+			for i := 0; i < nvars && i < len(n.Args); i++ {
+				if len(n.Args[i].Indexings) > 0 {
+					v := n.Args[i].Indexings[0].Head
+					e.AddStyling(v.Begin(), v.End(), styleForGoodVariable.String())
+				}
+			}
+			if len(n.Args) >= nvars+3 && n.Args[nvars+2].SourceText() == "else" {
+				a := n.Args[nvars+2]
 				e.AddStyling(a.Begin(), a.End(), styleForSep["else"])
 			}
 		case "try":
@@ -90,6 +102,11 @@ func (e *Emitter) form(n *parse.Form) {
 	}
 }
 
+func isLambda(n *parse.Compound) bool {
+	return len(n.Indexings) == 1 && len(n.Indexings[0].Indicies) == 0 &&
+		n.Indexings[0].Head.Type == parse.Lambda
+}
+
 func (e *Emitter) formHead(n *parse.Compound) {
 	head, err := eval.PurelyEvalCompound(n)
 	st := ui.Styles{}
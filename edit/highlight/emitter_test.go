@@ -73,6 +73,13 @@ var formTests = []emitTests{
 		{0, 3, styleForGoodCommand.String()},
 		{4, 5, styleForGoodVariable.String()},
 		{13, 17, styleForSep["else"]}}},
+	// Highlighting two variables and "else".
+	//0123456789012345678901234567
+	{"for k v [&] { } else { }", []styling{
+		{0, 3, styleForGoodCommand.String()},
+		{4, 5, styleForGoodVariable.String()},
+		{6, 7, styleForGoodVariable.String()},
+		{16, 20, styleForSep["else"]}}},
 
 	// "try".
 	// Highlighting except-variable.
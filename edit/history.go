@@ -3,18 +3,22 @@ package edit
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/elves/elvish/edit/history"
 	"github.com/elves/elvish/edit/ui"
 	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/store/storedefs"
 )
 
 // Command history mode.
 
 var _ = registerBuiltins("history", map[string]func(*Editor){
 	"start":              historyStart,
+	"substring-start":    historySubstringStart,
 	"up":                 wrapHistoryBuiltin(historyUp),
 	"down":               wrapHistoryBuiltin(historyDown),
 	"down-or-quit":       wrapHistoryBuiltin(historyDownOrQuit),
@@ -31,6 +35,9 @@ func (*hist) Binding(m map[string]vartypes.Variable, k ui.Key) eval.Fn {
 }
 
 func (h *hist) ModeLine() ui.Renderer {
+	if h.Substring() {
+		return modeLineRenderer{fmt.Sprintf(" HISTORY SUBSTRING #%d ", h.CurrentSeq()), ""}
+	}
 	return modeLineRenderer{fmt.Sprintf(" HISTORY #%d ", h.CurrentSeq()), ""}
 }
 
@@ -39,8 +46,20 @@ func historyStart(ed *Editor) {
 		ed.Notify("history offline")
 		return
 	}
-	prefix := ed.buffer[:ed.dot]
-	walker := ed.historyFuser.Walker(prefix)
+	startHistory(ed, ed.historyFuser.Walker(ed.buffer[:ed.dot]))
+}
+
+// historySubstringStart is like historyStart, but walks through commands that
+// contain the text before the cursor anywhere, highlighting the match.
+func historySubstringStart(ed *Editor) {
+	if ed.historyFuser == nil {
+		ed.Notify("history offline")
+		return
+	}
+	startHistory(ed, ed.historyFuser.SubstringWalker(ed.buffer[:ed.dot]))
+}
+
+func startHistory(ed *Editor, walker *history.Walker) {
 	hist := hist{walker}
 	_, _, err := hist.Prev()
 	if err == nil {
@@ -100,21 +119,65 @@ func historyDefault(ed *Editor, hist *hist) {
 }
 
 func (ed *Editor) appendHistory(line string) {
+	if ed.daemon == nil || ed.historyFuser == nil {
+		return
+	}
+	ed.historyMutex.Lock()
 	// TODO: should have a user variable to control the behavior
 	// Do not add command leading by space into history. This is
 	// useful for confidential operations.
 	if strings.HasPrefix(line, " ") {
+		ed.lastCmdSeq = 0
+		ed.historyMutex.Unlock()
 		return
 	}
+	ed.lastCmdDir, _ = os.Getwd()
+	go func() {
+		seq, err := ed.historyFuser.AddCmd(line)
+		ed.lastCmdSeq = seq
+		ed.historyMutex.Unlock()
+		if err != nil {
+			logger.Printf("Failed to AddCmd %q: %v", line, err)
+		}
+	}()
+}
 
-	if ed.daemon != nil && ed.historyFuser != nil {
-		ed.historyMutex.Lock()
-		go func() {
-			err := ed.historyFuser.AddCmd(line)
-			ed.historyMutex.Unlock()
-			if err != nil {
-				logger.Printf("Failed to AddCmd %q: %v", line, err)
+// recordCmdInfo records how the last line added to the command history was
+// run.
+func (ed *Editor) recordCmdInfo(duration time.Duration, err error) {
+	if ed.daemon == nil || ed.historyFuser == nil {
+		return
+	}
+	ed.historyMutex.Lock()
+	go func() {
+		defer ed.historyMutex.Unlock()
+		if ed.lastCmdSeq == 0 {
+			return
+		}
+		info := storedefs.CmdInfo{
+			Dir: ed.lastCmdDir, Time: time.Now().Add(-duration),
+			Duration: duration, Status: cmdStatus(err)}
+		if err := ed.daemon.SetCmdInfo(ed.lastCmdSeq, info); err != nil {
+			logger.Printf("Failed to SetCmdInfo %d: %v", ed.lastCmdSeq, err)
+		}
+		ed.lastCmdSeq = 0
+	}()
+}
+
+// cmdStatus converts the error caused by a command to an exit status, as
+// documented for storedefs.CmdInfo.
+func cmdStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exc, ok := err.(*eval.Exception); ok {
+		if exit, ok := exc.Cause.(eval.ExternalCmdExit); ok {
+			if exit.Exited() {
+				return exit.ExitStatus()
+			} else if exit.Signaled() {
+				return 128 + int(exit.Signal())
 			}
-		}()
+		}
 	}
+	return 1
 }
@@ -29,16 +29,18 @@ func NewFuser(store Store) (*Fuser, error) {
 	}, nil
 }
 
-func (f *Fuser) AddCmd(cmd string) error {
+// AddCmd adds a command to both the storage and the per-session history, and
+// returns its sequence number in the storage.
+func (f *Fuser) AddCmd(cmd string) (int, error) {
 	f.Lock()
 	defer f.Unlock()
 	seq, err := f.store.AddCmd(cmd)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	f.cmds = append(f.cmds, cmd)
 	f.seqs = append(f.seqs, seq)
-	return nil
+	return seq, nil
 }
 
 func (f *Fuser) AllCmds() ([]string, error) {
@@ -60,3 +62,9 @@ func (f *Fuser) Walker(prefix string) *Walker {
 	defer f.RUnlock()
 	return NewWalker(f.store, f.storeUpper, f.cmds, f.seqs, prefix)
 }
+
+func (f *Fuser) SubstringWalker(text string) *Walker {
+	f.RLock()
+	defer f.RUnlock()
+	return NewSubstringWalker(f.store, f.storeUpper, f.cmds, f.seqs, text)
+}
@@ -26,7 +26,7 @@ func TestFuser(t *testing.T) {
 	// adding the command.
 	mockError := errors.New("mock error")
 	fuserStore.oneOffError = mockError
-	err = f.AddCmd("haha")
+	_, err = f.AddCmd("haha")
 	if err != mockError {
 		t.Errorf("AddCmd doesn't forward backend error")
 	}
@@ -1,11 +1,17 @@
 package history
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/elves/elvish/store/storedefs"
+)
 
 // mockStore is an implementation of the Store interface that can be used for
 // testing.
 type mockStore struct {
 	cmds []string
+	// The number of calls to PrevCmds.
+	prevCmdsCalls int
 
 	oneOffError error
 }
@@ -46,3 +52,18 @@ func (s *mockStore) PrevCmd(upto int, prefix string) (int, string, error) {
 	}
 	return -1, "", ErrEndOfHistory
 }
+
+func (s *mockStore) PrevCmds(upto, n int) ([]storedefs.Cmd, error) {
+	s.prevCmdsCalls++
+	if s.oneOffError != nil {
+		return nil, s.error()
+	}
+	if upto < 0 || upto > len(s.cmds) {
+		upto = len(s.cmds)
+	}
+	var cmds []storedefs.Cmd
+	for i := upto - 1; i >= 0 && len(cmds) < n; i-- {
+		cmds = append(cmds, storedefs.Cmd{Seq: i, Text: s.cmds[i]})
+	}
+	return cmds, nil
+}
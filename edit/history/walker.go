@@ -9,14 +9,20 @@ import (
 
 var ErrEndOfHistory = errors.New("end of history")
 
+// The number of commands that substring walkers fetch from the storage backend
+// at a time.
+const substringBatchSize = 256
+
 // Walker is used for walking through history entries with a given (possibly
-// empty) prefix, skipping duplicates entries.
+// empty) prefix, skipping duplicates entries. A substring walker matches
+// entries that contain the prefix anywhere instead.
 type Walker struct {
 	store       Store
 	storeUpper  int
 	sessionCmds []string
 	sessionSeqs []int
 	prefix      string
+	substring   bool
 
 	// The next element to fetch from the session history. If equal to -1, the
 	// next element comes from the storage backend.
@@ -28,18 +34,43 @@ type Walker struct {
 	stack   []string
 	seq     []int
 	inStack map[string]bool
+
+	// Commands fetched from the storage backend by a substring walker but not
+	// yet examined, the latest first.
+	fetched []storedefs.Cmd
 }
 
 func NewWalker(store Store, upper int, cmds []string, seqs []int, prefix string) *Walker {
-	return &Walker{store, upper, cmds, seqs, prefix,
-		len(cmds) - 1, 0, nil, nil, map[string]bool{}}
+	return &Walker{store, upper, cmds, seqs, prefix, false,
+		len(cmds) - 1, 0, nil, nil, map[string]bool{}, nil}
 }
 
-// Prefix returns the prefix of the commands that the walker walks through.
+// NewSubstringWalker is like NewWalker, but the returned walker walks through
+// entries that contain the given text, not only those that start with it.
+func NewSubstringWalker(store Store, upper int, cmds []string, seqs []int, text string) *Walker {
+	w := NewWalker(store, upper, cmds, seqs, text)
+	w.substring = true
+	return w
+}
+
+// Prefix returns the prefix of the commands that the walker walks through. For
+// a substring walker, it is the text that the commands contain.
 func (w *Walker) Prefix() string {
 	return w.prefix
 }
 
+// Substring returns whether the walker matches substrings instead of prefixes.
+func (w *Walker) Substring() bool {
+	return w.substring
+}
+
+func (w *Walker) matches(cmd string) bool {
+	if w.substring {
+		return strings.Contains(cmd, w.prefix)
+	}
+	return strings.HasPrefix(cmd, w.prefix)
+}
+
 // CurrentSeq returns the sequence number of the current entry.
 func (w *Walker) CurrentSeq() int {
 	if len(w.seq) > 0 && w.top <= len(w.seq) && w.top > 0 {
@@ -69,7 +100,7 @@ func (w *Walker) Prev() (int, string, error) {
 	for i := w.sessionIdx; i >= 0; i-- {
 		seq := w.sessionSeqs[i]
 		cmd := w.sessionCmds[i]
-		if strings.HasPrefix(cmd, w.prefix) && !w.inStack[cmd] {
+		if w.matches(cmd) && !w.inStack[cmd] {
 			w.push(cmd, seq)
 			w.sessionIdx = i - 1
 			return seq, cmd, nil
@@ -82,6 +113,9 @@ func (w *Walker) Prev() (int, string, error) {
 	if len(w.seq) > 0 && seq > w.seq[len(w.seq)-1] {
 		seq = w.seq[len(w.seq)-1]
 	}
+	if w.substring {
+		return w.prevSubstring(seq)
+	}
 	for {
 		var (
 			cmd string
@@ -94,13 +128,38 @@ func (w *Walker) Prev() (int, string, error) {
 			}
 			return -1, "", err
 		}
-		if !w.inStack[cmd] {
+		if w.matches(cmd) && !w.inStack[cmd] {
 			w.push(cmd, seq)
 			return seq, cmd, nil
 		}
 	}
 }
 
+// prevSubstring finds the previous matching entry in the storage backend
+// before seq. The storage backend only supports prefix matching, so entries
+// are fetched in batches and filtered here.
+func (w *Walker) prevSubstring(seq int) (int, string, error) {
+	for {
+		if len(w.fetched) == 0 {
+			cmds, err := w.store.PrevCmds(seq, substringBatchSize)
+			if err != nil {
+				return -1, "", err
+			}
+			if len(cmds) == 0 {
+				return -1, "", ErrEndOfHistory
+			}
+			w.fetched = cmds
+		}
+		cmd := w.fetched[0]
+		w.fetched = w.fetched[1:]
+		seq = cmd.Seq
+		if w.matches(cmd.Text) && !w.inStack[cmd.Text] {
+			w.push(cmd.Text, cmd.Seq)
+			return cmd.Seq, cmd.Text, nil
+		}
+	}
+}
+
 func (w *Walker) push(cmd string, seq int) {
 	w.inStack[cmd] = true
 	w.stack = append(w.stack, cmd)
@@ -73,6 +73,24 @@ func TestWalker(t *testing.T) {
 	wantCmd(t, w.Prev, 3, "ls -a")
 	wantErr(t, w.Prev, ErrEndOfHistory)
 
+	// Substring matching, with session history.
+	walkerStore.prevCmdsCalls = 0
+	w = NewSubstringWalker(walkerStore, -1,
+		[]string{"cat a", "ls"}, []int{7, 10}, " a")
+	if !w.Substring() {
+		t.Errorf("got Substring() = false, want true")
+	}
+	wantCmd(t, w.Prev, 7, "cat a")
+	wantCmd(t, w.Prev, 5, "ls a")
+	wantCmd(t, w.Prev, 4, "echo a")
+	// "echo a" should be skipped
+	wantErr(t, w.Prev, ErrEndOfHistory)
+	// All of the storage backend fits in one batch; one more call finds that
+	// there is nothing left.
+	if walkerStore.prevCmdsCalls != 2 {
+		t.Errorf("got %d calls to PrevCmds, want 2", walkerStore.prevCmdsCalls)
+	}
+
 	// Backend error.
 	w = NewWalker(walkerStore, -1, nil, nil, "")
 	wantCmd(t, w.Prev, 5, "ls a")
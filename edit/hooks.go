@@ -3,14 +3,16 @@ package edit
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/eval/vartypes"
 )
 
-// The $le:{before,after}-readline lists that contain hooks. We might have more
-// hooks in future.
+// The $le:{before,after}-readline and $le:after-command lists that contain
+// hooks. We might have more hooks in future.
 
 var _ = RegisterVariable("before-readline", makeListVariable)
 
@@ -24,6 +26,42 @@ func (ed *Editor) afterReadLine() types.List {
 	return ed.variables["after-readline"].Get().(types.List)
 }
 
+var _ = RegisterVariable("after-command", makeListVariable)
+
+func (ed *Editor) afterCommand() types.List {
+	return ed.variables["after-command"].Get().(types.List)
+}
+
+// AfterCommand records how long a command read by the editor took and the
+// error it caused, for the rprompt segments and the command history, and calls the hooks in
+// $edit:after-command with a map containing the source code of the command,
+// its duration in seconds and its exception, which is $ok if there is none.
+func (ed *Editor) AfterCommand(src string, duration time.Duration, err error) {
+	ed.lastCmdDuration = duration
+	ed.lastCmdErr = err
+	ed.recordCmdInfo(duration, err)
+
+	var exc types.Value = eval.OK
+	if err != nil {
+		if e, ok := err.(*eval.Exception); ok {
+			exc = e
+		} else {
+			exc = &eval.Exception{Cause: err}
+		}
+	}
+	callHooks(ed.evaler, ed.afterCommand(), types.MakeMap(map[types.Value]types.Value{
+		types.String("src"): types.String(src),
+		types.String("duration"): types.String(
+			strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)),
+		types.String("error"): exc,
+	}))
+}
+
+// LastCommand returns how long the last command took and the error it caused.
+func (ed *Editor) LastCommand() (time.Duration, error) {
+	return ed.lastCmdDuration, ed.lastCmdErr
+}
+
 func makeListVariable() vartypes.Variable {
 	return vartypes.NewValidatedPtr(types.EmptyList, vartypes.ShouldBeList)
 }
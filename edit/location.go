@@ -9,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/elves/elvish/edit/ui"
-	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/eval/vartypes"
 	"github.com/elves/elvish/parse"
@@ -110,7 +109,7 @@ func makeLocationFilterPattern(s string) *regexp.Regexp {
 // Editor interface.
 
 func (loc *location) Accept(i int, ed *Editor) {
-	err := eval.Chdir(loc.filtered[i].Path, ed.daemon)
+	err := ed.evaler.Chdir(loc.filtered[i].Path)
 	if err != nil {
 		ed.Notify("%v", err)
 	}
@@ -58,7 +58,7 @@ func wrapMatcher(matcher func(s, p string) bool) eval.BuiltinFnImpl {
 			IgnoreCase bool
 			SmartCase  bool
 		}
-		eval.ScanOptsToStruct(opts, &options)
+		ec.ScanOptsToStruct(opts, &options)
 		switch {
 		case options.IgnoreCase && options.SmartCase:
 			throwf("-ignore-case and -smart-case cannot be used together")
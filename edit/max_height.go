@@ -1,22 +1,70 @@
 package edit
 
 import (
+	"errors"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/eval/vartypes"
 	"github.com/elves/elvish/util"
 )
 
+// Both max-height and max-listing-height are either a number of lines or a
+// percentage of the height of the terminal, like "40%".
+
 var _ = RegisterVariable("max-height", func() vartypes.Variable {
-	return vartypes.NewValidatedPtr(types.String("+Inf"), vartypes.ShouldBeNumber)
+	return vartypes.NewValidatedPtr(types.String("+Inf"), shouldBeHeightLimit)
+})
+
+var _ = RegisterVariable("max-listing-height", func() vartypes.Variable {
+	return vartypes.NewValidatedPtr(types.String("+Inf"), shouldBeHeightLimit)
 })
 
-func (ed *Editor) maxHeight() int {
-	f, _ := strconv.ParseFloat(string(ed.variables["max-height"].Get().(types.String)), 64)
-	if math.IsInf(f, 1) {
-		return util.MaxInt
+var errShouldBeHeightLimit = errors.New("should be non-negative number or percentage")
+
+func shouldBeHeightLimit(v types.Value) error {
+	s, ok := v.(types.String)
+	if !ok {
+		return errShouldBeHeightLimit
+	}
+	if _, ok := parseHeightLimit(string(s), 0); !ok {
+		return errShouldBeHeightLimit
+	}
+	return nil
+}
+
+// parseHeightLimit parses a height limit, resolving percentages against the
+// given terminal height. NaN and negative numbers are not valid height limits.
+func parseHeightLimit(s string, termHeight int) (int, bool) {
+	percent := strings.HasSuffix(s, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || math.IsNaN(f) || f < 0 {
+		return 0, false
 	}
-	return int(f)
+	if percent {
+		// Percentages above 100% allow no more than the whole terminal.
+		f = math.Min(f, 100) / 100 * float64(termHeight)
+	}
+	if f >= float64(util.MaxInt) {
+		return util.MaxInt, true
+	}
+	return int(f), true
+}
+
+func (ed *Editor) heightLimit(name string, termHeight int) int {
+	h, _ := parseHeightLimit(string(ed.variables[name].Get().(types.String)), termHeight)
+	return h
+}
+
+// maxHeight returns the maximum height of the whole editor.
+func (ed *Editor) maxHeight(termHeight int) int {
+	return ed.heightLimit("max-height", termHeight)
+}
+
+// maxListingHeight returns the maximum height of the listings of completion,
+// navigation, history listing and other listing modes.
+func (ed *Editor) maxListingHeight(termHeight int) int {
+	return ed.heightLimit("max-listing-height", termHeight)
 }
@@ -0,0 +1,39 @@
+package edit
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/util"
+)
+
+var parseHeightLimitTests = []struct {
+	s          string
+	termHeight int
+	want       int
+	wantOK     bool
+}{
+	{"10", 50, 10, true},
+	{"+Inf", 50, util.MaxInt, true},
+	{"40%", 50, 20, true},
+	{"100%", 7, 7, true},
+	{"x", 50, 0, false},
+	{"x%", 50, 0, false},
+	{"1e100", 50, util.MaxInt, true},
+	{"+Inf%", 50, 50, true},
+	{"200%", 50, 50, true},
+	{"NaN", 50, 0, false},
+	{"NaN%", 50, 0, false},
+	{"-1", 50, 0, false},
+	{"-Inf", 50, 0, false},
+	{"-10%", 50, 0, false},
+}
+
+func TestParseHeightLimit(t *testing.T) {
+	for _, test := range parseHeightLimitTests {
+		got, ok := parseHeightLimit(test.s, test.termHeight)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("parseHeightLimit(%q, %d) -> (%d, %v), want (%d, %v)",
+				test.s, test.termHeight, got, ok, test.want, test.wantOK)
+		}
+	}
+}
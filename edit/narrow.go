@@ -397,8 +397,8 @@ func NarrowRead(ec *eval.Frame, args []types.Value, opts map[string]types.Value)
 		},
 	}
 
-	eval.ScanArgs(args, &source, &action)
-	eval.ScanOptsToStruct(opts, &l.opts)
+	ec.ScanArgs(args, &source, &action)
+	ec.ScanOptsToStruct(opts, &l.opts)
 
 	l.opts.Bindings.IterateKey(func(k types.Value) bool {
 		key := ui.ToKey(k)
@@ -450,7 +450,7 @@ func CommandHistory(ec *eval.Frame, args []types.Value, opts map[string]types.Va
 		limit, start, end int
 	)
 
-	eval.ScanArgsVariadic(args, &rest)
+	ec.ScanArgsVariadic(args, &rest)
 	eval.TakeNoOpt(opts)
 
 	out := ec.OutputChan()
@@ -494,7 +494,7 @@ func CommandHistory(ec *eval.Frame, args []types.Value, opts map[string]types.Va
 func InsertAtDot(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 	var text types.String
 
-	eval.ScanArgs(args, &text)
+	ec.ScanArgs(args, &text)
 	eval.TakeNoOpt(opts)
 
 	ed := ec.Editor.(*Editor)
@@ -504,7 +504,7 @@ func InsertAtDot(ec *eval.Frame, args []types.Value, opts map[string]types.Value
 func ReplaceInput(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 	var text types.String
 
-	eval.ScanArgs(args, &text)
+	ec.ScanArgs(args, &text)
 	eval.TakeNoOpt(opts)
 
 	ed := ec.Editor.(*Editor)
@@ -514,7 +514,7 @@ func ReplaceInput(ec *eval.Frame, args []types.Value, opts map[string]types.Valu
 func Wordify(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 	var text types.String
 
-	eval.ScanArgs(args, &text)
+	ec.ScanArgs(args, &text)
 	eval.TakeNoOpt(opts)
 
 	out := ec.OutputChan()
@@ -162,7 +162,7 @@ var errorEmptyCwd = errors.New("current directory is empty")
 
 func initNavigation(n *navigation, ed *Editor) {
 	*n = navigation{chdir: func(dir string) error {
-		return eval.Chdir(dir, ed.daemon)
+		return ed.evaler.Chdir(dir)
 	}}
 	n.refresh()
 }
@@ -251,8 +251,6 @@ func (n *navigation) refresh() {
 }
 
 // ascend changes current directory to the parent.
-// TODO(xiaq): navigation.{ascend descend} bypasses the cd builtin. This can be
-// problematic if cd acquires more functionality (e.g. trigger a hook).
 func (n *navigation) ascend() error {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -263,7 +261,7 @@ func (n *navigation) ascend() error {
 	}
 
 	name := n.parent.selectedName()
-	err = os.Chdir("..")
+	err = n.chdir("..")
 	if err != nil {
 		return err
 	}
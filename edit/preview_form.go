@@ -0,0 +1,154 @@
+package edit
+
+import (
+	"os/exec"
+	"strconv"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/parse"
+)
+
+// Preview of the form under the cursor.
+
+var _ = registerBuiltins("", map[string]func(*Editor){
+	"preview-form": previewForm,
+})
+
+// previewForm shows, as tips, how the form under the cursor would be run: the
+// resolved command, the expanded arguments and options, the redirections and
+// the temporary assignments to environment variables. Nothing is run; parts
+// that cannot be evaluated without side effects are shown as source text.
+func previewForm(ed *Editor) {
+	form := formAtDot(ed.chunk, ed.dot)
+	if form == nil || form.Head == nil {
+		ed.addTip("no command under cursor")
+		return
+	}
+	for _, line := range describeForm(form, ed.evaler) {
+		ed.addTip("%s", line)
+	}
+}
+
+// formAtDot finds the innermost form that contains the given position.
+func formAtDot(n parse.Node, dot int) *parse.Form {
+	if n == nil {
+		return nil
+	}
+	for leaf := findLeafNode(n, dot); leaf != nil; leaf = leaf.Parent() {
+		if form := parse.GetForm(leaf); form != nil {
+			return form
+		}
+	}
+	return nil
+}
+
+func describeForm(form *parse.Form, ev *eval.Evaler) []string {
+	var lines []string
+	for _, a := range form.Assignments {
+		_, ns, name := eval.ParseVariable(a.Left.SourceText())
+		if ns == "E" {
+			lines = append(lines, "env "+name+"="+previewCompound(a.Right, ev))
+		}
+	}
+
+	head, err := ev.PurelyEvalCompound(form.Head)
+	if err != nil {
+		lines = append(lines, "command "+form.Head.SourceText()+" (not evaluated)")
+	} else {
+		lines = append(lines, "command "+parse.Quote(head)+" ("+describeFormHead(head, ev)+")")
+	}
+
+	for i, arg := range form.Args {
+		lines = append(lines, "argv["+strconv.Itoa(i+1)+"] "+previewCompound(arg, ev))
+	}
+	for _, opt := range form.Opts {
+		lines = append(lines, "option &"+previewCompound(opt.Key, ev)+"="+previewCompound(opt.Value, ev))
+	}
+	for _, redir := range form.Redirs {
+		lines = append(lines, describeRedir(redir, ev))
+	}
+	return lines
+}
+
+// previewCompound evaluates a compound purely and quotes the result, or
+// returns the source text followed by a note if that cannot be done.
+func previewCompound(cn *parse.Compound, ev *eval.Evaler) string {
+	if cn == nil {
+		return ""
+	}
+	s, err := ev.PurelyEvalCompound(cn)
+	if err != nil {
+		return cn.SourceText() + " (not evaluated)"
+	}
+	return parse.Quote(s)
+}
+
+// describeFormHead describes what a command name resolves to. It needs to be
+// kept in sync with goodFormHead.
+func describeFormHead(head string, ev *eval.Evaler) string {
+	if eval.IsBuiltinSpecial[head] {
+		return "special form"
+	}
+	explode, ns, name := eval.ParseVariable(head)
+	if !explode {
+		switch ns {
+		case "":
+			if ev.Global[name+eval.FnSuffix] != nil {
+				return "function"
+			}
+			if ev.Builtin[name+eval.FnSuffix] != nil {
+				return "builtin function"
+			}
+		case "builtin":
+			if eval.IsBuiltinSpecial[name] {
+				return "special form"
+			}
+			if ev.Builtin[name+eval.FnSuffix] != nil {
+				return "builtin function"
+			}
+			return "no such builtin"
+		case "e", "external":
+			head = name
+		default:
+			mod := ev.Global[ns+eval.NsSuffix]
+			if mod == nil {
+				mod = ev.Builtin[ns+eval.NsSuffix]
+			}
+			if mod != nil && mod.Get().(eval.Ns)[name+eval.FnSuffix] != nil {
+				return "function in module " + ns
+			}
+		}
+	}
+	path, err := exec.LookPath(head)
+	if err != nil {
+		return "external command, not found"
+	}
+	return "external command " + path
+}
+
+var redirModeSigns = map[parse.RedirMode]string{
+	parse.Read:       "<",
+	parse.Write:      ">",
+	parse.ReadWrite:  "<>",
+	parse.Append:     ">>",
+	parse.Capture:    ">>>",
+	parse.Heredoc:    "<<",
+	parse.HereString: "<<<",
+}
+
+func describeRedir(rn *parse.Redir, ev *eval.Evaler) string {
+	var fd string
+	switch {
+	case rn.Left != nil:
+		fd = previewCompound(rn.Left, ev)
+	case rn.Mode == parse.Read:
+		fd = "0"
+	default:
+		fd = "1"
+	}
+	dest := previewCompound(rn.Right, ev)
+	if rn.RightIsFd {
+		dest = "fd " + dest
+	}
+	return "redir fd " + fd + " " + redirModeSigns[rn.Mode] + " " + dest
+}
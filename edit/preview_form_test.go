@@ -0,0 +1,52 @@
+package edit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/parse"
+)
+
+var describeFormTests = []struct {
+	src  string
+	dot  int
+	want []string
+}{
+	{"E:X=1 put a'b c' &k=v $x >o 2>&1", 0, []string{
+		"env X=1",
+		"command put (builtin function)",
+		"argv[1] 'ab c'",
+		"argv[2] $x (not evaluated)",
+		"option &k=v",
+		"redir fd 1 > o",
+		"redir fd 2 > fd 1",
+	}},
+	{"echo (builtin:put x)", 8, []string{
+		"command builtin:put (builtin function)",
+		"argv[1] x",
+	}},
+	{"builtin:nosuch", 0, []string{
+		"command builtin:nosuch (no such builtin)",
+	}},
+}
+
+func TestDescribeForm(t *testing.T) {
+	ev := eval.NewEvaler()
+	defer ev.Close()
+	for _, test := range describeFormTests {
+		n, err := parse.Parse("[test]", test.src)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", test.src, err)
+		}
+		form := formAtDot(n, test.dot)
+		if form == nil {
+			t.Errorf("formAtDot(%q, %d) -> nil", test.src, test.dot)
+			continue
+		}
+		got := describeForm(form, ev)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("describeForm(%q) -> %q, want %q", test.src, got, test.want)
+		}
+	}
+}
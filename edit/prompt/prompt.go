@@ -24,6 +24,9 @@ type Editor interface {
 	Evaler() *eval.Evaler
 	Variable(string) vartypes.Variable
 	Notify(string, ...interface{})
+	// LastCommand returns how long the last command took and the error it
+	// caused, if any.
+	LastCommand() (time.Duration, error)
 }
 
 // maxSeconds is the maximum number of seconds time.Duration can represent.
@@ -70,6 +73,14 @@ func RpromptVariable() vartypes.Variable {
 		args []types.Value, opts map[string]types.Value) {
 
 		out := ec.OutputChan()
+		if ed, ok := ec.Editor.(Editor); ok {
+			for _, seg := range []*ui.Styled{StatusSegment(ed), DurationSegment(ed)} {
+				if seg != nil {
+					out <- seg
+					out <- &ui.Styled{" ", ui.Styles{}}
+				}
+			}
+		}
 		out <- &ui.Styled{rpromptStr, ui.Styles{"inverse"}}
 	}
 
@@ -0,0 +1,65 @@
+package prompt
+
+import (
+	"strconv"
+
+	"github.com/elves/elvish/edit/ui"
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/util"
+)
+
+// Segments for the rprompt that show information about the last command. They
+// are shown by the default rprompt, and are also available as
+// edit:rprompt-duration and edit:rprompt-status for custom ones.
+
+var (
+	styleForDuration = ui.Styles{"yellow"}
+	styleForStatus   = ui.Styles{"red"}
+)
+
+// DurationThresholdVariable returns a variable for
+// $edit:rprompt-duration-threshold.
+func DurationThresholdVariable() vartypes.Variable {
+	f := 5.0
+	return vartypes.NewNumber(&f)
+}
+
+// DurationThreshold extracts $edit:rprompt-duration-threshold.
+func DurationThreshold(ed Editor) float64 {
+	f, _ := strconv.ParseFloat(string(ed.Variable("rprompt-duration-threshold").Get().(types.String)), 64)
+	return f
+}
+
+// DurationSegment returns a segment showing how long the last command took, or
+// nil if it took less than $edit:rprompt-duration-threshold seconds.
+func DurationSegment(ed Editor) *ui.Styled {
+	d, _ := ed.LastCommand()
+	if d == 0 || d.Seconds() < DurationThreshold(ed) {
+		return nil
+	}
+	return &ui.Styled{"took " + util.FormatDuration(d), styleForDuration}
+}
+
+// StatusSegment returns a segment showing that the last command has failed,
+// or nil if it succeeded. When it failed because an external command exited
+// with a non-zero status or was killed by a signal, the status or signal is
+// shown as well.
+func StatusSegment(ed Editor) *ui.Styled {
+	_, err := ed.LastCommand()
+	if err == nil {
+		return nil
+	}
+	text := "✗"
+	if exc, ok := err.(*eval.Exception); ok {
+		if exit, ok := exc.Cause.(eval.ExternalCmdExit); ok {
+			if exit.Exited() {
+				text += " " + strconv.Itoa(exit.ExitStatus())
+			} else if exit.Signaled() {
+				text += " " + exit.Signal().String()
+			}
+		}
+	}
+	return &ui.Styled{text, styleForStatus}
+}
@@ -0,0 +1,79 @@
+// +build !windows,!plan9
+
+package prompt
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/edit/ui"
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+type fakeEditor struct {
+	duration time.Duration
+	err      error
+	vars     map[string]vartypes.Variable
+}
+
+func newFakeEditor(d time.Duration, err error) *fakeEditor {
+	return &fakeEditor{d, err, map[string]vartypes.Variable{
+		"rprompt-duration-threshold": DurationThresholdVariable(),
+	}}
+}
+
+func (ed *fakeEditor) Evaler() *eval.Evaler                   { return nil }
+func (ed *fakeEditor) Variable(name string) vartypes.Variable { return ed.vars[name] }
+func (ed *fakeEditor) Notify(string, ...interface{})          {}
+func (ed *fakeEditor) LastCommand() (time.Duration, error)    { return ed.duration, ed.err }
+
+var durationSegmentTests = []struct {
+	duration time.Duration
+	want     *ui.Styled
+}{
+	{0, nil},
+	{4 * time.Second, nil},
+	{5200 * time.Millisecond, &ui.Styled{"took 5.2s", styleForDuration}},
+	{63500 * time.Millisecond, &ui.Styled{"took 1m3s", styleForDuration}},
+}
+
+func TestDurationSegment(t *testing.T) {
+	for _, test := range durationSegmentTests {
+		got := DurationSegment(newFakeEditor(test.duration, nil))
+		if !eqStyled(got, test.want) {
+			t.Errorf("DurationSegment with %v => %v, want %v",
+				test.duration, got, test.want)
+		}
+	}
+}
+
+var statusSegmentTests = []struct {
+	err  error
+	want *ui.Styled
+}{
+	{nil, nil},
+	{errors.New("bad"), &ui.Styled{"✗", styleForStatus}},
+	{&eval.Exception{Cause: eval.ExternalCmdExit{WaitStatus: 1 << 8}},
+		&ui.Styled{"✗ 1", styleForStatus}},
+	{&eval.Exception{Cause: eval.ExternalCmdExit{WaitStatus: syscall.WaitStatus(syscall.SIGINT)}},
+		&ui.Styled{"✗ interrupt", styleForStatus}},
+}
+
+func TestStatusSegment(t *testing.T) {
+	for _, test := range statusSegmentTests {
+		got := StatusSegment(newFakeEditor(0, test.err))
+		if !eqStyled(got, test.want) {
+			t.Errorf("StatusSegment with %v => %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func eqStyled(a, b *ui.Styled) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Text == b.Text && a.Styles.Eq(b.Styles)
+}
@@ -1,10 +1,37 @@
 package edit
 
-import "github.com/elves/elvish/edit/prompt"
+import (
+	"github.com/elves/elvish/edit/prompt"
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+)
 
 var (
 	_ = RegisterVariable("prompt", prompt.PromptVariable)
 	_ = RegisterVariable("rprompt", prompt.RpromptVariable)
 	_ = RegisterVariable("rprompt-persistent", prompt.RpromptPersistentVariable)
 	_ = RegisterVariable("-prompts-max-wait", prompt.MaxWaitVariable)
+	_ = RegisterVariable("rprompt-duration-threshold", prompt.DurationThresholdVariable)
 )
+
+// rpromptDuration outputs the segment showing how long the last command took,
+// if it took long enough.
+func rpromptDuration(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	if seg := prompt.DurationSegment(ec.Editor.(*Editor)); seg != nil {
+		ec.OutputChan() <- seg
+	}
+}
+
+// rpromptStatus outputs the segment showing that the last command failed, if
+// it did.
+func rpromptStatus(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	if seg := prompt.StatusSegment(ec.Editor.(*Editor)); seg != nil {
+		ec.OutputChan() <- seg
+	}
+}
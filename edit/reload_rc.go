@@ -0,0 +1,43 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/store/storedefs"
+)
+
+var errRCNotUTF8 = errors.New("rc.elv is not valid UTF-8")
+
+// reloadRC evaluates rc.elv again. Its changes to variables, including
+// functions, bindings and editor configurations, are only applied when it
+// evaluates without errors, and are listed one per line, like "modified
+// edit:insert:binding[Ctrl-L]".
+func reloadRC(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	dataDir, err := storedefs.EnsureDataDir()
+	maybeThrow(err)
+	path := filepath.Join(dataDir, "rc.elv")
+	code, err := ioutil.ReadFile(path)
+	maybeThrow(err)
+	if !utf8.Valid(code) {
+		throw(errRCNotUTF8)
+	}
+
+	changes, err := ec.Evaler.SourceStaged(eval.NewScriptSource("rc.elv", path, string(code)))
+	maybeThrow(err)
+	out := ec.OutputFile()
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "no changes")
+	}
+	for _, change := range changes {
+		fmt.Fprintln(out, change.Kind, change.Name)
+	}
+}
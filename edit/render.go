@@ -123,6 +123,10 @@ type cmdlineRenderer struct {
 	hasHist   bool
 	histBegin int
 	histText  string
+	// Range of histText to highlight as matching a substring search; empty
+	// when there is none.
+	histMatchBegin int
+	histMatchEnd   int
 }
 
 func newCmdlineRenderer(p []*ui.Styled, l string, s *highlight.Styling, d int, rp []*ui.Styled) *cmdlineRenderer {
@@ -139,6 +143,10 @@ func (clr *cmdlineRenderer) setHist(b int, t string) {
 	clr.histBegin, clr.histText = b, t
 }
 
+func (clr *cmdlineRenderer) setHistMatch(b, e int) {
+	clr.histMatchBegin, clr.histMatchEnd = b, e
+}
+
 func (clr *cmdlineRenderer) Render(b *ui.Buffer) {
 	b.EagerWrap = true
 
@@ -167,6 +175,9 @@ func (clr *cmdlineRenderer) Render(b *ui.Buffer) {
 	nowAt(0)
 
 	for _, r := range clr.line {
+		if clr.hasHist && i == clr.histBegin {
+			break
+		}
 		if clr.hasComp && clr.compBegin <= i && i < clr.compEnd {
 			// Do nothing. This part is replaced by the completion candidate.
 		} else {
@@ -175,15 +186,15 @@ func (clr *cmdlineRenderer) Render(b *ui.Buffer) {
 		i += utf8.RuneLen(r)
 
 		nowAt(i)
-		if clr.hasHist && i == clr.histBegin {
-			break
-		}
 	}
 
 	if clr.hasHist {
 		// Put the rest of current history and position the cursor at the
 		// end of the line.
-		b.WriteString(clr.histText, styleForCompletedHistory.String())
+		t, mb, me := clr.histText, clr.histMatchBegin, clr.histMatchEnd
+		b.WriteString(t[:mb], styleForCompletedHistory.String())
+		b.WriteString(t[mb:me], styleForHistoryMatch.String())
+		b.WriteString(t[me:], styleForCompletedHistory.String())
 		b.Dot = b.Cursor()
 	}
 
@@ -206,14 +217,15 @@ var logEditorRender = false
 // editorRenderer renders the entire editor.
 type editorRenderer struct {
 	*editorState
-	height  int
-	bufNoti *ui.Buffer
+	height        int
+	listingHeight int
+	bufNoti       *ui.Buffer
 }
 
 func (er *editorRenderer) Render(buf *ui.Buffer) {
 	height, width, es := er.height, buf.Width, er.editorState
 
-	var bufNoti, bufLine, bufMode, bufTips, bufListing *ui.Buffer
+	var bufNoti, bufLine, bufMode, bufTips, bufWatch, bufListing *ui.Buffer
 	// butNoti
 	if len(es.notifications) > 0 {
 		bufNoti = ui.Render(linesRenderer{es.notifications, ""}, width)
@@ -229,8 +241,16 @@ func (er *editorRenderer) Render(buf *ui.Buffer) {
 		c := es.completion
 		clr.setComp(c.begin, c.end, c.selectedCandidate().code)
 	case *hist:
-		begin := len(mode.Prefix())
-		clr.setHist(begin, mode.CurrentCmd()[begin:])
+		if mode.Substring() {
+			cmd := mode.CurrentCmd()
+			clr.setHist(0, cmd)
+			if i := strings.Index(cmd, mode.Prefix()); i != -1 {
+				clr.setHistMatch(i, i+len(mode.Prefix()))
+			}
+		} else {
+			begin := len(mode.Prefix())
+			clr.setHist(begin, mode.CurrentCmd()[begin:])
+		}
 	}
 	bufLine = ui.Render(clr, width)
 
@@ -243,12 +263,21 @@ func (er *editorRenderer) Render(buf *ui.Buffer) {
 		bufTips = ui.Render(linesRenderer{es.tips, styleForTip.String()}, width)
 	}
 
+	// bufWatch
+	if len(es.watchLines) > 0 {
+		bufWatch = ui.Render(linesRenderer{es.watchLines, ""}, width)
+		// The watch panel is the first to go when the terminal is too short.
+		if height < ui.BuffersHeight(bufNoti, bufLine, bufMode, bufTips, bufWatch) {
+			bufWatch = nil
+		}
+	}
+
 	hListing := 0
 	// Trim lines and determine the maximum height for bufListing
 	// TODO come up with a UI to tell the user that something is not shown.
 	switch {
 	case height >= ui.BuffersHeight(bufNoti, bufLine, bufMode, bufTips):
-		hListing = height - ui.BuffersHeight(bufLine, bufMode, bufTips)
+		hListing = height - ui.BuffersHeight(bufLine, bufMode, bufTips, bufWatch)
 	case height >= ui.BuffersHeight(bufNoti, bufLine, bufTips):
 		bufMode = nil
 	case height >= ui.BuffersHeight(bufNoti, bufLine):
@@ -274,6 +303,7 @@ func (er *editorRenderer) Render(buf *ui.Buffer) {
 	}
 
 	// bufListing.
+	hListing = min(hListing, er.listingHeight)
 	if hListing > 0 {
 		if lister, ok := es.mode.(ListRenderer); ok {
 			bufListing = lister.ListRender(width, hListing)
@@ -291,8 +321,9 @@ func (er *editorRenderer) Render(buf *ui.Buffer) {
 	}
 
 	if logEditorRender {
-		logger.Printf("bufLine %d, bufMode %d, bufTips %d, bufListing %d",
-			ui.BuffersHeight(bufLine), ui.BuffersHeight(bufMode), ui.BuffersHeight(bufTips), ui.BuffersHeight(bufListing))
+		logger.Printf("bufLine %d, bufMode %d, bufTips %d, bufWatch %d, bufListing %d",
+			ui.BuffersHeight(bufLine), ui.BuffersHeight(bufMode), ui.BuffersHeight(bufTips),
+			ui.BuffersHeight(bufWatch), ui.BuffersHeight(bufListing))
 	}
 
 	// XXX
@@ -305,6 +336,7 @@ func (er *editorRenderer) Render(buf *ui.Buffer) {
 	}
 	buf.Extend(bufMode, cursorOnModeLine)
 	buf.Extend(bufTips, false)
+	buf.Extend(bufWatch, false)
 	buf.Extend(bufListing, false)
 
 	er.bufNoti = bufNoti
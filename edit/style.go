@@ -12,6 +12,7 @@ var (
 	//styleForRPrompt          = "inverse"
 	styleForCompleted        = ui.Styles{"underlined"}
 	styleForCompletedHistory = ui.Styles{"underlined"}
+	styleForHistoryMatch     = ui.Styles{"bold", "underlined"}
 	styleForMode             = ui.Styles{"bold", "lightgray", "bg-magenta"}
 	styleForTip              = ui.Styles{}
 	styleForFilter           = ui.Styles{"underlined"}
@@ -0,0 +1,84 @@
+package edit
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/xiaq/persistent/hashmap"
+)
+
+// Watch expressions, shown in a panel below the command line. $edit:watches is
+// a map from labels to functions, which are called before each ReadLine; their
+// outputs are shown next to their labels.
+
+var _ = RegisterVariable("watches", func() vartypes.Variable {
+	return vartypes.NewValidatedPtr(
+		types.NewMap(hashmap.Empty), vartypes.ShouldBeMap)
+})
+
+func (ed *Editor) watches() types.Map {
+	return ed.variables["watches"].Get().(types.Map)
+}
+
+// updateWatches calls all the watch functions and stores the lines to show.
+func (ed *Editor) updateWatches() {
+	var lines []string
+	ed.watches().IteratePair(func(k, v types.Value) bool {
+		label := types.ToString(k)
+		fn, ok := v.(eval.Fn)
+		if !ok {
+			lines = append(lines, label+": not a function")
+			return true
+		}
+		lines = append(lines, label+": "+ed.callWatch(fn))
+		return true
+	})
+	sort.Strings(lines)
+	ed.watchLines = lines
+}
+
+// callWatch calls a watch function, and joins its value and byte outputs into
+// one line. Its error output is shown as notifications.
+func (ed *Editor) callWatch(fn eval.Fn) string {
+	ports := []*eval.Port{
+		eval.DevNullClosedChan,
+		{}, // Will be replaced when capturing output
+		ed.notifyPort,
+	}
+	var (
+		outputs []string
+		mutex   sync.Mutex
+	)
+	add := func(s string) {
+		mutex.Lock()
+		outputs = append(outputs, s)
+		mutex.Unlock()
+	}
+	valuesCb := func(ch <-chan types.Value) {
+		for v := range ch {
+			add(types.ToString(v))
+		}
+	}
+	bytesCb := func(r *os.File) {
+		allBytes, err := ioutil.ReadAll(r)
+		if err != nil {
+			logger.Println("error reading watch byte output:", err)
+		}
+		if len(allBytes) > 0 {
+			add(strings.TrimRight(string(allBytes), "\n"))
+		}
+	}
+
+	ec := eval.NewTopFrame(ed.evaler, eval.NewInternalSource("[watch]"), ports)
+	err := ec.PCaptureOutputInner(fn, nil, eval.NoOpts, valuesCb, bytesCb)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return strings.Replace(strings.Join(outputs, " "), "\n", " ", -1)
+}
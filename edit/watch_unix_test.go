@@ -0,0 +1,46 @@
+// +build !windows,!plan9
+
+package edit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/kr/pty"
+)
+
+func TestUpdateWatches(t *testing.T) {
+	master, tty, err := pty.Open()
+	if err != nil {
+		panic(err)
+	}
+	defer master.Close()
+	defer tty.Close()
+
+	ev := eval.NewEvaler()
+	ev.SetLibDir("/non/exist/ent")
+	defer ev.Close()
+	ed := NewEditor(tty, tty, nil, ev)
+	defer ed.Close()
+
+	err = ev.SourceText(eval.NewInteractiveSource(`
+		n = 0
+		edit:watches[count] = { n = (+ $n 1); put $n }
+		edit:watches[bytes] = { echo "a\nb" }
+		edit:watches[fail] = { fail bad }
+		edit:watches[bad] = foo`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, count := range []string{"1", "2"} {
+		ed.updateWatches()
+		want := []string{
+			"bad: not a function", "bytes: a b",
+			"count: " + count, "fail: error: bad"}
+		if !reflect.DeepEqual(ed.watchLines, want) {
+			t.Errorf("watchLines = %q, want %q", ed.watchLines, want)
+		}
+	}
+}
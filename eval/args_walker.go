@@ -44,6 +44,13 @@ func (aw *argsWalker) nextIs(text string) bool {
 	return false
 }
 
+// peekIsLambda returns whether the next argument is a lambda, without
+// consuming it.
+func (aw *argsWalker) peekIsLambda() bool {
+	pn := onePrimary(aw.peek())
+	return pn != nil && pn.Type == parse.Lambda
+}
+
 // nextMustLambda fetches the next argument, raising an error if it is not a
 // lambda.
 func (aw *argsWalker) nextMustLambda() *parse.Primary {
@@ -73,3 +80,36 @@ func (aw *argsWalker) mustEnd() {
 		aw.cp.errorpf(aw.form.Args[aw.idx].Begin(), aw.form.End(), "too many arguments")
 	}
 }
+
+// literalOpts parses the options of the form. All option keys must be literal
+// strings among names, and all values must be literal strings. The returned
+// map maps each option that appears to its value; options without values are
+// mapped to an empty string.
+func (aw *argsWalker) literalOpts(names ...string) map[string]string {
+	opts := make(map[string]string)
+	for _, opt := range aw.form.Opts {
+		name, ok := oneString(opt.Key)
+		if !ok {
+			aw.cp.errorpf(opt.Key.Begin(), opt.Key.End(), "option name must be a literal string")
+		}
+		known := false
+		for _, n := range names {
+			if n == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			aw.cp.errorpf(opt.Begin(), opt.End(), "unknown option %s", parse.Quote(name))
+		}
+		value := ""
+		if opt.Value != nil {
+			value, ok = oneString(opt.Value)
+			if !ok {
+				aw.cp.errorpf(opt.Value.Begin(), opt.Value.End(), "option value must be a literal string")
+			}
+		}
+		opts[name] = value
+	}
+	return opts
+}
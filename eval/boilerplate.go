@@ -4,6 +4,7 @@ import "github.com/elves/elvish/parse"
 
 func (cp *compiler) chunkOp(n *parse.Chunk) Op {
 	cp.compiling(n)
+	defer cp.recordOp("chunk", n)()
 	return Op{cp.chunk(n), n.Begin(), n.End()}
 }
 
@@ -17,6 +18,7 @@ func (cp *compiler) chunkOps(ns []*parse.Chunk) []Op {
 
 func (cp *compiler) pipelineOp(n *parse.Pipeline) Op {
 	cp.compiling(n)
+	defer cp.recordOp("pipeline", n)()
 	return Op{cp.pipeline(n), n.Begin(), n.End()}
 }
 
@@ -30,6 +32,7 @@ func (cp *compiler) pipelineOps(ns []*parse.Pipeline) []Op {
 
 func (cp *compiler) formOp(n *parse.Form) Op {
 	cp.compiling(n)
+	defer cp.recordOp("form", n)()
 	return Op{cp.form(n), n.Begin(), n.End()}
 }
 
@@ -43,6 +46,7 @@ func (cp *compiler) formOps(ns []*parse.Form) []Op {
 
 func (cp *compiler) assignmentOp(n *parse.Assignment) Op {
 	cp.compiling(n)
+	defer cp.recordOp("assignment", n)()
 	return Op{cp.assignment(n), n.Begin(), n.End()}
 }
 
@@ -56,6 +60,7 @@ func (cp *compiler) assignmentOps(ns []*parse.Assignment) []Op {
 
 func (cp *compiler) redirOp(n *parse.Redir) Op {
 	cp.compiling(n)
+	defer cp.recordOp("redir", n)()
 	return Op{cp.redir(n), n.Begin(), n.End()}
 }
 
@@ -69,6 +74,7 @@ func (cp *compiler) redirOps(ns []*parse.Redir) []Op {
 
 func (cp *compiler) compoundOp(n *parse.Compound) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("compound", n)()
 	return ValuesOp{cp.compound(n), n.Begin(), n.End()}
 }
 
@@ -82,6 +88,7 @@ func (cp *compiler) compoundOps(ns []*parse.Compound) []ValuesOp {
 
 func (cp *compiler) arrayOp(n *parse.Array) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("array", n)()
 	return ValuesOp{cp.array(n), n.Begin(), n.End()}
 }
 
@@ -95,6 +102,7 @@ func (cp *compiler) arrayOps(ns []*parse.Array) []ValuesOp {
 
 func (cp *compiler) indexingOp(n *parse.Indexing) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("indexing", n)()
 	return ValuesOp{cp.indexing(n), n.Begin(), n.End()}
 }
 
@@ -108,6 +116,7 @@ func (cp *compiler) indexingOps(ns []*parse.Indexing) []ValuesOp {
 
 func (cp *compiler) primaryOp(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("primary", n)()
 	return ValuesOp{cp.primary(n), n.Begin(), n.End()}
 }
 
@@ -121,6 +130,7 @@ func (cp *compiler) primaryOps(ns []*parse.Primary) []ValuesOp {
 
 func (cp *compiler) listOp(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("list", n)()
 	return ValuesOp{cp.list(n), n.Begin(), n.End()}
 }
 
@@ -134,6 +144,7 @@ func (cp *compiler) listOps(ns []*parse.Primary) []ValuesOp {
 
 func (cp *compiler) exceptionCaptureOp(n *parse.Chunk) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("exceptionCapture", n)()
 	return ValuesOp{cp.exceptionCapture(n), n.Begin(), n.End()}
 }
 
@@ -147,6 +158,7 @@ func (cp *compiler) exceptionCaptureOps(ns []*parse.Chunk) []ValuesOp {
 
 func (cp *compiler) outputCaptureOp(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("outputCapture", n)()
 	return ValuesOp{cp.outputCapture(n), n.Begin(), n.End()}
 }
 
@@ -160,6 +172,7 @@ func (cp *compiler) outputCaptureOps(ns []*parse.Primary) []ValuesOp {
 
 func (cp *compiler) lambdaOp(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("lambda", n)()
 	return ValuesOp{cp.lambda(n), n.Begin(), n.End()}
 }
 
@@ -173,6 +186,7 @@ func (cp *compiler) lambdaOps(ns []*parse.Primary) []ValuesOp {
 
 func (cp *compiler) map_Op(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("map", n)()
 	return ValuesOp{cp.map_(n), n.Begin(), n.End()}
 }
 
@@ -186,6 +200,7 @@ func (cp *compiler) map_Ops(ns []*parse.Primary) []ValuesOp {
 
 func (cp *compiler) bracedOp(n *parse.Primary) ValuesOp {
 	cp.compiling(n)
+	defer cp.recordOp("braced", n)()
 	return ValuesOp{cp.braced(n), n.Begin(), n.End()}
 }
 
@@ -8,9 +8,11 @@ def put_compile_s(out, name, intype, extraargs, outtype):
         return
     outtype = outtype[:-4]
     extranames = ', '.join(a.split(' ')[0] for a in extraargs.split(', ')) if extraargs else ''
+    kind = name.rstrip('_')
     print >>out, '''
 func (cp *compiler) {name}Op(n {intype}{extraargs}) {outtype} {{
 	cp.compiling(n)
+	defer cp.recordOp("{kind}", n)()
 	return {outtype}{{cp.{name}(n{extranames}), n.Begin(), n.End()}}
 }}
 
@@ -21,7 +23,7 @@ func (cp *compiler) {name}Ops(ns []{intype}{extraargs}) []{outtype} {{
 	}}
 	return ops
 }}
-'''.format(name=name, intype=intype, outtype=outtype, extraargs=extraargs,
+'''.format(name=name, kind=kind, intype=intype, outtype=outtype, extraargs=extraargs,
              extranames=extranames)
 
 
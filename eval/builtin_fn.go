@@ -9,10 +9,12 @@ import (
 	"net"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"time"
 	"unsafe"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
 	"github.com/elves/elvish/util"
 	"github.com/xiaq/persistent/hash"
 )
@@ -25,7 +27,12 @@ type BuiltinFn struct {
 
 type BuiltinFnImpl func(*Frame, []types.Value, map[string]types.Value)
 
-var _ Fn = &BuiltinFn{}
+var (
+	_ Fn               = &BuiltinFn{}
+	_ types.IndexOneer = &BuiltinFn{}
+)
+
+var errNoSuchBuiltinFnField = errors.New("no such field of builtin")
 
 // Kind returns "fn".
 func (*BuiltinFn) Kind() string {
@@ -46,6 +53,15 @@ func (b *BuiltinFn) Repr(int) string {
 	return "<builtin " + b.Name + ">"
 }
 
+// IndexOne gives the fields of the builtin function. The only field is name.
+func (b *BuiltinFn) IndexOne(idx types.Value) types.Value {
+	if idx == types.String("name") {
+		return types.String(b.Name)
+	}
+	throw(errNoSuchBuiltinFnField)
+	panic("unreachable")
+}
+
 // Call calls a builtin function.
 func (b *BuiltinFn) Call(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	b.Impl(ec, args, opts)
@@ -76,6 +92,7 @@ func init() {
 		{"constantly", constantly},
 
 		{"-source", source},
+		{"eval", evalFn},
 
 		// Time
 		{"esleep", sleep},
@@ -106,7 +123,7 @@ func kindOf(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func boolFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var v types.Value
-	ScanArgs(args, &v)
+	ec.ScanArgs(args, &v)
 	TakeNoOpt(opts)
 
 	ec.OutputChan() <- types.Bool(types.ToBool(v))
@@ -114,7 +131,7 @@ func boolFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func not(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var v types.Value
-	ScanArgs(args, &v)
+	ec.ScanArgs(args, &v)
 	TakeNoOpt(opts)
 
 	ec.OutputChan() <- types.Bool(!types.ToBool(v))
@@ -178,8 +195,8 @@ func constantly(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func source(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var argFname types.String
-	ScanArgs(args, &argFname)
-	ScanOpts(opts)
+	ec.ScanArgs(args, &argFname)
+	ec.ScanOpts(opts)
 
 	fname := string(argFname)
 	abs, err := filepath.Abs(fname)
@@ -188,9 +205,87 @@ func source(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	maybeThrow(ec.Source(fname, abs))
 }
 
+// evalFn evaluates a piece of code in a namespace. New variables defined by the
+// code are put into the namespace, so that later calls can use them. If &ns is
+// not given, a new namespace with the variables of the local and upvalue
+// scopes of the caller is used; assigning to them from the code changes them
+// for the caller too, while new variables are dropped after the call.
+//
+// Since the namespace given as &ns can be shared by code running concurrently,
+// the code is evaluated in a copy of it, and the variables it defines or
+// deletes are merged back when it finishes. Both steps hold evalNsMutex.
+func evalFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		code  string
+		nsOpt types.Value
+	)
+	ec.ScanArgs(args, &code)
+	ec.ScanOpts(opts, OptToScan{"ns", &nsOpt, types.Nil})
+
+	var ns Ns
+	switch nsOpt := nsOpt.(type) {
+	case Ns:
+		evalNsMutex.Lock()
+		ns = copyNs(nsOpt)
+		evalNsMutex.Unlock()
+		before := copyNs(ns)
+		defer func() {
+			evalNsMutex.Lock()
+			defer evalNsMutex.Unlock()
+			for name := range before {
+				if _, ok := ns[name]; !ok {
+					delete(nsOpt, name)
+				}
+			}
+			for name, variable := range ns {
+				if before[name] != variable {
+					nsOpt[name] = variable
+				}
+			}
+		}()
+	case types.NilValue:
+		ns = make(Ns)
+		for _, scope := range []Ns{ec.up, ec.local} {
+			for name, variable := range scope {
+				ns[name] = variable
+			}
+		}
+	default:
+		throwf("&ns should be a namespace or $nil, got %s", nsOpt.Kind())
+	}
+
+	src := NewScriptSource("[eval]", "[eval]", code)
+	n, err := parse.Parse(src.name, code)
+	maybeThrow(err)
+	op, err := compile(ec.Builtin.static(), ns.static(), n, src)
+	maybeThrow(err)
+
+	newEc := &Frame{
+		ec.Evaler, src,
+		ns, make(Ns),
+		ec.ports,
+		0, len(code), ec.addTraceback(), ec.background,
+		&cleanups{}, ec.deadline, ec.job,
+		ec.recordPipeStatus, nil,
+	}
+	defer newEc.cleanups.run()
+	maybeThrow(newEc.PEval(op))
+}
+
+// evalNsMutex guards the namespaces given to eval as &ns.
+var evalNsMutex sync.Mutex
+
+func copyNs(ns Ns) Ns {
+	copied := make(Ns, len(ns))
+	for name, variable := range ns {
+		copied[name] = variable
+	}
+	return copied
+}
+
 func sleep(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var t float64
-	ScanArgs(args, &t)
+	ec.ScanArgs(args, &t)
 	TakeNoOpt(opts)
 
 	d := time.Duration(float64(time.Second) * t)
@@ -203,7 +298,7 @@ func sleep(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func _time(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var f Fn
-	ScanArgs(args, &f)
+	ec.ScanArgs(args, &f)
 	TakeNoOpt(opts)
 
 	t0 := time.Now()
@@ -243,7 +338,7 @@ func _stack(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func _log(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var fnamev types.String
-	ScanArgs(args, &fnamev)
+	ec.ScanArgs(args, &fnamev)
 	fname := string(fnamev)
 	TakeNoOpt(opts)
 
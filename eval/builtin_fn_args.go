@@ -0,0 +1,386 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/getopt"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+// Parsing of command-line arguments of scripts.
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"parse-args", parseArgs},
+		{"args-usage", argsUsage},
+		{"args-completer", argsCompleter},
+	})
+}
+
+// ArgError is thrown by parse-args when the arguments do not conform to the
+// spec. Flag is the offending flag as it appears in the arguments, and is empty
+// when the error is not about a particular flag.
+type ArgError struct {
+	Flag   string
+	Reason string
+}
+
+func (e ArgError) Error() string {
+	if e.Flag == "" {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Flag
+}
+
+// argSpec is the parsed spec of one flag.
+type argSpec struct {
+	name     string
+	typ      string
+	def      types.Value
+	doc      string
+	required bool
+	option   *getopt.Option
+}
+
+type parseArgsOptions struct {
+	Prompt bool
+}
+
+// parseArgs parses command-line arguments against a spec, and outputs a map of
+// the flags and a list of the positional arguments.
+//
+// The spec is a map from long flag names to maps with the following optional
+// keys: "type", one of "bool" (the default), "string" and "number"; "short",
+// a single-rune short name; "default", the value to use when the flag is not
+// given; "required", whether a string or number flag must be given; and "doc",
+// a description used by args-usage. Bool flags default to $false, while other
+// flags without a default are absent from the output map unless given.
+//
+// Arguments are parsed like GNU getopt_long; "--" terminates the flags. When
+// &prompt is true and the shell is interactive, missing required flags are
+// asked for with the editor instead of causing an error.
+func parseArgs(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		specv types.MapLike
+		argsv types.IteratorValue
+	)
+	ec.ScanArgs(args, &specv, &argsv)
+	var options parseArgsOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	specs := scanArgSpecs(specv)
+	var elems []string
+	argsv.Iterate(func(v types.Value) bool {
+		s, ok := v.(types.String)
+		if !ok {
+			throwf("argument should be string, got %s", v.Kind())
+		}
+		elems = append(elems, string(s))
+		return true
+	})
+
+	g := getopt.Getopt{Config: getopt.GNUGetoptLong}
+	byOption := make(map[*getopt.Option]*argSpec)
+	for _, spec := range specs {
+		g.Options = append(g.Options, spec.option)
+		byOption[spec.option] = spec
+	}
+	// Parse is designed for completion and treats the last element as being
+	// edited; an empty element makes it report a flag missing its argument.
+	parsed, positional, ctx := g.Parse(append(elems, ""))
+	if ctx.Type == getopt.OptionArgument {
+		throw(ArgError{flagText(ctx.Option), "missing argument"})
+	}
+
+	result := make(map[types.Value]types.Value)
+	for _, spec := range specs {
+		if spec.def != nil {
+			result[types.String(spec.name)] = spec.def
+		}
+	}
+	for _, p := range parsed {
+		spec, ok := byOption[p.Option]
+		if !ok {
+			throw(ArgError{flagText(p), "unknown flag"})
+		}
+		var v types.Value
+		if spec.typ == "bool" {
+			if p.HasArgument {
+				throw(ArgError{flagText(p), "flag takes no argument"})
+			}
+			v = types.Bool(true)
+		} else {
+			v = argValue(spec, flagText(p), p.Argument)
+		}
+		result[types.String(spec.name)] = v
+	}
+	for _, spec := range specs {
+		name := types.String(spec.name)
+		if !spec.required || result[name] != nil {
+			continue
+		}
+		if !options.Prompt || ec.Editor == nil {
+			throw(ArgError{"--" + spec.name, "missing required flag"})
+		}
+		result[name] = promptArg(ec.Editor, spec)
+	}
+
+	out := ec.OutputChan()
+	out <- types.MakeMap(result)
+	positionalValues := make([]types.Value, len(positional))
+	for i, s := range positional {
+		positionalValues[i] = types.String(s)
+	}
+	out <- types.MakeList(positionalValues...)
+}
+
+// argValue converts the argument of a string or number flag to a value.
+func argValue(spec *argSpec, flag, arg string) types.Value {
+	if spec.typ == "number" {
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			throw(ArgError{flag, "want number, got " + parse.Quote(arg)})
+		}
+	}
+	return types.String(arg)
+}
+
+// promptArg asks the user for the value of a missing required flag. String
+// flags are completed as file names by the editor.
+func promptArg(ed Editor, spec *argSpec) types.Value {
+	flag := "--" + spec.name
+	prompt := flag
+	if spec.doc != "" {
+		prompt += " (" + spec.doc + ")"
+	}
+	var complete func(string) []string
+	if spec.typ == "string" {
+		complete = ed.CompleteFilename
+	}
+	line, err := ed.Prompt(prompt+": ", complete)
+	if err == io.EOF {
+		throw(ArgError{flag, "missing required flag"})
+	}
+	maybeThrow(err)
+	return argValue(spec, flag, line)
+}
+
+// The column at which args-usage starts the descriptions of flags.
+const argsUsageDocColumn = 27
+
+type argsUsageOptions struct {
+	Width int
+}
+
+// argsUsage writes a description of the flags in a parse-args spec, one flag
+// per line. When &width is positive, descriptions are wrapped so that lines
+// are at most that wide, with continuation lines aligned with the first.
+func argsUsage(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var specv types.MapLike
+	ec.ScanArgs(args, &specv)
+	var options argsUsageOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	out := ec.OutputFile()
+	for _, spec := range scanArgSpecs(specv) {
+		flags := "    --" + spec.name
+		if spec.option.Short != 0 {
+			flags = "-" + string(spec.option.Short) + ", --" + spec.name
+		}
+		if spec.typ != "bool" {
+			flags += " <" + spec.typ + ">"
+		}
+		doc := spec.doc
+		if spec.def != nil && spec.typ != "bool" {
+			doc += " (default " + types.ToString(spec.def) + ")"
+		}
+		if spec.required {
+			doc += " (required)"
+		}
+		if options.Width > 0 {
+			lines := util.WrapWcwidth(doc, options.Width-argsUsageDocColumn)
+			doc = strings.Join(lines, "\n"+strings.Repeat(" ", argsUsageDocColumn))
+		}
+		fmt.Fprintf(out, "  %-24s %s\n", flags, doc)
+	}
+}
+
+// argsCompleter outputs an argument completer for commands that parse their
+// arguments with a parse-args spec. The completer completes flag names, file
+// names for arguments of string flags, and file names for positional
+// arguments; file names are completed by the editor, and not at all when there
+// is no editor. When &name is given, the completer is registered in
+// $edit:arg-completer for the command of that name instead, like in
+// "args-completer $spec &name=script"; this does nothing when there is no
+// editor.
+func argsCompleter(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		specv types.MapLike
+		name  string
+	)
+	ec.ScanArgs(args, &specv)
+	ec.ScanOpts(opts, OptToScan{"name", &name, types.String("")})
+
+	specs := scanArgSpecs(specv)
+	completer := &BuiltinFn{
+		"created by args-completer",
+		func(ec *Frame, a []types.Value, o map[string]types.Value) {
+			TakeNoOpt(o)
+			var words []string
+			for _, v := range a {
+				s, ok := v.(types.String)
+				if !ok {
+					throwf("argument should be string, got %s", v.Kind())
+				}
+				words = append(words, string(s))
+			}
+			if len(words) < 2 {
+				throw(ErrArgs)
+			}
+			out := ec.OutputChan()
+			var complete func(string) []string
+			if ec.Editor != nil {
+				complete = ec.Editor.CompleteFilename
+			}
+			for _, cand := range completeArgs(specs, words[1:], complete) {
+				out <- types.String(cand)
+			}
+		},
+	}
+	if name == "" {
+		ec.OutputChan() <- completer
+		return
+	}
+	registerArgCompleter(ec, name, completer)
+}
+
+// registerArgCompleter sets $edit:arg-completer[name] to f, if the editor is
+// present.
+func registerArgCompleter(ec *Frame, name string, f Fn) {
+	editNs, ok := ec.Builtin["edit"+NsSuffix]
+	if !ok {
+		return
+	}
+	variable, ok := editNs.Get().(Ns)["arg-completer"]
+	if !ok {
+		return
+	}
+	m, ok := variable.Get().(types.Assocer)
+	if !ok {
+		throwf("$edit:arg-completer should be a map, got %s", variable.Get().Kind())
+	}
+	maybeThrow(variable.Set(m.Assoc(types.String(name), f)))
+}
+
+// completeArgs returns the candidates for the last of the arguments, which is
+// being edited. File names are completed with completeFilename, which may be
+// nil.
+func completeArgs(specs []*argSpec, elems []string, completeFilename func(string) []string) []string {
+	g := getopt.Getopt{Config: getopt.GNUGetoptLong}
+	byOption := make(map[*getopt.Option]*argSpec)
+	for _, spec := range specs {
+		g.Options = append(g.Options, spec.option)
+		byOption[spec.option] = spec
+	}
+	_, _, ctx := g.Parse(elems)
+
+	if completeFilename == nil {
+		completeFilename = func(string) []string { return nil }
+	}
+	var cands []string
+	switch ctx.Type {
+	case getopt.NewOptionOrArgument, getopt.Argument:
+		cands = completeFilename(ctx.Text)
+	case getopt.NewOption, getopt.NewLongOption, getopt.LongOption:
+		for _, spec := range specs {
+			if strings.HasPrefix(spec.name, ctx.Text) {
+				cands = append(cands, "--"+spec.name)
+			}
+			if ctx.Type == getopt.NewOption && spec.option.Short != 0 {
+				cands = append(cands, "-"+string(spec.option.Short))
+			}
+		}
+	case getopt.OptionArgument:
+		spec, ok := byOption[ctx.Option.Option]
+		if !ok || spec.typ != "string" {
+			break
+		}
+		// The argument may follow the flag in the same element, like in
+		// "--name=value" or "-nvalue".
+		elem := elems[len(elems)-1]
+		prefix := elem[:len(elem)-len(ctx.Option.Argument)]
+		for _, path := range completeFilename(ctx.Option.Argument) {
+			cands = append(cands, prefix+path)
+		}
+	}
+	return cands
+}
+
+// scanArgSpecs converts a parse-args spec to argSpec's, sorted by name.
+func scanArgSpecs(specv types.MapLike) []*argSpec {
+	var specs []*argSpec
+	for _, name := range sortedKeys(specv) {
+		m, ok := specv.IndexOne(types.String(name)).(types.MapLike)
+		if !ok {
+			throwf("spec of flag %s should be map", name)
+		}
+		get := func(k string) (types.Value, bool) {
+			kv := types.String(k)
+			if !m.HasKey(kv) {
+				return nil, false
+			}
+			return m.IndexOne(kv), true
+		}
+
+		spec := &argSpec{name: name, typ: "bool",
+			option: &getopt.Option{Long: name}}
+		if v, ok := get("type"); ok {
+			spec.typ = types.ToString(v)
+		}
+		switch spec.typ {
+		case "bool":
+			spec.def = types.Bool(false)
+		case "string", "number":
+			spec.option.HasArg = getopt.RequiredArgument
+		default:
+			throwf("type of flag %s should be bool, string or number, got %s",
+				name, parse.Quote(spec.typ))
+		}
+		if v, ok := get("short"); ok {
+			s := types.ToString(v)
+			r, size := utf8.DecodeRuneInString(s)
+			if r == utf8.RuneError || size != len(s) {
+				throwf("short name of flag %s should be exactly one rune, got %s",
+					name, parse.Quote(s))
+			}
+			spec.option.Short = r
+		}
+		if v, ok := get("default"); ok {
+			spec.def = v
+		}
+		if v, ok := get("doc"); ok {
+			spec.doc = types.ToString(v)
+		}
+		if v, ok := get("required"); ok {
+			spec.required = types.ToBool(v)
+			if spec.required && spec.typ == "bool" {
+				throwf("bool flag %s cannot be required", name)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// flagText returns the text of a parsed flag as it appears in the arguments.
+func flagText(p *getopt.ParsedOption) string {
+	if p.Long {
+		return "--" + p.Option.Long
+	}
+	return "-" + string(p.Option.Short)
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+func TestBuiltinFnArgs(t *testing.T) {
+	spec := `[&verbose=[&short=v] &name=[&type=string &short=n &default=x] &count=[&type=number]]`
+	runTests(t, []Test{
+		{`parse-args ` + spec + ` [-v --count 3 a -- -b]`,
+			want{out: []types.Value{
+				types.MakeMap(map[types.Value]types.Value{
+					types.String("verbose"): types.Bool(true),
+					types.String("name"):    types.String("x"),
+					types.String("count"):   types.String("3"),
+				}),
+				types.MakeList(strs("a", "-b")...)}}},
+		{`parse-args ` + spec + ` [-vny --name=z]`,
+			want{out: []types.Value{
+				types.MakeMap(map[types.Value]types.Value{
+					types.String("verbose"): types.Bool(true),
+					types.String("name"):    types.String("z"),
+				}),
+				types.MakeList()}}},
+		{`parse-args ` + spec + ` [--bad]`,
+			want{err: ArgError{"--bad", "unknown flag"}}},
+		{`parse-args ` + spec + ` [-n]`,
+			want{err: ArgError{"-n", "missing argument"}}},
+		{`parse-args ` + spec + ` [--verbose=1]`,
+			want{err: ArgError{"--verbose", "flag takes no argument"}}},
+		{`parse-args ` + spec + ` [--verbose=]`,
+			want{err: ArgError{"--verbose", "flag takes no argument"}}},
+		{`parse-args ` + spec + ` [--count x]`,
+			want{err: ArgError{"--count", "want number, got x"}}},
+		{`parse-args [&name=[&type=string &required=$true]] []`,
+			want{err: ArgError{"--name", "missing required flag"}}},
+		{`parse-args &prompt [&name=[&type=string &required=$true]] []`,
+			want{err: ArgError{"--name", "missing required flag"}}},
+		{`parse-args [&a=[&required=$true]] []`, want{err: errAny}},
+		{`parse-args [&a=[&type=list]] []`, want{err: errAny}},
+		{`parse-args [&a=[&short=ab]] []`, want{err: errAny}},
+
+		{`args-usage [&verbose=[&short=v &doc='be verbose'] &name=[&type=string &default=x &doc=name]]`,
+			want{bytesOut: []byte(
+				"      --name <string>      name (default x)\n" +
+					"  -v, --verbose            be verbose\n")}},
+		{`args-usage &width=40 [&all=[&doc='list all entries, including hidden ones']]`,
+			want{bytesOut: []byte(
+				"      --all                list all\n" +
+					"                           entries,\n" +
+					"                           including\n" +
+					"                           hidden ones\n")}},
+	})
+}
+
+// promptEditor is an Editor that answers prompts from a list of lines, and
+// completes file names from a list of files.
+type promptEditor struct {
+	lines   []string
+	prompts []string
+	files   []string
+}
+
+func (*promptEditor) Active() bool                  { return false }
+func (*promptEditor) ActiveMutex() *sync.Mutex      { return new(sync.Mutex) }
+func (*promptEditor) Notify(string, ...interface{}) {}
+func (*promptEditor) RestoreTerminal() error        { return nil }
+
+func (ed *promptEditor) Prompt(prompt string, complete func(string) []string) (string, error) {
+	ed.prompts = append(ed.prompts, prompt)
+	if len(ed.lines) == 0 {
+		return "", io.EOF
+	}
+	line := ed.lines[0]
+	ed.lines = ed.lines[1:]
+	return line, nil
+}
+
+func (ed *promptEditor) CompleteFilename(seed string) []string {
+	var paths []string
+	for _, file := range ed.files {
+		if strings.HasPrefix(file, seed) {
+			paths = append(paths, file)
+		}
+	}
+	return paths
+}
+
+func TestParseArgsPrompt(t *testing.T) {
+	spec := `[&name=[&type=string &required=$true &doc=name] &count=[&type=number &required=$true]]`
+	makeEvaler := func(lines ...string) func() *Evaler {
+		return func() *Evaler {
+			ev := NewEvaler()
+			ev.Editor = &promptEditor{lines: lines}
+			return ev
+		}
+	}
+	RunTests(t, []Test{
+		NewTest(`parse-args &prompt `+spec+` [--name x]`).WantOut(
+			types.MakeMap(map[types.Value]types.Value{
+				types.String("name"):  types.String("x"),
+				types.String("count"): types.String("3"),
+			}),
+			types.MakeList()),
+	}, makeEvaler("3"))
+	RunTests(t, []Test{
+		NewTest(`parse-args &prompt ` + spec + ` []`).WantErr(
+			ArgError{"--count", "want number, got x"}),
+	}, makeEvaler("x"))
+	RunTests(t, []Test{
+		NewTest(`parse-args &prompt ` + spec + ` [--count 1]`).WantErr(
+			ArgError{"--name", "missing required flag"}),
+	}, makeEvaler())
+}
+
+func TestArgsCompleter(t *testing.T) {
+	complete := `f = (args-completer [&verbose=[&short=v] &name=[&type=string &short=n] &count=[&type=number]]); $f cmd `
+	RunTests(t, []Test{
+		NewTest(complete+`-`).WantOutStrings("--count", "--name", "-n", "--verbose", "-v"),
+		NewTest(complete+`--`).WantOutStrings("--count", "--name", "--verbose"),
+		NewTest(complete + `--n`).WantOutStrings("--name"),
+		NewTest(complete+`-v ''`).WantOutStrings("bar", "foo"),
+		NewTest(complete + `--name f`).WantOutStrings("foo"),
+		NewTest(complete + `--name=f`).WantOutStrings("--name=foo"),
+		NewTest(complete + `-nb`).WantOutStrings("-nbar"),
+		NewTest(complete + `--count ''`).WantOutStrings(),
+		NewTest(complete + `-- -`).WantOutStrings(),
+		NewTest(`args-completer [&verbose=[&short=v]] &name=foo
+		         $edit:arg-completer[foo] foo -`).WantOutStrings("--verbose", "-v"),
+		NewTest(`args-completer [&] &name=foo; keys $edit:arg-completer`).
+			WantOutStrings("foo"),
+	}, func() *Evaler {
+		ev := NewEvaler()
+		ev.Editor = &promptEditor{files: []string{"bar", "foo"}}
+		ev.Builtin["edit"+NsSuffix] = vartypes.NewPtr(Ns{
+			"arg-completer": vartypes.NewPtr(types.MakeMap(nil)),
+		})
+		return ev
+	})
+
+	// Without an editor, file names are not completed, and there is nowhere
+	// to register the completer.
+	runTests(t, []Test{
+		NewTest(complete + `-v ''`).WantOutStrings(),
+		NewTest(`args-completer [&verbose=[&short=v]] &name=foo`),
+	})
+}
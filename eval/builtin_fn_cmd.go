@@ -4,14 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
+	"strings"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
 )
 
 // Command and process control.
 
-var ErrNotInSameGroup = errors.New("not in the same process group")
+var (
+	errNoSuchJob     = errors.New("no such job")
+	errJobNotStopped = errors.New("job is not stopped")
+	errJobStopped    = errors.New("job is stopped; continue it with bg or fg first")
+	errJobNoProcess  = errors.New("job has no running processes")
+	errBadSignal     = errors.New("bad signal")
+)
 
 func init() {
 	addToBuiltinFns([]*BuiltinFn{
@@ -20,17 +29,29 @@ func init() {
 		{"external", external},
 		{"has-external", hasExternal},
 		{"search-external", searchExternal},
+		{"describe", describe},
+		{"kind-of-command", kindOfCommand},
 
 		// Process control
+		{"jobs", jobs},
 		{"fg", fg},
+		{"bg", bg},
+		{"kill-job", killJob},
+		{"wait", wait},
+		{"disown", disown},
+		{"daemonize", daemonize},
+		{"ulimit", ulimit},
 		{"exec", execFn},
 		{"exit", exit},
+
+		// Opening files and URLs
+		{"open-default", openDefault},
 	})
 }
 
 func resolveFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var cmd types.String
-	ScanArgs(args, &cmd)
+	ec.ScanArgs(args, &cmd)
 	TakeNoOpt(opts)
 
 	out := ec.ports[1].Chan
@@ -39,7 +60,7 @@ func resolveFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func external(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var cmd types.String
-	ScanArgs(args, &cmd)
+	ec.ScanArgs(args, &cmd)
 	TakeNoOpt(opts)
 
 	ec.OutputChan() <- ExternalCmd{string(cmd)}
@@ -47,28 +68,405 @@ func external(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func hasExternal(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var cmd types.String
-	ScanArgs(args, &cmd)
+	ec.ScanArgs(args, &cmd)
 	TakeNoOpt(opts)
 
-	_, err := exec.LookPath(string(cmd))
+	_, err := ec.pathHash.lookPath(string(cmd))
 	ec.OutputChan() <- types.Bool(err == nil)
 }
 
 func searchExternal(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var cmd types.String
-	ScanArgs(args, &cmd)
+	ec.ScanArgs(args, &cmd)
 	TakeNoOpt(opts)
 
-	path, err := exec.LookPath(string(cmd))
+	path, err := ec.pathHash.lookPath(string(cmd))
 	maybeThrow(err)
 
 	out := ec.ports[1].Chan
 	out <- types.String(path)
 }
 
+var describeDescriptor = types.NewStructDescriptor("kind", "scope", "location")
+
+// describe outputs what a command name can resolve to, in the order of
+// precedence, so that the first output is what is actually run. Each output
+// has a kind, one of "special", "fn", "builtin" and "external", which is that
+// of the function value for variables like "ll~" that are bound to other
+// commands; the scope in which the name is bound, like "local", "builtin" or
+// the name of a module; and where it is defined, namely the file and line of
+// the body of a fn, or the path of an external command.
+func describe(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var cmd types.String
+	ec.ScanArgs(args, &cmd)
+	TakeNoOpt(opts)
+
+	out := ec.OutputChan()
+	eachResolution(ec, string(cmd), func(kind, scope, location string) bool {
+		out <- newDescription(kind, scope, location)
+		return true
+	})
+}
+
+// kindOfCommand outputs what a command name actually resolves to, in the same
+// form as the first output of describe. It throws an exception if the name
+// does not resolve to anything, in which case running it would fail with a
+// "command not found" error.
+func kindOfCommand(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var cmd types.String
+	ec.ScanArgs(args, &cmd)
+	TakeNoOpt(opts)
+
+	var found types.Value
+	eachResolution(ec, string(cmd), func(kind, scope, location string) bool {
+		found = newDescription(kind, scope, location)
+		return false
+	})
+	if found == nil {
+		throwf("command not found: %s", parse.Quote(string(cmd)))
+	}
+	ec.OutputChan() <- found
+}
+
+func newDescription(kind, scope, location string) types.Value {
+	return types.NewStruct(describeDescriptor, []types.Value{
+		types.String(kind), types.String(scope), types.String(location)})
+}
+
+// eachResolution calls f with what cmd can resolve to, in the order of
+// precedence, until f returns false. The arguments to f are described in the
+// comment of describe.
+func eachResolution(ec *Frame, cmd string, f func(kind, scope, location string) bool) {
+	explode, ns, name := ParseVariable(cmd)
+	if explode {
+		throwf("bad command name %s", parse.Quote(cmd))
+	}
+	if IsBuiltinSpecial[name] && (ns == "" || ns == "builtin") {
+		if !f("special", "builtin", "") {
+			return
+		}
+	}
+	var scopes []string
+	switch ns {
+	case "":
+		scopes = []string{"local", "up", "builtin"}
+	case "e", "external":
+	default:
+		scopes = []string{ns}
+	}
+	for _, scope := range scopes {
+		v := ec.ResolveVar(scope, name+FnSuffix)
+		if v == nil {
+			continue
+		}
+		var cont bool
+		switch fn := v.Get().(type) {
+		case *Closure:
+			location := ""
+			if fn.SrcMeta != nil {
+				location = fn.SrcMeta.describePosition(fn.Op.Begin)
+			}
+			cont = f("fn", scope, location)
+		case *BuiltinFn:
+			cont = f("builtin", scope, "")
+		case ExternalCmd:
+			path, _ := ec.pathHash.lookPath(fn.Name)
+			cont = f("external", scope, path)
+		default:
+			cont = f(v.Get().Kind(), scope, "")
+		}
+		if !cont {
+			return
+		}
+	}
+	if ns == "" || ns == "e" || ns == "external" {
+		if util.DontSearch(name) {
+			if path := util.FindExecutable("", name); path != "" {
+				f("external", "", path)
+			}
+			return
+		}
+		for _, dir := range searchPaths() {
+			if path := util.FindExecutable(dir, name); path != "" {
+				if !f("external", "", path) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// jobs outputs the jobs that are stopped or in the background.
+func jobs(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	TakeNoOpt(opts)
+
+	out := ec.OutputChan()
+	for _, j := range ec.jobs.list() {
+		out <- j
+	}
+}
+
+// scanJob finds the job given by an optional argument, which is either a job
+// or a job number with an optional "%" prefix. Without an argument, the job
+// that most recently got stopped or started in the background is used.
+func scanJob(ec *Frame, args []types.Value) *job {
+	switch len(args) {
+	case 0:
+		return getJob(ec, 0)
+	case 1:
+		return toJob(ec, args[0])
+	default:
+		throw(ErrArgs)
+		panic("unreachable")
+	}
+}
+
+// toJob converts a job or a job number to a job.
+func toJob(ec *Frame, v types.Value) *job {
+	switch v := v.(type) {
+	case *job:
+		return v
+	case types.String:
+		id, err := strconv.Atoi(strings.TrimPrefix(string(v), "%"))
+		if err != nil || id <= 0 {
+			throwf("bad job number: %s", parse.Quote(string(v)))
+		}
+		return getJob(ec, id)
+	default:
+		throwf("need job or job number, got %s", v.Kind())
+		panic("unreachable")
+	}
+}
+
+func getJob(ec *Frame, id int) *job {
+	j := ec.jobs.get(id)
+	if j == nil {
+		throw(errNoSuchJob)
+	}
+	return j
+}
+
+// wait waits for the given jobs, or all jobs that are running in the
+// background, to finish, and outputs their results: $ok for each job that has
+// succeeded, and the exception for each one that has failed. The jobs are
+// removed from the job table, without telling the user that they have
+// finished.
+//
+// Waiting for a stopped job is an error, since it would never finish. A job
+// that gets stopped while being waited for keeps wait blocked until it is
+// continued or wait is interrupted.
+func wait(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+
+	var waiting []*job
+	if len(args) == 0 {
+		for _, j := range ec.jobs.list() {
+			j.mutex.Lock()
+			stopped := j.stopped
+			j.mutex.Unlock()
+			if !stopped {
+				waiting = append(waiting, j)
+			}
+		}
+	} else {
+		for _, arg := range args {
+			j := toJob(ec, arg)
+			j.mutex.Lock()
+			stopped := j.stopped
+			j.mutex.Unlock()
+			if stopped {
+				throw(errJobStopped)
+			}
+			waiting = append(waiting, j)
+		}
+	}
+
+	for _, j := range waiting {
+		j.mutex.Lock()
+		j.waited = true
+		j.mutex.Unlock()
+	}
+	out := ec.OutputChan()
+	for _, j := range waiting {
+		select {
+		case <-j.done:
+		case <-ec.Interrupts():
+			throw(ErrInterrupted)
+		}
+		ec.jobs.remove(j)
+		out <- j.result()
+	}
+}
+
+// fg continues a job in the foreground, and waits until it finishes or gets
+// stopped again. The exceptions of the job are rethrown.
+func fg(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	j := scanJob(ec, args)
+	TakeNoOpt(opts)
+
+	j.mutex.Lock()
+	j.foreground = true
+	j.stopped = false
+	pgid, restoreJobTTY := j.pgid, j.restoreTTY
+	j.mutex.Unlock()
+	// Discard stops that happened before.
+	select {
+	case <-j.stopCh:
+	default:
+	}
+
+	ec.ports[2].File.WriteString(j.src + "\n")
+	var restoreShellTTY func()
+	if j.control && pgid != 0 {
+		restoreShellTTY = saveTTY()
+		if restoreJobTTY != nil {
+			restoreJobTTY()
+		}
+		maybeThrow(giveTerminal(pgid))
+	}
+	j.mutex.Lock()
+	err := j.continueProcesses()
+	j.mutex.Unlock()
+	if err != nil && err != errJobNoProcess {
+		throw(err)
+	}
+	ec.EmitEvent(EventJobStateChange, j, types.String("running"))
+	if j.waitForeground(ec, restoreShellTTY) {
+		return
+	}
+	ec.jobs.remove(j)
+	maybeThrow(ComposeExceptionsFromPipeline(dropBrokenPipes(j.errors)))
+}
+
+// bg continues a stopped job in the background.
+func bg(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	j := scanJob(ec, args)
+	TakeNoOpt(opts)
+
+	j.mutex.Lock()
+	if !j.stopped {
+		j.mutex.Unlock()
+		throw(errJobNotStopped)
+	}
+	j.stopped = false
+	err := j.continueProcesses()
+	j.mutex.Unlock()
+	maybeThrow(err)
+	ec.EmitEvent(EventJobStateChange, j, types.String("running"))
+}
+
+// disown removes the given jobs, or the job that most recently got stopped or
+// started in the background, from the job table. Disowned jobs that are
+// stopped are continued, since they can no longer be continued with fg or bg.
+// When they finish, the user is not told.
+func disown(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+
+	var disowning []*job
+	if len(args) == 0 {
+		disowning = []*job{getJob(ec, 0)}
+	} else {
+		for _, arg := range args {
+			disowning = append(disowning, toJob(ec, arg))
+		}
+	}
+	for _, j := range disowning {
+		ec.jobs.remove(j)
+		j.mutex.Lock()
+		j.disowned = true
+		if j.stopped {
+			j.stopped = false
+			j.continueProcesses()
+		}
+		j.mutex.Unlock()
+	}
+}
+
+type daemonizeOptions struct {
+	Stdin, Stdout, Stderr string
+}
+
+// daemonize calls a function as a background job that is disowned from the
+// start, and outputs the job. The external commands of the job are each
+// started in a session of their own, so that they are not affected by the
+// terminal of Elvish going away. The standard input and outputs of the job are
+// the null device unless files are given with &stdin, &stdout and &stderr;
+// output files are appended to.
+func daemonize(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Fn
+	ec.ScanArgs(args, &f)
+	options := daemonizeOptions{os.DevNull, os.DevNull, os.DevNull}
+	ec.ScanOptsToStruct(opts, &options)
+
+	var files []*os.File
+	open := func(name string, flag int) *os.File {
+		file, err := os.OpenFile(name, flag, 0644)
+		if err != nil {
+			for _, file := range files {
+				file.Close()
+			}
+			throw(err)
+		}
+		files = append(files, file)
+		return file
+	}
+	stdin := open(options.Stdin, os.O_RDONLY)
+	stdout := open(options.Stdout, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	stderr := open(options.Stderr, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+
+	j := newJob(ec, "daemonize "+f.Repr(types.NoPretty), true)
+	j.detached, j.disowned = true, true
+
+	newEc := ec.fork("daemonized job")
+	newEc.background = true
+	newEc.job = j
+	newEc.ports = []*Port{
+		{File: stdin, Chan: ClosedChan, CloseFile: true},
+		{File: stdout, Chan: BlackholeChan, CloseFile: true},
+		{File: stderr, Chan: BlackholeChan, CloseFile: true},
+	}
+	release := ec.cleanups.hold()
+	go func() {
+		err := newEc.PCall(f, NoArgs, NoOpts)
+		ClosePorts(newEc.ports)
+		release()
+		var exc *Exception
+		if err != nil {
+			exc = err.(*Exception)
+		}
+		j.finish([]*Exception{exc})
+	}()
+	ec.OutputChan() <- j
+}
+
+type killJobOptions struct {
+	Signal string
+}
+
+// killJob sends a signal, SIGTERM by default, to the external commands of a
+// job. Stopped jobs are also continued, so that they can handle the signal.
+func killJob(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	j := scanJob(ec, args)
+	options := killJobOptions{"TERM"}
+	ec.ScanOptsToStruct(opts, &options)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	maybeThrow(j.sendSignal(options.Signal))
+	if j.stopped {
+		switch strings.TrimPrefix(strings.ToUpper(options.Signal), "SIG") {
+		case "STOP", "TSTP":
+		default:
+			j.stopped = false
+			maybeThrow(j.continueProcesses())
+		}
+	}
+}
+
 func exit(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var codes []int
-	ScanArgsVariadic(args, &codes)
+	ec.ScanArgsVariadic(args, &codes)
 	TakeNoOpt(opts)
 
 	doexit := func(i int) {
@@ -97,3 +495,20 @@ var errNotSupportedOnWindows = errors.New("not supported on Windows")
 func notSupportedOnWindows(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	throw(errNotSupportedOnWindows)
 }
+
+// openDefault opens a file or a URL with the default application of the
+// platform. The opener runs detached from the session and the terminal of the
+// shell, so that it neither blocks the prompt nor gets killed when the shell
+// exits. It does not wait for the opener to finish. It is not called "open" so
+// that it does not shadow the open command of macOS.
+func openDefault(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var target types.String
+	ec.ScanArgs(args, &target)
+	TakeNoOpt(opts)
+
+	cmd := openCommand(string(target))
+	ec.exports.sync()
+	maybeThrow(cmd.Start())
+	// Reap the opener when it exits.
+	go cmd.Wait()
+}
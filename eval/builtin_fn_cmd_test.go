@@ -3,5 +3,23 @@ package eval
 import "testing"
 
 func TestBuiltinFnCmd(t *testing.T) {
-	runTests(t, []Test{})
+	runTests(t, []Test{
+		NewTest("fn nop { }; describe nop | each [d]{ put $d[kind] $d[scope] }").
+			WantOutStrings("fn", "local", "builtin", "builtin"),
+		NewTest("fn f {\n nop\n}; put (describe f)[location]").
+			WantOutStrings("test0.elv:1"),
+		NewTest("describe builtin:if | each [d]{ put $d[kind] }").
+			WantOutStrings("special"),
+		NewTest("describe local:put").WantOutStrings(),
+		NewTest("describe '@x'").WantAnyErr(),
+
+		NewTest("fn nop { }; kind-of-command nop | each [d]{ put $d[kind] $d[scope] }").
+			WantOutStrings("fn", "local"),
+		NewTest("kind-of-command put | each [d]{ put $d[kind] $d[scope] }").
+			WantOutStrings("builtin", "builtin"),
+		NewTest("put (kind-of-command if)[kind]").WantOutStrings("special"),
+		NewTest("put (kind-of-command builtin:if)[kind]").WantOutStrings("special"),
+		NewTest("kind-of-command local:put").WantAnyErr(),
+		NewTest("kind-of-command a-command-that-does-not-exist").WantAnyErr(),
+	})
 }
@@ -3,15 +3,24 @@
 package eval
 
 import (
+	"errors"
 	"os"
 	"os/exec"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/sys"
 )
 
+var (
+	errBadResource = errors.New("bad resource")
+	errBadLimit    = errors.New("limit should be a non-negative integer or unlimited")
+)
+
 func execFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoOpt(opts)
 
@@ -26,60 +35,128 @@ func execFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	}
 
 	var err error
-	argstrings[0], err = exec.LookPath(argstrings[0])
+	argstrings[0], err = ec.pathHash.lookPath(argstrings[0])
 	maybeThrow(err)
 
+	// When exec is called from the editor, for instance in a key binding, the
+	// terminal has to be restored for the new program. The current state is
+	// saved first, so that it can be put back if exec fails.
+	restoreTTY := saveTTY()
+	if ec.Editor != nil {
+		maybeThrow(ec.Editor.RestoreTerminal())
+	}
+
 	preExit(ec)
 
+	ec.exports.sync()
 	err = syscall.Exec(argstrings[0], argstrings, os.Environ())
+	restoreTTY()
 	maybeThrow(err)
 }
 
-func fg(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var pids []int
-	ScanArgsVariadic(args, &pids)
-	TakeNoOpt(opts)
-
-	if len(pids) == 0 {
-		throw(ErrArgs)
+// openCommand returns the command that opens a file or a URL, in a new session
+// and with its standard IO connected to the null device.
+func openCommand(target string) *exec.Cmd {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
 	}
-	var thepgid int
-	for i, pid := range pids {
-		pgid, err := syscall.Getpgid(pid)
-		maybeThrow(err)
-		if i == 0 {
-			thepgid = pgid
-		} else if pgid != thepgid {
-			throw(ErrNotInSameGroup)
-		}
+	if strings.HasPrefix(target, "-") {
+		// Make sure that the target is not taken as an option. xdg-open does
+		// not accept "--" to end options, so this is done with a path that
+		// does not start with "-" instead.
+		target = "./" + target
 	}
+	cmd := exec.Command(opener, target)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	return cmd
+}
 
-	err := sys.Tcsetpgrp(0, thepgid)
-	maybeThrow(err)
+// rlimitResources maps the names of resources that ulimit accepts to their
+// numbers.
+var rlimitResources = map[string]int{
+	"as": syscall.RLIMIT_AS, "core": syscall.RLIMIT_CORE,
+	"cpu": syscall.RLIMIT_CPU, "data": syscall.RLIMIT_DATA,
+	"fsize": syscall.RLIMIT_FSIZE, "nofile": syscall.RLIMIT_NOFILE,
+	"stack": syscall.RLIMIT_STACK,
+}
 
-	errors := make([]*Exception, len(pids))
+var rlimitDescriptor = types.NewStructDescriptor("name", "soft", "hard")
+
+type ulimitOptions struct {
+	Hard bool
+}
 
-	for i, pid := range pids {
-		err := syscall.Kill(pid, syscall.SIGCONT)
-		if err != nil {
-			errors[i] = &Exception{err, nil}
+// ulimit outputs or sets a resource limit of Elvish, which is inherited by the
+// external commands it starts afterwards. With a resource name, it outputs the
+// soft limit, or the hard limit with &hard; with a resource name and a limit,
+// it sets the soft or hard limit. Without arguments, it outputs a struct with
+// the name and the soft and hard limits of each resource. Limits are integers
+// or "unlimited".
+func ulimit(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var options ulimitOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	if len(args) == 0 {
+		names := make([]string, 0, len(rlimitResources))
+		for name := range rlimitResources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		out := ec.OutputChan()
+		for _, name := range names {
+			soft, hard, err := sys.GetRlimit(rlimitResources[name])
+			maybeThrow(err)
+			out <- types.NewStruct(rlimitDescriptor, []types.Value{
+				types.String(name), limitToValue(soft), limitToValue(hard)})
 		}
+		return
 	}
 
-	for i, pid := range pids {
-		if errors[i] != nil {
-			continue
-		}
-		var ws syscall.WaitStatus
-		_, err = syscall.Wait4(pid, &ws, syscall.WUNTRACED, nil)
-		if err != nil {
-			errors[i] = &Exception{err, nil}
-		} else {
-			// TODO find command name
-			errors[i] = &Exception{NewExternalCmdExit(
-				"[pid "+strconv.Itoa(pid)+"]", ws, pid), nil}
+	var name string
+	var limit types.Value
+	if len(args) == 1 {
+		ec.ScanArgs(args, &name)
+	} else {
+		ec.ScanArgs(args, &name, &limit)
+	}
+	resource, ok := rlimitResources[name]
+	if !ok {
+		throw(errBadResource)
+	}
+	soft, hard, err := sys.GetRlimit(resource)
+	maybeThrow(err)
+
+	if limit == nil {
+		if options.Hard {
+			soft = hard
 		}
+		ec.OutputChan() <- limitToValue(soft)
+		return
+	}
+	if options.Hard {
+		hard = ec.limitArg(limit)
+	} else {
+		soft = ec.limitArg(limit)
 	}
+	maybeThrow(sys.SetRlimit(resource, soft, hard))
+}
 
-	maybeThrow(ComposeExceptionsFromPipeline(errors))
+func limitToValue(limit uint64) types.Value {
+	if limit == sys.RlimInfinity {
+		return types.String("unlimited")
+	}
+	return types.String(strconv.FormatUint(limit, 10))
+}
+
+// limitArg converts an argument of ulimit to a resource limit.
+func (ec *Frame) limitArg(arg types.Value) uint64 {
+	if arg == types.String("unlimited") {
+		return sys.RlimInfinity
+	}
+	i, err := parseInt(arg, ec.strictNumbers())
+	if err != nil || i < 0 {
+		throw(errBadLimit)
+	}
+	return uint64(i)
 }
@@ -0,0 +1,214 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+func TestOpenDefault(t *testing.T) {
+	util.InTempDir(func(dir string) {
+		// A fake opener that records its argument and whether it is a session
+		// leader.
+		opener := filepath.Base(openCommand("").Path)
+		script := "#!/bin/sh\n" +
+			`[ "$(ps -o sid= -p $$)" -eq $$ ] && echo "$1" > opened` + "\n"
+		if err := ioutil.WriteFile(opener, []byte(script), 0700); err != nil {
+			t.Fatal(err)
+		}
+		oldPath := os.Getenv("PATH")
+		os.Setenv("PATH", dir+":"+oldPath)
+		defer os.Setenv("PATH", oldPath)
+
+		for _, test := range []struct{ target, want string }{
+			{"a.txt", "a.txt"},
+			// Targets that look like options are turned into paths.
+			{"-a.txt", "./-a.txt"},
+		} {
+			os.Remove("opened")
+			runTests(t, []Test{NewTest("open-default " + test.target)})
+			if !waitForOpened(test.want) {
+				t.Errorf("opener not run in a new session with %q", test.want)
+			}
+		}
+	})
+}
+
+// waitForOpened waits until the fake opener of TestOpenDefault has been run
+// with the given target, and returns whether it has been within a second.
+func waitForOpened(target string) bool {
+	for i := 0; i < 100; i++ {
+		content, err := ioutil.ReadFile("opened")
+		if err == nil && string(content) == target+"\n" {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestDescribeExternal(t *testing.T) {
+	util.WithTempDirs(2, func(dirs []string) {
+		for _, dir := range dirs {
+			err := ioutil.WriteFile(filepath.Join(dir, "prog"), []byte("#!/bin/sh\n"), 0700)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		oldPath := os.Getenv("PATH")
+		os.Setenv("PATH", dirs[0]+":"+dirs[1])
+		defer os.Setenv("PATH", oldPath)
+
+		runTests(t, []Test{
+			NewTest("describe prog | each [d]{ put $d[location] }").
+				WantOutStrings(filepath.Join(dirs[0], "prog"), filepath.Join(dirs[1], "prog")),
+			NewTest("prog~ = (external prog); describe prog | each [d]{ put $d[kind] $d[scope] }").
+				WantOutStrings("external", "local", "external", "", "external", ""),
+		})
+	})
+}
+
+func TestJobControl(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("e:sleep 0.3 &; jobs | each [j]{ put $j[id] $j[state] $j[src] }").
+			WantOutStrings("1", "running", "e:sleep 0.3 &"),
+		NewTest("e:sleep 0.1 &; fg"),
+		NewTest("e:sleep 0.1 &; fg %1"),
+		NewTest("fg").WantAnyErr(),
+		NewTest("fg %x").WantAnyErr(),
+		NewTest("e:false &; fg").WantAnyErr(),
+		NewTest("e:sleep 0.1 &; bg").WantErr(errJobNotStopped),
+		NewTest("e:sleep 10 &; kill-job; fg").WantAnyErr(),
+		// A pipeline run in the background directly inside an output capture
+		// outputs its job.
+		NewTest("j = (e:sleep 0.3 > /dev/null &); put $j[id] $j[state] $j[src]; "+
+			"jobs | each [j2]{ eq $j $j2 }").
+			WantOut(types.String("1"), types.String("running"),
+				types.String("e:sleep 0.3 > /dev/null &"), types.Bool(true)),
+		NewTest("j = (e:sleep 0.1 > /dev/null &); fg $j"),
+		NewTest("put { e:true & } | each [f]{ $f }"),
+		// A foreground job that gets stopped is moved to the job table, and can
+		// be continued with fg.
+		NewTest("e:sh -c 'kill -STOP $$; echo resumed'; " +
+			"jobs | each [j]{ put $j[state] }; fg").
+			WantOutStrings("stopped").WantBytesOutString("resumed\n"),
+	})
+}
+
+func TestWait(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("j = (e:true > /dev/null &); wait $j; put $j[state] $j[status] (> $j[pid] 0)").
+			WantOut(OK, types.String("done"), OK, types.Bool(true)),
+		NewTest("j = (e:sh -c 'exit 3' > /dev/null &); wait $j | each [r]{ kind-of $r }").
+			WantOutStrings("exception"),
+		NewTest("e:sleep 0.1 &; e:sleep 0.1 &; wait | count; jobs | count").
+			WantOutStrings("2", "0"),
+		NewTest("j = (e:sleep 0.1 > /dev/null &); wait $j %2").WantErr(errNoSuchJob),
+		NewTest("wait foo").WantAnyErr(),
+		// Waiting for a stopped job fails instead of blocking.
+		NewTest("e:sh -c 'kill -STOP $$'; bool ?(wait %1); fg").
+			WantOut(types.Bool(false)),
+	})
+}
+
+// terminalEditor is an Editor that records whether the terminal was restored.
+type terminalEditor struct {
+	promptEditor
+	restored bool
+}
+
+func (ed *terminalEditor) RestoreTerminal() error {
+	ed.restored = true
+	return nil
+}
+
+func TestExec(t *testing.T) {
+	util.InTempDir(func(dir string) {
+		// An executable that cannot be run, so that exec fails after the
+		// terminal has been restored instead of replacing the test process.
+		if err := ioutil.WriteFile("bad", []byte{0, 0, 0, 0}, 0700); err != nil {
+			t.Fatal(err)
+		}
+		ed := &terminalEditor{}
+		RunTests(t, []Test{
+			NewTest("exec ./bad").WantAnyErr(),
+		}, func() *Evaler {
+			ev := NewEvaler()
+			ev.Editor = ed
+			return ev
+		})
+		if !ed.restored {
+			t.Errorf("exec did not restore the terminal")
+		}
+
+		runTests(t, []Test{
+			NewTest("exec ./nonexistent").WantAnyErr(),
+		})
+	})
+}
+
+func TestDisown(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("_ = (e:sleep 0.1 > /dev/null &); disown; jobs | count").
+			WantOutStrings("0"),
+		NewTest("j = (e:sleep 0.1 > /dev/null &); disown $j; wait $j; put $j[id]").
+			WantOut(OK, types.String("1")),
+		NewTest("disown").WantErr(errNoSuchJob),
+	})
+}
+
+func TestDaemonize(t *testing.T) {
+	util.InTempDir(func(string) {
+		runTests(t, []Test{
+			NewTest("j = (daemonize &stdout=out { "+
+				"e:sh -c '[ $(ps -o sid= -p $$) -eq $$ ] && echo detached'; cat }); "+
+				"jobs | count; wait $j; slurp < out").
+				WantOut(types.String("0"), OK, types.String("detached\n")),
+			NewTest("daemonize &stdin=nonexistent { }").WantAnyErr(),
+		})
+	})
+}
+
+func TestUlimit(t *testing.T) {
+	// Lowering a hard limit cannot be undone without privileges, so the test
+	// runs in a child process.
+	if os.Getenv("ELVISH_TEST_ULIMIT_CHILD") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestUlimit$")
+		cmd.Env = append(os.Environ(), "ELVISH_TEST_ULIMIT_CHILD=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("child process failed: %v\n%s", err, out)
+		}
+		return
+	}
+
+	runTests(t, []Test{
+		NewTest("ulimit | each [l]{ put $l[name] }").
+			WantOutStrings("as", "core", "cpu", "data", "fsize", "nofile", "stack"),
+		NewTest("ulimit core 0; ulimit core; e:sh -c 'ulimit -c'").
+			WantOutStrings("0").WantBytesOutString("0\n"),
+		NewTest("ulimit &hard core 0; ulimit &hard core").WantOutStrings("0"),
+		NewTest("ulimit core -1").WantErr(errBadLimit),
+		NewTest("ulimit core foo").WantErr(errBadLimit),
+		NewTest("ulimit foo").WantErr(errBadResource),
+	})
+}
+
+func TestExport_ExternalCommand(t *testing.T) {
+	defer os.Unsetenv("EXPORT_TEST_A")
+
+	runTests(t, []Test{
+		NewTest("EXPORT_TEST_A = foo; export EXPORT_TEST_A; EXPORT_TEST_A = bar; " +
+			"e:sh -c 'echo $EXPORT_TEST_A'").WantBytesOutString("bar\n"),
+		NewTest("EXPORT_TEST_A = foo; f = { EXPORT_TEST_A = bar }; export EXPORT_TEST_A; $f; " +
+			"e:sh -c 'echo $EXPORT_TEST_A'").WantBytesOutString("bar\n"),
+	})
+}
@@ -1,6 +1,25 @@
 package eval
 
+import (
+	"os/exec"
+	"syscall"
+)
+
 var (
 	execFn = notSupportedOnWindows
-	fg     = notSupportedOnWindows
+	ulimit = notSupportedOnWindows
 )
+
+// Process creation flags not defined in the syscall package.
+const detachedProcess = 0x00000008
+
+// openCommand returns the command that opens a file or a URL, detached from
+// the console of the shell. It uses the URL handler of the shell instead of
+// "cmd /c start", which would interpret special characters in the target.
+func openCommand(target string) *exec.Cmd {
+	cmd := exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+	return cmd
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/util"
@@ -17,8 +20,10 @@ func init() {
 		{"ns", nsFn},
 
 		{"range", rangeFn},
+		{"stream-close", streamClose},
 		{"repeat", repeat},
 		{"explode", explode},
+		{"flatten", flatten},
 
 		{"assoc", assoc},
 		{"dissoc", dissoc},
@@ -26,6 +31,8 @@ func init() {
 		{"all", all},
 		{"take", take},
 		{"drop", drop},
+		{"take-while", takeWhile},
+		{"drop-while", dropWhile},
 
 		{"has-key", hasKey},
 		{"has-value", hasValue},
@@ -33,6 +40,15 @@ func init() {
 		{"count", count},
 
 		{"keys", keys},
+		{"values", values},
+		{"merge", merge},
+		{"ordered-map", orderedMap},
+		{"record", record},
+
+		{"order", order},
+
+		{"group-by", groupBy},
+		{"frequencies", frequencies},
 	})
 }
 
@@ -43,65 +59,123 @@ func nsFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	ec.OutputChan() <- make(Ns)
 }
 
+// rangeFn outputs the numbers from the lower bound up to but excluding the
+// upper bound. With &stream, it outputs a stream of the numbers instead, which
+// is produced lazily as it is read; the upper bound may then be omitted, in
+// which case the stream is endless. The stream should be closed with
+// stream-close when it is no longer needed; until then, or until it is garbage
+// collected, its producer is kept around.
 func rangeFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var step float64
-	ScanOpts(opts, OptToScan{"step", &step, types.String("1")})
+	var (
+		stepArg types.Value
+		stream  bool
+	)
+	ec.ScanOpts(opts,
+		OptToScan{"step", &stepArg, types.String("1")},
+		OptToScan{"stream", &stream, types.Bool(false)})
+	step := ec.floatArg(stepArg)
 
 	var lower, upper float64
-	var err error
 
-	switch len(args) {
-	case 1:
-		upper, err = toFloat(args[0])
-		maybeThrow(err)
-	case 2:
-		lower, err = toFloat(args[0])
-		maybeThrow(err)
-		upper, err = toFloat(args[1])
-		maybeThrow(err)
+	switch {
+	case len(args) == 0 && stream:
+		upper = math.Inf(1)
+	case len(args) == 1:
+		upper = ec.floatArg(args[0])
+	case len(args) == 2:
+		lower, upper = ec.floatArg(args[0]), ec.floatArg(args[1])
 	default:
 		throw(ErrArgs)
 	}
 
-	out := ec.ports[1].Chan
+	if stream {
+		ch := make(chan types.Value)
+		done := make(chan struct{})
+		go func() {
+			defer close(ch)
+			for f := lower; f < upper; f += step {
+				select {
+				case ch <- types.String(floatToString(f)):
+				case <-done:
+					return
+				}
+			}
+		}()
+		ec.OutputChan() <- types.NewStream(ch, func() { close(done) })
+		return
+	}
+
 	for f := lower; f < upper; f += step {
-		out <- floatToString(f)
+		ec.Output(floatToString(f))
 	}
 }
 
+// streamClose closes a stream, stopping its producer. Values that have not been
+// read from the stream yet are discarded.
+func streamClose(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var s *types.Stream
+	ec.ScanArgs(args, &s)
+	TakeNoOpt(opts)
+
+	s.Close()
+}
+
 func repeat(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var (
 		n int
 		v types.Value
 	)
-	ScanArgs(args, &n, &v)
+	ec.ScanArgs(args, &n, &v)
 	TakeNoOpt(opts)
 
-	out := ec.OutputChan()
 	for i := 0; i < n; i++ {
-		out <- v
+		ec.Output(v)
 	}
 }
 
 // explode puts each element of the argument.
 func explode(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var v types.IteratorValue
-	ScanArgs(args, &v)
+	ec.ScanArgs(args, &v)
 	TakeNoOpt(opts)
 
-	out := ec.ports[1].Chan
 	v.Iterate(func(e types.Value) bool {
-		out <- e
+		ec.Output(e)
 		return true
 	})
 }
 
+// flatten outputs the inputs, with nested lists replaced by their elements.
+// Lists nested deeper than &depth are kept intact; a negative depth means no
+// limit.
+func flatten(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var depth int
+	iterate := ScanArgsOptionalInput(ec, args)
+	ec.ScanOpts(opts, OptToScan{"depth", &depth, types.String("-1")})
+
+	out := ec.ports[1].Chan
+	var flattenOne func(v types.Value, depth int)
+	flattenOne = func(v types.Value, depth int) {
+		if list, ok := v.(types.List); ok && depth != 0 {
+			list.Iterate(func(e types.Value) bool {
+				flattenOne(e, depth-1)
+				return true
+			})
+		} else {
+			out <- v
+		}
+	}
+	iterate(func(v types.Value) {
+		flattenOne(v, depth)
+	})
+}
+
 func assoc(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var (
 		a    types.Assocer
 		k, v types.Value
 	)
-	ScanArgs(args, &a, &k, &v)
+	ec.ScanArgs(args, &a, &k, &v)
 	TakeNoOpt(opts)
 	ec.OutputChan() <- a.Assoc(k, v)
 }
@@ -111,7 +185,7 @@ func dissoc(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		a types.Dissocer
 		k types.Value
 	)
-	ScanArgs(args, &a, &k)
+	ec.ScanArgs(args, &a, &k)
 	TakeNoOpt(opts)
 	ec.OutputChan() <- a.Dissoc(k)
 }
@@ -134,18 +208,25 @@ func all(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	}
 }
 
+// take outputs the first n inputs. It stops reading inputs as soon as it has
+// got enough of them.
 func take(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var n int
-	iterate := ScanArgsOptionalInput(ec, args, &n)
+	iterate := ScanArgsOptionalInputUntil(ec, args, &n)
 	TakeNoOpt(opts)
+	if n <= 0 {
+		// Don't consume any input.
+		return
+	}
 
 	out := ec.ports[1].Chan
 	i := 0
-	iterate(func(v types.Value) {
+	iterate(func(v types.Value) bool {
 		if i < n {
 			out <- v
 		}
 		i++
+		return i < n
 	})
 }
 
@@ -164,13 +245,49 @@ func drop(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	})
 }
 
+// takeWhile outputs inputs as long as the predicate returns a true value for
+// them. It stops reading inputs at the first input for which the predicate
+// returns a false value.
+func takeWhile(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Fn
+	iterate := ScanArgsOptionalInputUntil(ec, args, &f)
+	TakeNoOpt(opts)
+
+	out := ec.ports[1].Chan
+	iterate(func(v types.Value) bool {
+		if !types.ToBool(callForOneValue(ec, f, v)) {
+			return false
+		}
+		out <- v
+		return true
+	})
+}
+
+// dropWhile drops inputs as long as the predicate returns a true value for
+// them, and outputs the rest.
+func dropWhile(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Fn
+	iterate := ScanArgsOptionalInput(ec, args, &f)
+	TakeNoOpt(opts)
+
+	out := ec.ports[1].Chan
+	dropping := true
+	iterate(func(v types.Value) {
+		if dropping && types.ToBool(callForOneValue(ec, f, v)) {
+			return
+		}
+		dropping = false
+		out <- v
+	})
+}
+
 func hasValue(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoOpt(opts)
 
 	var container, value types.Value
 	var found bool
 
-	ScanArgs(args, &container, &value)
+	ec.ScanArgs(args, &container, &value)
 
 	switch container := container.(type) {
 	case types.Iterator:
@@ -196,7 +313,7 @@ func hasKey(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var container, key types.Value
 	var found bool
 
-	ScanArgs(args, &container, &key)
+	ec.ScanArgs(args, &container, &key)
 
 	switch container := container.(type) {
 	case types.HasKeyer:
@@ -247,7 +364,7 @@ func keys(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoOpt(opts)
 
 	var iter types.IterateKeyer
-	ScanArgs(args, &iter)
+	ec.ScanArgs(args, &iter)
 
 	out := ec.ports[1].Chan
 
@@ -256,3 +373,361 @@ func keys(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		return true
 	})
 }
+
+func values(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+
+	var iter types.IteratePairer
+	ec.ScanArgs(args, &iter)
+
+	out := ec.ports[1].Chan
+
+	iter.IteratePair(func(k, v types.Value) bool {
+		out <- v
+		return true
+	})
+}
+
+// merge outputs a map containing all the pairs of the argument maps. When a key
+// appears in multiple maps, the value from the last map wins, unless &deep is
+// true and all the values are maps, in which case they are merged recursively.
+func merge(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		maps []types.MapLike
+		deep bool
+	)
+	ec.ScanArgsVariadic(args, &maps)
+	ec.ScanOpts(opts, OptToScan{"deep", &deep, types.Bool(false)})
+
+	ec.OutputChan() <- mergeMaps(maps, deep)
+}
+
+func mergeMaps(maps []types.MapLike, deep bool) types.Map {
+	result := types.EmptyMap
+	for _, m := range maps {
+		m.IteratePair(func(k, v types.Value) bool {
+			if deep && result.HasKey(k) {
+				oldMap, oldIsMap := result.IndexOne(k).(types.MapLike)
+				newMap, newIsMap := v.(types.MapLike)
+				if oldIsMap && newIsMap {
+					v = mergeMaps([]types.MapLike{oldMap, newMap}, true)
+				}
+			}
+			result = result.Assoc(k, v).(types.Map)
+			return true
+		})
+	}
+	return result
+}
+
+// orderedMap outputs a map that keeps the order of its keys, made from the
+// inputs, which are lists of a key and a value. When a key appears several
+// times, the last value wins, but the key keeps the place where it first
+// appeared.
+func orderedMap(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	TakeNoOpt(opts)
+
+	m := types.EmptyOrderedMap
+	iterate(func(v types.Value) {
+		pair, ok := v.(types.List)
+		if !ok || pair.Len() != 2 {
+			throwf("need list of a key and a value, got %s", v.Repr(types.NoPretty))
+		}
+		m = m.Assoc(pair.IndexOne(types.String("0")),
+			pair.IndexOne(types.String("1"))).(types.OrderedMap)
+	})
+	ec.OutputChan() <- m
+}
+
+// record outputs a map with fixed keys, which are the keys of the argument
+// map, initially with the same values. Indexing it with another key, assigning
+// to one or dissociating any key throws an exception. The keys are in the
+// order of the argument map if it keeps one, like a map from ordered-map or
+// another record, and are sorted otherwise.
+func record(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var schema types.MapLike
+	ec.ScanArgs(args, &schema)
+	TakeNoOpt(opts)
+
+	var names []string
+	values := make(map[string]types.Value)
+	schema.IteratePair(func(k, v types.Value) bool {
+		name, ok := k.(types.String)
+		if !ok {
+			throwf("record field name must be string, got %s", k.Kind())
+		}
+		names = append(names, string(name))
+		values[string(name)] = v
+		return true
+	})
+	if _, ok := schema.(types.Map); ok {
+		sort.Strings(names)
+	}
+
+	fields := make([]types.Value, len(names))
+	for i, name := range names {
+		fields[i] = values[name]
+	}
+	ec.OutputChan() <- types.NewStruct(types.NewStructDescriptor(names...), fields)
+}
+
+type orderOptions struct {
+	Key      types.Value
+	Reverse  bool
+	LessThan Fn
+	Natural  bool
+}
+
+var errNaturalWithLessThan = errors.New("&natural cannot be used with &less-than")
+
+// orderKey is one sort key of order.
+type orderKey struct {
+	fn      Fn
+	reverse bool
+}
+
+// parseOrderKeys parses the &key option of order. It is either a function, or
+// a list of sort keys, each of which is either a function, or a map with a
+// "key" function and an optional boolean "reverse".
+func parseOrderKeys(v types.Value) []orderKey {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case Fn:
+		return []orderKey{{v, false}}
+	case types.List:
+		var keys []orderKey
+		v.Iterate(func(e types.Value) bool {
+			switch e := e.(type) {
+			case Fn:
+				keys = append(keys, orderKey{e, false})
+			case types.MapLike:
+				fn, ok := e.IndexOne(types.String("key")).(Fn)
+				if !ok {
+					throwf("key of a sort key must be fn")
+				}
+				reverse := false
+				if e.HasKey(types.String("reverse")) {
+					reverse = types.ToBool(e.IndexOne(types.String("reverse")))
+				}
+				keys = append(keys, orderKey{fn, reverse})
+			default:
+				throwf("sort key must be fn or map, got %s", e.Kind())
+			}
+			return true
+		})
+		return keys
+	default:
+		throwf("&key must be fn or list, got %s", v.Kind())
+		return nil
+	}
+}
+
+// order sorts its inputs. The sort is stable, so values that compare equal
+// keep their relative order, even when &reverse is used. When there are
+// multiple sort keys, values are compared by the first key, then the second
+// key when the first keys are equal, and so on, like ORDER BY in SQL. With
+// &natural, strings are compared in natural order, so that "file2" comes
+// before "file10".
+func order(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var options orderOptions
+	iterate := ScanArgsOptionalInput(ec, args)
+	ec.ScanOptsToStruct(opts, &options)
+	orderKeys := parseOrderKeys(options.Key)
+
+	var values []types.Value
+	iterate(func(v types.Value) {
+		values = append(values, v)
+	})
+
+	// keys[i] contains the sort keys of values[i].
+	keys := make([][]types.Value, len(values))
+	for i, v := range values {
+		if len(orderKeys) == 0 {
+			keys[i] = []types.Value{v}
+			continue
+		}
+		keys[i] = make([]types.Value, len(orderKeys))
+		for j, key := range orderKeys {
+			keys[i][j] = callForOneValue(ec, key.fn, v)
+		}
+	}
+
+	var compare func(a, b types.Value) int
+	if options.LessThan != nil {
+		if options.Natural {
+			throw(errNaturalWithLessThan)
+		}
+		less := func(a, b types.Value) bool {
+			return types.ToBool(callForOneValue(ec, options.LessThan, a, b))
+		}
+		compare = func(a, b types.Value) int {
+			switch {
+			case less(a, b):
+				return -1
+			case less(b, a):
+				return 1
+			default:
+				return 0
+			}
+		}
+	} else {
+		compareStrings := strings.Compare
+		if options.Natural {
+			compareStrings = util.NaturalCompare
+		}
+		compare = func(a, b types.Value) int {
+			return compareValues(a, b, compareStrings)
+		}
+	}
+
+	indices := make([]int, len(values))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		a, b := keys[indices[i]], keys[indices[j]]
+		for k := range a {
+			c := compare(a[k], b[k])
+			if k < len(orderKeys) && orderKeys[k].reverse {
+				c = -c
+			}
+			if c != 0 {
+				if options.Reverse {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	out := ec.ports[1].Chan
+	for _, i := range indices {
+		out <- values[i]
+	}
+}
+
+// groupBy outputs a map from keys computed by the function to lists of the
+// inputs with the same key, in the order they appear.
+func groupBy(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Fn
+	iterate := ScanArgsOptionalInput(ec, args, &f)
+	TakeNoOpt(opts)
+
+	groups := types.EmptyMap
+	iterate(func(v types.Value) {
+		k := callForOneValue(ec, f, v)
+		group := types.EmptyList
+		if groups.HasKey(k) {
+			group = groups.IndexOne(k).(types.List)
+		}
+		groups = groups.Assoc(k, group.Cons(v)).(types.Map)
+	})
+	ec.OutputChan() <- groups
+}
+
+// frequencies outputs a map from the inputs to the number of times they
+// appear.
+func frequencies(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	TakeNoOpt(opts)
+
+	counts := types.EmptyMap
+	iterate(func(v types.Value) {
+		n := 0
+		if counts.HasKey(v) {
+			n, _ = toInt(counts.IndexOne(v))
+		}
+		counts = counts.Assoc(v, types.String(strconv.Itoa(n+1))).(types.Map)
+	})
+	ec.OutputChan() <- counts
+}
+
+// callForOneValue calls f with the given arguments, and returns its only
+// output. An error is thrown if f does not output exactly one value.
+func callForOneValue(ec *Frame, f Fn, args ...types.Value) types.Value {
+	vs, err := ec.PCaptureOutput(f, args, NoOpts)
+	maybeThrow(err)
+	if len(vs) != 1 {
+		throwf("%s should output exactly one value, got %d", f.Repr(types.NoPretty), len(vs))
+	}
+	return vs[0]
+}
+
+// compareValues compares two values, returning -1, 0 or 1. Float64s and
+// strings that can be parsed as numbers are compared numerically, and such
+// strings sort before other strings; other strings are compared with
+// compareStrings. Lists are compared element-wise. Values of other kinds are
+// first ordered by their kinds, and then with types.Compare, or by their
+// reprs if they cannot be compared with it.
+func compareValues(a, b types.Value, compareStrings func(a, b string) int) int {
+	if hasFloat64([]types.Value{a, b}) {
+		fa, erra := toFloat(a)
+		fb, errb := toFloat(b)
+		if erra == nil && errb == nil {
+			return compareFloats(fa, fb)
+		}
+	}
+	switch a := a.(type) {
+	case types.String:
+		if b, ok := b.(types.String); ok {
+			fa, erra := toFloat(a)
+			fb, errb := toFloat(b)
+			switch {
+			case erra == nil && errb == nil:
+				return compareFloats(fa, fb)
+			case erra == nil:
+				return -1
+			case errb == nil:
+				return 1
+			}
+			return compareStrings(string(a), string(b))
+		}
+	case types.List:
+		if b, ok := b.(types.List); ok {
+			return compareLists(a, b, compareStrings)
+		}
+	}
+	if c := strings.Compare(a.Kind(), b.Kind()); c != 0 {
+		return c
+	}
+	if c, err := types.Compare(a, b); err == nil {
+		return c
+	}
+	return strings.Compare(a.Repr(types.NoPretty), b.Repr(types.NoPretty))
+}
+
+// compareFloats compares two numbers. NaN comes before all other numbers, and
+// is equal to itself, so that the ordering stays consistent when there are
+// NaNs.
+func compareFloats(a, b float64) int {
+	switch {
+	case math.IsNaN(a) || math.IsNaN(b):
+		switch {
+		case !math.IsNaN(b):
+			return -1
+		case !math.IsNaN(a):
+			return 1
+		}
+		return 0
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareLists(a, b types.List, compareStrings func(a, b string) int) int {
+	as := types.CollectFromIterator(a)
+	bs := types.CollectFromIterator(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareValues(as[i], bs[i], compareStrings); c != 0 {
+			return c
+		}
+	}
+	return compareFloats(float64(len(as)), float64(len(bs)))
+}
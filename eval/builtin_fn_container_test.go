@@ -1,14 +1,38 @@
 package eval
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
 
 func TestBuiltinFnContainer(t *testing.T) {
 	runTests(t, []Test{
 		{`range 3`, want{out: strs("0", "1", "2")}},
 		{`range 1 3`, want{out: strs("1", "2")}},
 		{`range 0 10 &step=3`, want{out: strs("0", "3", "6", "9")}},
+		NewTest(`kind-of (range &stream 3)`).WantOutStrings("stream"),
+		NewTest(`s = (range &stream 3); each $put~ $s`).WantOutStrings("0", "1", "2"),
+		NewTest(`s = (range &stream 10 &step=2); take 2 $s; take 2 $s`).
+			WantOutStrings("0", "2", "4", "6"),
+		// take 0 consumes nothing.
+		NewTest(`s = (range &stream 3); take 0 $s; take -1 $s; take 1 $s`).
+			WantOutStrings("0"),
+		NewTest(`s = (range &stream); take 3 $s; stream-close $s; count $s`).
+			WantOutStrings("0", "1", "2", "0"),
+		NewTest(`s = (range &stream); eq $s $s; stream-close $s; stream-close $s`).
+			WantOutBools(true),
+		NewTest(`range`).WantAnyErr(),
+		NewTest(`stream-close [a]`).WantAnyErr(),
 		{`repeat 4 foo`, want{out: strs("foo", "foo", "foo", "foo")}},
 		{`explode [foo bar]`, want{out: strs("foo", "bar")}},
+		{`put [a [b [c]]] d | flatten`, want{out: strs("a", "b", "c", "d")}},
+		{`flatten [[a [b [c]]] d]`, want{out: strs("a", "b", "c", "d")}},
+		{`flatten &depth=1 [[a [b]] c]`,
+			want{out: []types.Value{types.String("a"),
+				types.MakeList(types.String("b")), types.String("c")}}},
+		{`flatten &depth=0 [[a]]`,
+			want{out: []types.Value{types.MakeList(types.String("a"))}}},
 
 		{`put (assoc [0] 0 zero)[0]`, want{out: strs("zero")}},
 		{`put (assoc [&] k v)[k]`, want{out: strs("v")}},
@@ -20,7 +44,16 @@ func TestBuiltinFnContainer(t *testing.T) {
 		{`{ put foo bar; echo foobar } | all`,
 			want{out: strs("foo", "bar"), bytesOut: []byte("foobar\n")}},
 		{`range 100 | take 2`, want{out: strs("0", "1")}},
+		// Producers stop soon after take has taken enough values.
+		NewTest(`n = 0; { while $true { n = (+ $n 1); put $n } } | take 1; < $n 100`).
+			WantOut(types.String("1"), types.Bool(true)),
+		NewTest(`range 20000000 | take 1`).WantOutStrings("0"),
 		{`range 100 | drop 98`, want{out: strs("98", "99")}},
+		{`take 2 [a b c]`, want{out: strs("a", "b")}},
+		{`range 10 | take-while [x]{ < $x 3 }`, want{out: strs("0", "1", "2")}},
+		{`take-while [x]{ ==s $x a } [a a b a]`, want{out: strs("a", "a")}},
+		{`range 5 | drop-while [x]{ < $x 3 }`, want{out: strs("3", "4")}},
+		{`drop-while [x]{ ==s $x a } [a b a]`, want{out: strs("b", "a")}},
 
 		{`has-key [foo bar] 0`, wantTrue},
 		{`has-key [foo bar] 0:1`, wantTrue},
@@ -40,8 +73,74 @@ func TestBuiltinFnContainer(t *testing.T) {
 
 		{`keys [&]`, wantNothing},
 		{`keys [&a=foo]`, want{out: strs("a")}},
-		// Windows does not have an external sort command. Disabled until we have a
-		// builtin sort command.
-		// {`keys [&a=foo &b=bar] | each echo | sort | each $put~`, want{out: strs("a", "b")}},
+
+		{`values [&]`, wantNothing},
+		{`values [&a=foo]`, want{out: strs("foo")}},
+
+		{`merge`, want{out: []types.Value{types.EmptyMap}}},
+		{`m = (merge [&a=1 &b=2] [&b=3 &c=4]); put $m[a] $m[b] $m[c]`,
+			want{out: strs("1", "3", "4")}},
+		{`has-key (merge [&a=[&x=1]] [&a=[&y=2]])[a] x`, wantFalse},
+		{`put (merge &deep [&a=[&x=1]] [&a=[&y=2]])[a][x]`,
+			want{out: strs("1")}},
+		{`merge [a]`, want{err: errAny}},
+		{`keys [&a=foo &b=bar] | order`, want{out: strs("a", "b")}},
+
+		{`keys (ordered-map [[z 1] [a 2] [m 3] [a 4]])`, want{out: strs("z", "a", "m")}},
+		{`repr (put [b 1] [a 2] | ordered-map)`, want{bytesOut: []byte("[&b=1 &a=2]\n")}},
+		{`keys (dissoc (ordered-map [[z 1] [a 2] [m 3]]) a)`, want{out: strs("z", "m")}},
+		{`eq (ordered-map [[a 1] [b 2]]) [&b=2 &a=1]`, wantTrue},
+		{`ordered-map [a]`, want{err: errAny}},
+
+		{`repr (record [&b=2 &a=1])`, want{bytesOut: []byte("[&a=1 &b=2]\n")}},
+		{`repr (record (ordered-map [[b 2] [a 1]]))`, want{bytesOut: []byte("[&b=2 &a=1]\n")}},
+		{`r = (record [&a=1 &b=2]); r[a] = 3; put $r[a] $r[b]`, want{out: strs("3", "2")}},
+		{`r = (record [&a=1]); r[c] = 3`, want{err: errAny}},
+		{`put (record [&a=1])[c]`, want{err: errAny}},
+		{`dissoc (record [&a=1]) a`, want{err: errAny}},
+		{`eq (record [&a=1]) [&a=1]`, wantTrue},
+		{`record [&[a]=1]`, want{err: errAny}},
+		NewTest(`repr (record [&a=1 &b=2]) (record [&"a\x00b"=1])`).
+			WantBytesOutString("[&a=1 &b=2] [&\"a\\x00b\"=1]\n"),
+
+		{`put 10 9 1 b a | order`, want{out: strs("1", "9", "10", "a", "b")}},
+		// NaN comes before all other numbers.
+		{`put 2 nan 1 -inf nan | order`, want{out: strs("nan", "nan", "-inf", "1", "2")}},
+		{`put 2 nan 1 | order &reverse`, want{out: strs("2", "1", "nan")}},
+		{`order [c a b] &reverse`, want{out: strs("c", "b", "a")}},
+		{`put ab b abc | order &key=$count~`, want{out: strs("b", "ab", "abc")}},
+		// Stable, even when reversed
+		{`put b1 a1 b2 a2 | order &key=[x]{ put $x[0] } &reverse`,
+			want{out: strs("b1", "b2", "a1", "a2")}},
+		// Natural order
+		{`put f10 f2 f1 | order`, want{out: strs("f1", "f10", "f2")}},
+		{`put f10 f2 f1 10 | order &natural`, want{out: strs("10", "f1", "f2", "f10")}},
+		{`order &natural &reverse [[f10] [f9]]`,
+			want{out: []types.Value{
+				types.MakeList(types.String("f10")),
+				types.MakeList(types.String("f9"))}}},
+		{`order &natural &less-than=[a b]{ put $true } [a b]`,
+			want{err: errNaturalWithLessThan}},
+		{`put 1 3 2 | order &less-than=[a b]{ > $a $b }`,
+			want{out: strs("3", "2", "1")}},
+		{`order [[b] [a c] [a]]`,
+			want{out: []types.Value{
+				types.MakeList(types.String("a")),
+				types.MakeList(types.String("a"), types.String("c")),
+				types.MakeList(types.String("b"))}}},
+		{`put a b | order &key=[x]{ put 1 2 }`, want{err: errAny}},
+		// Multiple keys
+		{`put b2 a2 b1 a1 | order &key=[[x]{ put $x[0] } [x]{ put $x[1] }]`,
+			want{out: strs("a1", "a2", "b1", "b2")}},
+		{`put b2 a2 b1 a1 | order &key=[[x]{ put $x[0] } [&key=[x]{ put $x[1] } &reverse]]`,
+			want{out: strs("a2", "a1", "b2", "b1")}},
+		{`order &key=[foo] [a]`, want{err: errAny}},
+
+		{`put (put a1 b1 a2 | group-by [x]{ put $x[0] })[a]`,
+			want{out: []types.Value{
+				types.MakeList(types.String("a1"), types.String("a2"))}}},
+		{`count (group-by [x]{ put $x[0] } [a1 b1 a2])`, want{out: strs("2")}},
+		{`put (put a b a | frequencies)[a]`, want{out: strs("2")}},
+		{`put (frequencies [a b a])[b]`, want{out: strs("1")}},
 	})
 }
@@ -2,9 +2,12 @@ package eval
 
 import (
 	"errors"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
 )
 
 // Flow control.
@@ -12,10 +15,15 @@ import (
 func init() {
 	addToBuiltinFns([]*BuiltinFn{
 		{"run-parallel", runParallel},
+		{"and-then", andThen},
+		{"or-else", orElse},
+		{"fan-out", fanOut},
+		{"with-timeout", withTimeout},
 
 		// Iterations.
 		{"each", each},
 		{"peach", peach},
+		{"reduce", reduce},
 
 		// Exception and control
 		{"fail", fail},
@@ -28,7 +36,7 @@ func init() {
 
 func runParallel(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var functions []Fn
-	ScanArgsVariadic(args, &functions)
+	ec.ScanArgsVariadic(args, &functions)
 	TakeNoOpt(opts)
 
 	var waitg sync.WaitGroup
@@ -48,17 +56,103 @@ func runParallel(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	maybeThrow(ComposeExceptionsFromPipeline(exceptions))
 }
 
-// each takes a single closure and applies it to all input values.
-func each(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var f Fn
-	iterate := ScanArgsOptionalInput(ec, args, &f)
+// andThen calls the functions in turn, stopping at the first one that throws
+// an exception. The exception is rethrown, so that it can be caught. This is
+// similar to "a && b && c" in POSIX shells.
+func andThen(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var functions []Fn
+	ec.ScanArgsVariadic(args, &functions)
 	TakeNoOpt(opts)
 
-	broken := false
-	iterate(func(v types.Value) {
-		if broken {
+	for _, function := range functions {
+		function.Call(ec.fork("and-then function"), NoArgs, NoOpts)
+	}
+}
+
+// orElse calls the functions in turn, stopping at the first one that does not
+// throw any exception. If all of them throw exceptions, the last one is
+// rethrown. This is similar to "a || b || c" in POSIX shells.
+func orElse(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var functions []Fn
+	ec.ScanArgsVariadic(args, &functions)
+	TakeNoOpt(opts)
+
+	var err error
+	for _, function := range functions {
+		err = ec.fork("or-else function").PCall(function, NoArgs, NoOpts)
+		if err == nil {
 			return
 		}
+	}
+	maybeThrow(err)
+}
+
+// fanOut reads the inputs once, and sends each of them to all the functions,
+// which run in parallel. The outputs of the functions are merged, or collected
+// into one list for each function when &collect is true.
+func fanOut(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var functions []Fn
+	var collect bool
+	ec.ScanArgsVariadic(args, &functions)
+	ec.ScanOpts(opts, OptToScan{"collect", &collect, types.Bool(false)})
+
+	var waitg sync.WaitGroup
+	waitg.Add(len(functions))
+	inputs := make([]chan types.Value, len(functions))
+	exceptions := make([]*Exception, len(functions))
+	collected := make([][]types.Value, len(functions))
+	for i, function := range functions {
+		inputs[i] = make(chan types.Value, ec.valueBufferSize())
+		newec := ec.fork("[fan-out function]")
+		newec.ports[0] = &Port{File: DevNull, Chan: inputs[i]}
+		go func(i int, function Fn) {
+			var err error
+			if collect {
+				collected[i], err = newec.PCaptureOutput(function, NoArgs, NoOpts)
+			} else {
+				err = newec.PCall(function, NoArgs, NoOpts)
+			}
+			if err != nil {
+				exceptions[i] = err.(*Exception)
+			}
+			waitg.Done()
+			// Drain the remaining inputs, so that other functions do not get
+			// blocked.
+			for range inputs[i] {
+			}
+		}(i, function)
+	}
+
+	ec.IterateInputs(func(v types.Value) {
+		for _, input := range inputs {
+			input <- v
+		}
+	})
+	for _, input := range inputs {
+		close(input)
+	}
+
+	waitg.Wait()
+	maybeThrow(ComposeExceptionsFromPipeline(exceptions))
+	if collect {
+		out := ec.ports[1].Chan
+		for _, vs := range collected {
+			out <- types.MakeList(vs...)
+		}
+	}
+}
+
+// each takes a single closure and applies it to all input values. Like in a
+// for loop, break in the closure stops the iteration, without reading further
+// inputs, and continue skips to the next input. The closure can also be given
+// as a command name, like "each put".
+func each(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var fv types.Value
+	iterate := ScanArgsOptionalInputUntil(ec, args, &fv)
+	TakeNoOpt(opts)
+	f := fnOrCommand(ec, fv)
+
+	iterate(func(v types.Value) bool {
 		// NOTE We don't have the position range of the closure in the source.
 		// Ideally, it should be kept in the Closure itself.
 		newec := ec.fork("closure of each")
@@ -67,60 +161,233 @@ func each(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		ClosePorts(newec.ports)
 
 		if ex != nil {
-			switch ex.(*Exception).Cause {
-			case nil, Continue:
-				// nop
-			case Break:
-				broken = true
-			default:
+			flow, ok := loopFlow(ex.(*Exception).Cause, "")
+			if !ok {
 				throw(ex)
 			}
+			return flow != Break
 		}
+		return true
 	})
 }
 
 // peach takes a single closure and applies it to all input values in parallel.
+// At most &max-workers calls run at the same time, unless it is 0. When
+// &ordered is true, the value outputs of the calls are output in the order of
+// the inputs. A break in any call stops reading further inputs; calls that
+// have already started are still waited for. Like with each, the closure can
+// also be given as a command name.
 func peach(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var f Fn
-	iterate := ScanArgsOptionalInput(ec, args, &f)
-	TakeNoOpt(opts)
+	var (
+		fv         types.Value
+		maxWorkers int
+		ordered    bool
+	)
+	iterate := ScanArgsOptionalInputUntil(ec, args, &fv)
+	ec.ScanOpts(opts,
+		OptToScan{"max-workers", &maxWorkers, types.String("0")},
+		OptToScan{"ordered", &ordered, types.Bool(false)})
+	if maxWorkers < 0 {
+		throwf("&max-workers must be non-negative, got %d", maxWorkers)
+	}
+	f := fnOrCommand(ec, fv)
+
+	var sem chan struct{}
+	if maxWorkers > 0 {
+		sem = make(chan struct{}, maxWorkers)
+	}
+
+	// When ordered, outputs of each call are collected and sent to the
+	// emitter in the order of the inputs.
+	var results chan *peachResult
+	emitterDone := make(chan struct{})
+	if ordered {
+		results = make(chan *peachResult, ec.valueBufferSize())
+		go func() {
+			out := ec.ports[1].Chan
+			for r := range results {
+				<-r.done
+				for _, v := range r.values {
+					out <- v
+				}
+			}
+			close(emitterDone)
+		}()
+	}
 
 	var w sync.WaitGroup
+	var m sync.Mutex
 	broken := false
 	var err error
-	iterate(func(v types.Value) {
-		if broken || err != nil {
-			return
+	stopped := func() bool {
+		m.Lock()
+		defer m.Unlock()
+		return broken || err != nil
+	}
+	iterate(func(v types.Value) bool {
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		if stopped() {
+			if sem != nil {
+				<-sem
+			}
+			return false
 		}
 		w.Add(1)
+		// NOTE We don't have the position range of the closure in the source.
+		// Ideally, it should be kept in the Closure itself.
+		newec := ec.fork("closure of peach")
+		newec.ports[0] = DevNullClosedChan
+		var result *peachResult
+		if ordered {
+			result = &peachResult{done: make(chan struct{})}
+			results <- result
+		}
 		go func() {
-			// NOTE We don't have the position range of the closure in the source.
-			// Ideally, it should be kept in the Closure itself.
-			newec := ec.fork("closure of each")
-			newec.ports[0] = DevNullClosedChan
-			ex := newec.PCall(f, []types.Value{v}, NoOpts)
+			var ex error
+			if result != nil {
+				ch := make(chan types.Value)
+				collected := make(chan struct{})
+				go func() {
+					for v := range ch {
+						result.values = append(result.values, v)
+					}
+					close(collected)
+				}()
+				newec.ports[1] = &Port{File: newec.ports[1].File, Chan: ch}
+				ex = newec.PCall(f, []types.Value{v}, NoOpts)
+				close(ch)
+				<-collected
+				close(result.done)
+			} else {
+				ex = newec.PCall(f, []types.Value{v}, NoOpts)
+			}
 			ClosePorts(newec.ports)
 
 			if ex != nil {
-				switch ex.(*Exception).Cause {
-				case nil, Continue:
-					// nop
-				case Break:
-					broken = true
-				default:
+				m.Lock()
+				flow, ok := loopFlow(ex.(*Exception).Cause, "")
+				if !ok {
 					err = ex
+				} else if flow == Break {
+					broken = true
 				}
+				m.Unlock()
+			}
+			if sem != nil {
+				<-sem
 			}
 			w.Done()
 		}()
+		return true
 	})
 	w.Wait()
+	if ordered {
+		close(results)
+		<-emitterDone
+	}
 	maybeThrow(err)
 }
 
+type peachResult struct {
+	values []types.Value
+	done   chan struct{}
+}
+
+// Timeout is thrown by with-timeout when its body does not finish in time.
+type Timeout struct {
+	Duration time.Duration
+}
+
+func (t Timeout) Error() string {
+	return "timed out after " + t.Duration.String()
+}
+
+// withTimeout calls a function, and cancels it if it does not finish within
+// the given duration, which is either a number of seconds or a string like
+// "1m30s". Cancelling interrupts the function like ^C does, and also kills
+// the external commands that it is running. A Timeout is then thrown in place
+// of whatever exception the function has thrown.
+//
+// The function is waited for even after cancelling, so that it does not keep
+// running. Its value output is relayed, and dropped once it is cancelled, so
+// that it cannot get blocked writing values nobody reads; builtins that are
+// blocked reading may still delay it.
+func withTimeout(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		durationv types.String
+		f         Fn
+	)
+	ec.ScanArgs(args, &durationv, &f)
+	TakeNoOpt(opts)
+	d := parseTimeout(string(durationv))
+
+	dl, cancel, stop := ec.newCancelDeadline()
+	defer stop()
+	timer := time.AfterFunc(d, cancel)
+	defer timer.Stop()
+
+	newec := ec.fork("with-timeout body")
+	newec.deadline = dl
+	out := ec.ports[1]
+	relayed := make(chan types.Value)
+	newec.ports[1] = &Port{out.File, relayed, false, true, out.readerGone}
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		for v := range relayed {
+			select {
+			case out.Chan <- v:
+			case <-dl.expired:
+			case <-out.readerGone.done():
+			}
+		}
+	}()
+	err := newec.PCall(f, NoArgs, NoOpts)
+	ClosePorts(newec.ports)
+	<-relayDone
+	if err != nil {
+		select {
+		case <-dl.expired:
+			throw(Timeout{d})
+		default:
+		}
+	}
+	maybeThrow(err)
+}
+
+func parseTimeout(s string) time.Duration {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		throwf("bad duration %s", parse.Quote(s))
+	}
+	return d
+}
+
+// reduce threads an accumulator through all input values, starting with the
+// given initial value. Each call of the function receives the accumulator and
+// the input value, and outputs the new accumulator.
+func reduce(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		f   Fn
+		acc types.Value
+	)
+	iterate := ScanArgsOptionalInput(ec, args, &f, &acc)
+	TakeNoOpt(opts)
+
+	iterate(func(v types.Value) {
+		acc = callForOneValue(ec, f, acc, v)
+	})
+	ec.OutputChan() <- acc
+}
+
 func fail(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var msg types.String
-	ScanArgs(args, &msg)
+	ec.ScanArgs(args, &msg)
 	TakeNoOpt(opts)
 
 	throw(errors.New(string(msg)))
@@ -128,7 +395,7 @@ func fail(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func multiErrorFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var excs []*Exception
-	ScanArgsVariadic(args, &excs)
+	ec.ScanArgsVariadic(args, &excs)
 	TakeNoOpt(opts)
 
 	throw(PipelineError{excs})
@@ -142,15 +409,22 @@ func returnFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 }
 
 func breakFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	TakeNoArg(args)
-	TakeNoOpt(opts)
-
-	throw(Break)
+	throwLoopFlow(Break, args, opts)
 }
 
 func continueFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	throwLoopFlow(Continue, args, opts)
+}
+
+// throwLoopFlow throws the given flow, or a LabeledFlow if the &label option
+// is given.
+func throwLoopFlow(flow Flow, args []types.Value, opts map[string]types.Value) {
+	var label string
 	TakeNoArg(args)
-	TakeNoOpt(opts)
+	ScanOpts(opts, OptToScan{"label", &label, types.String("")})
 
-	throw(Continue)
+	if label == "" {
+		throw(flow)
+	}
+	throw(LabeledFlow{flow, label})
 }
@@ -1,20 +1,82 @@
 package eval
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/eval/types"
+)
 
 func TestBuiltinFnFlow(t *testing.T) {
 	runTests(t, []Test{
 		{`run-parallel { put lorem } { echo ipsum }`,
 			want{out: strs("lorem"), bytesOut: []byte("ipsum\n")}},
 
+		{`and-then { put a } { put b }`, want{out: strs("a", "b")}},
+		{`and-then { put a } { fail bad } { put c }`,
+			want{out: strs("a"), err: errAny}},
+		{`try { and-then { fail bad } { put b } } except e { put caught }`,
+			want{out: strs("caught")}},
+		{`or-else { fail bad } { put b } { put c }`, want{out: strs("b")}},
+		{`or-else { put a } { put b }`, want{out: strs("a")}},
+		{`or-else { fail bad } { fail worse }`, want{err: errAny}},
+
+		{`range 1 5 | fan-out &collect { count } { reduce $+~ 0 }`,
+			want{out: []types.Value{
+				types.MakeList(types.String("4")),
+				types.MakeList(types.String("10"))}}},
+		{`put a | fan-out { put (all)x }`, want{out: strs("ax")}},
+		{`put a | fan-out { fail bad }`, want{err: errAny}},
+
 		{`put 1 233 | each $put~`, want{out: strs("1", "233")}},
 		{`echo "1\n233" | each $put~`, want{out: strs("1", "233")}},
 		{`each $put~ [1 233]`, want{out: strs("1", "233")}},
+		// Command names are resolved like the heads of command forms.
+		{`each put [1 233]`, want{out: strs("1", "233")}},
+		{`fn f [x]{ put f$x }; each f [1 2]`, want{out: strs("f1", "f2")}},
+		{`each [1 2] [3]`, want{err: errAny}},
 		{`range 10 | each [x]{ if (== $x 4) { break }; put $x }`,
 			want{out: strs("0", "1", "2", "3")}},
+		{`each [x]{ if (== $x 2) { continue }; put $x } [1 2 3]`,
+			want{out: strs("1", "3")}},
+		{`for &label=outer x [a b] { each [y]{ put $x$y; break &label=outer } [c d] }`,
+			want{out: strs("ac")}},
 		{`range 10 | each [x]{ if (== $x 4) { fail haha }; put $x }`,
 			want{out: strs("0", "1", "2", "3"), err: errAny}},
-		// TODO: test peach
+		{`range 3 | peach &ordered put`, want{out: strs("0", "1", "2")}},
+		{`range 5 | peach [x]{ + $x 1 } | order`,
+			want{out: strs("1", "2", "3", "4", "5")}},
+		{`range 5 | peach &ordered [x]{ esleep (/ (- 5 $x) 200); put $x $x }`,
+			want{out: strs("0", "0", "1", "1", "2", "2", "3", "3", "4", "4")}},
+		{`range 5 | peach &max-workers=1 &ordered [x]{ put $x }`,
+			want{out: strs("0", "1", "2", "3", "4")}},
+		{`peach &max-workers=-1 $put~ [a]`, want{err: errAny}},
+		{`peach [x]{ fail bad } [a]`, want{err: errAny}},
+		{`range 5 | peach &max-workers=1 &ordered [x]{ if (== $x 2) { break }; put $x }`,
+			want{out: strs("0", "1")}},
+		{`peach &ordered [x]{ if (== $x 2) { continue }; put $x } [1 2 3]`,
+			want{out: strs("1", "3")}},
+
+		{`with-timeout 1 { put a }`, want{out: strs("a")}},
+		{`with-timeout 1 { put a b; put c } | take 2`, want{out: strs("a", "b")}},
+		// Values output before the deadline expires are kept.
+		{`with-timeout 50ms { put a; sleep 5; put b }`,
+			want{out: strs("a"), err: Timeout{50 * time.Millisecond}}},
+		{`with-timeout 0.05 { sleep 5 }`,
+			want{err: Timeout{50 * time.Millisecond}}},
+		{`with-timeout 50ms { while $true { } }`,
+			want{err: Timeout{50 * time.Millisecond}}},
+		{`with-timeout 50ms { e:sleep 5 }`,
+			want{err: Timeout{50 * time.Millisecond}}},
+		{`with-timeout 50ms { with-timeout 10s { e:sleep 5 } }`,
+			want{err: Timeout{50 * time.Millisecond}}},
+		{`with-timeout 1 { fail bad }`, want{err: errAny}},
+		{`with-timeout bad { }`, want{err: errAny}},
+
+		{`range 1 5 | reduce $+~ 0`, want{out: strs("10")}},
+		{`reduce [acc x]{ put $acc$x } '' [a b c]`, want{out: strs("abc")}},
+		{`reduce $+~ 0 []`, want{out: strs("0")}},
+		{`put 1 | reduce [acc x]{ } 0`, want{err: errAny}},
 
 		{`fail haha`, want{err: errAny}},
 		{`return`, want{err: Return}},
@@ -0,0 +1,155 @@
+package eval
+
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/xiaq/persistent/hash"
+)
+
+// Function composition and partial application.
+//
+// The builtins here take functions either as values, like $put~, or as
+// command names, like put, which are resolved in the same way as the head of
+// a command form, at the time the new function is created. The iterating
+// builtins each and peach take their functions in the same way.
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"comp", comp},
+		{"partial", partial},
+	})
+}
+
+// comp outputs the composition of its arguments. Calling the composition with
+// some arguments and options calls the last function with them, then each
+// function before it with the outputs of the function after it as arguments,
+// like command substitution does. For instance, "(comp $f~ $g~) a" is
+// equivalent to "f (g a)". Only the outputs of the first function are written
+// to the output of the composition.
+func comp(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+	if len(args) == 0 {
+		throw(ErrArgs)
+	}
+
+	fns := make([]Fn, len(args))
+	for i, arg := range args {
+		fns[i] = fnOrCommand(ec, arg)
+	}
+	ec.OutputChan() <- &composedFn{fns}
+}
+
+// partial outputs a function that calls the first argument with the rest of
+// the arguments followed by its own arguments. Options given to partial are
+// also passed on, unless they are overridden by options of the same names.
+// For instance, "(partial put a) b" is equivalent to "put a b".
+func partial(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	if len(args) == 0 {
+		throw(ErrArgs)
+	}
+
+	ec.OutputChan() <- &partialFn{fnOrCommand(ec, args[0]), args[1:], opts}
+}
+
+// fnOrCommand returns v if it is a function, or the command it names if it is
+// a string.
+func fnOrCommand(ec *Frame, v types.Value) Fn {
+	switch v := v.(type) {
+	case Fn:
+		return v
+	case types.String:
+		return resolve(string(v), ec)
+	}
+	throwf("need fn or command name, got %s", v.Kind())
+	panic("unreachable")
+}
+
+// composedFn is a function created by comp.
+type composedFn struct {
+	fns []Fn
+}
+
+var _ Fn = &composedFn{}
+
+func (*composedFn) Kind() string {
+	return "fn"
+}
+
+// Equal compares based on identity.
+func (c *composedFn) Equal(rhs interface{}) bool {
+	return c == rhs
+}
+
+func (c *composedFn) Hash() uint32 {
+	return hash.Pointer(unsafe.Pointer(c))
+}
+
+// Repr returns an opaque representation like "<comp <builtin put> <closure
+// 0x23333333>>".
+func (c *composedFn) Repr(int) string {
+	reprs := make([]string, len(c.fns))
+	for i, fn := range c.fns {
+		reprs[i] = fn.Repr(types.NoPretty)
+	}
+	return "<comp " + strings.Join(reprs, " ") + ">"
+}
+
+func (c *composedFn) Call(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	last := len(c.fns) - 1
+	for i := last; i > 0; i-- {
+		var err error
+		args, err = ec.PCaptureOutput(c.fns[i], args, opts)
+		maybeThrow(err)
+		opts = NoOpts
+	}
+	c.fns[0].Call(ec, args, opts)
+}
+
+// partialFn is a function created by partial.
+type partialFn struct {
+	fn   Fn
+	args []types.Value
+	opts map[string]types.Value
+}
+
+var _ Fn = &partialFn{}
+
+func (*partialFn) Kind() string {
+	return "fn"
+}
+
+// Equal compares based on identity.
+func (p *partialFn) Equal(rhs interface{}) bool {
+	return p == rhs
+}
+
+func (p *partialFn) Hash() uint32 {
+	return hash.Pointer(unsafe.Pointer(p))
+}
+
+// Repr returns an opaque representation like "<partial <builtin put> a b>".
+func (p *partialFn) Repr(int) string {
+	reprs := []string{p.fn.Repr(types.NoPretty)}
+	for _, arg := range p.args {
+		reprs = append(reprs, arg.Repr(types.NoPretty))
+	}
+	return "<partial " + strings.Join(reprs, " ") + ">"
+}
+
+func (p *partialFn) Call(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	allArgs := make([]types.Value, 0, len(p.args)+len(args))
+	allArgs = append(append(allArgs, p.args...), args...)
+	allOpts := opts
+	if len(p.opts) > 0 {
+		allOpts = make(map[string]types.Value, len(p.opts)+len(opts))
+		for k, v := range p.opts {
+			allOpts[k] = v
+		}
+		for k, v := range opts {
+			allOpts[k] = v
+		}
+	}
+	p.fn.Call(ec, allArgs, allOpts)
+}
@@ -0,0 +1,32 @@
+package eval
+
+import "testing"
+
+func TestBuiltinFnFn(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("(partial put a b) c").WantOutStrings("a", "b", "c"),
+		NewTest("(partial $put~) a").WantOutStrings("a"),
+		NewTest("f = (partial repr); $f [a]").WantBytesOutString("[a]\n"),
+		NewTest("(partial take 2) [a b c]").WantOutStrings("a", "b"),
+		// Options are merged, with those given to the call taking precedence.
+		NewTest("(partial print &sep=, a) b").WantBytesOutString("a,b"),
+		NewTest("(partial print &sep=, a) &sep=- b").WantBytesOutString("a-b"),
+		NewTest("fn f [x]{ put $x$x }; (partial f) a").WantOutStrings("aa"),
+		NewTest("repr (partial put a)").WantBytesOutString("<partial <builtin put> a>\n"),
+		NewTest("partial").WantAnyErr(),
+		NewTest("partial [a]").WantAnyErr(),
+
+		NewTest("(comp put) a b").WantOutStrings("a", "b"),
+		NewTest("(comp count $put~) [a b]").WantOutStrings("2"),
+		NewTest("(comp [@xs]{ put (count $xs) } (partial splits ,)) a,b,c").WantOutStrings("3"),
+		// Byte outputs of inner functions are taken as lines.
+		NewTest("(comp put echo) a b").WantOutStrings("a b"),
+		// Options go to the last function, the first one to be called.
+		NewTest("(comp [@xs]{ count $xs } range) &step=2 10").WantOutStrings("5"),
+		NewTest("repr (comp count put)").
+			WantBytesOutString("<comp <builtin count> <builtin put>>\n"),
+		NewTest("(comp put [x]{ fail bad }) a").WantAnyErr(),
+		NewTest("comp").WantAnyErr(),
+		NewTest("comp put &foo=bar").WantAnyErr(),
+	})
+}
@@ -0,0 +1,688 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/sys"
+	"github.com/elves/elvish/util"
+)
+
+// Parsing and writing of textual data formats.
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"from-columns", fromColumns},
+		{"to-table", toTable},
+
+		{"from-ini", fromINI},
+		{"to-ini", toINI},
+
+		{"from-dotenv", fromDotenv},
+		{"to-dotenv", toDotenv},
+		{"load-env", loadEnv},
+	})
+}
+
+type fromColumnsOptions struct {
+	Header  bool
+	Columns types.Value
+}
+
+// fromColumns parses whitespace-aligned columnar text, like the output of ps
+// or df, into maps.
+//
+// When &header is true (the default), the first line contains the column
+// names, and the boundaries of columns are inferred from the positions of the
+// column names and the runs of whitespace shared by all lines; this works for
+// both left- and right-aligned columns. The &columns option can be used to
+// supply the column names instead; when there is no header, fields are split
+// by whitespace, and the last column takes the rest of the line.
+func fromColumns(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	options := fromColumnsOptions{Header: true}
+	ec.ScanOptsToStruct(opts, &options)
+
+	var names []string
+	if options.Columns != nil {
+		columns, ok := options.Columns.(types.List)
+		if !ok {
+			throwf("&columns must be list, got %s", options.Columns.Kind())
+		}
+		columns.Iterate(func(v types.Value) bool {
+			names = append(names, types.ToString(v))
+			return true
+		})
+	}
+
+	lines := readLines(ec.ports[0].File)
+	out := ec.ports[1].Chan
+	if !options.Header {
+		if names == nil {
+			throwf("need &columns when there is no header")
+		}
+		for _, line := range lines {
+			out <- makeRow(names, splitFields(line, len(names)))
+		}
+		return
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+	header, rows := lines[0], lines[1:]
+	headerNames, starts := columnStarts(header, lines)
+	if names == nil {
+		names = headerNames
+	} else if len(names) != len(headerNames) {
+		throwf("&columns has %d names, but the header has %d columns", len(names), len(headerNames))
+	}
+	for _, line := range rows {
+		out <- makeRow(names, splitAt([]rune(line), starts))
+	}
+}
+
+func readLines(r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	maybeThrow(scanner.Err())
+	return lines
+}
+
+// splitFields splits a line into at most n whitespace-separated fields.
+func splitFields(line string, n int) []string {
+	var fields []string
+	line = strings.TrimSpace(line)
+	for len(fields) < n-1 && line != "" {
+		i := strings.IndexFunc(line, unicode.IsSpace)
+		if i == -1 {
+			break
+		}
+		fields = append(fields, line[:i])
+		line = strings.TrimLeftFunc(line[i:], unicode.IsSpace)
+	}
+	if line != "" {
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+// columnStarts finds the names of the columns in the header, and the rune
+// offset at which each column starts. A column starts at the beginning of the
+// run of non-blank text, across all lines, that contains the column name.
+func columnStarts(header string, lines []string) ([]string, []int) {
+	var names []string
+	var nameStarts []int
+	headerRunes := []rune(header)
+	for i := 0; i < len(headerRunes); {
+		if unicode.IsSpace(headerRunes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(headerRunes) && !unicode.IsSpace(headerRunes[j]) {
+			j++
+		}
+		names = append(names, string(headerRunes[i:j]))
+		nameStarts = append(nameStarts, i)
+		i = j
+	}
+
+	// blank[i] is whether the i-th rune is blank in all lines.
+	var blank []bool
+	for _, line := range lines {
+		for i, r := range []rune(line) {
+			for len(blank) <= i {
+				blank = append(blank, true)
+			}
+			if !unicode.IsSpace(r) {
+				blank[i] = false
+			}
+		}
+	}
+
+	starts := make([]int, len(nameStarts))
+	for k, pos := range nameStarts {
+		start := pos
+		for start > 0 && !blank[start-1] {
+			start--
+		}
+		if k > 0 && start <= starts[k-1] {
+			// Shares a run with the previous column.
+			start = pos
+		}
+		starts[k] = start
+	}
+	return names, starts
+}
+
+// splitAt splits a line into fields at the given rune offsets, trimming
+// whitespace around each field.
+func splitAt(line []rune, starts []int) []string {
+	fields := make([]string, len(starts))
+	for k, start := range starts {
+		end := len(line)
+		if k+1 < len(starts) && starts[k+1] < end {
+			end = starts[k+1]
+		}
+		if start < end {
+			fields[k] = strings.TrimSpace(string(line[start:end]))
+		}
+	}
+	return fields
+}
+
+func makeRow(names, fields []string) types.Map {
+	m := types.EmptyMap
+	for i, name := range names {
+		field := ""
+		if i < len(fields) {
+			field = fields[i]
+		}
+		m = m.Assoc(types.String(name), types.String(field)).(types.Map)
+	}
+	return m
+}
+
+type toTableOptions struct {
+	Columns  types.Value
+	Header   bool
+	MaxWidth int
+	Width    int
+	Sep      string
+	Color    string
+}
+
+// toTable writes maps or lists as an aligned table, one row for each value.
+//
+// The columns are the indices of the lists, or the keys of the maps: the
+// sorted keys of the first map, followed by the sorted keys of each later map
+// that are not columns yet. The &columns option selects the columns instead.
+// A header with the column names is written when the rows are maps and &header
+// is true (the default). Columns where all cells are numbers are aligned to
+// the right.
+//
+// Each column is at most &max-width wide, unless it is 0 (the default). The
+// whole table is at most &width wide; the widest columns are narrowed to fit.
+// When &width is -1 (the default), it is the width of the terminal if the
+// output is one, and unlimited otherwise. Cells that are too wide are truncated
+// with an ellipsis. &color is one of "auto" (the default), "always" and
+// "never", and controls whether the header is written in bold; "auto" does so
+// when the output is a terminal.
+func toTable(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	options := toTableOptions{Header: true, Width: -1, Sep: "  ", Color: "auto"}
+	ec.ScanOptsToStruct(opts, &options)
+
+	out := ec.OutputFile()
+	var color bool
+	switch options.Color {
+	case "auto":
+		color = sys.IsATTY(out)
+	case "always":
+		color = true
+	case "never":
+		color = false
+	default:
+		throwf("&color must be auto, always or never, got %s", options.Color)
+	}
+	width := options.Width
+	if width == -1 {
+		width = 0
+		if sys.IsATTY(out) {
+			_, width = sys.GetWinsize(out)
+		}
+	}
+
+	var names []string
+	if options.Columns != nil {
+		columns, ok := options.Columns.(types.List)
+		if !ok {
+			throwf("&columns must be list, got %s", options.Columns.Kind())
+		}
+		columns.Iterate(func(v types.Value) bool {
+			names = append(names, types.ToString(v))
+			return true
+		})
+	}
+	seen := make(map[string]bool)
+	addName := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	hasMap := false
+	var rows []types.Value
+	iterate(func(v types.Value) {
+		switch v := v.(type) {
+		case types.List:
+			if options.Columns == nil {
+				for i := 0; i < v.Len(); i++ {
+					addName(strconv.Itoa(i))
+				}
+			}
+		case types.MapLike:
+			hasMap = true
+			if options.Columns == nil {
+				for _, k := range sortedKeys(v) {
+					addName(k)
+				}
+			}
+		default:
+			throwf("need map or list, got %s", v.Kind())
+		}
+		rows = append(rows, v)
+	})
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(names))
+		for j, name := range names {
+			cells[i][j] = tableCell(row, name)
+		}
+	}
+	header := options.Header && hasMap
+
+	widths := make([]int, len(names))
+	rightAligned := make([]bool, len(names))
+	for j, name := range names {
+		if header {
+			widths[j] = util.StyledWcswidth(name)
+		}
+		numeric, nonEmpty := true, false
+		for i := range cells {
+			cell := cells[i][j]
+			if w := util.StyledWcswidth(cell); w > widths[j] {
+				widths[j] = w
+			}
+			if cell != "" {
+				nonEmpty = true
+				if _, err := strconv.ParseFloat(cell, 64); err != nil {
+					numeric = false
+				}
+			}
+		}
+		rightAligned[j] = numeric && nonEmpty
+		if options.MaxWidth > 0 && widths[j] > options.MaxWidth {
+			widths[j] = options.MaxWidth
+		}
+	}
+	if width > 0 {
+		shrinkColumns(widths, width-util.Wcswidth(options.Sep)*(len(names)-1))
+	}
+
+	writeRow := func(row []string, bold bool) {
+		var line bytes.Buffer
+		for j, cell := range row {
+			if j > 0 {
+				line.WriteString(options.Sep)
+			}
+			cell = util.TruncateWcwidth(cell, widths[j], "…")
+			if bold {
+				cell = "\033[1m" + cell + "\033[m"
+			}
+			if rightAligned[j] {
+				line.WriteString(util.PadWcwidth(cell, widths[j], 1))
+			} else if j < len(row)-1 {
+				line.WriteString(util.PadWcwidth(cell, widths[j], -1))
+			} else {
+				line.WriteString(cell)
+			}
+		}
+		line.WriteString("\n")
+		out.Write(line.Bytes())
+	}
+	if header {
+		writeRow(names, color)
+	}
+	for _, row := range cells {
+		writeRow(row, false)
+	}
+}
+
+// tableCell returns the content of one cell of a to-table row. Newlines are
+// replaced with spaces so that they do not break the table.
+func tableCell(row types.Value, name string) string {
+	var v types.Value
+	switch row := row.(type) {
+	case types.List:
+		i, err := strconv.Atoi(name)
+		if err == nil && 0 <= i && i < row.Len() {
+			v = row.IndexOne(types.String(name))
+		}
+	case types.MapLike:
+		if row.HasKey(types.String(name)) {
+			v = row.IndexOne(types.String(name))
+		}
+	}
+	if v == nil {
+		return ""
+	}
+	return strings.Replace(types.ToString(v), "\n", " ", -1)
+}
+
+// shrinkColumns narrows the widest of the columns until their widths add up
+// to at most total, keeping every column at least one wide.
+func shrinkColumns(widths []int, total int) {
+	sum := 0
+	for _, w := range widths {
+		sum += w
+	}
+	for sum > total {
+		widest := 0
+		for j, w := range widths {
+			if w > widths[widest] {
+				widest = j
+			}
+		}
+		if widths[widest] <= 1 {
+			return
+		}
+		widths[widest]--
+		sum--
+	}
+}
+
+// fromINI parses INI data into a map from section names to maps from keys to
+// values. Keys that appear before any section header are put in the section
+// with an empty name.
+func fromINI(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- parseINI(ec.ports[0].File)
+}
+
+func parseINI(r io.Reader) types.Map {
+	sections := types.EmptyMap
+	section := types.String("")
+	lineno := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			continue
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				throwf("line %d: unterminated section header", lineno)
+			}
+			section = types.String(strings.TrimSpace(line[1 : len(line)-1]))
+			if !sections.HasKey(section) {
+				sections = sections.Assoc(section, types.EmptyMap).(types.Map)
+			}
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i == -1 {
+			throwf("line %d: missing = in key-value pair", lineno)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := unquoteINIValue(strings.TrimSpace(line[i+1:]))
+		m := types.EmptyMap
+		if sections.HasKey(section) {
+			m = sections.IndexOne(section).(types.Map)
+		}
+		m = m.Assoc(types.String(key), types.String(value)).(types.Map)
+		sections = sections.Assoc(section, m).(types.Map)
+	}
+	maybeThrow(scanner.Err())
+	return sections
+}
+
+// unquoteINIValue removes the quotes around a value. Single-quoted values are
+// taken literally, while double-quoted values support the escape sequences
+// written by quoteValue.
+func unquoteINIValue(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			return unescapeValue(s[1 : len(s)-1])
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// toINI writes maps in the format that from-ini outputs as INI data. The
+// section with an empty name is written first, without a section header.
+// Values that contain newlines, have spaces around them or start with a quote
+// are double-quoted.
+func toINI(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := scanOptionalMapArg(ec, args)
+	TakeNoOpt(opts)
+
+	out := ec.ports[1].File
+	iterate(func(v types.Value) {
+		sections, ok := v.(types.MapLike)
+		if !ok {
+			throwf("to-ini wants map input, got %s", v.Kind())
+		}
+		for i, name := range sortedKeys(sections) {
+			section, ok := sections.IndexOne(types.String(name)).(types.MapLike)
+			if !ok {
+				throwf("section %s must be map", name)
+			}
+			if name != "" {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				fmt.Fprintf(out, "[%s]\n", name)
+			}
+			for _, key := range sortedKeys(section) {
+				value := types.ToString(section.IndexOne(types.String(key)))
+				if needsINIQuote(value) {
+					value = quoteValue(value)
+				}
+				fmt.Fprintf(out, "%s = %s\n", key, value)
+			}
+		}
+	})
+}
+
+func needsINIQuote(s string) bool {
+	return strings.ContainsAny(s, "\n\r") || s != strings.TrimSpace(s) ||
+		strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'")
+}
+
+// quoteValue double-quotes a value of INI or dotenv data, escaping double
+// quotes, backslashes, newlines, carriage returns and tabs with backslashes.
+func quoteValue(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// unescapeValue is the inverse of quoteValue, applied to the text between the
+// quotes. Backslashes followed by other characters are kept.
+func unescapeValue(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '"', '\\':
+			buf.WriteByte(s[i])
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		default:
+			buf.WriteByte('\\')
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String()
+}
+
+// scanOptionalMapArg scans an optional argument that is a map to be written.
+// The return value is a function that calls its argument with the map if it
+// exists, or with each input otherwise.
+func scanOptionalMapArg(ec *Frame, args []types.Value) func(func(types.Value)) {
+	switch len(args) {
+	case 0:
+		return ec.IterateInputs
+	case 1:
+		return func(f func(types.Value)) { f(args[0]) }
+	default:
+		throwf("arity mistmatch: want 0 or 1 arguments, got %d", len(args))
+		return nil
+	}
+}
+
+// sortedKeys returns the keys of a map as sorted strings.
+func sortedKeys(m types.MapLike) []string {
+	var keys []string
+	m.IterateKey(func(k types.Value) bool {
+		keys = append(keys, types.ToString(k))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// fromDotenv parses dotenv data into a map from variable names to values.
+func fromDotenv(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- parseDotenv(ec.ports[0].File)
+}
+
+// parseDotenv parses dotenv data. Each non-empty line that is not a comment is
+// a NAME=value pair, optionally preceded by "export". Values may be quoted;
+// single-quoted values are taken literally, while double-quoted values support
+// the escape sequences written by quoteValue. Unquoted values end at a " #"
+// comment.
+func parseDotenv(r io.Reader) types.Map {
+	vars := types.EmptyMap
+	lineno := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			throwf("line %d: missing = in variable definition", lineno)
+		}
+		name := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		switch {
+		case strings.HasPrefix(value, "'"):
+			j := strings.IndexByte(value[1:], '\'')
+			if j == -1 {
+				throwf("line %d: unterminated single-quoted value", lineno)
+			}
+			value = value[1 : j+1]
+		case strings.HasPrefix(value, "\""):
+			j := 1
+			for j < len(value) && value[j] != '"' {
+				if value[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(value) {
+				throwf("line %d: unterminated double-quoted value", lineno)
+			}
+			value = unescapeValue(value[1:j])
+		default:
+			if j := strings.Index(value, " #"); j != -1 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		vars = vars.Assoc(types.String(name), types.String(value)).(types.Map)
+	}
+	maybeThrow(scanner.Err())
+	return vars
+}
+
+// toDotenv writes maps as dotenv data. Values that are not made up of only
+// "safe" characters are double-quoted.
+func toDotenv(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := scanOptionalMapArg(ec, args)
+	TakeNoOpt(opts)
+
+	out := ec.ports[1].File
+	iterate(func(v types.Value) {
+		vars, ok := v.(types.MapLike)
+		if !ok {
+			throwf("to-dotenv wants map input, got %s", v.Kind())
+		}
+		for _, name := range sortedKeys(vars) {
+			value := types.ToString(vars.IndexOne(types.String(name)))
+			if strings.IndexFunc(value, isUnsafeDotenvRune) != -1 {
+				value = quoteValue(value)
+			}
+			fmt.Fprintf(out, "%s=%s\n", name, value)
+		}
+	})
+}
+
+func isUnsafeDotenvRune(r rune) bool {
+	return !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_./:,@%+", r))
+}
+
+// loadEnv reads a dotenv file and sets the environment variables in it. When
+// &override is false, variables that are already set are left intact.
+func loadEnv(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		fname    string
+		override bool
+	)
+	ec.ScanArgs(args, &fname)
+	ec.ScanOpts(opts, OptToScan{"override", &override, types.Bool(true)})
+
+	f, err := os.Open(fname)
+	maybeThrow(err)
+	defer f.Close()
+	vars := parseDotenv(f)
+	vars.IteratePair(func(k, v types.Value) bool {
+		name := types.ToString(k)
+		if _, exists := os.LookupEnv(name); exists && !override {
+			return true
+		}
+		maybeThrow(os.Setenv(name, types.ToString(v)))
+		return true
+	})
+}
@@ -0,0 +1,96 @@
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func row(pairs ...string) types.Value {
+	m := make(map[types.Value]types.Value)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		m[types.String(pairs[i])] = types.String(pairs[i+1])
+	}
+	return types.MakeMap(m)
+}
+
+func TestBuiltinFnFormat(t *testing.T) {
+	runTests(t, []Test{
+		{`print "  PID TTY          TIME CMD
+    1 ?        00:00:01 init
+12345 pts/0    00:00:00 bash -l
+" | from-columns`,
+			want{out: []types.Value{
+				row("PID", "1", "TTY", "?", "TIME", "00:00:01", "CMD", "init"),
+				row("PID", "12345", "TTY", "pts/0", "TIME", "00:00:00", "CMD", "bash -l"),
+			}}},
+		{`print "a  b\n1\n" | from-columns`,
+			want{out: []types.Value{row("a", "1", "b", "")}}},
+		{`print "x y z w\n" | from-columns &header=$false &columns=[a b c]`,
+			want{out: []types.Value{row("a", "x", "b", "y", "c", "z w")}}},
+		{`print "A B\n1 2\n" | from-columns &columns=[a b]`,
+			want{out: []types.Value{row("a", "1", "b", "2")}}},
+		{`print "x\n" | from-columns &header=$false`, want{err: errAny}},
+
+		{`to-table [[&name=foo &size=1] [&name=barbaz &size=100]]`,
+			want{bytesOut: []byte("name    size\nfoo        1\nbarbaz   100\n")}},
+		{`to-table [[&b=1 &a=2] [&c=3 &a=4]]`,
+			want{bytesOut: []byte("a  b  c\n2  1   \n4     3\n")}},
+		{`put [a bb] [ccc d] | to-table &sep=' | '`,
+			want{bytesOut: []byte("a   | bb\nccc | d\n")}},
+		{`to-table &columns=[size] &header=$false [[&name=foo &size=1]]`,
+			want{bytesOut: []byte("1\n")}},
+		{`to-table &max-width=4 &color=always [[&name=abcdefg]]`,
+			want{bytesOut: []byte("\033[1mname\033[m\nabc…\n")}},
+		{`to-table &width=7 [[&a=abcdef &b=xyz]]`,
+			want{bytesOut: []byte("a   b\na…  xyz\n")}},
+		{`to-table [foo]`, want{err: errAny}},
+
+		{`print "top = 1\n; comment\n[sec]\nk = 'v v'\nk2: v2\n" | from-ini`,
+			want{out: []types.Value{types.MakeMap(map[types.Value]types.Value{
+				types.String(""):    row("top", "1"),
+				types.String("sec"): row("k", "v v", "k2", "v2"),
+			})}}},
+		{`print "[sec\n" | from-ini`, want{err: errAny}},
+		{`to-ini [&''=[&top=1] &sec=[&k=v &a=b]]`,
+			want{bytesOut: []byte("top = 1\n\n[sec]\na = b\nk = v\n")}},
+		{`to-ini [&''=[&k="a\nb" &s=' x' &q='"x"' &b='a\b']]`,
+			want{bytesOut: []byte("b = a\\b\nk = \"a\\nb\"\nq = \"\\\"x\\\"\"\ns = \" x\"\n")}},
+		{`to-ini [&''=[&k="a\nb\\c\t" &s=' x' &q='"x"']] | from-ini`,
+			want{out: []types.Value{types.MakeMap(map[types.Value]types.Value{
+				types.String(""): row("k", "a\nb\\c\t", "q", `"x"`, "s", " x"),
+			})}}},
+
+		{`print "# comment\nA=1\nexport B='x y'\nC=\"a\\nb\"\nD=v # comment\n" | from-dotenv`,
+			want{out: []types.Value{row("A", "1", "B", "x y", "C", "a\nb", "D", "v")}}},
+		{`print "A\n" | from-dotenv`, want{err: errAny}},
+		// Only the escape sequences of quoteValue are recognized.
+		{`print 'A="\x41\$"' | from-dotenv`,
+			want{out: []types.Value{row("A", `\x41\$`)}}},
+		{`to-dotenv [&A="a\tb\x01"]`,
+			want{bytesOut: []byte("A=\"a\\tb\x01\"\n")}},
+		{`to-dotenv [&A=1 &B='x y']`,
+			want{bytesOut: []byte("A=1\nB=\"x y\"\n")}},
+		{`to-dotenv [&A=1 &B='x y'] | from-dotenv`,
+			want{out: []types.Value{row("A", "1", "B", "x y")}}},
+	})
+}
+
+func TestLoadEnv(t *testing.T) {
+	f, err := ioutil.TempFile("", "elvishtest.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("LOAD_ENV_TEST=new\n")
+	f.Close()
+
+	runTests(t, []Test{
+		NewTest(`E:LOAD_ENV_TEST=old; load-env `+f.Name()+` &override=$false
+		         put $E:LOAD_ENV_TEST; load-env `+f.Name()+`
+		         put $E:LOAD_ENV_TEST`).WantOutStrings("old", "new"),
+		NewTest(`load-env nonexistent`).WantAnyErr(),
+	})
+}
@@ -2,10 +2,14 @@ package eval
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/glob"
+	"github.com/elves/elvish/parse"
 	"github.com/elves/elvish/store/storedefs"
 	"github.com/elves/elvish/util"
 )
@@ -32,6 +36,13 @@ func init() {
 
 		// File types
 		{"-is-dir", isDir},
+
+		// Wildcard expansion
+		{"glob", globFn},
+
+		// Temporary files
+		{"temp-file", tempFile},
+		{"temp-dir", tempDir},
 	})
 }
 
@@ -82,7 +93,7 @@ func cd(ec *Frame, args []types.Value, opts map[string]types.Value) {
 }
 
 func cdInner(dir string, ec *Frame) {
-	maybeThrow(Chdir(dir, ec.DaemonClient))
+	maybeThrow(ec.Chdir(dir))
 }
 
 var dirDescriptor = types.NewStructDescriptor("path", "score")
@@ -111,7 +122,7 @@ func dirs(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func tildeAbbr(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var pathv types.String
-	ScanArgs(args, &pathv)
+	ec.ScanArgs(args, &pathv)
 	path := string(pathv)
 	TakeNoOpt(opts)
 
@@ -121,7 +132,7 @@ func tildeAbbr(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func isDir(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var pathv types.String
-	ScanArgs(args, &pathv)
+	ec.ScanArgs(args, &pathv)
 	path := string(pathv)
 	TakeNoOpt(opts)
 
@@ -132,3 +143,144 @@ func isDirInner(path string) bool {
 	fi, err := os.Stat(path)
 	return err == nil && fi.Mode().IsDir()
 }
+
+type globOptions struct {
+	Type           string
+	Hidden         bool
+	FollowSymlinks bool
+}
+
+// globFn outputs the names of files that match a pattern, which uses the same
+// wildcards as wildcard expansion in syntax and may start with a tilde. The
+// &type option restricts the output to "file" (regular files), "dir" or
+// "symlink"; &hidden makes wildcards match names that start with a dot; and
+// &follow-symlinks makes wildcards descend into symbolic links to directories.
+// Unlike wildcard expansion, having no match is not an error.
+func globFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var patternv types.String
+	ec.ScanArgs(args, &patternv)
+	var options globOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	var typeOK func(string) bool
+	stat := os.Lstat
+	if options.FollowSymlinks {
+		stat = os.Stat
+	}
+	switch options.Type {
+	case "":
+	case "file":
+		typeOK = func(name string) bool {
+			info, err := stat(name)
+			return err == nil && info.Mode().IsRegular()
+		}
+	case "dir":
+		typeOK = func(name string) bool {
+			info, err := stat(name)
+			return err == nil && info.IsDir()
+		}
+	case "symlink":
+		typeOK = func(name string) bool {
+			info, err := os.Lstat(name)
+			return err == nil && info.Mode()&os.ModeSymlink != 0
+		}
+	default:
+		throwf("&type should be file, dir or symlink, got %s", parse.Quote(options.Type))
+	}
+
+	p := parseGlobArg(string(patternv))
+	if options.Hidden {
+		for i, seg := range p.Segments {
+			if wild, ok := seg.(glob.Wild); ok {
+				wild.MatchHidden = true
+				p.Segments[i] = wild
+			}
+		}
+	}
+
+	out := ec.OutputChan()
+	interrupts := ec.Interrupts()
+	if !p.GlobWithOptions(glob.Options{FollowSymlinks: options.FollowSymlinks, FS: ec.Mounts}, func(name string) bool {
+		select {
+		case <-interrupts:
+			return false
+		default:
+		}
+		if typeOK == nil || typeOK(name) {
+			out <- types.String(name)
+		}
+		return true
+	}) {
+		throw(ErrInterrupted)
+	}
+}
+
+// parseGlobArg parses a glob pattern, expanding a leading tilde.
+func parseGlobArg(s string) glob.Pattern {
+	if !strings.HasPrefix(s, "~") {
+		return glob.Parse(s)
+	}
+	i := strings.IndexByte(s, '/')
+	if i == -1 {
+		i = len(s)
+	}
+	home := mustGetHome(s[1:i])
+	if i == len(s) {
+		return glob.Pattern{[]glob.Segment{glob.Literal{home}}, ""}
+	}
+	p := glob.Parse(s[i:])
+	p.DirOverride = home
+	return p
+}
+
+type tempOptions struct {
+	Dir     string
+	Cleanup bool
+}
+
+// tempFile creates a new empty file in &dir, or the default directory for
+// temporary files if it is empty, and outputs its path. The name of the file
+// is built from the optional pattern argument like in ioutil.TempFile. When
+// &cleanup is true, the file is removed when the enclosing function call or
+// top-level evaluation, and the background jobs started in it, have finished.
+func tempFile(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	pattern := scanTempPattern(args)
+	var options tempOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	f, err := ioutil.TempFile(options.Dir, pattern)
+	maybeThrow(err)
+	maybeThrow(f.Close())
+	if options.Cleanup {
+		name := f.Name()
+		ec.AddCleanup(func() { os.Remove(name) })
+	}
+	ec.OutputChan() <- types.String(f.Name())
+}
+
+// tempDir is like tempFile, but creates a directory. With &cleanup, the
+// directory is removed along with everything in it.
+func tempDir(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	pattern := scanTempPattern(args)
+	var options tempOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	name, err := ioutil.TempDir(options.Dir, pattern)
+	maybeThrow(err)
+	if options.Cleanup {
+		ec.AddCleanup(func() { os.RemoveAll(name) })
+	}
+	ec.OutputChan() <- types.String(name)
+}
+
+func scanTempPattern(args []types.Value) string {
+	pattern := types.String("elvish-*")
+	switch len(args) {
+	case 0:
+	case 1:
+		ScanArgs(args, &pattern)
+	default:
+		throwf("arity mistmatch: want 0 or 1 arguments, got %d", len(args))
+	}
+	return string(pattern)
+}
@@ -14,5 +14,24 @@ func TestBuiltinFnFS(t *testing.T) {
 
 		{`-is-dir ~/dir`, wantTrue}, // see testmain_test.go for setup
 		{`-is-dir ~/lorem`, wantFalse},
+
+		{`glob 'a*'`, want{out: strs("a1", "a10", "a2", "a3")}},
+		{`p = b; glob $p'?'`, want{out: strs("b1", "b2", "b3")}},
+		{`glob '*' &type=dir`, want{out: strs("dir", "dir2")}},
+		{`glob '?1' &type=file`, want{out: strs("a1", "b1", "c1")}},
+		{`eq [(glob '~/dir*')] [(put ~/dir*)]`, wantTrue},
+		{`count [(glob 'x*')]`, want{out: strs("0")}},
+		{`glob '*' &type=bad`, want{err: errAny}},
+
+		{`d = (temp-dir); -is-dir $d; rm -r $d; -is-dir $d`,
+			want{out: bools(true, false)}},
+		{`fn f { d = (temp-dir &cleanup); path-base (temp-file &dir=$d 'x-*.txt') }; has-prefix (f) x-`,
+			wantTrue},
+		{`f = (temp-file &cleanup); bool ?(test -f $f)`, wantTrue},
+		{`fn f { temp-dir &cleanup }; d = (f); -is-dir $d`, wantFalse},
+		{`fn f { d = (temp-dir &cleanup); put $d; { esleep 0.05; echo > $d/x } & }
+		  d = (f); -is-dir $d; esleep 0.3; -is-dir $d`,
+			want{out: bools(true, false)}},
+		{`temp-file a b`, want{err: errAny}},
 	})
 }
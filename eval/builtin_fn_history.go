@@ -0,0 +1,164 @@
+package eval
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/store/storedefs"
+)
+
+// Command history.
+
+var (
+	errBadHistoryTime   = errors.New("time should be a Unix timestamp, a duration like 2h30m, or a date like 2006-01-02")
+	errBadHistoryStatus = errors.New("status should be ok, failed or a number")
+)
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"history", historyFn},
+	})
+}
+
+var historyCmdDescriptor = types.NewStructDescriptor(
+	"seq", "cmd", "dir", "time", "duration", "status")
+
+// newHistoryCmdStruct converts a command history entry to a struct. The time
+// is a Unix timestamp, and the duration is in seconds. Information that is not
+// known is an empty string.
+func newHistoryCmdStruct(cmd storedefs.Cmd) *types.Struct {
+	var t, duration, status types.String
+	if !cmd.Time.IsZero() {
+		t = types.String(strconv.FormatInt(cmd.Time.Unix(), 10))
+		duration = floatToString(cmd.Duration.Seconds())
+	}
+	if cmd.Status != -1 {
+		status = types.String(strconv.Itoa(cmd.Status))
+	}
+	return types.NewStruct(historyCmdDescriptor, []types.Value{
+		types.String(strconv.Itoa(cmd.Seq)), types.String(cmd.Text),
+		types.String(cmd.Dir), t, duration, status})
+}
+
+type historyOptions struct {
+	Dir    string
+	Since  string
+	Until  string
+	Status string
+	Match  string
+}
+
+// historyFilter decides which entries of the command history are output by the
+// history builtin. Entries whose information is not known are only accepted
+// when there is no condition on it.
+type historyFilter struct {
+	dir          string
+	since, until time.Time
+	status       func(int) bool
+	match        *regexp.Regexp
+}
+
+func newHistoryFilter(opts historyOptions, now time.Time) (*historyFilter, error) {
+	f := &historyFilter{}
+	if opts.Dir != "" {
+		dir, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			return nil, err
+		}
+		f.dir = dir
+	}
+	var err error
+	if opts.Since != "" {
+		if f.since, err = parseHistoryTime(opts.Since, now); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Until != "" {
+		if f.until, err = parseHistoryTime(opts.Until, now); err != nil {
+			return nil, err
+		}
+	}
+	switch opts.Status {
+	case "":
+	case "ok":
+		f.status = func(s int) bool { return s == 0 }
+	case "failed":
+		f.status = func(s int) bool { return s > 0 }
+	default:
+		want, err := strconv.Atoi(opts.Status)
+		if err != nil || want < 0 {
+			return nil, errBadHistoryStatus
+		}
+		f.status = func(s int) bool { return s == want }
+	}
+	if opts.Match != "" {
+		if f.match, err = regexp.Compile(opts.Match); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseHistoryTime parses a Unix timestamp, a duration that is taken to be the
+// time that long ago, or a date in the local time zone.
+func parseHistoryTime(s string, now time.Time) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(f*float64(time.Second))), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errBadHistoryTime
+}
+
+func (f *historyFilter) accepts(cmd storedefs.Cmd) bool {
+	if f.dir != "" && cmd.Dir != f.dir {
+		return false
+	}
+	if !f.since.IsZero() && (cmd.Time.IsZero() || cmd.Time.Before(f.since)) {
+		return false
+	}
+	if !f.until.IsZero() && (cmd.Time.IsZero() || !cmd.Time.Before(f.until)) {
+		return false
+	}
+	if f.status != nil && (cmd.Status == -1 || !f.status(cmd.Status)) {
+		return false
+	}
+	if f.match != nil && !f.match.MatchString(cmd.Text) {
+		return false
+	}
+	return true
+}
+
+func historyFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	var options historyOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	if ec.DaemonClient == nil {
+		throw(ErrStoreNotConnected)
+	}
+	filter, err := newHistoryFilter(options, time.Now())
+	maybeThrow(err)
+	upto, err := ec.DaemonClient.NextCmdSeq()
+	if err != nil {
+		throw(errors.New("store error: " + err.Error()))
+	}
+	cmds, err := ec.DaemonClient.CmdsWithInfo(0, upto)
+	if err != nil {
+		throw(errors.New("store error: " + err.Error()))
+	}
+	out := ec.ports[1].Chan
+	for _, cmd := range cmds {
+		if filter.accepts(cmd) {
+			out <- newHistoryCmdStruct(cmd)
+		}
+	}
+}
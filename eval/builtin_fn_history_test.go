@@ -0,0 +1,63 @@
+package eval
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/store/storedefs"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	cmd := func(text, dir string, ago time.Duration, status int) storedefs.Cmd {
+		return storedefs.Cmd{Text: text, CmdInfo: storedefs.CmdInfo{
+			Dir: dir, Time: now.Add(-ago), Status: status}}
+	}
+	old := storedefs.Cmd{Text: "echo old", CmdInfo: storedefs.CmdInfo{Status: -1}}
+	cmds := []storedefs.Cmd{
+		old,
+		cmd("echo a", "/a", 3*time.Hour, 0),
+		cmd("make", "/a", 2*time.Hour, 2),
+		cmd("echo b", "/b", time.Hour, 0),
+		cmd("sleep 10", "/b", time.Minute, 130),
+	}
+	for _, test := range []struct {
+		opts historyOptions
+		want []string
+	}{
+		{historyOptions{}, []string{"echo old", "echo a", "make", "echo b", "sleep 10"}},
+		{historyOptions{Dir: "/a"}, []string{"echo a", "make"}},
+		{historyOptions{Since: "90m"}, []string{"echo b", "sleep 10"}},
+		{historyOptions{Until: "1h"}, []string{"echo a", "make"}},
+		{historyOptions{Since: "1499990000", Until: "1499999000"}, []string{"make", "echo b"}},
+		{historyOptions{Status: "ok"}, []string{"echo a", "echo b"}},
+		{historyOptions{Status: "failed"}, []string{"make", "sleep 10"}},
+		{historyOptions{Status: "130"}, []string{"sleep 10"}},
+		{historyOptions{Match: "^echo [ab]"}, []string{"echo a", "echo b"}},
+		{historyOptions{Dir: "/b", Status: "ok"}, []string{"echo b"}},
+	} {
+		f, err := newHistoryFilter(test.opts, now)
+		if err != nil {
+			t.Errorf("newHistoryFilter(%v) => error %v", test.opts, err)
+			continue
+		}
+		var got []string
+		for _, cmd := range cmds {
+			if f.accepts(cmd) {
+				got = append(got, cmd.Text)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("history with %v => %v, want %v", test.opts, got, test.want)
+		}
+	}
+
+	for _, opts := range []historyOptions{
+		{Since: "yesterday"}, {Status: "bad"}, {Match: "("},
+	} {
+		if _, err := newHistoryFilter(opts, now); err == nil {
+			t.Errorf("newHistoryFilter(%v) => no error", opts)
+		}
+	}
+}
@@ -1,13 +1,20 @@
 package eval
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
 )
 
 // Input and output.
@@ -25,33 +32,46 @@ func init() {
 
 		// Bytes to value
 		{"slurp", slurp},
+		{"bytes", bytesFn},
 		{"from-lines", fromLines},
+		{"from-terminated", fromTerminated},
 		{"from-json", fromJSON},
 
 		// Value to bytes
 		{"to-lines", toLines},
+		{"to-terminated", toTerminated},
 		{"to-json", toJSON},
 
+		// Conversion between strings and bytes values
+		{"string-to-bytes", stringToBytes},
+		{"bytes-to-string", bytesToString},
+		{"encode-hex", encodeHex},
+		{"encode-base64", encodeBase64},
+		{"decode-base64", decodeBase64},
+
 		// File and pipe
+		{"print-file", printFile},
 		{"fopen", fopen},
 		{"fclose", fclose},
 		{"pipe", pipe},
 		{"prclose", prclose},
 		{"pwclose", pwclose},
+		{"coproc", coproc},
+		{"output-fifo", outputFifo},
+		{"tee", tee},
 	})
 }
 
 func put(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoOpt(opts)
-	out := ec.ports[1].Chan
 	for _, a := range args {
-		out <- a
+		ec.Output(a)
 	}
 }
 
 func print(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var sepv types.String
-	ScanOpts(opts, OptToScan{"sep", &sepv, types.String(" ")})
+	ec.ScanOpts(opts, OptToScan{"sep", &sepv, types.String(" ")})
 
 	out := ec.ports[1].File
 	sep := string(sepv)
@@ -68,11 +88,15 @@ func echo(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	ec.ports[1].File.WriteString("\n")
 }
 
+// pprint writes the representations of the arguments laid out on multiple
+// lines. The &indent, &max-depth and &max-items options correspond to the
+// fields of types.PrettyOptions.
 func pprint(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	TakeNoOpt(opts)
+	var options types.PrettyOptions
+	ec.ScanOptsToStruct(opts, &options)
 	out := ec.ports[1].File
 	for _, arg := range args {
-		out.WriteString(arg.Repr(0))
+		out.WriteString(types.Pretty(arg, options))
 		out.WriteString("\n")
 	}
 }
@@ -89,47 +113,181 @@ func repr(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	out.WriteString("\n")
 }
 
+type slurpOptions struct {
+	Bytes bool
+}
+
+// slurp outputs all of the byte input as one string, or as a bytes value when
+// &bytes is set.
 func slurp(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoArg(args)
-	TakeNoOpt(opts)
+	var options slurpOptions
+	ec.ScanOptsToStruct(opts, &options)
 
 	in := ec.ports[0].File
 	out := ec.ports[1].Chan
 
 	all, err := ioutil.ReadAll(in)
 	maybeThrow(err)
-	out <- types.String(string(all))
+	if options.Bytes {
+		out <- types.Bytes(all)
+	} else {
+		out <- types.String(string(all))
+	}
+}
+
+// bytesFn outputs a bytes value from the hex digits of the bytes; it is the
+// inverse of the repr of bytes values.
+func bytesFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	TakeNoOpt(opts)
+
+	b, err := hex.DecodeString(string(s))
+	if err != nil {
+		throwf("bad hex string %s", parse.Quote(string(s)))
+	}
+	ec.OutputChan() <- types.Bytes(b)
+}
+
+// stringToBytes outputs a bytes value with the bytes of a string.
+func stringToBytes(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Bytes(s)
+}
+
+// bytesToString outputs a string with the bytes of a bytes value, which must
+// be valid UTF-8.
+func bytesToString(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var b types.Bytes
+	ec.ScanArgs(args, &b)
+	TakeNoOpt(opts)
+
+	if !utf8.ValidString(string(b)) {
+		throwf("%s is not valid UTF-8", b.Repr(types.NoPretty))
+	}
+	ec.OutputChan() <- types.String(b)
+}
+
+// encodeHex outputs the hex digits of the bytes of a string or bytes value.
+// The bytes builtin does the reverse.
+func encodeHex(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	b := scanStringOrBytes(args)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.String(hex.EncodeToString(b))
+}
+
+// encodeBase64 outputs the standard base64 encoding of the bytes of a string
+// or bytes value.
+func encodeBase64(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	b := scanStringOrBytes(args)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.String(base64.StdEncoding.EncodeToString(b))
 }
 
+// decodeBase64 outputs a bytes value from the standard base64 encoding of the
+// bytes.
+func decodeBase64(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	TakeNoOpt(opts)
+
+	b, err := base64.StdEncoding.DecodeString(string(s))
+	if err != nil {
+		throwf("bad base64 string %s", parse.Quote(string(s)))
+	}
+	ec.OutputChan() <- types.Bytes(b)
+}
+
+func scanStringOrBytes(args []types.Value) []byte {
+	var v types.Value
+	ScanArgs(args, &v)
+	switch v := v.(type) {
+	case types.String:
+		return []byte(v)
+	case types.Bytes:
+		return []byte(v)
+	default:
+		throwf("need string or bytes, got %s", v.Kind())
+		panic("unreachable")
+	}
+}
+
+type fromTerminatedOptions struct {
+	KeepTerminator bool
+}
+
+// fromLines outputs each line of the byte input as a value. With
+// &keep-terminator, the values keep their trailing newlines.
 func fromLines(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoArg(args)
-	TakeNoOpt(opts)
+	var options fromTerminatedOptions
+	ec.ScanOptsToStruct(opts, &options)
 
-	in := ec.ports[0].File
-	out := ec.ports[1].Chan
+	outputRecords(ec, "\n", options.KeepTerminator)
+}
 
-	linesToChan(in, out)
+// fromTerminated is like from-lines, but splits the byte input on the given
+// terminator, such as "\000" for the output of "find -print0".
+func fromTerminated(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var terminator types.String
+	ec.ScanArgs(args, &terminator)
+	var options fromTerminatedOptions
+	ec.ScanOptsToStruct(opts, &options)
+	if terminator == "" {
+		throwf("terminator must not be empty")
+	}
+
+	outputRecords(ec, string(terminator), options.KeepTerminator)
+}
+
+func outputRecords(ec *Frame, terminator string, keep bool) {
+	recordsToFunc(ec.ports[0].File, terminator, keep, func(v types.Value) bool {
+		ec.Output(v)
+		return true
+	})
+}
+
+type fromJSONOptions struct {
+	Ordered bool
 }
 
-// fromJSON parses a stream of JSON data into Value's.
+// fromJSON parses a stream of JSON data into Value's. When &ordered is set,
+// objects become maps that keep the order of their keys, so that they are
+// written back in the same order by to-json.
 func fromJSON(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	TakeNoArg(args)
-	TakeNoOpt(opts)
+	var options fromJSONOptions
+	ec.ScanOptsToStruct(opts, &options)
 
 	in := ec.ports[0].File
 	out := ec.ports[1].Chan
 
 	dec := json.NewDecoder(in)
-	var v interface{}
 	for {
-		err := dec.Decode(&v)
+		var v types.Value
+		var err error
+		if options.Ordered {
+			v, err = fromJSONTokens(dec)
+		} else {
+			var i interface{}
+			err = dec.Decode(&i)
+			if err == nil {
+				v = FromJSONInterface(i)
+			}
+		}
 		if err != nil {
 			if err == io.EOF {
 				return
 			}
 			throw(err)
 		}
-		out <- FromJSONInterface(v)
+		out <- v
 	}
 }
 
@@ -144,6 +302,20 @@ func toLines(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	})
 }
 
+// toTerminated writes each value followed by the given terminator, such as
+// "\000" for "xargs -0".
+func toTerminated(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var terminator types.String
+	iterate := ScanArgsOptionalInput(ec, args, &terminator)
+	TakeNoOpt(opts)
+
+	out := ec.ports[1].File
+
+	iterate(func(v types.Value) {
+		out.WriteString(types.ToString(v) + string(terminator))
+	})
+}
+
 // toJSON converts a stream of Value's to JSON data.
 func toJSON(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	iterate := ScanArgsOptionalInput(ec, args)
@@ -158,9 +330,95 @@ func toJSON(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	})
 }
 
+type printFileOptions struct {
+	From   int
+	To     int
+	Follow bool
+}
+
+// followInterval is how often print-file checks for new content in &follow
+// mode.
+var followInterval = 100 * time.Millisecond
+
+// printFile writes the content of a file, or of the byte input when no file is
+// given, to the byte output.
+//
+// Only bytes starting from the offset &from, up to but not including the
+// offset &to, are written; a negative &from counts from the end of the file,
+// and a negative &to (the default) means the end of the file. Inputs that are
+// not seekable are read and discarded up to &from instead, and do not support
+// a negative &from. When &follow is true, print-file keeps waiting for more
+// content after reaching the end of the file, like tail -f, until it is
+// interrupted or &to is reached.
+func printFile(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	options := printFileOptions{To: -1}
+	ec.ScanOptsToStruct(opts, &options)
+
+	var in *os.File
+	switch len(args) {
+	case 0:
+		in = ec.InputFile()
+	case 1:
+		var name types.String
+		ec.ScanArgs(args, &name)
+		f, err := os.Open(string(name))
+		maybeThrow(err)
+		defer f.Close()
+		in = f
+	default:
+		throwf("arity mistmatch: want 0 or 1 arguments, got %d", len(args))
+	}
+
+	pos := int64(options.From)
+	if pos < 0 {
+		end, err := in.Seek(0, io.SeekEnd)
+		if err != nil {
+			throwf("negative &from needs a seekable input")
+		}
+		pos += end
+		if pos < 0 {
+			pos = 0
+		}
+		_, err = in.Seek(pos, io.SeekStart)
+		maybeThrow(err)
+	} else if pos > 0 {
+		if _, err := in.Seek(pos, io.SeekStart); err != nil {
+			_, err := io.CopyN(ioutil.Discard, in, pos)
+			if err == io.EOF {
+				return
+			}
+			maybeThrow(err)
+		}
+	}
+
+	var r io.Reader = in
+	var limited *io.LimitedReader
+	if options.To >= 0 {
+		if int64(options.To) <= pos {
+			return
+		}
+		limited = &io.LimitedReader{R: in, N: int64(options.To) - pos}
+		r = limited
+	}
+
+	out := ec.OutputFile()
+	for {
+		_, err := io.Copy(out, r)
+		maybeThrow(err)
+		if !options.Follow || (limited != nil && limited.N == 0) {
+			return
+		}
+		select {
+		case <-ec.Interrupts():
+			throw(ErrInterrupted)
+		case <-time.After(followInterval):
+		}
+	}
+}
+
 func fopen(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var namev types.String
-	ScanArgs(args, &namev)
+	ec.ScanArgs(args, &namev)
 	name := string(namev)
 	TakeNoOpt(opts)
 
@@ -173,7 +431,7 @@ func fopen(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func fclose(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var f types.File
-	ScanArgs(args, &f)
+	ec.ScanArgs(args, &f)
 	TakeNoOpt(opts)
 
 	maybeThrow(f.Inner.Close())
@@ -191,7 +449,7 @@ func pipe(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func prclose(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var p types.Pipe
-	ScanArgs(args, &p)
+	ec.ScanArgs(args, &p)
 	TakeNoOpt(opts)
 
 	maybeThrow(p.ReadEnd.Close())
@@ -199,8 +457,167 @@ func prclose(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func pwclose(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var p types.Pipe
-	ScanArgs(args, &p)
+	ec.ScanArgs(args, &p)
 	TakeNoOpt(opts)
 
 	maybeThrow(p.WriteEnd.Close())
 }
+
+var coprocDescriptor = types.NewStructDescriptor("stdin", "stdout", "wait")
+
+// coproc calls a function in the background, with its byte input and output
+// connected to pipes, and outputs a struct with three fields: stdin, the file
+// that writes to its input; stdout, the file that reads from its output; and
+// wait, a function that closes stdin, waits for the function to finish and
+// rethrows the exception it throws, if any. Like with redirections to files,
+// the value input of the function is closed, and its value outputs are
+// discarded. Both files are closed when the innermost function call or
+// top-level evaluation finishes, which also ends the input of the function.
+func coproc(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Fn
+	ec.ScanArgs(args, &f)
+	TakeNoOpt(opts)
+
+	inReader, inWriter, err := os.Pipe()
+	maybeThrow(err)
+	outReader, outWriter, err := os.Pipe()
+	if err != nil {
+		inReader.Close()
+		inWriter.Close()
+		throw(err)
+	}
+
+	newEc := ec.fork("[coproc]")
+	newEc.ports[0] = &Port{File: inReader, Chan: ClosedChan, CloseFile: true}
+	newEc.ports[1] = &Port{File: outWriter, Chan: BlackholeChan, CloseFile: true}
+	done := make(chan struct{})
+	var callErr error
+	go func() {
+		callErr = newEc.PCall(f, NoArgs, NoOpts)
+		ClosePorts(newEc.ports)
+		close(done)
+	}()
+
+	wait := &BuiltinFn{"coproc:wait", func(ec *Frame, args []types.Value, opts map[string]types.Value) {
+		TakeNoArg(args)
+		TakeNoOpt(opts)
+
+		// Closing stdin again when wait is called more than once is harmless.
+		inWriter.Close()
+		select {
+		case <-done:
+		case <-ec.Interrupts():
+			throw(ErrInterrupted)
+		}
+		maybeThrow(callErr)
+	}}
+
+	ec.AddCleanup(func() {
+		inWriter.Close()
+		outReader.Close()
+	})
+	ec.OutputChan() <- types.NewStruct(coprocDescriptor, []types.Value{
+		types.File{inWriter}, types.File{outReader}, wait})
+}
+
+type teeOptions struct {
+	Append bool
+}
+
+// tee passes both the values and the bytes of its input through to its
+// output, while also copying them to a destination. The destination is either
+// the name of a file, which is truncated first unless &append is set, or a
+// file value; bytes are written to it verbatim, and values as their reprs, one
+// per line. It can also be a function, which is called after the input ends
+// with the bytes as a string and the values as a list.
+func tee(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var dest types.Value
+	ec.ScanArgs(args, &dest)
+	var options teeOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	var (
+		w        io.Writer
+		f        Fn
+		captured bytes.Buffer
+		values   []types.Value
+	)
+	switch dest := dest.(type) {
+	case types.String:
+		flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if options.Append {
+			flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		file, err := os.OpenFile(string(dest), flag, 0644)
+		maybeThrow(err)
+		defer file.Close()
+		w = file
+	case types.File:
+		w = dest.Inner
+	case Fn:
+		f = dest
+		w = &captured
+	default:
+		throwf("destination should be string, file or function, got %s", dest.Kind())
+	}
+
+	// Both bands are copied concurrently, so that neither can block the
+	// other; writes to the destination are serialized.
+	var m sync.Mutex
+	write := func(p []byte) error {
+		m.Lock()
+		defer m.Unlock()
+		_, err := w.Write(p)
+		return err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var bytesErr, valuesErr error
+	go func() {
+		defer wg.Done()
+		in, out := ec.ports[0].File, ec.ports[1].File
+		buf := make([]byte, 4096)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				if _, err := out.Write(buf[:n]); err != nil {
+					bytesErr = err
+					return
+				}
+				if err := write(buf[:n]); err != nil {
+					bytesErr = err
+					return
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					bytesErr = err
+				}
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		out := ec.ports[1].Chan
+		for v := range ec.ports[0].Chan {
+			out <- v
+			if valuesErr != nil {
+				continue
+			}
+			if f != nil {
+				values = append(values, v)
+			} else {
+				valuesErr = write([]byte(v.Repr(types.NoPretty) + "\n"))
+			}
+		}
+	}()
+	wg.Wait()
+	maybeThrow(bytesErr)
+	maybeThrow(valuesErr)
+
+	if f != nil {
+		f.Call(ec.fork("tee destination"),
+			[]types.Value{types.String(captured.String()), types.MakeList(values...)}, NoOpts)
+	}
+}
@@ -1,7 +1,10 @@
 package eval
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/elves/elvish/eval/types"
 )
@@ -13,11 +16,57 @@ func TestBuiltinFnIO(t *testing.T) {
 		{`print [foo bar]`, want{bytesOut: []byte("[foo bar]")}},
 		{`echo [foo bar]`, want{bytesOut: []byte("[foo bar]\n")}},
 		{`pprint [foo bar]`, want{bytesOut: []byte("[\n foo\n bar\n]\n")}},
+		NewTest(`pprint &indent=2 [foo [bar]]`).WantBytesOutString(
+			"[\n  foo\n  [\n    bar\n  ]\n]\n"),
+		NewTest(`pprint &max-depth=1 [foo [bar] [&k=v]]`).WantBytesOutString(
+			"[\n foo\n [...]\n [&...]\n]\n"),
+		NewTest(`pprint &max-items=1 [foo bar baz]`).WantBytesOutString(
+			"[\n foo\n ...\n]\n"),
+		NewTest(`pprint &max-items=foo [foo]`).WantAnyErr(),
 		NewTest(`repr foo bar ['foo bar']`).WantBytesOutString("foo bar ['foo bar']\n"),
 
+		{`print abcdef > f; print-file f; rm f`,
+			want{bytesOut: []byte("abcdef")}},
+		{`print abcdef > f; print-file &from=2 &to=4 f; rm f`,
+			want{bytesOut: []byte("cd")}},
+		{`print abcdef > f; print-file &from=-2 f; rm f`,
+			want{bytesOut: []byte("ef")}},
+		{`print abcdef | print-file &from=2 &to=4`,
+			want{bytesOut: []byte("cd")}},
+		{`print abcdef | print-file &from=10`, wantNothing},
+		{`print abcdef | print-file &from=-2`, want{err: errAny}},
+		{`print-file nonexistent`, want{err: errAny}},
+
 		{`print "a\nb" | slurp`, want{out: strs("a\nb")}},
+		{`bytes 61ff00 | print (all) | slurp &bytes`,
+			want{out: []types.Value{types.Bytes("a\xff\x00")}}},
+		{`repr (bytes 61FF)`, want{bytesOut: []byte("(bytes 61ff)\n")}},
+		{`count (bytes 61ff)`, want{out: strs("2")}},
+		{`bytes 6`, want{err: errAny}},
+		{`repr (bytes 61ff00)[1] (bytes 61ff00)[1:]`,
+			want{bytesOut: []byte("(bytes ff) (bytes ff00)\n")}},
+		{`put (bytes 6162)[2]`, want{err: errAny}},
+		{`each $repr~ (bytes 61ff)`, want{bytesOut: []byte("(bytes 61)\n(bytes ff)\n")}},
+		{`put (bytes 61ff) | to-json`, want{bytesOut: []byte(`"Yf8="` + "\n")}},
+		{`repr (string-to-bytes aé)`, want{bytesOut: []byte("(bytes 61c3a9)\n")}},
+		{`bytes-to-string (bytes 61c3a9)`, want{out: strs("aé")}},
+		{`bytes-to-string (bytes 61ff)`, want{err: errAny}},
+		{`encode-hex (bytes 61ff)`, want{out: strs("61ff")}},
+		{`encode-hex ab`, want{out: strs("6162")}},
+		{`encode-base64 (bytes 61ff)`, want{out: strs("Yf8=")}},
+		{`encode-hex [a]`, want{err: errAny}},
+		{`repr (decode-base64 Yf8=)`, want{bytesOut: []byte("(bytes 61ff)\n")}},
+		{`decode-base64 '!'`, want{err: errAny}},
 		{`print "a\nb" | from-lines`, want{out: strs("a", "b")}},
 		{`print "a\nb\n" | from-lines`, want{out: strs("a", "b")}},
+		{`print "a\n\nb" | from-lines &keep-terminator`,
+			want{out: strs("a\n", "\n", "b")}},
+		{`print "a b\000c\000" | from-terminated "\000"`,
+			want{out: strs("a b", "c")}},
+		{`print "a--b-c--" | from-terminated &keep-terminator --`,
+			want{out: strs("a--", "b-c--")}},
+		{`print "a-" | from-terminated --`, want{out: strs("a-")}},
+		{`print a | from-terminated ''`, want{err: errAny}},
 		{`echo '{"k": "v", "a": [1, 2]}' '"foo"' | from-json`,
 			want{out: []types.Value{
 				types.MakeMap(map[types.Value]types.Value{
@@ -26,12 +75,61 @@ func TestBuiltinFnIO(t *testing.T) {
 				types.String("foo"),
 			}}},
 		{`echo 'invalid' | from-json`, want{err: errAny}},
+		{`echo '{"k": "v", "a": {"z": 1, "y": [2]}}' | from-json &ordered | to-json`,
+			want{bytesOut: []byte(`{"k":"v","a":{"z":"1","y":["2"]}}` + "\n")}},
+		{`echo '{"k": [' | from-json &ordered`, want{err: errAny}},
 
 		{`put "l\norem" ipsum | to-lines`,
 			want{bytesOut: []byte("l\norem\nipsum\n")}},
+		{`put a 'b c' | to-terminated "\000"`,
+			want{bytesOut: []byte("a\000b c\000")}},
+		{`to-terminated , [a b]`, want{bytesOut: []byte("a,b,")}},
 		{`put [&k=v &a=[1 2]] foo | to-json`,
 			want{bytesOut: []byte(`{"a":["1","2"],"k":"v"}
 "foo"
 `)}},
+
+		{`put a 'b c' | tee f; slurp < f; rm f`,
+			want{out: strs("a", "b c", "a\n'b c'\n")}},
+		{`print ab | tee f; put (slurp < f); rm f`,
+			want{out: strs("ab"), bytesOut: []byte("ab")}},
+		{`put a | tee f; put b | tee &append f; slurp < f; rm f`,
+			want{out: strs("a", "b", "a\nb\n")}},
+		{`l = []; put a b | tee [_ v]{ l = $v }; put $@l`,
+			want{out: strs("a", "b", "a", "b")}},
+		{`print ab | tee [b _]{ put $b }`,
+			want{out: strs("ab"), bytesOut: []byte("ab")}},
+		{`tee [&]`, want{err: errAny}},
+
+		{`co = (coproc { e:sh -c 'while read x; do echo $x$x; done' })
+		  echo a > $co[stdin]; e:sh -c 'read l; echo $l' < $co[stdout]
+		  echo b > $co[stdin]; e:sh -c 'read l; echo $l' < $co[stdout]
+		  $co[wait]`, want{bytesOut: []byte("aa\nbb\n")}},
+		{`co = (coproc { cat; fail bad }); echo x > $co[stdin]; $co[wait]`,
+			want{err: errAny}},
+		{`co = (coproc { put x; echo y }); $co[wait]; slurp < $co[stdout]`,
+			want{out: strs("y\n")}},
+		// The files are closed when the function that starts the coprocess
+		// returns.
+		{`fn f { put (coproc { cat }) }; co = (f); $co[wait]; slurp < $co[stdout]`,
+			want{err: errAny}},
+	})
+}
+
+func TestPrintFileFollow(t *testing.T) {
+	f, err := ioutil.TempFile("", "elvishtest.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString("abc")
+	go func() {
+		time.Sleep(2 * followInterval)
+		f.WriteString("defgh")
+	}()
+
+	runTests(t, []Test{
+		NewTest(`print-file &follow &to=6 ` + f.Name()).WantBytesOutString("abcdef"),
 	})
 }
@@ -0,0 +1,83 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+// outputFifo runs a function in the background with its output connected to a
+// FIFO, and outputs the path of the FIFO. It is the equivalent of process
+// substitution in other shells, like "diff (output-fifo { sort a }) (output-fifo
+// { sort b })".
+//
+// The function starts running when the FIFO is opened for reading, and its
+// input is empty. The FIFO is removed when the enclosing function call or
+// top-level evaluation finishes, which also waits for the function to finish;
+// if the FIFO has not been opened by then, the function never runs.
+func outputFifo(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var f Callable
+	ec.ScanArgs(args, &f)
+	TakeNoOpt(opts)
+
+	dir, err := ioutil.TempDir("", "elvish-fifo")
+	maybeThrow(err)
+	name := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(name, 0600); err != nil {
+		os.RemoveAll(dir)
+		throw(err)
+	}
+
+	newEc := ec.fork("[output-fifo function]")
+	newEc.ports[0] = &Port{File: DevNull, Chan: ClosedChan}
+	stderr := newEc.ports[2].File
+	removed := make(chan struct{})
+	opened := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Opening for writing blocks until the FIFO is opened for reading.
+		file, err := os.OpenFile(name, os.O_WRONLY, 0)
+		close(opened)
+		select {
+		case <-removed:
+			// Opened or removed by the cleanup function.
+			if err == nil {
+				file.Close()
+			}
+			return
+		default:
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "output-fifo:", err)
+			return
+		}
+		newEc.ports[1] = &Port{File: file, Chan: BlackholeChan, CloseFile: true}
+		err = newEc.PCall(f, NoArgs, NoOpts)
+		newEc.ports[1].Close()
+		if err != nil && !isBrokenPipe(err.(*Exception).Cause) {
+			fmt.Fprintln(stderr, "output-fifo:", err)
+		}
+	}()
+
+	ec.AddCleanup(func() {
+		close(removed)
+		// While the FIFO is open for reading here, opening it for writing
+		// does not block; once it is removed, opening it fails. Either way,
+		// the goroutine cannot block on opening it forever.
+		r, err := os.OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+		os.RemoveAll(dir)
+		<-opened
+		if err == nil {
+			r.Close()
+		}
+		<-done
+	})
+	ec.OutputChan() <- types.String(name)
+}
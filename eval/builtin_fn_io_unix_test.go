@@ -0,0 +1,60 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/parse"
+)
+
+func TestOutputFifo(t *testing.T) {
+	runTests(t, []Test{
+		NewTest(`cat (output-fifo { echo foo })`).WantBytesOutString("foo\n"),
+		NewTest(`slurp < (output-fifo { echo foo; echo bar })`).WantOutStrings("foo\nbar\n"),
+		NewTest(`cat (output-fifo { echo a }) (output-fifo { echo b })`).WantBytesOutString("a\nb\n"),
+		// The FIFO is removed when the top-level evaluation finishes, even if
+		// it has never been opened.
+		NewTest(`f = (output-fifo { echo foo })`),
+		NewTest(`fn f { put (output-fifo { echo foo }) }; -is-dir (path-dir (f))`).WantOutBools(false),
+	})
+}
+
+func TestOutputFifo_NoErrorWhenRemovedBeforeOpened(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ev := NewEvaler()
+	defer ev.Close()
+	ports := []*Port{
+		{File: DevNull, Chan: ClosedChan},
+		{File: DevNull, Chan: BlackholeChan},
+		{File: w, Chan: BlackholeChan},
+	}
+	// Each FIFO made in f is removed when f returns, usually before the
+	// function of output-fifo gets to open it for writing. The last one is
+	// opened by a redirection.
+	code := "fn f { _ = (output-fifo { echo foo }) }; range 20 | each [_]{ f }; " +
+		"slurp < (output-fifo { echo foo }) | nop"
+	src := NewInteractiveSource(code)
+	n, err := parse.Parse("[test]", code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err := ev.Compile(n, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ev.EvalWithPorts(ports, op, src); err != nil {
+		t.Errorf("eval -> %v, want no error", err)
+	}
+	// The functions of output-fifo have finished when the evaluation returns.
+	w.Close()
+	if stderr, _ := ioutil.ReadAll(r); len(stderr) != 0 {
+		t.Errorf("got stderr %q, want nothing", stderr)
+	}
+}
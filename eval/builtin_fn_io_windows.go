@@ -0,0 +1,3 @@
+package eval
+
+var outputFifo = notSupportedOnWindows
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"errors"
 	"math"
 	"math/rand"
 	"strconv"
@@ -13,18 +14,22 @@ import (
 func init() {
 	addToBuiltinFns([]*BuiltinFn{
 		// Comparison
-		{"<",
-			wrapNumCompare(func(a, b float64) bool { return a < b })},
-		{"<=",
-			wrapNumCompare(func(a, b float64) bool { return a <= b })},
+		{"<", wrapCompare(
+			func(a, b float64) bool { return a < b },
+			func(c int) bool { return c < 0 })},
+		{"<=", wrapCompare(
+			func(a, b float64) bool { return a <= b },
+			func(c int) bool { return c <= 0 })},
 		{"==",
 			wrapNumCompare(func(a, b float64) bool { return a == b })},
 		{"!=",
 			wrapNumCompare(func(a, b float64) bool { return a != b })},
-		{">",
-			wrapNumCompare(func(a, b float64) bool { return a > b })},
-		{">=",
-			wrapNumCompare(func(a, b float64) bool { return a >= b })},
+		{">", wrapCompare(
+			func(a, b float64) bool { return a > b },
+			func(c int) bool { return c > 0 })},
+		{">=", wrapCompare(
+			func(a, b float64) bool { return a >= b },
+			func(c int) bool { return c >= 0 })},
 
 		// Arithmetics
 		{"+", plus},
@@ -34,21 +39,34 @@ func init() {
 		{"^", pow},
 		{"%", mod},
 
+		// Aggregation
+		{"min", minFn},
+		{"max", maxFn},
+		{"sum", sum},
+		{"mean", mean},
+
+		// Conversion
+		{"float64", float64Fn},
+
 		// Random
 		{"rand", randFn},
 		{"randint", randint},
 	})
 }
 
+// float64Fn converts a number to a float64.
+func float64Fn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var arg types.Value
+	ec.ScanArgs(args, &arg)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Float64(ec.floatArg(arg))
+}
+
 func wrapNumCompare(cmp func(a, b float64) bool) BuiltinFnImpl {
 	return func(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		TakeNoOpt(opts)
-		floats := make([]float64, len(args))
-		for i, a := range args {
-			f, err := toFloat(a)
-			maybeThrow(err)
-			floats[i] = f
-		}
+		floats := ec.floatArgs(args)
 		result := true
 		for i := 0; i < len(floats)-1; i++ {
 			if !cmp(floats[i], floats[i+1]) {
@@ -60,26 +78,62 @@ func wrapNumCompare(cmp func(a, b float64) bool) BuiltinFnImpl {
 	}
 }
 
+// wrapCompare is like wrapNumCompare when all the arguments are strings or
+// numbers. Otherwise, the arguments are compared with types.Compare, so that
+// for instance lists are compared element by element, and ok is called with
+// the result of each comparison.
+func wrapCompare(cmp func(a, b float64) bool, ok func(c int) bool) BuiltinFnImpl {
+	numCompare := wrapNumCompare(cmp)
+	return func(ec *Frame, args []types.Value, opts map[string]types.Value) {
+		if allNumberLike(args) {
+			numCompare(ec, args, opts)
+			return
+		}
+		TakeNoOpt(opts)
+		result := true
+		for i := 0; i < len(args)-1; i++ {
+			c, err := types.Compare(args[i], args[i+1])
+			maybeThrow(err)
+			if !ok(c) {
+				result = false
+				break
+			}
+		}
+		ec.OutputChan() <- types.Bool(result)
+	}
+}
+
+// allNumberLike returns whether all the values are strings or numbers, which
+// numeric builtins try to convert to numbers.
+func allNumberLike(vs []types.Value) bool {
+	for _, v := range vs {
+		switch v.(type) {
+		case types.String, types.Float64, types.Rat:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func plus(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var nums []float64
-	ScanArgsVariadic(args, &nums)
 	TakeNoOpt(opts)
+	nums := ec.floatArgs(args)
 
 	out := ec.ports[1].Chan
 	sum := 0.0
 	for _, f := range nums {
 		sum += f
 	}
-	out <- floatToString(sum)
+	out <- numResult(args, sum)
 }
 
 func minus(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var (
-		sum  float64
-		nums []float64
-	)
-	ScanArgsVariadic(args, &sum, &nums)
 	TakeNoOpt(opts)
+	if len(args) == 0 {
+		throw(ErrArgs)
+	}
+	sum, nums := ec.floatArg(args[0]), ec.floatArgs(args[1:])
 
 	out := ec.ports[1].Chan
 	if len(nums) == 0 {
@@ -90,20 +144,19 @@ func minus(ec *Frame, args []types.Value, opts map[string]types.Value) {
 			sum -= f
 		}
 	}
-	out <- floatToString(sum)
+	out <- numResult(args, sum)
 }
 
 func times(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var nums []float64
-	ScanArgsVariadic(args, &nums)
 	TakeNoOpt(opts)
+	nums := ec.floatArgs(args)
 
 	out := ec.ports[1].Chan
 	prod := 1.0
 	for _, f := range nums {
 		prod *= f
 	}
-	out <- floatToString(prod)
+	out <- numResult(args, prod)
 }
 
 func slash(ec *Frame, args []types.Value, opts map[string]types.Value) {
@@ -118,36 +171,128 @@ func slash(ec *Frame, args []types.Value, opts map[string]types.Value) {
 }
 
 func divide(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var (
-		prod float64
-		nums []float64
-	)
-	ScanArgsVariadic(args, &prod, &nums)
 	TakeNoOpt(opts)
+	if len(args) == 0 {
+		throw(ErrArgs)
+	}
+	prod, nums := ec.floatArg(args[0]), ec.floatArgs(args[1:])
 
 	out := ec.ports[1].Chan
 	for _, f := range nums {
 		prod /= f
 	}
-	out <- floatToString(prod)
+	out <- numResult(args, prod)
 }
 
 func pow(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var b, p float64
-	ScanArgs(args, &b, &p)
 	TakeNoOpt(opts)
+	if len(args) != 2 {
+		throw(ErrArgs)
+	}
+	b, p := ec.floatArg(args[0]), ec.floatArg(args[1])
 
 	out := ec.ports[1].Chan
-	out <- floatToString(math.Pow(b, p))
+	out <- numResult(args, math.Pow(b, p))
 }
 
 func mod(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var a, b int
-	ScanArgs(args, &a, &b)
 	TakeNoOpt(opts)
+	if len(args) != 2 {
+		throw(ErrArgs)
+	}
+	a, b := ec.intArg(args[0]), ec.intArg(args[1])
 
 	out := ec.ports[1].Chan
-	out <- types.String(strconv.Itoa(a % b))
+	if hasFloat64(args) {
+		out <- types.Float64(a % b)
+	} else {
+		out <- types.String(strconv.Itoa(a % b))
+	}
+}
+
+// ErrNoInput is thrown by aggregation functions that need at least one input
+// when there are none.
+var ErrNoInput = errors.New("no input")
+
+func minFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	extremum(ec, args, opts, func(a, b float64) bool { return a < b })
+}
+
+func maxFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	extremum(ec, args, opts, func(a, b float64) bool { return a > b })
+}
+
+// extremum outputs the input with the number that is "better" than all the
+// others. The number of an input is the input itself, or the output of the
+// &key function when it is given. When several inputs have the same number,
+// the first one is output.
+func extremum(ec *Frame, args []types.Value, opts map[string]types.Value, better func(a, b float64) bool) {
+	var options struct{ Key Fn }
+	iterate := ScanArgsOptionalInput(ec, args)
+	ec.ScanOptsToStruct(opts, &options)
+
+	var (
+		found bool
+		best  types.Value
+		bestF float64
+	)
+	iterate(func(v types.Value) {
+		k := v
+		if options.Key != nil {
+			k = callForOneValue(ec, options.Key, v)
+		}
+		f := ec.floatArg(k)
+		if !found || better(f, bestF) {
+			found, best, bestF = true, v, f
+		}
+	})
+	if !found {
+		throw(ErrNoInput)
+	}
+	ec.OutputChan() <- best
+}
+
+func sum(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	TakeNoOpt(opts)
+
+	total, sawFloat64 := 0.0, false
+	iterate(func(v types.Value) {
+		f := ec.floatArg(v)
+		total += f
+		if _, ok := v.(types.Float64); ok {
+			sawFloat64 = true
+		}
+	})
+	ec.OutputChan() <- aggregateResult(sawFloat64, total)
+}
+
+func mean(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	TakeNoOpt(opts)
+
+	total, n, sawFloat64 := 0.0, 0, false
+	iterate(func(v types.Value) {
+		f := ec.floatArg(v)
+		total += f
+		n++
+		if _, ok := v.(types.Float64); ok {
+			sawFloat64 = true
+		}
+	})
+	if n == 0 {
+		throw(ErrNoInput)
+	}
+	ec.OutputChan() <- aggregateResult(sawFloat64, total/float64(n))
+}
+
+// aggregateResult makes the output of an aggregation function: a float64 if
+// any of its inputs is one, or a string otherwise.
+func aggregateResult(sawFloat64 bool, f float64) types.Value {
+	if sawFloat64 {
+		return types.Float64(f)
+	}
+	return floatToString(f)
 }
 
 func randFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
@@ -159,9 +304,11 @@ func randFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
 }
 
 func randint(ec *Frame, args []types.Value, opts map[string]types.Value) {
-	var low, high int
-	ScanArgs(args, &low, &high)
 	TakeNoOpt(opts)
+	if len(args) != 2 {
+		throw(ErrArgs)
+	}
+	low, high := ec.intArg(args[0]), ec.intArg(args[1])
 
 	if low >= high {
 		throw(ErrArgs)
@@ -1,6 +1,10 @@
 package eval
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
 
 func TestBuiltinFnNum(t *testing.T) {
 	runTests(t, []Test{
@@ -9,6 +13,20 @@ func TestBuiltinFnNum(t *testing.T) {
 		{`== a a`, want{err: errAny}},
 		{`> 0x10 1`, wantTrue},
 
+		// Values that are not strings or numbers are compared with their
+		// Compare methods.
+		{`< [a b] [a c] [a c d]`, wantTrue},
+		{`< [a b] [a]`, wantFalse},
+		// List elements are compared like order compares them.
+		{`< [2] [10]`, wantTrue},
+		{`< [10] [a]`, wantTrue},
+		{`put [10] [2] | order | each $repr~`, want{bytesOut: []byte("[2]\n[10]\n")}},
+		{`>= [a] [a]`, wantTrue},
+		{`< $false $true`, wantTrue},
+		{`> (bytes 01) (bytes 00ff)`, wantTrue},
+		{`< [a] $true`, want{err: errAny}},
+		{`< [&] [&]`, want{err: errAny}},
+
 		// TODO test more edge cases
 		{"+ 233100 233", want{out: strs("233333")}},
 		{"- 233333 233100", want{out: strs("233")}},
@@ -18,5 +36,115 @@ func TestBuiltinFnNum(t *testing.T) {
 		{"/ 1 0", want{out: strs("+Inf")}},
 		{"^ 16 2", want{out: strs("256")}},
 		{"% 23 7", want{out: strs("2")}},
+		{"% 9007199254740993 10000000000000000", want{out: strs("9007199254740993")}},
+
+		{"put 3 1 0x10 2 | min", want{out: strs("1")}},
+		{"max [3 1 0x10 2]", want{out: strs("0x10")}},
+		{"max &key=$count~ [ab abc a]", want{out: strs("abc")}},
+		{"min &key=[x]{ put $x[n] } [[&n=2] [&n=1]]",
+			want{out: []types.Value{types.MakeMap(map[types.Value]types.Value{
+				types.String("n"): types.String("1")})}}},
+		{"min []", want{err: ErrNoInput}},
+		{"min [a]", want{err: errAny}},
+		{"range 1 5 | sum", want{out: strs("10")}},
+		{"sum []", want{out: strs("0")}},
+		{"mean [1 2 3 4]", want{out: strs("2.5")}},
+		{"mean []", want{err: ErrNoInput}},
+
+		// Int arguments accept numbers with integral values.
+		{"% 23.0 7", want{out: strs("2")}},
+		{"% 23.5 7", want{err: errNotInteger(types.String("23.5"))}},
+		{"+ 1 a", want{err: errNotNumber(types.String("a"))}},
+		{"+ 1 [a]", want{err: errAny}},
+
+		// With $strict-numbers, only numbers in plain decimal notation are
+		// accepted.
+		{"strict-numbers = $true; + 1 -2.5 1e1", want{out: strs("8.5")}},
+		{"strict-numbers = $true; + 1 0x10", want{err: errNotNumber(types.String("0x10"))}},
+		{"strict-numbers = $true; < 1 inf", want{err: errAny}},
+		{"strict-numbers = $true; % 23.0 7", want{err: errNotInteger(types.String("23.0"))}},
+		{"strict-numbers = $true; range &step=0x1 2", want{err: errAny}},
+		{"strict-numbers = $true; put 0x10 | max", want{err: errAny}},
+		{"strict-numbers=$true { nop }; + 1 0x10", want{out: strs("17")}},
+		// Builtins that scan int and float64 arguments and options honor it
+		// too.
+		{"strict-numbers = $true; take 0x1 [a b]", want{err: errNotInteger(types.String("0x1"))}},
+		{"strict-numbers = $true; take 1 [a b]", want{out: strs("a")}},
+		{"take 0x1 [a b]", want{out: strs("a")}},
+		{"strict-numbers = $true; pprint &indent=0x2 []", want{err: errNotInteger(types.String("0x2"))}},
+		{"pprint &indent=0x2 []", want{bytesOut: []byte("[]\n")}},
+		{"strict-numbers = $true; esleep 0x0", want{err: errNotNumber(types.String("0x0"))}},
+		{"esleep 0x0", want{}},
+	})
+}
+
+var numConversionTests = []struct {
+	v          types.Value
+	strict     bool
+	wantFloat  float64
+	wantInt    int
+	floatValid bool
+	intValid   bool
+}{
+	{types.String("12"), false, 12, 12, true, true},
+	{types.String("12"), true, 12, 12, true, true},
+	{types.String("0x10"), false, 16, 16, true, true},
+	{types.String("0x10"), true, 0, 0, false, false},
+	{types.String("1e3"), false, 1000, 1000, true, true},
+	{types.String("1e3"), true, 1000, 0, true, false},
+	{types.String("1.5"), false, 1.5, 0, true, false},
+	{types.String(" 1"), false, 0, 0, false, false},
+	{mustRat("3/2"), true, 1.5, 0, true, false},
+	{mustRat("6/2"), true, 3, 3, true, true},
+	{types.Float64(1.5), true, 1.5, 0, true, false},
+	{types.Float64(3), true, 3, 3, true, true},
+	{types.MakeList(), false, 0, 0, false, false},
+}
+
+func mustRat(s string) types.Rat {
+	r, err := types.ToRat(types.String(s))
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestNumConversion(t *testing.T) {
+	for _, test := range numConversionTests {
+		f, err := parseFloat(test.v, test.strict)
+		if (err == nil) != test.floatValid || f != test.wantFloat {
+			t.Errorf("parseFloat(%s, %v) => (%v, %v)", test.v.Repr(types.NoPretty), test.strict, f, err)
+		}
+		i, err := parseInt(test.v, test.strict)
+		if (err == nil) != test.intValid || i != test.wantInt {
+			t.Errorf("parseInt(%s, %v) => (%v, %v)", test.v.Repr(types.NoPretty), test.strict, i, err)
+		}
+	}
+}
+
+func TestFloat64(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("kind-of (float64 1.5)").WantOutStrings("float64"),
+		NewTest("repr (float64 0x10)").WantBytesOutString("(float64 16)\n"),
+		NewTest("echo (float64 1.5)").WantBytesOutString("1.5\n"),
+		NewTest("float64 a").WantAnyErr(),
+		NewTest("eq (float64 1) (float64 1.0)").WantOutBools(true),
+		NewTest("eq (float64 1) 1").WantOutBools(false),
+		NewTest("== (float64 1) 1").WantOutBools(true),
+		NewTest("< 1 (float64 1.5) 2").WantOutBools(true),
+
+		// Arithmetic outputs a float64 if any argument is one.
+		NewTest("+ 1 (float64 2)").WantOut(types.Float64(3)),
+		NewTest("- (float64 1)").WantOut(types.Float64(-1)),
+		NewTest("* 2 3").WantOutStrings("6"),
+		NewTest("/ (float64 1) 4").WantOut(types.Float64(0.25)),
+		NewTest("% (float64 7) 4").WantOut(types.Float64(3)),
+		NewTest("% (float64 7.5) 4").WantAnyErr(),
+		NewTest("sum [1 (float64 2)]").WantOut(types.Float64(3)),
+		NewTest("mean [1 2]").WantOutStrings("1.5"),
+
+		NewTest("put 3 (float64 2) 10 a | order").
+			WantOut(types.Float64(2), types.String("3"), types.String("10"), types.String("a")),
+		NewTest("range (float64 3)").WantOutStrings("0", "1", "2"),
 	})
 }
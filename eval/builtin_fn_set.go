@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"github.com/elves/elvish/eval/types"
+)
+
+// Sets.
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"set", setFn},
+		{"has", has},
+		{"union", union},
+		{"intersection", intersection},
+		{"difference", difference},
+	})
+}
+
+// setFn outputs a set of the inputs, for instance "set [a b a]" outputs a set
+// of a and b.
+func setFn(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	iterate := ScanArgsOptionalInput(ec, args)
+	TakeNoOpt(opts)
+
+	s := types.EmptySet
+	iterate(func(v types.Value) {
+		s = s.Add(v)
+	})
+	ec.OutputChan() <- s
+}
+
+// has outputs whether a value is an element of a set.
+func has(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		s types.Set
+		v types.Value
+	)
+	ec.ScanArgs(args, &s, &v)
+	TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Bool(s.Has(v))
+}
+
+// union outputs the set of the elements of any of the argument sets.
+func union(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var sets []types.Set
+	ec.ScanArgsVariadic(args, &sets)
+	TakeNoOpt(opts)
+
+	result := types.EmptySet
+	for _, s := range sets {
+		result = result.Union(s)
+	}
+	ec.OutputChan() <- result
+}
+
+// intersection outputs the set of the elements of all of the argument sets.
+func intersection(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		first types.Set
+		rest  []types.Set
+	)
+	ec.ScanArgsVariadic(args, &first, &rest)
+	TakeNoOpt(opts)
+
+	for _, s := range rest {
+		first = first.Intersection(s)
+	}
+	ec.OutputChan() <- first
+}
+
+// difference outputs the set of the elements of the first argument set that
+// are not in any of the others.
+func difference(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		first types.Set
+		rest  []types.Set
+	)
+	ec.ScanArgsVariadic(args, &first, &rest)
+	TakeNoOpt(opts)
+
+	for _, s := range rest {
+		first = first.Difference(s)
+	}
+	ec.OutputChan() <- first
+}
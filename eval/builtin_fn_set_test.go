@@ -0,0 +1,30 @@
+package eval
+
+import "testing"
+
+func TestBuiltinFnSet(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("kind-of (set [])").WantOutStrings("set"),
+		NewTest("repr (set [b a b])").WantBytesOutString("(set [a b])\n"),
+		NewTest("count (put a b a | set)").WantOutStrings("2"),
+		NewTest("order (set [b a])").WantOutStrings("a", "b"),
+		NewTest("eq (set [a b]) (set [b a])").WantOutBools(true),
+		NewTest("eq (set [a b]) [a b]").WantOutBools(false),
+		NewTest("put [&(set [a])=x][(set [a])]").WantOutStrings("x"),
+
+		NewTest("has (set [a b]) a").WantOutBools(true),
+		NewTest("has (set [a b]) c").WantOutBools(false),
+		NewTest("has [a b] a").WantAnyErr(),
+
+		NewTest("repr (union (set [a b]) (set [b c]))").
+			WantBytesOutString("(set [a b c])\n"),
+		NewTest("repr (union)").WantBytesOutString("(set [])\n"),
+		NewTest("repr (intersection (set [a b c]) (set [b c d]) (set [c]))").
+			WantBytesOutString("(set [c])\n"),
+		NewTest("repr (difference (set [a b c]) (set [b]) (set [c]))").
+			WantBytesOutString("(set [a])\n"),
+		NewTest("intersection").WantAnyErr(),
+
+		NewTest("set [b a] | to-json").WantBytesOutString(`["a","b"]` + "\n"),
+	})
+}
@@ -105,8 +105,8 @@ func splits(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		s, sep types.String
 		optMax int
 	)
-	ScanArgs(args, &sep, &s)
-	ScanOpts(opts, OptToScan{"max", &optMax, types.String("-1")})
+	ec.ScanArgs(args, &sep, &s)
+	ec.ScanOpts(opts, OptToScan{"max", &optMax, types.String("-1")})
 
 	out := ec.ports[1].Chan
 	parts := strings.SplitN(string(s), string(sep), optMax)
@@ -120,15 +120,15 @@ func replaces(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		old, repl, s types.String
 		optMax       int
 	)
-	ScanArgs(args, &old, &repl, &s)
-	ScanOpts(opts, OptToScan{"max", &optMax, types.String("-1")})
+	ec.ScanArgs(args, &old, &repl, &s)
+	ec.ScanOpts(opts, OptToScan{"max", &optMax, types.String("-1")})
 
 	ec.ports[1].Chan <- types.String(strings.Replace(string(s), string(old), string(repl), optMax))
 }
 
 func ord(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var s types.String
-	ScanArgs(args, &s)
+	ec.ScanArgs(args, &s)
 	TakeNoOpt(opts)
 
 	out := ec.ports[1].Chan
@@ -146,7 +146,7 @@ func base(ec *Frame, args []types.Value, opts map[string]types.Value) {
 		b    int
 		nums []int
 	)
-	ScanArgsVariadic(args, &b, &nums)
+	ec.ScanArgsVariadic(args, &b, &nums)
 	TakeNoOpt(opts)
 
 	if b < 2 || b > 36 {
@@ -162,7 +162,7 @@ func base(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func wcswidth(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var s types.String
-	ScanArgs(args, &s)
+	ec.ScanArgs(args, &s)
 	TakeNoOpt(opts)
 
 	out := ec.ports[1].Chan
@@ -174,7 +174,7 @@ func overrideWcwidth(ec *Frame, args []types.Value, opts map[string]types.Value)
 		s types.String
 		w int
 	)
-	ScanArgs(args, &s, &w)
+	ec.ScanArgs(args, &s, &w)
 	TakeNoOpt(opts)
 
 	r, err := toRune(s)
@@ -184,7 +184,7 @@ func overrideWcwidth(ec *Frame, args []types.Value, opts map[string]types.Value)
 
 func hasPrefix(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var s, prefix types.String
-	ScanArgs(args, &s, &prefix)
+	ec.ScanArgs(args, &s, &prefix)
 	TakeNoOpt(opts)
 
 	ec.OutputChan() <- types.Bool(strings.HasPrefix(string(s), string(prefix)))
@@ -192,7 +192,7 @@ func hasPrefix(ec *Frame, args []types.Value, opts map[string]types.Value) {
 
 func hasSuffix(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	var s, suffix types.String
-	ScanArgs(args, &s, &suffix)
+	ec.ScanArgs(args, &s, &suffix)
 	TakeNoOpt(opts)
 
 	ec.OutputChan() <- types.Bool(strings.HasSuffix(string(s), string(suffix)))
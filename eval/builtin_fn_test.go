@@ -1,12 +1,18 @@
 package eval
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
 
 var builtinFnTests = []Test{
 	NewTest("kind-of $nop~").WantOutStrings("fn"),
 	NewTest("eq $nop~ { }").WantOutBools(false),
 	NewTest("put [&$nop~= foo][$nop~]").WantOutStrings("foo"),
 	NewTest("repr $nop~").WantBytesOutString("<builtin nop>\n"),
+	NewTest("put $nop~[name]").WantOutStrings("nop"),
+	NewTest("put $nop~[foo]").WantAnyErr(),
 
 	{"nop", wantNothing},
 	{"nop a b", wantNothing},
@@ -41,10 +47,35 @@ var builtinFnTests = []Test{
 	{`eq a b`, wantFalse},
 	{`eq [] []`, wantTrue},
 	{`eq [1] [1]`, wantTrue},
+	{`eq [1] [2]`, wantFalse},
+	{`eq [a] a`, wantFalse},
+	{`eq [[a] [&k=[b]]] [[a] [&k=[b]]]`, wantTrue},
+	{`eq [[a] [&k=[b]]] [[a] [&k=[c]]]`, wantFalse},
+	{`not-eq [1] [2]`, wantTrue},
 	{`not-eq a b`, wantTrue},
 
 	{`f=(constantly foo); $f; $f`, want{out: strs("foo", "foo")}},
 	{`(constantly foo) bad`, want{err: errAny}},
+
+	NewTest("eval 'put foo; echo bar'").WantOutStrings("foo").WantBytesOutString("bar\n"),
+	NewTest("x = foo; eval 'put $x'").WantOutStrings("foo"),
+	NewTest("fn f [x]{ eval 'put $x' }; f foo").WantOutStrings("foo"),
+	// Assigning to existing variables affects the caller, while new
+	// variables are dropped.
+	NewTest("x = foo; eval 'x = bar'; put $x").WantOutStrings("bar"),
+	NewTest("eval 'y = foo'; eval 'put $y'").WantAnyErr(),
+	// New variables are kept in a given namespace.
+	NewTest("n = (ns); eval 'y = foo' &ns=$n; eval 'put $y' &ns=$n").
+		WantOutStrings("foo"),
+	NewTest("n = (ns); eval 'y = foo' &ns=$n; eval 'del y' &ns=$n; eval 'put $y' &ns=$n").
+		WantAnyErr(),
+	NewTest("n = (ns); range 10 | peach [i]{ eval 'y'$i' = '$i &ns=$n }; "+
+		"eval 'put $y0 $y9' &ns=$n").WantOutStrings("0", "9"),
+	NewTest("n = (ns); eval 'put [a]' &ns=$n").WantOut(types.MakeList(types.String("a"))),
+	NewTest("eval 'put (' ").WantAnyErr(),
+	NewTest("eval 'put $nonexistent'").WantAnyErr(),
+	NewTest("eval 'fail foo'").WantAnyErr(),
+	NewTest("eval put &ns=foo").WantAnyErr(),
 }
 
 func TestBuiltinFn(t *testing.T) {
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"os"
 	"strconv"
 	"strings"
 	"syscall"
@@ -11,18 +12,28 @@ import (
 
 func makeBuiltinNs() Ns {
 	ns := Ns{
-		"_":     vartypes.NewBlackhole(),
-		"pid":   vartypes.NewRo(types.String(strconv.Itoa(syscall.Getpid()))),
-		"ok":    vartypes.NewRo(OK),
-		"true":  vartypes.NewRo(types.Bool(true)),
-		"false": vartypes.NewRo(types.Bool(false)),
-		"paths": &EnvList{envName: "PATH"},
-		"pwd":   PwdVariable{},
+		"_":        vartypes.NewBlackhole(),
+		"pid":      vartypes.NewRo(types.String(strconv.Itoa(syscall.Getpid()))),
+		"uid":      vartypes.NewRo(types.String(strconv.Itoa(os.Getuid()))),
+		"gid":      vartypes.NewRo(types.String(strconv.Itoa(os.Getgid()))),
+		"hostname": vartypes.NewRoCallback(getHostname),
+		"ok":       vartypes.NewRo(OK),
+		"true":     vartypes.NewRo(types.Bool(true)),
+		"false":    vartypes.NewRo(types.Bool(false)),
+		"nil":      vartypes.NewRo(types.Nil),
+		"paths":    &EnvList{envName: "PATH"},
+		"umask":    UmaskVariable{},
 	}
 	AddBuiltinFns(ns, builtinFns...)
 	return ns
 }
 
+func getHostname() types.Value {
+	name, err := os.Hostname()
+	maybeThrow(err)
+	return types.String(name)
+}
+
 // AddBuiltinFns adds builtin functions to a namespace.
 func AddBuiltinFns(ns Ns, fns ...*BuiltinFn) {
 	for _, b := range fns {
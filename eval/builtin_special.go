@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/elves/elvish/eval/types"
@@ -57,6 +58,7 @@ func init() {
 		"and":   compileAnd,
 		"or":    compileOr,
 		"if":    compileIf,
+		"cond":  compileCond,
 		"while": compileWhile,
 		"for":   compileFor,
 		"try":   compileTry,
@@ -277,7 +279,10 @@ func loadModule(ec *Frame, name string) Ns {
 		modGlobal, make(Ns),
 		ec.ports,
 		0, len(code), ec.addTraceback(), false,
+		&cleanups{}, ec.deadline, ec.job,
+		ec.recordPipeStatus, nil,
 	}
+	defer newEc.cleanups.run()
 
 	op, err := newEc.Compile(n, meta)
 	maybeThrow(err)
@@ -291,6 +296,7 @@ func loadModule(ec *Frame, name string) Ns {
 		delete(ec.modules, name)
 		throw(err)
 	}
+	ec.EmitEvent(EventModuleLoaded, types.String(name))
 	return modGlobal
 }
 
@@ -369,18 +375,73 @@ func compileIf(cp *compiler, fn *parse.Form) OpFunc {
 	}
 }
 
+// CondForm = 'cond' { Compound Compound } [ Compound ]
+//
+// The cond special form is a value-producing conditional: it evaluates the
+// conditions from left to right, and outputs the values of the compound
+// following the first true one. If there are no true conditions and there is
+// a trailing compound, its values are output. Only the chosen compound is
+// evaluated.
+func compileCond(cp *compiler, fn *parse.Form) OpFunc {
+	var condNodes, valueNodes []*parse.Compound
+	args := fn.Args
+	for len(args) >= 2 {
+		condNodes = append(condNodes, args[0])
+		valueNodes = append(valueNodes, args[1])
+		args = args[2:]
+	}
+	condOps := cp.compoundOps(condNodes)
+	valueOps := cp.compoundOps(valueNodes)
+	var elseOp ValuesOp
+	if len(args) == 1 {
+		elseOp = cp.compoundOp(args[0])
+	}
+
+	return func(ec *Frame) {
+		out := ec.OutputChan()
+		outputAll := func(op ValuesOp) {
+			for _, v := range op.Exec(ec) {
+				out <- v
+			}
+		}
+		for i, condOp := range condOps {
+			if allTrue(condOp.Exec(ec.fork("cond cond"))) {
+				outputAll(valueOps[i])
+				return
+			}
+		}
+		if elseOp.Func != nil {
+			outputAll(elseOp)
+		}
+	}
+}
+
+// WhileForm = 'while' Compound Lambda [ 'else' Lambda ]
+//
+// The else body is executed when the loop ends without being broken out of,
+// including when the body is never executed. A &label option names the loop,
+// so that it can be the target of labeled break and continue from nested
+// loops.
 func compileWhile(cp *compiler, fn *parse.Form) OpFunc {
 	args := cp.walkArgs(fn)
 	condNode := args.next()
 	bodyNode := args.nextMustLambda()
+	elseNode := args.nextMustLambdaIfAfter("else")
 	args.mustEnd()
+	label := args.literalOpts("label")["label"]
 
 	condOp := cp.compoundOp(condNode)
 	bodyOp := cp.primaryOp(bodyNode)
+	var elseOp ValuesOp
+	if elseNode != nil {
+		elseOp = cp.primaryOp(elseNode)
+	}
 
 	return func(ec *Frame) {
 		body := bodyOp.execlambdaOp(ec)
+		elseBody := elseOp.execlambdaOp(ec)
 
+		broken := false
 		for {
 			cond := condOp.Exec(ec.fork("while cond"))
 			if !allTrue(cond) {
@@ -389,30 +450,54 @@ func compileWhile(cp *compiler, fn *parse.Form) OpFunc {
 			err := ec.fork("while").PCall(body, NoArgs, NoOpts)
 			if err != nil {
 				exc := err.(*Exception)
-				if exc.Cause == Continue {
-					// do nothing
-				} else if exc.Cause == Break {
-					continue
-				} else {
+				flow, ok := loopFlow(exc.Cause, label)
+				if !ok {
 					throw(err)
 				}
+				if flow == Break {
+					broken = true
+					break
+				}
 			}
 		}
+
+		if !broken && elseBody != nil {
+			elseBody.Call(ec.fork("while else"), NoArgs, NoOpts)
+		}
 	}
 }
 
+// ForForm = 'for' Variable [ Variable ] Compound Lambda [ 'else' Lambda ]
+//
+// When two variables are given, the iterated value must be a map, and the
+// variables are bound to each key and value. With the &indexed option, the
+// iterated value may be any iterable, and the variables are bound to the index
+// and the element instead. Like in while, the else body is executed when the
+// loop is not broken out of, and a &label option names the loop.
 func compileFor(cp *compiler, fn *parse.Form) OpFunc {
 	args := cp.walkArgs(fn)
+	var keyVarNode *parse.Compound
 	varNode := args.next()
 	iterNode := args.next()
+	if !args.peekIsLambda() {
+		keyVarNode, varNode = varNode, iterNode
+		iterNode = args.next()
+	}
 	bodyNode := args.nextMustLambda()
 	elseNode := args.nextMustLambdaIfAfter("else")
 	args.mustEnd()
+	opts := args.literalOpts("indexed", "label")
+	_, indexed := opts["indexed"]
+	label := opts["label"]
+	if indexed && keyVarNode == nil {
+		cp.errorpf(fn.Begin(), fn.End(), "&indexed needs two variables")
+	}
 
-	varOp, restOp := cp.lvaluesOp(varNode.Indexings[0])
-	if restOp.Func != nil {
-		cp.errorpf(restOp.Begin, restOp.End, "rest not allowed")
+	var keyVarOp LValuesOp
+	if keyVarNode != nil {
+		keyVarOp = cp.forVarOp(keyVarNode)
 	}
+	varOp := cp.forVarOp(varNode)
 
 	iterOp := cp.compoundOp(iterNode)
 	bodyOp := cp.primaryOp(bodyNode)
@@ -422,42 +507,79 @@ func compileFor(cp *compiler, fn *parse.Form) OpFunc {
 	}
 
 	return func(ec *Frame) {
-		variables := varOp.Exec(ec)
-		if len(variables) != 1 {
-			ec.errorpf(varOp.Begin, varOp.End, "only one variable allowed")
-		}
-		variable := variables[0]
+		keyVariable := keyVarOp.execForVar(ec)
+		variable := varOp.execForVar(ec)
 
-		iterable := ec.ExecAndUnwrap("value being iterated", iterOp).One().Iterable()
+		iterated := ec.ExecAndUnwrap("value being iterated", iterOp).One()
 
 		body := bodyOp.execlambdaOp(ec)
 		elseBody := elseOp.execlambdaOp(ec)
 
-		iterated := false
-		iterable.Iterate(func(v types.Value) bool {
-			iterated = true
-			err := variable.Set(v)
-			maybeThrow(err)
-			err = ec.fork("for").PCall(body, NoArgs, NoOpts)
+		broken := false
+		iterate := func(k, v types.Value) bool {
+			if keyVariable != nil {
+				maybeThrow(keyVariable.Set(k))
+			}
+			maybeThrow(variable.Set(v))
+			err := ec.fork("for").PCall(body, NoArgs, NoOpts)
 			if err != nil {
 				exc := err.(*Exception)
-				if exc.Cause == Continue {
-					// do nothing
-				} else if exc.Cause == Break {
-					return false
-				} else {
+				flow, ok := loopFlow(exc.Cause, label)
+				if !ok {
 					throw(err)
 				}
+				if flow == Break {
+					broken = true
+					return false
+				}
 			}
 			return true
-		})
+		}
+
+		switch {
+		case keyVariable == nil:
+			iterated.Iterable().Iterate(func(v types.Value) bool {
+				return iterate(nil, v)
+			})
+		case indexed:
+			i := 0
+			iterated.Iterable().Iterate(func(v types.Value) bool {
+				k := types.String(strconv.Itoa(i))
+				i++
+				return iterate(k, v)
+			})
+		default:
+			iterated.IteratePairer().IteratePair(iterate)
+		}
 
-		if !iterated && elseBody != nil {
+		if !broken && elseBody != nil {
 			elseBody.Call(ec.fork("for else"), NoArgs, NoOpts)
 		}
 	}
 }
 
+// forVarOp compiles a variable of the for special form.
+func (cp *compiler) forVarOp(n *parse.Compound) LValuesOp {
+	varOp, restOp := cp.lvaluesOp(n.Indexings[0])
+	if restOp.Func != nil {
+		cp.errorpf(restOp.Begin, restOp.End, "rest not allowed")
+	}
+	return varOp
+}
+
+// execForVar executes the LValuesOp for a variable of the for special form. If
+// the given LValuesOp is empty, it returns nil.
+func (op LValuesOp) execForVar(ec *Frame) vartypes.Variable {
+	if op.Func == nil {
+		return nil
+	}
+	variables := op.Exec(ec)
+	if len(variables) != 1 {
+		ec.errorpf(op.Begin, op.End, "only one variable allowed")
+	}
+	return variables[0]
+}
+
 func compileTry(cp *compiler, fn *parse.Form) OpFunc {
 	logger.Println("compiling try")
 	args := cp.walkArgs(fn)
@@ -15,6 +15,17 @@ var builtinSpecialTests = []Test{
 	{"if $false { put 2 } elif true { put 2 } else { put 3 }",
 		want{out: strs("2")}},
 
+	// if in value position
+	{"x = (if $false { put 1 } else { put 2 }); put $x", want{out: strs("2")}},
+
+	// cond
+	{"cond $true a $true b", want{out: strs("a")}},
+	{"cond $false a (eq x x) b c", want{out: strs("b")}},
+	{"cond $false a $false b c", want{out: strs("c")}},
+	{"cond $false a", wantNothing},
+	{"x = (cond $false (fail bad) $true [a b]); put $x[1]",
+		want{out: strs("b")}},
+
 	// try
 	{"try { nop } except { put bad } else { put good }", want{out: strs("good")}},
 	{"try { e:false } except - { put bad } else { put good }", want{out: strs("bad")}},
@@ -23,15 +34,43 @@ var builtinSpecialTests = []Test{
 	{"x=0; while (< $x 4) { put $x; x=(+ $x 1) }",
 		want{out: strs("0", "1", "2", "3")}},
 
+	{"x=0; while (< $x 4) { x=(+ $x 1); if (== $x 2) { break }; put $x }",
+		want{out: strs("1")}},
+	// else runs when the loop is not broken out of
+	{"while $false { } else { put else }", want{out: strs("else")}},
+	{"x=0; while (< $x 2) { x=(+ $x 1) } else { put $x }", want{out: strs("2")}},
+	{"while $true { break } else { put else }", wantNothing},
+	// labeled break and continue
+	{"i=0; while &label=outer (< $i 3) { i=(+ $i 1); for x [a b] { if (== $i 2) { continue &label=outer }; put $i$x } }",
+		want{out: strs("1a", "1b", "3a", "3b")}},
+
 	// for
 	{"for x [tempora mores] { put 'O '$x }",
 		want{out: strs("O tempora", "O mores")}},
+	// key and value
+	{"for k v [&a=b] { put $k $v }", want{out: strs("a", "b")}},
+	{"for k v [a b] { put $k $v }", want{err: errAny}},
+	// index and element
+	{"for i x [a b] &indexed { put $i $x }",
+		want{out: strs("0", "a", "1", "b")}},
+	{"for i x [] &indexed { put $i } else { put empty }",
+		want{out: strs("empty")}},
 	// break
 	{"for x [a] { break } else { put $x }", wantNothing},
-	// else
-	{"for x [a] { put $x } else { put $x }", want{out: strs("a")}},
+	// else runs when the loop is not broken out of
+	{"for x [a] { put $x } else { put $x }", want{out: strs("a", "a")}},
+	{"for x [a b] { if (eq $x b) { break } } else { put else }", wantNothing},
+	// breaking out of an inner loop does not count
+	{"for x [a] { for y [b] { break } } else { put else }",
+		want{out: strs("else")}},
 	// continue
 	{"for x [a b] { put $x; continue; put $x; }", want{out: strs("a", "b")}},
+	// labeled break and continue
+	{"for &label=outer x [a b] { for y [c d] { put $x$y; break &label=outer } }",
+		want{out: strs("ac")}},
+	{"for &label=outer x [a b] { for y [c d] { put $x$y; continue &label=outer } }",
+		want{out: strs("ac", "bc")}},
+	{"for x [a] { break &label=nosuch }", want{err: LabeledFlow{Break, "nosuch"}}},
 
 	// fn.
 	{"fn f [x]{ put x=$x'.' }; f lorem; f ipsum",
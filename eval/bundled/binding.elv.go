@@ -5,7 +5,7 @@ fn install {
     edit:insert:binding = (edit:binding-table [
         &Default=    $edit:insert:default~
         &F2=         $edit:toggle-quote-paste~
-        &Up=         $edit:history:start~
+        &Up=         $edit:history:substring-start~
         &Down=       $edit:end-of-history~
         &Right=      $edit:move-dot-right~
         &Left=       $edit:move-dot-left~
@@ -21,7 +21,9 @@ fn install {
         &Alt-.=      $edit:insert-last-word~
         &Alt-1=      $edit:lastcmd:start~
         &Alt-b=      $edit:move-dot-left-word~
+        &Alt-e=      $edit:edit-command-in-editor~
         &Alt-f=      $edit:move-dot-right-word~
+        &Alt-p=      $edit:preview-form~
         &Ctrl-Right= $edit:move-dot-right-word~
         &Ctrl-Left=  $edit:move-dot-left-word~
         &Ctrl-D=     $edit:return-eof~
@@ -6,6 +6,7 @@ func Get() map[string]string {
 	return map[string]string{
 		"binding":          bindingElv,
 		"epm":              epmElv,
+		"list":             listElv,
 		"narrow":           narrowElv,
 		"readline-binding": readlineBindingElv,
 	}
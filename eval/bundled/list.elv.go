@@ -0,0 +1,112 @@
+package bundled
+
+const listElv = `
+# Utilities for working with lists, written in pure Elvish.
+#
+# Usage:
+#   use list
+#   list:contains [a b c] b
+#
+# Functions that take a predicate call it with one element at a time, and
+# treat the element as matching when all the values it outputs are true.
+
+# Outputs the index of the first element of $li that matches $pred, or -1 if
+# there is no such element.
+fn find-index [pred li]{
+    i = 0
+    for x $li {
+        if ($pred $x) {
+            put $i
+            return
+        }
+        i = (+ $i 1)
+    }
+    put -1
+}
+
+# Outputs whether $li has an element equal to $x.
+fn contains [li x]{
+    for y $li {
+        if (eq $x $y) {
+            put $true
+            return
+        }
+    }
+    put $false
+}
+
+# Outputs the elements of $li, expanding nested lists down to &depth levels.
+# A negative &depth expands all levels.
+fn flatten [li &depth=-1]{
+    for x $li {
+        if (and (eq (kind-of $x) list) (!= $depth 0)) {
+            flatten $x &depth=(- $depth 1)
+        } else {
+            put $x
+        }
+    }
+}
+
+# Outputs two lists: the elements of $li that match $pred, and the rest.
+fn partition [pred li]{
+    yes no = [] []
+    for x $li {
+        if ($pred $x) {
+            yes = [$@yes $x]
+        } else {
+            no = [$@no $x]
+        }
+    }
+    put $yes $no
+}
+
+# Outputs lists of $n consecutive elements of $li. The last list is shorter
+# when the number of elements is not a multiple of $n.
+fn chunk [n li]{
+    if (<= $n 0) {
+        fail 'chunk size should be positive, got '$n
+    }
+    cur = []
+    for x $li {
+        cur = [$@cur $x]
+        if (== (count $cur) $n) {
+            put $cur
+            cur = []
+        }
+    }
+    if (> (count $cur) 0) {
+        put $cur
+    }
+}
+
+# Outputs the first elements of all the lists, then the second elements, and
+# so on. Lists that have run out of elements are skipped.
+fn interleave [@lists]{
+    n = 0
+    for li $lists {
+        if (> (count $li) $n) {
+            n = (count $li)
+        }
+    }
+    for i [(range $n)] {
+        for li $lists {
+            if (< $i (count $li)) {
+                put $li[$i]
+            }
+        }
+    }
+}
+
+# Outputs the elements of $li, skipping those whose key, the single value
+# output by $f, equals that of an earlier element.
+fn dedupe-by [f li]{
+    seen = [&]
+    for x $li {
+        k = ($f $x)
+        if (not (has-key $seen $k)) {
+            seen[$k] = $true
+            put $x
+        }
+    }
+}
+`
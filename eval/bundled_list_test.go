@@ -0,0 +1,35 @@
+package eval
+
+import "testing"
+
+func TestBundledList(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("use list; list:find-index [x]{ > $x 2 } [1 3 5]").
+			WantOutStrings("1"),
+		NewTest("use list; list:find-index [x]{ > $x 9 } [1 3 5]").
+			WantOutStrings("-1"),
+
+		NewTest("use list; list:contains [a b c] b").WantOutBools(true),
+		NewTest("use list; list:contains [a [b]] [b]").WantOutBools(true),
+		NewTest("use list; list:contains [] a").WantOutBools(false),
+
+		NewTest("use list; list:flatten [a [b [c [d]]] e]").
+			WantOutStrings("a", "b", "c", "d", "e"),
+		NewTest("use list; li = [(list:flatten &depth=1 [a [b [c]]])]; put $li[2][0]").
+			WantOutStrings("c"),
+
+		NewTest("use list; yes no = (list:partition [x]{ > $x 2 } [1 3 2 4]); put $@yes ' ' $@no").
+			WantOutStrings("3", "4", " ", "1", "2"),
+
+		NewTest("use list; list:chunk 2 [a b c d e] | each [c]{ joins , $c }").
+			WantOutStrings("a,b", "c,d", "e"),
+		NewTest("use list; list:chunk 0 [a]").WantErr(errAny),
+
+		NewTest("use list; list:interleave [a b c] [1] [x y]").
+			WantOutStrings("a", "1", "x", "b", "y", "c"),
+		NewTest("use list; list:interleave").WantOutStrings(),
+
+		NewTest("use list; list:dedupe-by $count~ [a bb c dd eee]").
+			WantOutStrings("a", "bb", "eee"),
+	})
+}
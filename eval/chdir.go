@@ -2,6 +2,9 @@ package eval
 
 import (
 	"os"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
 )
 
 // AddDirer wraps the AddDir function.
@@ -34,3 +37,35 @@ func Chdir(path string, store AddDirer) error {
 	}
 	return nil
 }
+
+// Chdir changes the current directory like the Chdir function, recording the
+// new directory with the daemon. Before changing the directory, it calls the
+// hooks in $before-chdir with the directory to change to; after the directory
+// has been changed, it calls the hooks in $after-chdir with the old directory,
+// and publishes the chdir event.
+func (ev *Evaler) Chdir(path string) error {
+	ev.callHooks(ev.Builtin["before-chdir"].Get().(types.List), "before-chdir hook",
+		types.String(path))
+	oldPwd, err := os.Getwd()
+	if err != nil {
+		logger.Println("getwd before cd:", err)
+	}
+	var store AddDirer
+	if ev.DaemonClient != nil {
+		store = ev.DaemonClient
+	}
+	err = Chdir(path, store)
+	if err != nil {
+		return err
+	}
+	ev.callHooks(ev.Builtin["after-chdir"].Get().(types.List), "after-chdir hook",
+		types.String(oldPwd))
+	if pwd, err := os.Getwd(); err == nil {
+		ev.EmitEvent(EventChdir, types.String(oldPwd), types.String(pwd))
+	}
+	return nil
+}
+
+func newChdirHooksVariable() vartypes.Variable {
+	return vartypes.NewValidatedPtr(types.EmptyList, vartypes.ShouldBeList)
+}
@@ -3,9 +3,11 @@ package eval
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/util"
 )
 
@@ -72,3 +74,43 @@ func TestChdirError(t *testing.T) {
 		t.Errorf("Chdir => no error when dir does not exist")
 	}
 }
+
+func TestEvalerChdirHooks(t *testing.T) {
+	util.InTempDir(func(string) {
+		tmpDir, err := os.Getwd()
+		if err != nil {
+			panic(err)
+		}
+		err = os.Mkdir("d", 0755)
+		if err != nil {
+			panic(err)
+		}
+		ev := NewEvaler()
+		defer ev.Close()
+		err = ev.SourceText(NewInteractiveSource(`
+			before = []; after = []
+			before-chdir = [[d]{ before = [$@before $d] }]
+			after-chdir = [[d]{ after = [$@after $d] }]
+			cd d; pwd = ..; cd /i/dont/exist`))
+		if err == nil {
+			t.Errorf("cd to nonexistent directory did not error")
+		}
+		wantBefore := types.MakeList(
+			types.String("d"), types.String(".."), types.String(badDir))
+		if before := ev.Global["before"].Get(); !before.Equal(wantBefore) {
+			t.Errorf("before-chdir hooks got %s, want %s",
+				before.Repr(types.NoPretty), wantBefore.Repr(types.NoPretty))
+		}
+		wantAfter := types.MakeList(
+			types.String(tmpDir), types.String(filepath.Join(tmpDir, "d")))
+		if after := ev.Global["after"].Get(); !after.Equal(wantAfter) {
+			t.Errorf("after-chdir hooks got %s, want %s",
+				after.Repr(types.NoPretty), wantAfter.Repr(types.NoPretty))
+		}
+	})
+
+	runTests(t, []Test{
+		NewTest("before-chdir = foo").WantAnyErr(),
+		NewTest("after-chdir = [foo]; cd .; put ok").WantOutStrings("ok"),
+	})
+}
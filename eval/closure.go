@@ -3,6 +3,7 @@ package eval
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"unsafe"
 
 	"github.com/elves/elvish/eval/types"
@@ -15,6 +16,8 @@ import (
 // supplies does not match with what is required.
 var ErrArityMismatch = errors.New("arity mismatch")
 
+var errNoSuchClosureField = errors.New("no such field of closure")
+
 // Closure is a closure defined in elvish script.
 type Closure struct {
 	ArgNames []string
@@ -27,7 +30,10 @@ type Closure struct {
 	SrcMeta     *Source
 }
 
-var _ Fn = &Closure{}
+var (
+	_ Fn               = &Closure{}
+	_ types.IndexOneer = &Closure{}
+)
 
 // Kind returns "fn".
 func (*Closure) Kind() string {
@@ -48,6 +54,50 @@ func (c *Closure) Repr(int) string {
 	return fmt.Sprintf("<closure %p>", c)
 }
 
+// IndexOne gives the fields of the closure: arg-names (a list), rest-arg (an
+// empty string if there is none), opt-names and opt-defaults (lists in the
+// same order), src (the source the closure was defined in), line (the line
+// number of the start of its body in the source) and body (the code of its
+// body).
+func (c *Closure) IndexOne(idx types.Value) types.Value {
+	switch idx {
+	case types.String("arg-names"):
+		return stringList(c.ArgNames)
+	case types.String("rest-arg"):
+		return types.String(c.RestArg)
+	case types.String("opt-names"):
+		return stringList(c.OptNames)
+	case types.String("opt-defaults"):
+		return types.MakeList(c.OptDefaults...)
+	case types.String("src"):
+		if c.SrcMeta == nil {
+			return types.Nil
+		}
+		return c.SrcMeta
+	case types.String("line"):
+		line := 0
+		if c.SrcMeta != nil {
+			line = c.SrcMeta.lineOf(c.Op.Begin)
+		}
+		return types.String(strconv.Itoa(line))
+	case types.String("body"):
+		if c.SrcMeta == nil || c.Op.End > len(c.SrcMeta.code) {
+			return types.String("")
+		}
+		return types.String(c.SrcMeta.code[c.Op.Begin:c.Op.End])
+	}
+	throw(errNoSuchClosureField)
+	panic("unreachable")
+}
+
+func stringList(ss []string) types.List {
+	vs := make([]types.Value, len(ss))
+	for i, s := range ss {
+		vs[i] = types.String(s)
+	}
+	return types.MakeList(vs...)
+}
+
 // Call calls a closure.
 func (c *Closure) Call(ec *Frame, args []types.Value, opts map[string]types.Value) {
 	if c.RestArg != "" {
@@ -100,5 +150,7 @@ func (c *Closure) Call(ec *Frame, args []types.Value, opts map[string]types.Valu
 	ec.traceback = ec.addTraceback()
 
 	ec.srcMeta = c.SrcMeta
+	ec.cleanups = &cleanups{}
+	defer ec.cleanups.run()
 	c.Op.Exec(ec)
 }
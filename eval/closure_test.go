@@ -13,3 +13,18 @@ func TestClosure(t *testing.T) {
 		NewTest("[]{ } &k=v").WantAnyErr(),
 	})
 }
+
+func TestClosureFields(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("f = [a b @c &k=v &l=w]{ }; explode $f[arg-names]; put $f[rest-arg]").
+			WantOutStrings("a", "b", "c"),
+		NewTest("f = [a &k=v &l=w]{ }; explode $f[opt-names]; explode $f[opt-defaults]").
+			WantOutStrings("k", "l", "v", "w"),
+		NewTest("f = { }; count $f[arg-names]; put $f[rest-arg]").
+			WantOutStrings("0", ""),
+		NewTest("f = [x]{ put $x }; put $f[body]").WantOutStrings(" put $x "),
+		NewTest("nop\nfn f {\n  nop\n}; put $f~[line]").WantOutStrings("2"),
+		NewTest("f = { }; put $f[src][type]").WantOutStrings("script"),
+		NewTest("f = { }; put $f[foo]").WantAnyErr(),
+	})
+}
@@ -0,0 +1,256 @@
+// Package color implements the color: module for converting colors between
+// notations and computing with them.
+package color
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+// Colors can be given as "#rrggbb" or "#rgb" hex triplets, indices into the
+// 256-color palette of xterm, or the names of the first 16 colors in the
+// palette as used by edit:styled, like "red" and "lightblue".
+
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"rgb", rgbFn},
+	{"hex", hexFn},
+	{"ansi256", ansi256Fn},
+	{"sgr", sgr},
+	{"contrast", contrast},
+	{"gradient", gradient},
+}
+
+var errBadColor = errors.New("bad color")
+
+type rgb struct{ r, g, b uint8 }
+
+var names = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "lightgray",
+	"gray", "lightred", "lightgreen", "lightyellow", "lightblue",
+	"lightmagenta", "lightcyan", "white",
+}
+
+// The first 16 colors of the palette, as used by xterm by default.
+var basicColors = [16]rgb{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// Levels of the components in the 6x6x6 color cube of the palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// paletteColor returns the color at an index of the 256-color palette.
+func paletteColor(i int) rgb {
+	switch {
+	case i < 16:
+		return basicColors[i]
+	case i < 232:
+		i -= 16
+		return rgb{cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]}
+	default:
+		level := uint8(8 + 10*(i-232))
+		return rgb{level, level, level}
+	}
+}
+
+func parseColor(s string) (rgb, error) {
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) == 6 {
+			if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return rgb{uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+			}
+		}
+		return rgb{}, errBadColor
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 0 || i > 255 {
+			return rgb{}, errBadColor
+		}
+		return paletteColor(i), nil
+	}
+	for i, name := range names {
+		if s == name {
+			return basicColors[i], nil
+		}
+	}
+	return rgb{}, errBadColor
+}
+
+func scanColor(v types.String) rgb {
+	c, err := parseColor(string(v))
+	if err != nil {
+		throwf("%v: %s", err, v.Repr(types.NoPretty))
+	}
+	return c
+}
+
+func (c rgb) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+// ansi256 returns the index of the closest color in the palette, excluding the
+// first 16 colors, which are often changed by terminal themes.
+func (c rgb) ansi256() int {
+	best, bestDist := 16, math.MaxInt32
+	for i := 16; i < 256; i++ {
+		p := paletteColor(i)
+		dr, dg, db := int(c.r)-int(p.r), int(c.g)-int(p.g), int(c.b)-int(p.b)
+		if dist := dr*dr + dg*dg + db*db; dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// luminance returns the relative luminance as defined by WCAG 2.0.
+func (c rgb) luminance() float64 {
+	linear := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.r) + 0.7152*linear(c.g) + 0.0722*linear(c.b)
+}
+
+func formatFloat(f float64) types.String {
+	return types.String(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// rgbFn outputs the red, green and blue components of a color, from 0 to 255.
+func rgbFn(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	c := scanColor(s)
+	out := ec.OutputChan()
+	for _, v := range []uint8{c.r, c.g, c.b} {
+		out <- types.String(strconv.Itoa(int(v)))
+	}
+}
+
+// hexFn outputs a color as a "#rrggbb" hex triplet. The color is either given
+// as one argument, or as its red, green and blue components.
+func hexFn(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoOpt(opts)
+	var c rgb
+	if len(args) == 3 {
+		var r, g, b int
+		ec.ScanArgs(args, &r, &g, &b)
+		for _, v := range []int{r, g, b} {
+			if v < 0 || v > 255 {
+				throwf("component should be from 0 to 255, got %d", v)
+			}
+		}
+		c = rgb{uint8(r), uint8(g), uint8(b)}
+	} else {
+		var s types.String
+		ec.ScanArgs(args, &s)
+		c = scanColor(s)
+	}
+	ec.OutputChan() <- types.String(c.hex())
+}
+
+// ansi256Fn outputs the index of the closest color in the 256-color palette.
+func ansi256Fn(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.String(strconv.Itoa(scanColor(s).ansi256()))
+}
+
+type sgrOptions struct {
+	Bg        bool
+	TrueColor bool
+}
+
+// sgr outputs the SGR parameters that set a color as the foreground, or the
+// background with &bg, suitable as a style of edit:styled. The closest color
+// in the 256-color palette is used, unless &true-color is set.
+func sgr(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	var options sgrOptions
+	ec.ScanOptsToStruct(opts, &options)
+
+	c := scanColor(s)
+	prefix := "38"
+	if options.Bg {
+		prefix = "48"
+	}
+	var code string
+	if options.TrueColor {
+		code = fmt.Sprintf("%s;2;%d;%d;%d", prefix, c.r, c.g, c.b)
+	} else {
+		code = fmt.Sprintf("%s;5;%d", prefix, c.ansi256())
+	}
+	ec.OutputChan() <- types.String(code)
+}
+
+// contrast outputs the contrast ratio between two colors as defined by WCAG
+// 2.0, from 1 to 21.
+func contrast(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s1, s2 types.String
+	ec.ScanArgs(args, &s1, &s2)
+	eval.TakeNoOpt(opts)
+
+	l1, l2 := scanColor(s1).luminance(), scanColor(s2).luminance()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	ec.OutputChan() <- formatFloat((l1 + 0.05) / (l2 + 0.05))
+}
+
+// gradient outputs n colors evenly spaced between two colors, including both,
+// as hex triplets.
+func gradient(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		s1, s2 types.String
+		n      int
+	)
+	ec.ScanArgs(args, &s1, &s2, &n)
+	eval.TakeNoOpt(opts)
+
+	if n < 1 {
+		throwf("number of colors should be positive, got %d", n)
+	}
+	c1, c2 := scanColor(s1), scanColor(s2)
+	mix := func(a, b uint8, t float64) uint8 {
+		return uint8(math.Floor(float64(a) + (float64(b)-float64(a))*t + 0.5))
+	}
+	out := ec.OutputChan()
+	for i := 0; i < n; i++ {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+		c := rgb{mix(c1.r, c2.r, t), mix(c1.g, c2.g, t), mix(c1.b, c2.b, t)}
+		out <- types.String(c.hex())
+	}
+}
+
+func throwf(format string, args ...interface{}) {
+	util.Throw(fmt.Errorf(format, args...))
+}
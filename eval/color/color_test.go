@@ -0,0 +1,47 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`color:rgb '#ff8000'`).WantOutStrings("255", "128", "0"),
+	eval.NewTest(`color:rgb '#f80'`).WantOutStrings("255", "136", "0"),
+	eval.NewTest(`color:rgb 208`).WantOutStrings("255", "135", "0"),
+	eval.NewTest(`color:rgb 244`).WantOutStrings("128", "128", "128"),
+	eval.NewTest(`color:rgb lightblue`).WantOutStrings("92", "92", "255"),
+	eval.NewTest(`color:rgb '#ff80'`).WantAnyErr(),
+	eval.NewTest(`color:rgb 256`).WantAnyErr(),
+	eval.NewTest(`color:rgb purple`).WantAnyErr(),
+
+	eval.NewTest(`color:hex red`).WantOutStrings("#cd0000"),
+	eval.NewTest(`color:hex 255 128 0`).WantOutStrings("#ff8000"),
+	eval.NewTest(`color:hex 256 128 0`).WantAnyErr(),
+
+	eval.NewTest(`color:ansi256 '#ff8700'`).WantOutStrings("208"),
+	eval.NewTest(`color:ansi256 '#ff8801'`).WantOutStrings("208"),
+	eval.NewTest(`color:ansi256 white`).WantOutStrings("231"),
+
+	eval.NewTest(`color:sgr '#ff8700'`).WantOutStrings("38;5;208"),
+	eval.NewTest(`color:sgr &bg '#ff8700'`).WantOutStrings("48;5;208"),
+	eval.NewTest(`color:sgr &true-color '#ff8001'`).WantOutStrings("38;2;255;128;1"),
+
+	eval.NewTest(`color:contrast '#000' '#fff'`).WantOutStrings("21"),
+	eval.NewTest(`color:contrast '#fff' '#fff'`).WantOutStrings("1"),
+
+	eval.NewTest(`color:gradient '#000000' '#ffffff' 3`).
+		WantOutStrings("#000000", "#808080", "#ffffff"),
+	eval.NewTest(`color:gradient red blue 1`).WantOutStrings("#cd0000"),
+	eval.NewTest(`color:gradient red blue 0`).WantAnyErr(),
+}
+
+func TestColor(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["color"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
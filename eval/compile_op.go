@@ -1,9 +1,13 @@
 package eval
 
 import (
+	"bytes"
 	"errors"
+	"io/ioutil"
 	"os"
+	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/eval/vartypes"
@@ -27,10 +31,16 @@ func (op Op) Exec(ec *Frame) {
 
 func (cp *compiler) chunk(n *parse.Chunk) OpFunc {
 	ops := cp.pipelineOps(n.Pipelines)
+	// Queued hooks are run between the pipelines of top-level chunks, where
+	// no other code is running on the goroutine.
+	top := n.Parent() == nil
 
 	return func(ec *Frame) {
 		for _, op := range ops {
 			op.Exec(ec)
+			if top && !ec.background {
+				ec.RunQueuedHooks()
+			}
 		}
 		// Check for interrupts after the chunk.
 		// We also check for interrupts before each pipeline, so there is no
@@ -39,18 +49,17 @@ func (cp *compiler) chunk(n *parse.Chunk) OpFunc {
 	}
 }
 
-const pipelineChanBufferSize = 32
-
 func (cp *compiler) pipeline(n *parse.Pipeline) OpFunc {
 	ops := cp.formOps(n.Forms)
+	outputJob := n.Background && inOutputCapture(n)
 
 	return func(ec *Frame) {
 		ec.CheckInterrupts()
 
 		bg := n.Background
+		parent := ec
 		if bg {
 			ec = ec.fork("background job " + n.SourceText())
-			ec.intCh = nil
 			ec.background = true
 
 			if ec.Editor != nil {
@@ -59,86 +68,123 @@ func (cp *compiler) pipeline(n *parse.Pipeline) OpFunc {
 			}
 		}
 
+		// Top-level and background pipelines start new jobs, while other
+		// pipelines are part of the job of the pipeline they are run in.
+		j := ec.job
+		isNewJob := j == nil || bg
+		var restoreShellTTY func()
+		if isNewJob {
+			j = newJob(ec, n.SourceText(), bg)
+			if j.control && !bg {
+				restoreShellTTY = saveTTY()
+			}
+		}
+
 		nforms := len(ops)
 
-		var wg sync.WaitGroup
-		wg.Add(nforms)
-		errors := make([]*Exception, nforms)
+		// With $pipeline-fail-fast, the first form that fails cancels all the
+		// others: external commands are killed, and builtins are interrupted
+		// like with ^C. The exception of that form is thrown alone.
+		failFast := !bg && nforms > 1 && ec.pipelineFailFast()
+		var (
+			dl        *deadline
+			cancel    func()
+			firstFail = -1
+			failOnce  sync.Once
+		)
+		if failFast {
+			var stop func()
+			dl, cancel, stop = ec.newCancelDeadline()
+			defer stop()
+		}
 
-		var nextIn *Port
+		// With $pipeline-instrument, the forms are measured, and the results
+		// are put in $pipemetrics.
+		var meter *pipeMeter
+		if !bg && nforms > 1 && ec.pipelineInstrument() {
+			meter = newPipeMeter(nforms)
+		}
 
-		// For each form, create a dedicated evalCtx and run asynchronously
-		for i, op := range ops {
-			hasChanInput := i > 0
-			newEc := ec.fork("[form op]")
-			if i > 0 {
-				newEc.ports[0] = nextIn
-			}
-			if i < nforms-1 {
-				// Each internal port pair consists of a (byte) pipe pair and a
-				// channel.
-				// os.Pipe sets O_CLOEXEC, which is what we want.
-				reader, writer, e := os.Pipe()
-				if e != nil {
-					throwf("failed to create pipe: %s", e)
+		// Run each form with a dedicated Frame asynchronously.
+		wg, errors, err := startStages(ec, "[form op]", nforms, meter,
+			func(i int, newEc *Frame) {
+				newEc.job = j
+				if failFast {
+					newEc.deadline = dl
 				}
-				ch := make(chan types.Value, pipelineChanBufferSize)
-				newEc.ports[1] = &Port{
-					File: writer, Chan: ch, CloseFile: true, CloseChan: true}
-				nextIn = &Port{
-					File: reader, Chan: ch, CloseFile: true, CloseChan: false}
-			}
-			thisOp := op
-			thisError := &errors[i]
-			go func() {
-				err := newEc.PEval(thisOp)
-				// Logger.Printf("closing ports of %s", newEc.context)
-				ClosePorts(newEc.ports)
-				if err != nil {
-					*thisError = err.(*Exception)
+				if bg {
+					newEc.releasePorts = ec.cleanups.holdPorts()
 				}
-				wg.Done()
-				if hasChanInput {
-					// If the command has channel input, drain it. This
-					// mitigates the effect of erroneous pipelines like
-					// "range 100 | cat"; without draining the pipeline will
-					// lock up.
-					for range newEc.ports[0].Chan {
-					}
+			},
+			func(i int, newEc *Frame) error {
+				if newEc.releasePorts != nil {
+					defer newEc.releasePorts()
 				}
-			}()
+				err := newEc.PEval(ops[i])
+				if err != nil && failFast && !isBrokenPipe(err.(*Exception).Cause) {
+					failOnce.Do(func() {
+						firstFail = i
+						cancel()
+					})
+				}
+				return err
+			})
+		if err != nil {
+			throw(err)
 		}
 
+		if isNewJob {
+			go func() {
+				wg.Wait()
+				j.finish(errors)
+			}()
+		}
 		if bg {
 			// Background job, wait for form termination asynchronously.
+			release := ec.cleanups.hold()
 			go func() {
 				wg.Wait()
-				msg := "job " + n.SourceText() + " finished"
-				err := ComposeExceptionsFromPipeline(errors)
-				if err != nil {
-					msg += ", errors = " + err.Error()
-				}
-				if ec.Editor != nil {
-					m := ec.Editor.ActiveMutex()
-					m.Lock()
-					defer m.Unlock()
-
-					if ec.Editor.Active() {
-						ec.Editor.Notify("%s", msg)
-					} else {
-						ec.ports[2].File.WriteString(msg + "\n")
-					}
-				} else {
-					ec.ports[2].File.WriteString(msg + "\n")
-				}
+				release()
 			}()
+			ec.jobs.add(j)
+			go j.notifyWhenDone(ec)
+			if outputJob {
+				parent.OutputChan() <- j
+			}
 		} else {
-			wg.Wait()
+			if isNewJob {
+				if j.waitForeground(ec, restoreShellTTY) {
+					// The job has been stopped and moved to the background.
+					return
+				}
+			} else {
+				wg.Wait()
+			}
+			errors = dropBrokenPipes(errors)
+			if nforms > 1 && ec.recordPipeStatus {
+				ec.pipeStatus.set(errors)
+			}
+			if meter != nil {
+				ec.pipeMetrics.set(meter.result())
+			}
+			if firstFail != -1 {
+				throw(errors[firstFail])
+			}
 			maybeThrow(ComposeExceptionsFromPipeline(errors))
 		}
 	}
 }
 
+// inOutputCapture returns whether a pipeline is directly inside an output
+// capture.
+func inOutputCapture(n *parse.Pipeline) bool {
+	if chunk, ok := n.Parent().(*parse.Chunk); ok {
+		primary, ok := chunk.Parent().(*parse.Primary)
+		return ok && primary.Type == parse.OutputCapture
+	}
+	return false
+}
+
 func (cp *compiler) form(n *parse.Form) OpFunc {
 	var saveVarsOps []LValuesOp
 	var assignmentOps []Op
@@ -173,16 +219,22 @@ func (cp *compiler) form(n *parse.Form) OpFunc {
 	if n.Head != nil {
 		headStr, ok := oneString(n.Head)
 		if ok {
+			explode, ns, name := ParseVariable(headStr)
 			compileForm, ok := builtinSpecials[headStr]
+			if !ok && !explode && ns == "builtin" {
+				// builtin:if etc.
+				compileForm, ok = builtinSpecials[name]
+			}
 			if ok {
 				// Special form.
 				specialOpFunc = compileForm(cp, n)
 			} else {
 				var headOpFunc ValuesOpFunc
-				explode, ns, name := ParseVariable(headStr)
 				if !explode && cp.registerVariableGet(ns, name+FnSuffix) {
 					// $head~ resolves.
 					headOpFunc = variable(headStr + FnSuffix)
+				} else if ns == "builtin" {
+					cp.errorpf(n.Head.Begin(), n.Head.End(), "no builtin command %s", name)
 				} else {
 					// Fall back to $e:head~.
 					headOpFunc = func(f *Frame) []types.Value {
@@ -221,7 +273,15 @@ func (cp *compiler) form(n *parse.Form) OpFunc {
 
 	argOps = cp.compoundOps(n.Args)
 	optsOp := cp.mapPairs(n.Opts)
-	redirOps := cp.redirOps(n.Redirs)
+	redirOps := make([]Op, len(n.Redirs))
+	captureOps := make([]captureOp, len(n.Redirs))
+	for i, rn := range n.Redirs {
+		if rn.Mode == parse.Capture {
+			captureOps[i] = cp.captureRedirOp(rn)
+		} else {
+			redirOps[i] = cp.redirOp(rn)
+		}
+	}
 	// TODO: n.ErrorRedir
 
 	begin, end := n.Begin(), n.End()
@@ -271,8 +331,22 @@ func (cp *compiler) form(n *parse.Form) OpFunc {
 		}
 
 		// redirs
-		for _, redirOp := range redirOps {
-			redirOp.Exec(ec)
+		for i, redirOp := range redirOps {
+			if captureOps[i].Func != nil {
+				finish := captureOps[i].Exec(ec)
+				defer func() {
+					// Finish the capture even if the form has thrown, but
+					// let the original exception take precedence.
+					r := recover()
+					err := finish()
+					if r != nil {
+						panic(r)
+					}
+					maybeThrow(err)
+				}()
+			} else {
+				redirOp.Exec(ec)
+			}
 		}
 
 		if specialOpFunc != nil {
@@ -304,6 +378,11 @@ func (cp *compiler) form(n *parse.Form) OpFunc {
 			})
 			ec.begin, ec.end = begin, end
 
+			if _, ok := headFn.(ExternalCmd); !ok && ec.releasePorts != nil {
+				// Only external commands need the port files to stay open
+				// until they start.
+				ec.releasePorts()
+			}
 			if headFn != nil {
 				headFn.Call(ec, args, convertedOpts)
 			} else {
@@ -313,6 +392,186 @@ func (cp *compiler) form(n *parse.Form) OpFunc {
 	}
 }
 
+// hereRedir compiles a Redir with the Heredoc or HereString mode. The byte
+// band of the port is connected to a pipe, into which the string is written.
+// A here-string is followed by a newline; a here-document already ends with
+// one.
+func (cp *compiler) hereRedir(n *parse.Redir) OpFunc {
+	var dstOp ValuesOp
+	if n.Left != nil {
+		dstOp = cp.compoundOp(n.Left)
+	}
+	var contentOp func(*Frame) string
+	switch {
+	case n.Mode == parse.HereString:
+		valueOp := cp.compoundOp(n.Right)
+		contentOp = func(ec *Frame) string {
+			return string(ec.ExecAndUnwrap("here-string", valueOp).One().String()) + "\n"
+		}
+	case n.HeredocLiteral:
+		body := n.Heredoc
+		contentOp = func(*Frame) string { return body }
+	default:
+		contentOp = cp.interpolate(n.Heredoc)
+	}
+
+	return func(ec *Frame) {
+		dst := 0
+		if dstOp.Func != nil {
+			dst = ec.ExecAndUnwrap("Fd", dstOp).One().NonNegativeInt()
+		}
+		content := contentOp(ec)
+
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			throwf("failed to create pipe: %s", err)
+		}
+		// Write in the background, since the content may not fit in the
+		// buffer of the pipe.
+		go func() {
+			writer.WriteString(content)
+			writer.Close()
+		}()
+
+		ec.growPorts(dst + 1)
+		ec.ports[dst].Close()
+		ec.ports[dst] = &Port{File: reader, Chan: ClosedChan, CloseFile: true}
+	}
+}
+
+// interpolate compiles the body of a here-document with variable
+// interpolation: "$name" is replaced by the value of the variable, with the
+// values of a rest variable like "$@name" separated by spaces, and "$$" by a
+// single "$". A "$" not followed by a variable name is kept as is. Trailing
+// colons are not considered part of variable names.
+func (cp *compiler) interpolate(s string) func(*Frame) string {
+	var (
+		literals []string
+		vars     []ValuesOpFunc
+	)
+	var literal bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			literal.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '$' {
+			literal.WriteByte('$')
+			i++
+			continue
+		}
+		j := i + 1
+		if j < len(s) && s[j] == '@' {
+			j++
+		}
+		for j < len(s) {
+			r, size := utf8.DecodeRuneInString(s[j:])
+			if !parse.AllowedInVariableName(r) {
+				break
+			}
+			j += size
+		}
+		qname := strings.TrimRight(s[i+1:j], ":")
+		if qname == "" || qname == "@" {
+			literal.WriteByte('$')
+			continue
+		}
+		if !cp.registerVariableGetQname(qname) {
+			cp.errorf("variable $%s not found", qname)
+		}
+		literals = append(literals, literal.String())
+		literal.Reset()
+		vars = append(vars, variable(qname))
+		i += len(qname)
+	}
+	literals = append(literals, literal.String())
+
+	return func(ec *Frame) string {
+		var buf bytes.Buffer
+		for i, op := range vars {
+			buf.WriteString(literals[i])
+			for j, v := range op(ec) {
+				if j > 0 {
+					buf.WriteByte(' ')
+				}
+				buf.WriteString(types.ToString(v))
+			}
+		}
+		buf.WriteString(literals[len(vars)])
+		return buf.String()
+	}
+}
+
+// captureOp is like Op, but Func returns a function to be called after the
+// form has been run.
+type captureOp struct {
+	Func       func(*Frame) func() error
+	Begin, End int
+}
+
+func (op captureOp) Exec(ec *Frame) func() error {
+	ec.begin, ec.end = op.Begin, op.End
+	return op.Func(ec)
+}
+
+// captureRedirOp compiles a Redir with the Capture mode, like "2>>> var". The
+// byte band of the port is connected to a pipe, whose content is assigned to
+// the variable as a string after the form has been run. The channel band of
+// the port is discarded.
+func (cp *compiler) captureRedirOp(n *parse.Redir) captureOp {
+	cp.compiling(n)
+	var dstOp ValuesOp
+	if n.Left != nil {
+		dstOp = cp.compoundOp(n.Left)
+	}
+	if len(n.Right.Indexings) != 1 {
+		cp.errorpf(n.Right.Begin(), n.Right.End(), "must be a variable")
+	}
+	rest, variableOp := cp.lvalueBase(n.Right.Indexings[0], "must be a variable")
+	if rest {
+		cp.errorpf(n.Right.Begin(), n.Right.End(), "must not be a rest variable")
+	}
+
+	return captureOp{func(ec *Frame) func() error {
+		dst := 1
+		if dstOp.Func != nil {
+			dst = ec.ExecAndUnwrap("Fd", dstOp).One().NonNegativeInt()
+		}
+		variable := variableOp(ec)[0]
+
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			throwf("failed to create pipe: %s", err)
+		}
+		var content []byte
+		done := make(chan struct{})
+		go func() {
+			content, _ = ioutil.ReadAll(reader)
+			reader.Close()
+			close(done)
+		}()
+
+		ec.growPorts(dst + 1)
+		ec.ports[dst].Close()
+		ec.ports[dst] = &Port{File: writer, Chan: BlackholeChan, CloseFile: true}
+
+		return func() error {
+			// The port may have been closed by a later redirection; closing
+			// the pipe again is harmless.
+			writer.Close()
+			// Background processes may still hold the pipe open.
+			select {
+			case <-done:
+			case <-ec.Interrupts():
+				reader.Close()
+				<-done
+				return ErrInterrupted
+			}
+			return variable.Set(types.String(content))
+		}
+	}, n.Begin(), n.End()}
+}
+
 func allTrue(vs []types.Value) bool {
 	for _, v := range vs {
 		if !types.ToBool(v) {
@@ -401,6 +660,9 @@ const defaultFileRedirPerm = 0644
 
 // redir compiles a Redir into a op.
 func (cp *compiler) redir(n *parse.Redir) OpFunc {
+	if n.Mode == parse.Heredoc || n.Mode == parse.HereString {
+		return cp.hereRedir(n)
+	}
 	var dstOp ValuesOp
 	if n.Left != nil {
 		dstOp = cp.compoundOp(n.Left)
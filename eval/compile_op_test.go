@@ -1,6 +1,10 @@
 package eval
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
 
 var opTests = []Test{
 	// Chunks
@@ -21,10 +25,61 @@ var opTests = []Test{
 		want{bytesOut: []byte("A1bert\nBer1in\n")}},
 	// Pure channel pipeline
 	{`put 233 42 19 | each [x]{+ $x 10}`, want{out: strs("243", "52", "29")}},
+	// Binary data passes through builtins in pipelines untouched.
+	{`e:printf '\377\000\376' | slurp &bytes`,
+		want{out: []types.Value{types.Bytes("\xff\x00\xfe")}}},
+	{`e:printf '\377\000\376' | slurp &bytes | each [b]{ print $b } | e:od -An -tx1`,
+		want{bytesOut: []byte(" ff 00 fe\n")}},
 	// Pipeline draining.
 	{`range 100 | put x`, want{out: strs("x")}},
+	// Results of the forms of the last pipeline with more than one form.
+	{"nop | nop; put (count $pipestatus) (eq $ok $@pipestatus)",
+		want{out: []types.Value{types.String("2"), types.Bool(true)}}},
+	{"try { fail bad | nop | nop } except { }; nop; put (each $bool~ $pipestatus)",
+		want{out: bools(false, true, true)}},
+	// With $pipeline-fail-fast, a failing form cancels the others.
+	{"pipeline-fail-fast = $true; try { fail bad | while $true { nop } } except { }; put (each $bool~ $pipestatus)",
+		want{out: bools(false, false)}},
+	{"pipeline-fail-fast = $true; while $true { nop } | fail bad", want{err: errAny}},
+	{"pipeline-fail-fast = $true; put x | put y", want{out: strs("y")}},
+	// Producers can be interrupted while they keep writing.
+	{"pipeline-fail-fast = $true; range 100000000000 | fail bad", want{err: errAny}},
+	// With $pipeline-instrument, the forms of pipelines are measured.
+	{"pipeline-instrument = $true; range 5 | each [x]{ echo $x } | e:cat > /dev/null; each [m]{ put $m[values-out] $m[bytes-in] } $pipemetrics",
+		want{out: strs("5", "0", "0", "0", "0", "10")}},
+	{"pipeline-instrument = $true; range 3 | count; put $pipemetrics[1][values-in]",
+		want{out: strs("3", "3")}},
+	{"range 3 | count; put (count $pipemetrics)", want{out: strs("3", "0")}},
+	// $value-buffer-size sets the size of the channels of pipelines.
+	{"value-buffer-size=0 { range 3 | each [x]{ put $x } }",
+		want{out: strs("0", "1", "2")}},
+	{"value-buffer-size = -1", want{err: errAny}},
+	{"value-buffer-size = x", want{err: errAny}},
+	{"value-buffer-size = 100000000000000", want{err: errAny}},
 	// TODO: Add a useful hybrid pipeline sample
 
+	// Command resolution
+	// ------------------
+
+	// builtin: bypasses functions that shadow builtins
+	{"fn put [x]{ builtin:put wrapped-$x }; put a", want{out: strs("wrapped-a")}},
+	{"put $builtin:true", want{out: bools(true)}},
+	{"builtin:if $true { put x }", want{out: strs("x")}},
+	// e: and external: force using external commands
+	{"fn true { fail bad }; e:true; external:true; put ok", want{out: strs("ok")}},
+	{"fn false { put shadowed }; external:false", want{err: errAny}},
+	// &env sets environment variables for one invocation of an external command
+	{"E:X=old; put (e:sh -c 'echo $X$Y' &env=[&X=new &Y=1]) $E:X",
+		want{out: strs("new1", "old")}},
+	{"e:true &x=y", want{err: ErrExternalCmdOpts}},
+	{"e:true &env=[&a=b &c=d]", want{}},
+	{"e:true &env=foo", want{err: errAny}},
+	{"e:true &env=[&a=b &''=c]", want{err: errAny}},
+	// &clear-env starts external commands with only the variables in &env
+	{"E:X=old; e:env &clear-env &env=[&Y=1]", want{bytesOut: []byte("Y=1\n")}},
+	{"E:X=old; e:env &clear-env", want{}},
+	{"e:true &clear-env=yes", want{err: errAny}},
+
 	// Assignments
 	// -----------
 
@@ -78,6 +133,31 @@ var opTests = []Test{
 	// Redirections from Pipe object.
 	{`p=(pipe); echo haha > $p; pwclose $p; cat < $p; prclose $p`,
 		want{bytesOut: []byte("haha\n")}},
+
+	// Capturing the byte band into a variable.
+	{"echo haha >>> x; put $x", want{out: strs("haha\n")}},
+	{"{ echo out; echo err >&2 } >>> o 2>>> e; put $o $e",
+		want{out: strs("out\n", "err\n")}},
+	{"{ echo err >&2 } 2>>> e 1>&2; put $e", want{out: strs("err\n")}},
+	{"li = [a b]; echo haha >>> li[1]; put $@li", want{out: strs("a", "haha\n")}},
+	{"echo haha >>> x > /dev/null; put $x", want{out: strs("")}},
+	{"x = old; fail bad >>> x", want{err: errAny}},
+	{"x = old; try { { echo new; fail bad } >>> x } except { }; put $x",
+		want{out: strs("new\n")}},
+	{"put foo >>> x; put $x", want{out: strs("")}},
+	// The original exception is kept when the variable can't be set.
+	{"try { fail bad >>> value-buffer-size } except e { put (echo $e) }",
+		want{out: strs("?(fail bad)")}},
+	// ">=" is still a redirection to a file whose name starts with "=".
+	{"echo haha >=b; slurp < =b; rm =b", want{out: strs("haha\n")}},
+
+	// Here-strings and here-documents.
+	{"x = foo; slurp <<< $x", want{out: strs("foo\n")}},
+	{"{ slurp <&3 } 3<<< foo", want{out: strs("foo\n")}},
+	{"x = foo; li = [a b]; slurp << EOF\n$x $@li:$$x $\n  EOF\nEOF\n",
+		want{out: strs("foo a b:$x $\n  EOF\n")}},
+	{"x = foo; slurp << 'EOF'\n$x\nEOF", want{out: strs("$x\n")}},
+	{"slurp << EOF; put bar\nfoo\nEOF", want{out: strs("foo\n", "bar")}},
 }
 
 func TestOp(t *testing.T) {
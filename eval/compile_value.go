@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/glob"
@@ -84,7 +87,7 @@ func (cp *compiler) compound(n *parse.Compound) ValuesOpFunc {
 			for _, v := range vs {
 				if gp, ok := v.(GlobPattern); ok {
 					// Logger.Printf("globbing %v", gp)
-					newvs = append(newvs, doGlob(gp, ec.Interrupts())...)
+					newvs = append(newvs, doGlob(gp, ec.Mounts, ec.Interrupts())...)
 				} else {
 					newvs = append(newvs, v)
 				}
@@ -142,11 +145,14 @@ var (
 	ErrCannotDetermineUsername = errors.New("cannot determine user name from glob pattern")
 )
 
+// Runes that end the user name after a tilde.
+const pathSeparators = "/" + string(filepath.Separator)
+
 func doTilde(v types.Value) types.Value {
 	switch v := v.(type) {
 	case types.String:
 		s := string(v)
-		i := strings.Index(s, "/")
+		i := strings.IndexAny(s, pathSeparators)
 		var uname, rest string
 		if i == -1 {
 			uname = s
@@ -155,7 +161,7 @@ func doTilde(v types.Value) types.Value {
 			rest = s[i+1:]
 		}
 		dir := mustGetHome(uname)
-		return types.String(path.Join(dir, rest))
+		return types.String(filepath.Join(dir, rest))
 	case GlobPattern:
 		if len(v.Segments) == 0 {
 			throw(ErrBadGlobPattern)
@@ -164,7 +170,7 @@ func doTilde(v types.Value) types.Value {
 		case glob.Literal:
 			s := seg.Data
 			// Find / in the first segment to determine the username.
-			i := strings.Index(s, "/")
+			i := strings.IndexAny(s, pathSeparators)
 			if i == -1 {
 				throw(ErrCannotDetermineUsername)
 			}
@@ -525,8 +531,111 @@ func (cp *compiler) mapPairs(pairs []*parse.MapPair) ValuesOpFunc {
 }
 
 func (cp *compiler) braced(n *parse.Primary) ValuesOpFunc {
+	if len(n.Braced) == 1 {
+		seq, ok, err := braceSequence(n.Braced[0])
+		if err != nil {
+			cp.errorpf(n.Begin(), n.End(), "%s", err)
+		}
+		if ok {
+			return literalValues(seq...)
+		}
+	}
 	ops := cp.compoundOps(n.Braced)
-	// TODO: n.IsRange
-	// isRange := n.IsRange
 	return catValuesOps(ops)
 }
+
+// The maximum number of words a brace sequence can expand to.
+const maxBraceSequenceLen = 1 << 16
+
+var (
+	numberSequence = regexp.MustCompile(`^([+-]?[0-9]+)\.\.([+-]?[0-9]+)(?:\.\.([+-]?[0-9]+))?$`)
+	runeSequence   = regexp.MustCompile(`^(.)\.\.(.)(?:\.\.([+-]?[0-9]+))?$`)
+)
+
+// braceSequence expands a braced element that is a bareword like 1..10,
+// 01..10, a..f or 1..10..2 into a sequence. The step defaults to 1, and its
+// sign is ignored: the sequence goes down when the end is smaller than the
+// start. When either end of a numeric sequence has a leading zero, all
+// numbers are padded with zeros to the same width. It returns an error if the
+// sequence would be longer than maxBraceSequenceLen.
+func braceSequence(cn *parse.Compound) ([]types.Value, bool, error) {
+	if len(cn.Indexings) != 1 || len(cn.Indexings[0].Indicies) > 0 ||
+		cn.Indexings[0].Head.Type != parse.Bareword {
+		return nil, false, nil
+	}
+	text := cn.Indexings[0].Head.Value
+
+	if m := numberSequence.FindStringSubmatch(text); m != nil {
+		from, err1 := strconv.Atoi(m[1])
+		to, err2 := strconv.Atoi(m[2])
+		if err1 != nil || err2 != nil {
+			return nil, false, nil
+		}
+		width := 0
+		if hasLeadingZero(m[1]) || hasLeadingZero(m[2]) {
+			width = len(m[1])
+			if len(m[2]) > width {
+				width = len(m[2])
+			}
+		}
+		return stepSequence(from, to, m[3], func(i int) types.Value {
+			s := strconv.Itoa(i)
+			if width > 0 {
+				s = fmt.Sprintf("%0*d", width, i)
+			}
+			return types.String(s)
+		})
+	}
+	if m := runeSequence.FindStringSubmatch(text); m != nil {
+		from, _ := utf8.DecodeRuneInString(m[1])
+		to, _ := utf8.DecodeRuneInString(m[2])
+		return stepSequence(int(from), int(to), m[3], func(i int) types.Value {
+			return types.String(string(rune(i)))
+		})
+	}
+	return nil, false, nil
+}
+
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+func stepSequence(from, to int, stepText string, f func(int) types.Value) ([]types.Value, bool, error) {
+	var step uint64 = 1
+	if stepText != "" {
+		i, err := strconv.ParseInt(stepText, 10, 64)
+		if err != nil {
+			return nil, false, nil
+		}
+		// Take the absolute value without overflowing on the minimum int64.
+		if i < 0 {
+			step = uint64(-(i + 1)) + 1
+		} else if i > 0 {
+			step = uint64(i)
+		}
+	}
+	// The distance between the ends, computed in uint64 so that it does not
+	// overflow even when the ends are far apart.
+	var dist uint64
+	if from <= to {
+		dist = uint64(to) - uint64(from)
+	} else {
+		dist = uint64(from) - uint64(to)
+	}
+	if dist/step >= maxBraceSequenceLen {
+		return nil, false, fmt.Errorf(
+			"brace sequence has more than %d elements", maxBraceSequenceLen)
+	}
+	n := int(dist/step) + 1
+	seq := make([]types.Value, n)
+	for k := 0; k < n; k++ {
+		offset := uint64(k) * step
+		if from <= to {
+			seq[k] = f(int(uint64(from) + offset))
+		} else {
+			seq[k] = f(int(uint64(from) - offset))
+		}
+	}
+	return seq, true, nil
+}
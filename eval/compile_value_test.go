@@ -1,9 +1,11 @@
 package eval
 
 import (
+	"os"
 	"testing"
 
 	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
 )
 
 var valueTests = []Test{
@@ -11,6 +13,21 @@ var valueTests = []Test{
 	// -----------
 	{"put {fi,elvi}sh{1.0,1.1}",
 		want{out: strs("fish1.0", "fish1.1", "elvish1.0", "elvish1.1")}},
+	// Sequences
+	{"put {1..4}", want{out: strs("1", "2", "3", "4")}},
+	{"put {3..-1}", want{out: strs("3", "2", "1", "0", "-1")}},
+	{"put {1..10..3}", want{out: strs("1", "4", "7", "10")}},
+	{"put {10..1..-4}", want{out: strs("10", "6", "2")}},
+	{"put {08..11}", want{out: strs("08", "09", "10", "11")}},
+	{"put {a..e..2}", want{out: strs("a", "c", "e")}},
+	{"put x{c..a}", want{out: strs("xc", "xb", "xa")}},
+	{"put {'1..3'} {1..3,x}", want{out: strs("1..3", "1..3", "x")}},
+	{"put {9223372036854775806..9223372036854775807}",
+		want{out: strs("9223372036854775806", "9223372036854775807")}},
+	{"put {-9223372036854775808..9223372036854775807..-9223372036854775808}",
+		want{out: strs("-9223372036854775808", "0")}},
+	// Sequences that are too long are compilation errors.
+	{"eval 'put {1..1000000}'", want{err: errAny}},
 
 	// List, Map and Indexing
 	// ----------------------
@@ -57,6 +74,15 @@ var valueTests = []Test{
 	{"put ?[range:a-c]*", want{out: strs(getFilesWithPrefix("a", "b", "c")...)}},
 	{"put ?[range:a~c]*", want{out: strs(getFilesWithPrefix("a", "b")...)}},
 	{"put *[range:a-z]", want{out: strs("bar", "dir", "foo", "ipsum", "lorem")}},
+	{"put ?[class:a-bf]*", want{out: strs(getFilesWithPrefix("a", "b", "f")...)}},
+	{"put ?[class:!a-c]*", want{out: strs(getFilesWithPrefix("d", "f", "i", "l")...)}},
+	{"put ?[class:]", want{err: errAny}},
+	// Negated modifiers exclude runes regardless of other modifiers
+	{"put ?[!set:abc]*", want{out: strs(getFilesWithPrefix("d", "f", "i", "l")...)}},
+	{"put ?[range:a-c][!set:c]*", want{out: strs(getFilesWithPrefix("a", "b")...)}},
+	{"put *[!digit][nomatch-ok][range:a-z]", want{out: strs("bar", "dir", "foo", "ipsum", "lorem")}},
+	{"put *[!bad]", want{err: errAny}},
+	{"put *[follow-symlinks][range:a-z]", want{out: strs("bar", "dir", "foo", "ipsum", "lorem")}},
 
 	// Exclusion
 	{"put *[but:foo but:lorem]", want{out: strs(getFilesBut("foo", "lorem")...)}},
@@ -103,3 +129,18 @@ var valueTests = []Test{
 func TestValue(t *testing.T) {
 	runTests(t, valueTests)
 }
+
+func TestGlobNatural(t *testing.T) {
+	util.InTempDir(func(string) {
+		for _, name := range []string{"f10", "f2", "f1", "d10/a", "d9/a", "d9-1/a"} {
+			if err := os.MkdirAll(name, 0700); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runTests(t, []Test{
+			NewTest("put f*").WantOutStrings("f1", "f10", "f2"),
+			NewTest("put f*[natural]").WantOutStrings("f1", "f2", "f10"),
+			NewTest("put d*[natural]/a").WantOutStrings("d9/a", "d9-1/a", "d10/a"),
+		})
+	})
+}
@@ -23,10 +23,13 @@ type compiler struct {
 	begin, end int
 	// Information about the source.
 	srcMeta *Source
+	// Ops being compiled, innermost last. Only used when recording the op
+	// tree.
+	opStack []*OpNode
 }
 
 func compile(b, g staticNs, n *parse.Chunk, src *Source) (op Op, err error) {
-	cp := &compiler{b, []staticNs{g}, make(staticNs), 0, 0, src}
+	cp := &compiler{b, []staticNs{g}, make(staticNs), 0, 0, src, nil}
 	defer util.Catch(&err)
 	return cp.chunkOp(n), nil
 }
@@ -66,9 +69,9 @@ func (cp *compiler) registerVariableGetQname(qname string) bool {
 
 func (cp *compiler) registerVariableGet(ns, name string) bool {
 	switch ns {
-	case "", "local", "up":
+	case "", "local", "up", "builtin":
 		// Handled below
-	case "e", "E", "shared":
+	case "e", "external", "E", "shared":
 		return true
 	default:
 		return cp.registerModAccess(ns)
@@ -138,7 +141,7 @@ func (cp *compiler) registerVariableSet(ns, name string) bool {
 		// New name. Register on this scope!
 		cp.thisScope().set(name)
 		return true
-	case "e", "E", "shared":
+	case "e", "external", "E", "shared":
 		// Special namespaces, do nothing
 		return true
 	default:
@@ -2,7 +2,9 @@ package eval
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
 	"strconv"
 	"unicode/utf8"
 
@@ -11,36 +13,166 @@ import (
 
 // Conversion between Go value and Value.
 
-func toFloat(arg types.Value) (float64, error) {
-	if _, ok := arg.(types.String); !ok {
-		return 0, fmt.Errorf("must be string")
+// Numbers.
+//
+// Numbers are strings, float64s or rats. All the conversions of values to
+// numbers go through parseFloat and parseInt, which accept strings that can be
+// parsed as numbers, including integers in hex or octal notation and "inf" and
+// "nan", as well as float64s and rats. Integers are also accepted as float64
+// and any number with an integral value as int.
+//
+// Arithmetic builtins output float64s when any of their arguments is one, and
+// strings otherwise, so that float64s are kept through a computation while
+// strings stay strings.
+//
+// When $strict-numbers is true, numeric builtins convert their arguments
+// strictly: strings must be numbers in plain decimal notation, like "-12" or
+// "1.5e3", and int arguments must be integers written as such, like "3" but
+// not "3.0". Float64s and rats are still accepted, as long as they are
+// integers where ints are needed.
+//
+// This also applies to int and float64 arguments and options of other builtins,
+// when they are scanned with the ScanArgs, ScanArgsVariadic and ScanOpts
+// methods of Frame.
+
+var (
+	strictFloatPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+	strictIntPattern   = regexp.MustCompile(`^-?[0-9]+$`)
+)
+
+func errNotNumber(v types.Value) error {
+	return fmt.Errorf("need number, got %s", v.Repr(types.NoPretty))
+}
+
+func errNotInteger(v types.Value) error {
+	return fmt.Errorf("need integer, got %s", v.Repr(types.NoPretty))
+}
+
+func parseFloat(arg types.Value, strict bool) (float64, error) {
+	switch arg := arg.(type) {
+	case types.Float64:
+		return float64(arg), nil
+	case types.Rat:
+		return arg.Float64(), nil
+	case types.String:
+		s := string(arg)
+		if strict && !strictFloatPattern.MatchString(s) {
+			return 0, errNotNumber(arg)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return float64(i), nil
+		}
 	}
-	s := string(arg.(types.String))
-	num, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		num, err2 := strconv.ParseInt(s, 0, 64)
-		if err2 != nil {
-			return 0, err
+	return 0, errNotNumber(arg)
+}
+
+func parseInt(arg types.Value, strict bool) (int, error) {
+	switch arg := arg.(type) {
+	case types.Float64:
+		if i, ok := floatToInt(float64(arg)); ok {
+			return i, nil
+		}
+	case types.Rat:
+		if i, ok := arg.Int(); ok {
+			return i, nil
+		}
+	case types.String:
+		s := string(arg)
+		if strict && !strictIntPattern.MatchString(s) {
+			return 0, errNotInteger(arg)
+		}
+		if i, err := strconv.ParseInt(s, 0, 0); err == nil {
+			return int(i), nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errNotNumber(arg)
 		}
-		return float64(num), nil
+		if i, ok := floatToInt(f); ok {
+			return i, nil
+		}
+	default:
+		return 0, errNotNumber(arg)
+	}
+	return 0, errNotInteger(arg)
+}
+
+// floatToInt returns the value of f as an int, and whether it is an integer
+// that fits in one.
+func floatToInt(f float64) (int, bool) {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int(f), true
 	}
-	return num, nil
+	return 0, false
+}
+
+// toFloat converts a value to float64, without $strict-numbers.
+func toFloat(arg types.Value) (float64, error) {
+	return parseFloat(arg, false)
 }
 
 func floatToString(f float64) types.String {
-	return types.String(strconv.FormatFloat(f, 'g', -1, 64))
+	return types.String(types.Float64(f).String())
 }
 
-func toInt(arg types.Value) (int, error) {
-	arg, ok := arg.(types.String)
-	if !ok {
-		return 0, fmt.Errorf("must be string")
+// hasFloat64 returns whether any of the values is a float64.
+func hasFloat64(vs []types.Value) bool {
+	for _, v := range vs {
+		if _, ok := v.(types.Float64); ok {
+			return true
+		}
 	}
-	num, err := strconv.ParseInt(string(arg.(types.String)), 0, 0)
-	if err != nil {
-		return 0, err
+	return false
+}
+
+// numResult makes the output of an arithmetic builtin: a float64 if any of
+// its arguments is one, or a string otherwise.
+func numResult(args []types.Value, f float64) types.Value {
+	if hasFloat64(args) {
+		return types.Float64(f)
 	}
-	return int(num), nil
+	return floatToString(f)
+}
+
+// toInt converts a value to int, without $strict-numbers.
+func toInt(arg types.Value) (int, error) {
+	return parseInt(arg, false)
+}
+
+// floatArg converts an argument of a numeric builtin to float64, honoring
+// $strict-numbers.
+func (ec *Frame) floatArg(arg types.Value) float64 {
+	f, err := parseFloat(arg, ec.strictNumbers())
+	maybeThrow(err)
+	return f
+}
+
+// floatArgs is like floatArg, but converts all the arguments.
+func (ec *Frame) floatArgs(args []types.Value) []float64 {
+	strict := ec.strictNumbers()
+	fs := make([]float64, len(args))
+	for i, arg := range args {
+		f, err := parseFloat(arg, strict)
+		maybeThrow(err)
+		fs[i] = f
+	}
+	return fs
+}
+
+// intArg converts an argument of a numeric builtin to int, honoring
+// $strict-numbers.
+func (ec *Frame) intArg(arg types.Value) int {
+	i, err := parseInt(arg, ec.strictNumbers())
+	maybeThrow(err)
+	return i
+}
+
+// strictNumbers returns the value of $strict-numbers.
+func (ev *Evaler) strictNumbers() bool {
+	return types.ToBool(ev.Builtin["strict-numbers"].Get())
 }
 
 func toRune(arg types.Value) (rune, error) {
@@ -60,8 +192,8 @@ func toRune(arg types.Value) (rune, error) {
 }
 
 // scanValueToGo converts Value to Go data, depending on the type of the
-// destination.
-func scanValueToGo(src types.Value, dstPtr interface{}) {
+// destination. Numbers are converted strictly if strict is true.
+func scanValueToGo(src types.Value, dstPtr interface{}, strict bool) {
 	switch dstPtr := dstPtr.(type) {
 	case *string:
 		s, ok := src.(types.String)
@@ -70,11 +202,11 @@ func scanValueToGo(src types.Value, dstPtr interface{}) {
 		}
 		*dstPtr = string(s)
 	case *int:
-		i, err := toInt(src)
+		i, err := parseInt(src, strict)
 		maybeThrow(err)
 		*dstPtr = i
 	case *float64:
-		f, err := toFloat(src)
+		f, err := parseFloat(src, strict)
 		maybeThrow(err)
 		*dstPtr = f
 	default:
@@ -34,6 +34,7 @@ func Ns(daemon *daemon.Client, spawner *daemonp.Daemon) eval.Ns {
 		if spawner == nil {
 			util.Throw(errDontKnowHowToSpawnDaemon)
 		}
+		ec.SyncExports()
 		err := spawner.Spawn()
 		if err != nil {
 			util.Throw(err)
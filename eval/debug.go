@@ -0,0 +1,84 @@
+package eval
+
+import (
+	"errors"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/parse"
+)
+
+// Support for inspecting the scope in which an exception was raised.
+
+// Values of $debug-on-exception. The empty string turns debugging off.
+const (
+	DebugReadOnly  = "read-only"
+	DebugReadWrite = "read-write"
+)
+
+var errBadDebugMode = errors.New(`should be "", "read-only" or "read-write"`)
+
+func newDebugOnExceptionVariable() vartypes.Variable {
+	return vartypes.NewValidatedPtr(types.String(""), func(v types.Value) error {
+		switch v {
+		case types.String(""), types.String(DebugReadOnly), types.String(DebugReadWrite):
+			return nil
+		}
+		return errBadDebugMode
+	})
+}
+
+// DebugOnException returns the value of $debug-on-exception, which determines
+// whether uncaught exceptions in interactive mode open a debug REPL, and
+// whether the REPL can modify variables.
+func (ev *Evaler) DebugOnException() string {
+	return types.ToString(ev.Builtin["debug-on-exception"].Get())
+}
+
+// DebugScope keeps the local and upvalue namespaces of the frame in which an
+// exception was raised, which outlive the frame itself.
+type DebugScope struct {
+	local, up Ns
+}
+
+// DebugNs returns a namespace for inspecting the variables of the innermost
+// function in which the exception was raised, including the global variables
+// that it does not shadow. If writable is false, the variables are read-only
+// and keep the values they had when DebugNs was called. Variables defined in
+// the returned namespace do not affect the original scope. It returns nil if
+// the scope is not known, or if the exception is a control flow.
+func (exc *Exception) DebugNs(ev *Evaler, writable bool) Ns {
+	if exc.Scope == nil {
+		return nil
+	}
+	if _, ok := exc.Cause.(Flow); ok {
+		return nil
+	}
+	ns := make(Ns)
+	for _, scope := range []Ns{ev.Global, exc.Scope.up, exc.Scope.local} {
+		for name, variable := range scope {
+			if !writable {
+				variable = vartypes.NewRo(variable.Get())
+			}
+			ns[name] = variable
+		}
+	}
+	return ns
+}
+
+// SourceTextInNs evaluates a chunk of elvish source with ns in place of the
+// global namespace.
+func (ev *Evaler) SourceTextInNs(src *Source, ns Ns) error {
+	n, err := parse.Parse(src.name, src.code)
+	if err != nil {
+		return err
+	}
+	op, err := compile(ev.Builtin.static(), ns.static(), n, src)
+	if err != nil {
+		return err
+	}
+	return ev.Eval(Op{func(ec *Frame) {
+		ec.local = ns
+		op.Exec(ec)
+	}, op.Begin, op.End}, src)
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestDebugNs(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+
+	err := ev.SourceText(NewInteractiveSource("fail bad"))
+	if err.(*Exception).Scope != nil {
+		t.Errorf("scope recorded without $debug-on-exception")
+	}
+
+	ev.Builtin["debug-on-exception"].Set(types.String(DebugReadOnly))
+	err = ev.SourceText(NewInteractiveSource(
+		"g = global; fn f [x]{ y = local; fail bad }; f arg"))
+	exc, ok := err.(*Exception)
+	if !ok {
+		t.Fatalf("got error %v, want exception", err)
+	}
+
+	ns := exc.DebugNs(ev, false)
+	for name, want := range map[string]string{"g": "global", "x": "arg", "y": "local"} {
+		if ns[name] == nil {
+			t.Errorf("variable %s missing in debug namespace", name)
+		} else if got := ns[name].Get(); got != types.String(want) {
+			t.Errorf("$%s is %v, want %s", name, got, want)
+		}
+	}
+	if ev.SourceTextInNs(NewInteractiveSource("y = changed"), ns) == nil {
+		t.Errorf("read-only debug namespace allows assignment")
+	}
+
+	ns = exc.DebugNs(ev, true)
+	if err := ev.SourceTextInNs(NewInteractiveSource("g = changed; z = new"), ns); err != nil {
+		t.Errorf("SourceTextInNs -> %v", err)
+	}
+	if got := ev.Global["g"].Get(); got != types.String("changed") {
+		t.Errorf("$g is %v after assignment in read-write debug namespace", got)
+	}
+	if ev.Global["z"] != nil {
+		t.Errorf("new variable in debug namespace leaked to global")
+	}
+
+	err = ev.SourceText(NewInteractiveSource("return"))
+	if err.(*Exception).DebugNs(ev, false) != nil {
+		t.Errorf("DebugNs of control flow is not nil")
+	}
+}
+
+func TestDebugOnException(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("put $debug-on-exception").WantOutStrings(""),
+		NewTest("debug-on-exception = read-write; put $debug-on-exception").
+			WantOutStrings("read-write"),
+		NewTest("debug-on-exception = bad").WantErr(errAny),
+	})
+}
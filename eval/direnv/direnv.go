@@ -0,0 +1,382 @@
+// Package direnv implements the direnv: module, which loads per-directory
+// environments from .elvish-env files.
+//
+// Once enabled with direnv:enable, the module looks for the nearest
+// .elvish-env file in the current directory and its ancestors every time the
+// directory changes. If the file has been allowed with direnv:allow and has not
+// changed since, it is evaluated with a fresh Evaler, which shares nothing with
+// the user's namespaces. Changes the file makes to environment variables are
+// reverted when leaving the directory tree that it belongs to.
+//
+// Environment files may only change environment variables. They can use
+// special forms other than fn and use, and the builtin commands in
+// allowedBuiltins, none of which has other side effects. They cannot run
+// external commands, redirect to or from files, or change the directory. A file
+// that does any of these is rejected as a whole before it is evaluated.
+package direnv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+// EnvFileName is the name of per-directory environment files.
+const EnvFileName = ".elvish-env"
+
+var errStoreOffline = errors.New("store offline")
+
+// Special forms that an environment file can use.
+var allowedSpecials = map[string]bool{
+	"del": true, "and": true, "or": true, "if": true, "cond": true,
+	"while": true, "for": true, "try": true,
+}
+
+// Builtin functions that an environment file can use. They must neither have
+// side effects other than changing environment variables nor accept command
+// names, which could resolve to external commands.
+var allowedBuiltins = map[string]bool{
+	"put": true, "print": true, "echo": true, "pprint": true, "repr": true,
+	"nop": true, "fail": true, "not": true, "bool": true, "is": true,
+	"eq": true, "not-eq": true, "kind-of": true, "to-string": true,
+	"float64": true, "count": true, "keys": true, "has-key": true,
+	"has-value": true, "has-prefix": true, "has-suffix": true, "joins": true,
+	"splits": true, "replaces": true, "ord": true, "base": true,
+	"wcswidth": true, "range": true, "repeat": true, "explode": true,
+	"all": true, "take": true, "drop": true, "each": true, "assoc": true,
+	"dissoc": true, "order": true, "sum": true, "min": true, "max": true,
+	"+": true, "-": true, "*": true, "/": true, "%": true, "^": true,
+	"<": true, "<=": true, "==": true, "!=": true, ">": true, ">=": true,
+	"<s": true, "<=s": true, "==s": true, "!=s": true, ">s": true, ">=s": true,
+	"path-abs": true, "path-base": true, "path-clean": true, "path-dir": true,
+	"path-ext": true, "tilde-abbr": true, "-is-dir": true, "load-env": true,
+}
+
+// loader keeps track of the environment file that is currently loaded.
+type loader struct {
+	hook eval.Fn
+	// The path of the loaded environment file, or "" if there is none.
+	active string
+	// The values that environment variables changed by the loaded file had
+	// before it was loaded; nil means unset.
+	saved map[string]*string
+}
+
+// Ns makes the direnv: namespace.
+func Ns() eval.Ns {
+	l := &loader{}
+	l.hook = &eval.BuiltinFn{"direnv:-hook", l.callHook}
+	ns := eval.Ns{
+		"active": vartypes.NewRoCallback(func() types.Value {
+			return types.String(l.active)
+		}),
+	}
+	eval.AddBuiltinFns(ns,
+		&eval.BuiltinFn{"direnv:enable", l.enable},
+		&eval.BuiltinFn{"direnv:disable", l.disable},
+		&eval.BuiltinFn{"direnv:allow", l.allow},
+		&eval.BuiltinFn{"direnv:deny", l.deny},
+	)
+	return ns
+}
+
+// enable adds the hook to $after-chdir and loads the environment of the
+// current directory. It does nothing if the hook has already been added.
+func (l *loader) enable(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	hooks := ec.Builtin["after-chdir"]
+	if !l.hasHook(hooks.Get().(types.List)) {
+		err := hooks.Set(hooks.Get().(types.List).Cons(l.hook))
+		maybeThrow(err)
+	}
+	l.update(ec)
+}
+
+// disable removes the hook from $after-chdir and unloads the current
+// environment.
+func (l *loader) disable(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	hooks := ec.Builtin["after-chdir"]
+	var kept []types.Value
+	hooks.Get().(types.List).Iterate(func(v types.Value) bool {
+		if v != l.hook {
+			kept = append(kept, v)
+		}
+		return true
+	})
+	maybeThrow(hooks.Set(types.MakeList(kept...)))
+	l.unload()
+}
+
+// allow records the hash of the environment file in a directory, the current
+// directory by default, in the store, and reloads the environment.
+func (l *loader) allow(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoOpt(opts)
+	path := envFileArg(args)
+	if ec.DaemonClient == nil {
+		util.Throw(errStoreOffline)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	maybeThrow(err)
+	maybeThrow(ec.DaemonClient.AllowEnvFile(path, hash(content)))
+	if path == l.active {
+		l.unload()
+	}
+	if l.hasHook(ec.Builtin["after-chdir"].Get().(types.List)) {
+		l.update(ec)
+	}
+}
+
+// deny removes the environment file in a directory, the current directory by
+// default, from the allowed ones, and unloads it if it is loaded.
+func (l *loader) deny(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoOpt(opts)
+	path := envFileArg(args)
+	if ec.DaemonClient == nil {
+		util.Throw(errStoreOffline)
+	}
+
+	maybeThrow(ec.DaemonClient.DisallowEnvFile(path))
+	if path == l.active {
+		l.unload()
+	}
+}
+
+func (l *loader) callHook(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var oldPwd string
+	ec.ScanArgs(args, &oldPwd)
+	eval.TakeNoOpt(opts)
+	l.update(ec)
+}
+
+func (l *loader) hasHook(hooks types.List) bool {
+	found := false
+	hooks.Iterate(func(v types.Value) bool {
+		found = v == l.hook
+		return !found
+	})
+	return found
+}
+
+// update makes the loaded environment file that of the current directory.
+// Problems are reported on the standard error of ec instead of being thrown,
+// so that they do not break the changing of directories.
+func (l *loader) update(ec *eval.Frame) {
+	stderr := ec.ErrorFile()
+	pwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(stderr, "direnv:", err)
+		return
+	}
+	path := findEnvFile(pwd)
+	if path == l.active {
+		return
+	}
+	l.unload()
+	if path == "" {
+		return
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, "direnv:", err)
+		return
+	}
+	if ec.DaemonClient == nil {
+		fmt.Fprintf(stderr, "direnv: not loading %s: %v\n", path, errStoreOffline)
+		return
+	}
+	allowed, err := ec.DaemonClient.EnvFileHash(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "direnv: not loading %s: %v\n", path, err)
+		return
+	}
+	if allowed != hash(content) {
+		fmt.Fprintf(stderr,
+			"direnv: %s is not allowed; review it and run direnv:allow to load it\n", path)
+		return
+	}
+	l.load(path, string(content), stderr)
+}
+
+// load evaluates an environment file and records the changes it makes to
+// environment variables.
+func (l *loader) load(path, content string, stderr io.Writer) {
+	before := environ()
+
+	ev := newEnvEvaler()
+	defer ev.Close()
+	n, err := parse.Parse(path, content)
+	if err == nil {
+		err = checkEnvFile(n)
+	}
+	if err == nil {
+		err = ev.SourceText(eval.NewScriptSource(EnvFileName, path, content))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "direnv: error in %s: %v\n", path, err)
+	}
+
+	after := environ()
+	saved := make(map[string]*string)
+	for name, value := range before {
+		if newValue, ok := after[name]; !ok || newValue != value {
+			value := value
+			saved[name] = &value
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			saved[name] = nil
+		}
+	}
+	l.active, l.saved = path, saved
+}
+
+// newEnvEvaler creates an Evaler whose builtin namespace only has the allowed
+// builtin functions.
+func newEnvEvaler() *eval.Evaler {
+	ev := eval.NewEvaler()
+	for name := range ev.Builtin {
+		switch {
+		case strings.HasSuffix(name, eval.FnSuffix):
+			if !allowedBuiltins[strings.TrimSuffix(name, eval.FnSuffix)] {
+				delete(ev.Builtin, name)
+			}
+		case strings.HasSuffix(name, eval.NsSuffix):
+			delete(ev.Builtin, name)
+		}
+	}
+	// Assigning $pwd changes the directory.
+	ev.Builtin["pwd"] = vartypes.NewRo(ev.Builtin["pwd"].Get())
+	return ev
+}
+
+// checkEnvFile returns an error if the environment file parsed into n uses a
+// command, variable or redirection that is not allowed.
+func checkEnvFile(n parse.Node) error {
+	switch n := n.(type) {
+	case *parse.Form:
+		if n.Head != nil {
+			if name, ok := literal(n.Head); ok {
+				name = strings.TrimPrefix(name, "builtin:")
+				if !allowedSpecials[name] && !allowedBuiltins[name] {
+					return fmt.Errorf("command %s is not allowed", parse.Quote(name))
+				}
+			}
+		}
+	case *parse.Redir:
+		switch n.Mode {
+		case parse.Capture, parse.Heredoc, parse.HereString:
+		default:
+			if !n.RightIsFd {
+				return errors.New("redirections to or from files are not allowed")
+			}
+		}
+	case *parse.Primary:
+		if n.Type == parse.Variable {
+			_, ns, _ := eval.ParseVariable(n.Value)
+			if ns == "e" || ns == "external" {
+				return fmt.Errorf("variable $%s is not allowed", n.Value)
+			}
+		}
+	}
+	for _, child := range n.Children() {
+		if err := checkEnvFile(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// literal returns the string that a compound expression consists of, if it is
+// a single string literal.
+func literal(n *parse.Compound) (string, bool) {
+	if len(n.Indexings) != 1 || len(n.Indexings[0].Indicies) != 0 {
+		return "", false
+	}
+	switch pn := n.Indexings[0].Head; pn.Type {
+	case parse.Bareword, parse.SingleQuoted, parse.DoubleQuoted:
+		return pn.Value, true
+	}
+	return "", false
+}
+
+// unload reverts the changes that the loaded environment file made to
+// environment variables.
+func (l *loader) unload() {
+	for name, value := range l.saved {
+		if value == nil {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, *value)
+		}
+	}
+	l.active, l.saved = "", nil
+}
+
+// envFileArg returns the absolute path of the environment file in the
+// directory given in args, or the current directory if args is empty.
+func envFileArg(args []types.Value) string {
+	dir := "."
+	if len(args) > 0 {
+		eval.ScanArgs(args, &dir)
+	}
+	dir, err := filepath.Abs(dir)
+	maybeThrow(err)
+	return filepath.Join(dir, EnvFileName)
+}
+
+// findEnvFile returns the path of the environment file in dir or its nearest
+// ancestor that has one, or "" if there is none.
+func findEnvFile(dir string) string {
+	for {
+		path := filepath.Join(dir, EnvFileName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func environ() map[string]string {
+	m := make(map[string]string)
+	for _, s := range os.Environ() {
+		if i := strings.IndexByte(s, '='); i > 0 {
+			m[s[:i]] = s[i+1:]
+		}
+	}
+	return m
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func maybeThrow(err error) {
+	if err != nil {
+		util.Throw(err)
+	}
+}
@@ -0,0 +1,168 @@
+package direnv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+func TestFindEnvFile(t *testing.T) {
+	util.InTempDir(func(string) {
+		dir, err := os.Getwd()
+		if err != nil {
+			panic(err)
+		}
+		mustWriteFile(filepath.Join(dir, "a", EnvFileName), "")
+		mustMkdirAll(filepath.Join(dir, "a", "b"))
+		mustMkdirAll(filepath.Join(dir, "c", EnvFileName))
+
+		for _, test := range []struct{ dir, want string }{
+			{"a", filepath.Join(dir, "a", EnvFileName)},
+			{"a/b", filepath.Join(dir, "a", EnvFileName)},
+			// Directories with the name of environment files are ignored.
+			{"c", ""},
+			{".", ""},
+		} {
+			got := findEnvFile(filepath.Join(dir, test.dir))
+			if got != test.want {
+				t.Errorf("findEnvFile(%q) -> %q, want %q", test.dir, got, test.want)
+			}
+		}
+	})
+}
+
+func TestLoadAndUnload(t *testing.T) {
+	os.Setenv("DIRENV_TEST_KEPT", "kept")
+	os.Setenv("DIRENV_TEST_CHANGED", "old")
+	os.Setenv("DIRENV_TEST_REMOVED", "removed")
+	os.Unsetenv("DIRENV_TEST_ADDED")
+	defer os.Unsetenv("DIRENV_TEST_KEPT")
+	defer os.Unsetenv("DIRENV_TEST_CHANGED")
+	defer os.Unsetenv("DIRENV_TEST_REMOVED")
+	defer os.Unsetenv("DIRENV_TEST_ADDED")
+
+	l := &loader{}
+	l.load("/env", `
+		E:DIRENV_TEST_CHANGED = new
+		E:DIRENV_TEST_ADDED = added
+		del E:DIRENV_TEST_REMOVED`, ioutil.Discard)
+
+	if l.active != "/env" {
+		t.Errorf("active = %q, want /env", l.active)
+	}
+	wantEnv(t, "DIRENV_TEST_KEPT", "kept", true)
+	wantEnv(t, "DIRENV_TEST_CHANGED", "new", true)
+	wantEnv(t, "DIRENV_TEST_ADDED", "added", true)
+	wantEnv(t, "DIRENV_TEST_REMOVED", "", false)
+
+	l.unload()
+	if l.active != "" {
+		t.Errorf("active = %q after unload, want empty", l.active)
+	}
+	wantEnv(t, "DIRENV_TEST_KEPT", "kept", true)
+	wantEnv(t, "DIRENV_TEST_CHANGED", "old", true)
+	wantEnv(t, "DIRENV_TEST_ADDED", "", false)
+	wantEnv(t, "DIRENV_TEST_REMOVED", "removed", true)
+}
+
+func TestLoad_CannotChangeDirectory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	for _, content := range []string{"cd /", "pwd = /"} {
+		(&loader{}).load("/env", content, ioutil.Discard)
+		if newWd, _ := os.Getwd(); newWd != wd {
+			t.Errorf("environment file changed directory to %s", newWd)
+		}
+	}
+}
+
+func TestCheckEnvFile(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		ok      bool
+	}{
+		{"E:A = a; E:B = (joins : [$E:A b])", true},
+		{"if (has-prefix $E:A a) { del E:A } else { E:A = (+ 1 2) }", true},
+		{"each [x]{ E:X = $x } [a]; echo a >&2", true},
+		{"E:A=a touch f", false},
+		{"touch f", false},
+		{"'touch' f", false},
+		{"e:touch f", false},
+		{"builtin:exec touch f", false},
+		{"each $e:touch~ [f]", false},
+		{"put [x]{ touch $x }", false},
+		{"echo a > f", false},
+		{"use re", false},
+		{"fn f { }", false},
+		{"cd /", false},
+		{"eval 'touch f'", false},
+	} {
+		n, err := parse.Parse("[test]", test.content)
+		if err != nil {
+			panic(err)
+		}
+		err = checkEnvFile(n)
+		if (err == nil) != test.ok {
+			t.Errorf("checkEnvFile(%q) -> %v, want ok = %v", test.content, err, test.ok)
+		}
+	}
+}
+
+func TestLoad_RejectsWholeFile(t *testing.T) {
+	os.Unsetenv("DIRENV_TEST_REJECTED")
+	defer os.Unsetenv("DIRENV_TEST_REJECTED")
+	util.InTempDir(func(string) {
+		(&loader{}).load("/env", "E:DIRENV_TEST_REJECTED = x; touch f", ioutil.Discard)
+		wantEnv(t, "DIRENV_TEST_REJECTED", "", false)
+		if _, err := os.Stat("f"); err == nil {
+			t.Errorf("environment file ran an external command")
+		}
+	})
+}
+
+func TestDirenv(t *testing.T) {
+	eval.RunTests(t, []eval.Test{
+		eval.NewTest(`direnv:enable; direnv:enable; count $after-chdir`).
+			WantOutStrings("1"),
+		eval.NewTest(`after-chdir = [{ }]; direnv:enable; direnv:disable; count $after-chdir`).
+			WantOutStrings("1"),
+		eval.NewTest(`put $direnv:active`).WantOutStrings(""),
+		// Without a daemon, files cannot be allowed or denied.
+		eval.NewTest(`direnv:allow`).WantAnyErr(),
+		eval.NewTest(`direnv:deny`).WantAnyErr(),
+	}, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["direnv"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
+
+func wantEnv(t *testing.T, name, value string, set bool) {
+	got, ok := os.LookupEnv(name)
+	if got != value || ok != set {
+		t.Errorf("$E:%s = %q (set: %v), want %q (set: %v)", name, got, ok, value, set)
+	}
+}
+
+func mustWriteFile(path, content string) {
+	mustMkdirAll(filepath.Dir(path))
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustMkdirAll(path string) {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -8,4 +8,15 @@ type Editor interface {
 	Active() bool
 	ActiveMutex() *sync.Mutex
 	Notify(string, ...interface{})
+	// Prompt reads a line with a custom prompt, for asking the user for a
+	// value. If complete is not nil, it is called with the text before the
+	// cursor to find candidates that can replace the text.
+	Prompt(prompt string, complete func(seed string) []string) (string, error)
+	// CompleteFilename returns the paths that complete seed as a file name,
+	// found in the same way as when completing arguments.
+	CompleteFilename(seed string) []string
+	// RestoreTerminal puts the terminal back in the state it was in before the
+	// editor became active, if it is active. It is used before Elvish gets
+	// replaced by another program.
+	RestoreTerminal() error
 }
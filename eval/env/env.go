@@ -0,0 +1,145 @@
+// Package env implements the env: module for working with the environment as
+// a whole.
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+// Ns returns the namespace of the env: module.
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"all", all},
+	{"set", set},
+	{"unset", unset},
+	{"with", with},
+}
+
+// all outputs a map of all environment variables.
+func all(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	m := make(map[types.Value]types.Value)
+	for _, s := range os.Environ() {
+		if i := strings.IndexByte(s, '='); i > 0 {
+			m[types.String(s[:i])] = types.String(s[i+1:])
+		}
+	}
+	ec.OutputChan() <- types.MakeMap(m)
+}
+
+// set sets environment variables from a map. All the names are checked before
+// any variable is set, and variables already set are restored if setting one
+// of them fails.
+func set(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var m types.MapLike
+	ec.ScanArgs(args, &m)
+	eval.TakeNoOpt(opts)
+
+	vars := scanVars(m)
+	saved := saveVars(vars)
+	for name, value := range vars {
+		if err := os.Setenv(name, value); err != nil {
+			restoreVars(saved)
+			maybeThrow(err)
+		}
+	}
+}
+
+// unset unsets the given environment variables.
+func unset(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var names []types.String
+	ec.ScanArgsVariadic(args, &names)
+	eval.TakeNoOpt(opts)
+
+	for _, name := range names {
+		maybeThrow(os.Unsetenv(string(name)))
+	}
+}
+
+// with sets the environment variables in a map, calls a function, and restores
+// the variables to their previous values or absence afterwards, even if the
+// function throws an exception. Since the environment is shared by the whole
+// process, the changes are also visible to code running concurrently.
+func with(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		m types.MapLike
+		f eval.Fn
+	)
+	ec.ScanArgs(args, &m, &f)
+	eval.TakeNoOpt(opts)
+
+	vars := scanVars(m)
+	saved := saveVars(vars)
+	defer restoreVars(saved)
+	for name, value := range vars {
+		maybeThrow(os.Setenv(name, value))
+	}
+	f.Call(ec, eval.NoArgs, eval.NoOpts)
+}
+
+// scanVars converts a map to environment variables, checking that the names
+// are valid.
+func scanVars(m types.MapLike) map[string]string {
+	vars := make(map[string]string)
+	m.IteratePair(func(k, v types.Value) bool {
+		name, ok := k.(types.String)
+		if !ok {
+			throwf("environment variable name should be string, got %s", k.Kind())
+		}
+		if !eval.ValidEnvName(string(name)) {
+			throwf("bad environment variable name %s", parse.Quote(string(name)))
+		}
+		vars[string(name)] = types.ToString(v)
+		return true
+	})
+	return vars
+}
+
+// savedVar is the value of an environment variable before it was changed.
+type savedVar struct {
+	value string
+	isSet bool
+}
+
+func saveVars(vars map[string]string) map[string]savedVar {
+	saved := make(map[string]savedVar)
+	for name := range vars {
+		value, isSet := os.LookupEnv(name)
+		saved[name] = savedVar{value, isSet}
+	}
+	return saved
+}
+
+func restoreVars(saved map[string]savedVar) {
+	for name, v := range saved {
+		if v.isSet {
+			os.Setenv(name, v.value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func throwf(format string, args ...interface{}) {
+	util.Throw(fmt.Errorf(format, args...))
+}
+
+func maybeThrow(err error) {
+	if err != nil {
+		util.Throw(err)
+	}
+}
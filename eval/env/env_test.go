@@ -0,0 +1,40 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`E:ENV_TEST_A=a; put (env:all)[ENV_TEST_A]`).WantOutStrings("a"),
+
+	eval.NewTest(`env:set [&ENV_TEST_A=x &ENV_TEST_B=y]; put $E:ENV_TEST_A $E:ENV_TEST_B`).
+		WantOutStrings("x", "y"),
+	eval.NewTest(`E:ENV_TEST_A=a; env:set [&ENV_TEST_A=x &'B=C'=y]`).WantAnyErr(),
+	eval.NewTest(`E:ENV_TEST_A=a; try { env:set [&ENV_TEST_A=x &'B=C'=y] } except _ { }; put $E:ENV_TEST_A`).
+		WantOutStrings("a"),
+
+	eval.NewTest(`env:set [&ENV_TEST_A=x]; env:unset ENV_TEST_A; has-key (env:all) ENV_TEST_A`).
+		WantOutBools(false),
+
+	eval.NewTest(`E:ENV_TEST_A=a; env:unset ENV_TEST_B
+	              env:with [&ENV_TEST_A=x &ENV_TEST_B=y] { put $E:ENV_TEST_A $E:ENV_TEST_B }
+	              put $E:ENV_TEST_A
+	              if (has-key (env:all) ENV_TEST_B) { put set } else { put unset }`).
+		WantOutStrings("x", "y", "a", "unset"),
+	eval.NewTest(`E:ENV_TEST_A=a; try { env:with [&ENV_TEST_A=x] { fail bad } } except _ { }; put $E:ENV_TEST_A`).
+		WantOutStrings("a"),
+}
+
+func TestEnv(t *testing.T) {
+	defer os.Unsetenv("ENV_TEST_A")
+	defer os.Unsetenv("ENV_TEST_B")
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["env"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
@@ -7,7 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
-	"strings"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"unicode/utf8"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/elves/elvish/eval/bundled"
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/glob"
 	"github.com/elves/elvish/parse"
 	"github.com/elves/elvish/sys"
 	"github.com/elves/elvish/util"
@@ -50,7 +52,10 @@ type Evaler struct {
 	bundled map[string]string
 	Editor  Editor
 	libDir  string
-	intCh   chan struct{}
+	*evalerState
+	// The file system that wildcards are expanded against, with the virtual
+	// file systems mounted on it.
+	Mounts *glob.Mounts
 }
 
 type evalerScopes struct {
@@ -58,6 +63,27 @@ type evalerScopes struct {
 	Builtin Ns
 }
 
+// evalerState keeps the state of an Evaler that is not in its namespaces. It
+// is shared by the Evaler used for staged evaluation.
+type evalerState struct {
+	// Results of the last pipeline with more than one form.
+	pipeStatus pipeStatus
+	// Measurements of the last instrumented pipeline.
+	pipeMetrics pipeMetrics
+	// Jobs that are stopped or in the background.
+	jobs jobTable
+	// Closed when an interrupt signal comes during the current evaluation.
+	intCh interruptChan
+	// Variables marked with export.
+	exports exportTable
+	// Handlers of events.
+	events eventBus
+	// Paths of external commands that have been found.
+	pathHash pathHash
+	// Serializes calls to $value-printer.
+	valuePrinterMutex sync.Mutex
+}
+
 // NewEvaler creates a new Evaler.
 func NewEvaler() *Evaler {
 	builtin := makeBuiltinNs()
@@ -72,12 +98,26 @@ func NewEvaler() *Evaler {
 		},
 		bundled: bundled.Get(),
 		Editor:  nil,
-		intCh:   nil,
+
+		evalerState: &evalerState{},
+		Mounts:      glob.NewMounts(glob.OSFS),
 	}
 
 	valueOutIndicator := defaultValueOutIndicator
-	ev.evalerPorts = newEvalerPorts(os.Stdin, os.Stdout, os.Stderr, &valueOutIndicator)
+	ev.evalerPorts = newEvalerPorts(os.Stdin, os.Stdout, os.Stderr, &valueOutIndicator, ev.formatValue)
 	builtin["value-out-indicator"] = vartypes.NewString(&valueOutIndicator)
+	builtin["value-printer"] = newValuePrinterVariable()
+	builtin["debug-on-exception"] = newDebugOnExceptionVariable()
+	builtin["pipestatus"] = ev.pipeStatus.variable()
+	builtin["value-buffer-size"] = newValueBufferSizeVariable()
+	builtin["pipeline-fail-fast"] = vartypes.NewValidatedPtr(types.Bool(false), vartypes.ShouldBeBool)
+	builtin["signal-hooks"] = newSignalHooksVariable()
+	builtin["pwd"] = PwdVariable{ev}
+	builtin["before-chdir"] = newChdirHooksVariable()
+	builtin["after-chdir"] = newChdirHooksVariable()
+	builtin["strict-numbers"] = vartypes.NewValidatedPtr(types.Bool(false), vartypes.ShouldBeBool)
+	builtin["pipemetrics"] = ev.pipeMetrics.variable()
+	builtin["pipeline-instrument"] = vartypes.NewValidatedPtr(types.Bool(false), vartypes.ShouldBeBool)
 
 	return ev
 }
@@ -90,8 +130,6 @@ func (ev *Evaler) Close() {
 // InstallDaemonClient installs a daemon client to the Evaler.
 func (ev *Evaler) InstallDaemonClient(client *daemon.Client) {
 	ev.DaemonClient = client
-	// XXX This is really brittle
-	ev.Builtin["pwd"] = PwdVariable{client}
 }
 
 // InstallModule installs a module to the Evaler so that it can be used with
@@ -121,7 +159,7 @@ func (ev *Evaler) SetLibDir(libDir string) {
 }
 
 func searchPaths() []string {
-	return strings.Split(os.Getenv("PATH"), ":")
+	return filepath.SplitList(os.Getenv("PATH"))
 }
 
 // growPorts makes the size of ec.ports at least n, adding nil's if necessary.
@@ -138,6 +176,7 @@ func (ec *Frame) growPorts(n int) {
 // diagnostic messages.
 func (ev *Evaler) eval(op Op, ports []*Port, src *Source) error {
 	ec := NewTopFrame(ev, src, ports)
+	defer ec.cleanups.run()
 	return ec.PEval(op)
 }
 
@@ -163,11 +202,15 @@ func (ev *Evaler) EvalWithPorts(ports []*Port, op Op, src *Source) error {
 	// itself back to the foreground later, we need to ignore TTOU now.
 	ignoreTTOU()
 	defer unignoreTTOU()
+	if ev.Editor != nil {
+		defer catchTSTP()()
+	}
 
 	stopSigGoroutine := make(chan struct{})
 	sigGoRoutineDone := make(chan struct{})
 	// Set up intCh.
-	ev.intCh = make(chan struct{})
+	intCh := make(chan struct{})
+	ev.intCh.set(intCh)
 	sigCh := make(chan os.Signal)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGQUIT)
 	go func() {
@@ -177,14 +220,14 @@ func (ev *Evaler) EvalWithPorts(ports []*Port, op Op, src *Source) error {
 			select {
 			case <-sigCh:
 				if !closedIntCh {
-					close(ev.intCh)
+					close(intCh)
 					closedIntCh = true
 				}
 			case <-stopSigGoroutine:
 				break loop
 			}
 		}
-		ev.intCh = nil
+		ev.intCh.set(nil)
 		signal.Stop(sigCh)
 		close(sigGoRoutineDone)
 	}()
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"os"
 	"reflect"
 	"strconv"
 	"syscall"
@@ -17,6 +18,25 @@ func TestBuiltinPid(t *testing.T) {
 	}
 }
 
+func TestBuiltinIdentity(t *testing.T) {
+	ns := makeBuiltinNs()
+	uid := strconv.Itoa(os.Getuid())
+	if got := types.ToString(ns["uid"].Get()); got != uid {
+		t.Errorf(`ev.builtin["uid"] = %v, want %v`, got, uid)
+	}
+	gid := strconv.Itoa(os.Getgid())
+	if got := types.ToString(ns["gid"].Get()); got != gid {
+		t.Errorf(`ev.builtin["gid"] = %v, want %v`, got, gid)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := types.ToString(ns["hostname"].Get()); got != hostname {
+		t.Errorf(`ev.builtin["hostname"] = %v, want %v`, got, hostname)
+	}
+}
+
 var miscEvalTests = []Test{
 	// Pseudo-namespaces local: and up:
 	{"x=lorem; []{local:x=ipsum; put $up:x $local:x}",
@@ -17,14 +17,17 @@ type evalerPorts struct {
 	relayeWait *sync.WaitGroup
 }
 
-func newEvalerPorts(stdin, stdout, stderr *os.File, prefix *string) evalerPorts {
+// newEvalerPorts creates the ports of an Evaler. Values written to the value
+// channels of stdout and stderr are formatted with format and written to the
+// files after *prefix.
+func newEvalerPorts(stdin, stdout, stderr *os.File, prefix *string, format func(types.Value) string) evalerPorts {
 	stdoutChan := make(chan types.Value, stdoutChanSize)
 	stderrChan := make(chan types.Value, stderrChanSize)
 
 	var relayerWait sync.WaitGroup
 	relayerWait.Add(2)
-	go relayChanToFile(stdoutChan, stdout, prefix, &relayerWait)
-	go relayChanToFile(stderrChan, stderr, prefix, &relayerWait)
+	go relayChanToFile(stdoutChan, stdout, prefix, format, &relayerWait)
+	go relayChanToFile(stderrChan, stderr, prefix, format, &relayerWait)
 
 	return evalerPorts{
 		[3]*Port{
@@ -36,10 +39,10 @@ func newEvalerPorts(stdin, stdout, stderr *os.File, prefix *string) evalerPorts
 	}
 }
 
-func relayChanToFile(ch <-chan types.Value, file *os.File, prefix *string, w *sync.WaitGroup) {
+func relayChanToFile(ch <-chan types.Value, file *os.File, prefix *string, format func(types.Value) string, w *sync.WaitGroup) {
 	for v := range ch {
 		file.WriteString(*prefix)
-		file.WriteString(v.Repr(initIndent))
+		file.WriteString(format(v))
 		file.WriteString("\n")
 	}
 	w.Done()
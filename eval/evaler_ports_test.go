@@ -17,7 +17,9 @@ func TestEvalerPorts(t *testing.T) {
 	defer stderrReader.Close()
 
 	prefix := "> "
-	ep := newEvalerPorts(DevNull, stdout, stderr, &prefix)
+	ep := newEvalerPorts(DevNull, stdout, stderr, &prefix, func(v types.Value) string {
+		return v.Repr(types.NoPretty)
+	})
 	ep.ports[1].Chan <- types.String("x")
 	ep.ports[1].Chan <- types.String("y")
 	ep.ports[2].Chan <- types.String("bad")
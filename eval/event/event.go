@@ -0,0 +1,73 @@
+// Package event implements the event: module for subscribing to and publishing
+// events.
+package event
+
+import (
+	"errors"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/util"
+)
+
+var errNotSubscribed = errors.New("handler not subscribed to the event")
+
+func Ns() eval.Ns {
+	ns := eval.Ns{
+		"core-events": vartypes.NewRo(types.MakeList(
+			types.String(eval.EventChdir),
+			types.String(eval.EventCommandStart),
+			types.String(eval.EventCommandEnd),
+			types.String(eval.EventJobStateChange),
+			types.String(eval.EventModuleLoaded),
+			types.String(eval.EventStoreDegraded),
+		)),
+	}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"event:on", on},
+	{"event:off", off},
+	{"event:emit", emit},
+}
+
+// on subscribes a function to an event.
+func on(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		name string
+		fn   eval.Fn
+	)
+	ec.ScanArgs(args, &name, &fn)
+	eval.TakeNoOpt(opts)
+
+	ec.OnEvent(name, fn)
+}
+
+// off unsubscribes a function from an event.
+func off(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		name string
+		fn   eval.Fn
+	)
+	ec.ScanArgs(args, &name, &fn)
+	eval.TakeNoOpt(opts)
+
+	if !ec.OffEvent(name, fn) {
+		util.Throw(errNotSubscribed)
+	}
+}
+
+// emit publishes an event with the remaining arguments.
+func emit(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		name     string
+		emitArgs []types.Value
+	)
+	ec.ScanArgsVariadic(args, &name, &emitArgs)
+	eval.TakeNoOpt(opts)
+
+	ec.EmitEvent(name, emitArgs...)
+}
@@ -0,0 +1,32 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`x = []; event:on foo [a b]{ x = [$@x $a$b] }; event:emit foo 1 2; event:emit bar 3 4; put $@x`).
+		WantOutStrings("12"),
+	eval.NewTest(`x = []; event:on foo { x = [$@x a] }; event:on foo { x = [$@x b] }; event:emit foo; put $@x`).
+		WantOutStrings("a", "b"),
+	eval.NewTest(`x = 0; f = { x = (+ $x 1) }; event:on foo $f; event:off foo $f; event:emit foo; put $x`).
+		WantOutStrings("0"),
+	eval.NewTest(`event:off foo { }`).WantAnyErr(),
+	eval.NewTest(`x = 0; event:on foo { fail bad }; event:on foo { x = 1 }; event:emit foo; put $x`).
+		WantOutStrings("1"),
+	eval.NewTest(`x = []; event:on chdir [old new]{ x = [$old $new] }; cd /; put $x[1]`).
+		WantOutStrings("/"),
+	eval.NewTest(`count $event:core-events`).WantOutStrings("6"),
+	eval.NewTest(`event:on foo bar`).WantAnyErr(),
+}
+
+func TestEvent(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["event"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
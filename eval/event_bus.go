@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+// Event bus.
+//
+// Events are published by name together with some arguments, and delivered to
+// the handlers subscribed to that name, in the order they subscribed. Elvish
+// code subscribes and publishes with the event: module. Elvish itself
+// publishes the core events below.
+//
+// Handlers are Elvish code and must run on the goroutine that evaluates code.
+// Events that happen on other goroutines, like a background job finishing,
+// are queued with QueueEvent, and delivered with RunQueuedHooks, which is called
+// between top-level pipelines and by the interactive shell before reading a
+// command.
+
+// Names of the core events.
+const (
+	// Published after the current directory has changed, with the old and the
+	// new directory.
+	EventChdir = "chdir"
+	// Published before an interactive command is run, with its source.
+	EventCommandStart = "command-start"
+	// Published after an interactive command has run, with a map of its source
+	// (src), how long it took in seconds (duration) and its exception, or $ok
+	// (error).
+	EventCommandEnd = "command-end"
+	// Published when a job gets stopped, continued or done, with the job and
+	// its new state.
+	EventJobStateChange = "job-state-change"
+	// Published after a module has been loaded with use, with its name.
+	EventModuleLoaded = "module-loaded"
+	// Published when the daemon stops working, with the error.
+	EventStoreDegraded = "store-degraded"
+)
+
+type eventBus struct {
+	mutex    sync.Mutex
+	handlers map[string][]Fn
+	// Calls of event handlers and signal hooks waiting to be made by
+	// RunQueuedHooks.
+	queued []func()
+	// Receives a value when the queue becomes non-empty.
+	ready chan struct{}
+}
+
+// OnEvent subscribes a handler to an event.
+func (ev *Evaler) OnEvent(name string, fn Fn) {
+	b := &ev.events
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[string][]Fn)
+	}
+	b.handlers[name] = append(b.handlers[name], fn)
+}
+
+// OffEvent unsubscribes a handler from an event, and returns whether it was
+// subscribed. When the handler has subscribed several times, the first
+// subscription is removed.
+func (ev *Evaler) OffEvent(name string, fn Fn) bool {
+	b := &ev.events
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	handlers := b.handlers[name]
+	for i, h := range handlers {
+		if h.Equal(fn) {
+			b.handlers[name] = append(handlers[:i:i], handlers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// EmitEvent publishes an event, calling its handlers one after another.
+// Errors thrown by handlers are written to the standard error.
+func (ev *Evaler) EmitEvent(name string, args ...types.Value) {
+	b := &ev.events
+	b.mutex.Lock()
+	handlers := b.handlers[name]
+	b.mutex.Unlock()
+	if len(handlers) == 0 {
+		return
+	}
+	vs := make([]types.Value, len(handlers))
+	for i, h := range handlers {
+		vs[i] = h
+	}
+	ev.callHooks(types.MakeList(vs...), "event "+name+" handler", args...)
+}
+
+// QueueEvent publishes an event from a goroutine other than the one that
+// evaluates code. The handlers are called by the next RunQueuedHooks. Events
+// that have no handlers are dropped instead of queued, so that they don't pile
+// up when nothing is interested in them.
+func (ev *Evaler) QueueEvent(name string, args ...types.Value) {
+	b := &ev.events
+	b.mutex.Lock()
+	subscribed := len(b.handlers[name]) > 0
+	b.mutex.Unlock()
+	if subscribed {
+		ev.queueHooks(func() { ev.EmitEvent(name, args...) })
+	}
+}
+
+func (ev *Evaler) queueHooks(f func()) {
+	b := &ev.events
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.queued = append(b.queued, f)
+	select {
+	case b.readyChan() <- struct{}{}:
+	default:
+	}
+}
+
+// readyChan returns b.ready, creating it if needed. It must be called with
+// b.mutex held.
+func (b *eventBus) readyChan() chan struct{} {
+	if b.ready == nil {
+		b.ready = make(chan struct{}, 1)
+	}
+	return b.ready
+}
+
+// QueuedHooksReady returns a channel that receives a value when hooks are
+// queued, so that code waiting for something else, like the editor waiting for
+// keys, can call RunQueuedHooks in time.
+func (ev *Evaler) QueuedHooksReady() <-chan struct{} {
+	b := &ev.events
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.readyChan()
+}
+
+// RunQueuedHooks calls the handlers of the events published with QueueEvent
+// and the hooks of the signals queued with QueueSignalHooks, in the order they
+// were queued. It must be called on the goroutine that evaluates code.
+func (ev *Evaler) RunQueuedHooks() {
+	b := &ev.events
+	b.mutex.Lock()
+	queued := b.queued
+	b.queued = nil
+	b.mutex.Unlock()
+	for _, f := range queued {
+		f()
+	}
+}
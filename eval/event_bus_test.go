@@ -0,0 +1,34 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestQueueEvent(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	var got []types.Value
+	ev.OnEvent("foo", &BuiltinFn{"record", func(ec *Frame, args []types.Value, opts map[string]types.Value) {
+		got = append(got, args...)
+	}})
+
+	ev.QueueEvent("foo", types.String("a"))
+	ev.QueueEvent("bar", types.String("x"))
+	ev.QueueEvent("foo", types.String("b"))
+	if n := len(ev.events.queued); n != 2 {
+		t.Errorf("%d events queued, want 2 (events without handlers dropped)", n)
+	}
+	if len(got) != 0 {
+		t.Errorf("queued events delivered before RunQueuedHooks: %v", got)
+	}
+	ev.RunQueuedHooks()
+	if len(got) != 2 || got[0] != types.String("a") || got[1] != types.String("b") {
+		t.Errorf("got %v after RunQueuedHooks, want [a b]", got)
+	}
+	ev.RunQueuedHooks()
+	if len(got) != 2 {
+		t.Errorf("events delivered again: %v", got)
+	}
+}
@@ -20,6 +20,9 @@ import (
 type Exception struct {
 	Cause     error
 	Traceback *util.SourceRange
+	// Scope of the innermost function in which the exception was raised. It
+	// is only recorded when $debug-on-exception is set, and is nil otherwise.
+	Scope *DebugScope
 }
 
 // OK is a pointer to the zero value of Exception, representing the absence of
@@ -189,6 +192,39 @@ func (f Flow) Pprint(string) string {
 	return "\033[33;1m" + f.Error() + "\033[m"
 }
 
+// LabeledFlow is a control flow that targets the loop with the given label,
+// instead of the innermost loop.
+type LabeledFlow struct {
+	Flow  Flow
+	Label string
+}
+
+func (f LabeledFlow) Repr(int) string {
+	return "?(" + f.Error() + ")"
+}
+
+func (f LabeledFlow) Error() string {
+	return f.Flow.Error() + " &label=" + parse.Quote(f.Label)
+}
+
+func (f LabeledFlow) Pprint(string) string {
+	return "\033[33;1m" + f.Error() + "\033[m"
+}
+
+// loopFlow returns the control flow that an exception cause means to a loop
+// with the given label, and whether the cause targets the loop at all.
+// Unlabeled break and continue target the innermost loop, while labeled ones
+// target the loop with the same label.
+func loopFlow(cause error, label string) (Flow, bool) {
+	switch cause := cause.(type) {
+	case Flow:
+		return cause, cause == Break || cause == Continue
+	case LabeledFlow:
+		return cause.Flow, label != "" && cause.Label == label
+	}
+	return 0, false
+}
+
 // ExternalCmdExit contains the exit status of external commands. If the
 // command was stopped rather than terminated, the Pid field contains the pid
 // of the process.
@@ -208,6 +244,16 @@ func NewExternalCmdExit(name string, ws syscall.WaitStatus, pid int) error {
 	return ExternalCmdExit{ws, name, pid}
 }
 
+// isBrokenPipe returns whether err is an ExternalCmdExit caused by SIGPIPE, or
+// errReaderGone, which is its equivalent for the value output.
+func isBrokenPipe(err error) bool {
+	if err == errReaderGone {
+		return true
+	}
+	exit, ok := err.(ExternalCmdExit)
+	return ok && exit.Signaled() && exit.Signal() == syscall.SIGPIPE
+}
+
 func (exit ExternalCmdExit) Error() string {
 	ws := exit.WaitStatus
 	quotedName := parse.Quote(exit.CmdName)
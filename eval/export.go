@@ -0,0 +1,140 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/parse"
+)
+
+// Exported variables.
+//
+// "export x" marks the variable $x as exported: from then on, the string
+// representation of its value is written to the environment variable $E:x
+// before every external command is run, so that child processes see the value
+// the variable has at that time. "unexport x" removes the mark and the
+// environment variable, but keeps $x. The mark is tied to the variable itself,
+// so it is followed by closures that have captured the variable, and lasts
+// until the variable is unexported.
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"export", export},
+		{"unexport", unexport},
+	})
+}
+
+// exportTable keeps the exported variables, keyed by the name of the
+// environment variable they are written to.
+type exportTable struct {
+	mutex sync.Mutex
+	vars  map[string]vartypes.Variable
+}
+
+func (t *exportTable) add(name string, v vartypes.Variable) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.vars == nil {
+		t.vars = make(map[string]vartypes.Variable)
+	}
+	t.vars[name] = v
+}
+
+func (t *exportTable) remove(name string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, ok := t.vars[name]
+	delete(t.vars, name)
+	return ok
+}
+
+func (t *exportTable) names() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	names := make([]string, 0, len(t.vars))
+	for name := range t.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sync writes the values of all exported variables to the environment.
+func (t *exportTable) sync() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for name, v := range t.vars {
+		os.Setenv(name, types.ToString(v.Get()))
+	}
+}
+
+// SyncExports writes the values of all exported variables to the environment.
+// It is called before starting child processes, so that they see the current
+// values.
+func (ev *Evaler) SyncExports() {
+	ev.exports.sync()
+}
+
+// export marks variables as exported and writes their values to the
+// environment. Without arguments, it outputs the names of the exported
+// variables.
+func export(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+
+	if len(args) == 0 {
+		out := ec.ports[1].Chan
+		for _, name := range ec.exports.names() {
+			out <- types.String(name)
+		}
+		return
+	}
+	names := mustGetExportNames(args)
+	vars := make([]vartypes.Variable, len(names))
+	for i, name := range names {
+		v := ec.ResolveVar("", name)
+		if v == nil {
+			throw(fmt.Errorf("variable $%s not found", name))
+		}
+		vars[i] = v
+	}
+	for i, name := range names {
+		ec.exports.add(name, vars[i])
+	}
+	ec.exports.sync()
+}
+
+// unexport removes the exported mark from variables, and removes the
+// corresponding environment variables.
+func unexport(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoOpt(opts)
+
+	for _, name := range mustGetExportNames(args) {
+		if !ec.exports.remove(name) {
+			throw(fmt.Errorf("variable $%s not exported", name))
+		}
+		os.Unsetenv(name)
+	}
+}
+
+func mustGetExportNames(args []types.Value) []string {
+	names := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(types.String)
+		if !ok {
+			throwf("variable name must be string, got %s", arg.Kind())
+		}
+		name := string(s)
+		// Names with ":" or "~" are not plain variables of the global
+		// namespace.
+		if !ValidEnvName(name) || strings.ContainsAny(name, ":~") {
+			throwf("cannot export %s", parse.Quote(name))
+		}
+		names[i] = name
+	}
+	return names
+}
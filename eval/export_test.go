@@ -0,0 +1,28 @@
+package eval
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	defer os.Unsetenv("EXPORT_TEST_A")
+	defer os.Unsetenv("EXPORT_TEST_B")
+
+	runTests(t, []Test{
+		NewTest("EXPORT_TEST_A = foo; export EXPORT_TEST_A; put $E:EXPORT_TEST_A").
+			WantOutStrings("foo"),
+		NewTest("EXPORT_TEST_A = [a b]; export EXPORT_TEST_A; put $E:EXPORT_TEST_A").
+			WantOutStrings("[a b]"),
+		NewTest("EXPORT_TEST_A = foo; EXPORT_TEST_B = bar; export EXPORT_TEST_B EXPORT_TEST_A; export").
+			WantOutStrings("EXPORT_TEST_A", "EXPORT_TEST_B"),
+		NewTest("EXPORT_TEST_A = foo; export EXPORT_TEST_A; unexport EXPORT_TEST_A; "+
+			"put $EXPORT_TEST_A $E:EXPORT_TEST_A; export").
+			WantOutStrings("foo", ""),
+		NewTest("export EXPORT_TEST_NONEXISTENT").WantAnyErr(),
+		NewTest("unexport EXPORT_TEST_NONEXISTENT").WantAnyErr(),
+		NewTest("export E:PATH").WantAnyErr(),
+		NewTest("export []").WantAnyErr(),
+		NewTest(`export "a\x00b"`).WantAnyErr(),
+	})
+}
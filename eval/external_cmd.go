@@ -2,19 +2,22 @@ package eval
 
 import (
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
-	"syscall"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/sys"
 	"github.com/elves/elvish/util"
 	"github.com/xiaq/persistent/hash"
 )
 
 var (
-	ErrExternalCmdOpts = errors.New("external commands don't accept elvish options")
+	ErrExternalCmdOpts = errors.New("external commands only accept the &env, &clear-env and &pty options")
 	ErrCdNoArg         = errors.New("implicit cd accepts no arguments")
 )
 
@@ -39,10 +42,54 @@ func (e ExternalCmd) Repr(int) string {
 	return "<external " + parse.Quote(e.Name) + ">"
 }
 
-// Call calls an external command.
+// How long to wait for more output of a command run with &pty after it has
+// exited and no output has been read.
+const ptyDrainTimeout = 100 * time.Millisecond
+
+// Call calls an external command. It accepts three options: &env, a map of
+// environment variables that are set for this invocation only; &clear-env,
+// which starts the command with only the variables in &env instead of on top
+// of the environment of the Elvish process; and &pty, which attaches the
+// standard output of the command to a pseudo-terminal, so that commands that
+// only colorize or prompt when writing to a terminal do so, and copies what it
+// writes to the byte output.
 func (e ExternalCmd) Call(ec *Frame, argVals []types.Value, opts map[string]types.Value) {
-	if len(opts) > 0 {
-		throw(ErrExternalCmdOpts)
+	var (
+		envMap   types.MapLike = types.EmptyMap
+		clearEnv bool
+		usePty   bool
+	)
+	for k, v := range opts {
+		switch k {
+		case "env":
+			m, ok := v.(types.MapLike)
+			if !ok {
+				throwf("&env should be map, got %s", v.Kind())
+			}
+			envMap = m
+		case "clear-env":
+			b, ok := v.(types.Bool)
+			if !ok {
+				throwf("&clear-env should be bool, got %s", v.Kind())
+			}
+			clearEnv = bool(b)
+		case "pty":
+			b, ok := v.(types.Bool)
+			if !ok {
+				throwf("&pty should be bool, got %s", v.Kind())
+			}
+			usePty = bool(b)
+		default:
+			throw(ErrExternalCmdOpts)
+		}
+	}
+	ec.exports.sync()
+	var env []string
+	if clearEnv {
+		// A non-nil empty slice, since nil means inheriting the environment.
+		env = overrideEnv([]string{}, envMap)
+	} else if _, ok := opts["env"]; ok {
+		env = overrideEnv(os.Environ(), envMap)
 	}
 	if util.DontSearch(e.Name) {
 		stat, err := os.Stat(e.Name)
@@ -68,38 +115,187 @@ func (e ExternalCmd) Call(ec *Frame, argVals []types.Value, opts map[string]type
 		args[i+1] = types.ToString(a)
 	}
 
-	path, err := exec.LookPath(e.Name)
+	path, err := ec.pathHash.lookPath(e.Name)
 	if err != nil {
 		throw(err)
 	}
 
 	args[0] = path
 
-	sys := makeSysProcAttr(ec.background)
-	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	var copier *ptyCopier
+	if usePty {
+		row, col := 24, 80
+		if sys.IsATTY(os.Stdout) {
+			row, col = sys.GetWinsize(os.Stdout)
+		}
+		master, slave, err := sys.OpenPty(row, col)
+		if err != nil {
+			throw(err)
+		}
+		defer master.Close()
+		files[1] = slave
+		copier = startPtyCopier(master, ec.ports[1].File)
+	}
+
+	attr := &os.ProcAttr{Env: env, Files: files}
+	var proc *os.Process
+	if ec.job != nil {
+		proc, err = ec.job.startProcess(path, args, attr)
+	} else {
+		attr.Sys = makeSysProcAttr(ec.background, 0)
+		proc, err = os.StartProcess(path, args, attr)
+	}
 
+	if ec.releasePorts != nil {
+		ec.releasePorts()
+	}
+	if usePty {
+		// The slave side is only needed by the command.
+		files[1].Close()
+	}
 	if err != nil {
 		throw(err)
 	}
 
-	state, err := proc.Wait()
+	if ec.deadline != nil {
+		// Kill the process when the deadline of with-timeout expires.
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ec.deadline.expired:
+				proc.Kill()
+			case <-done:
+			}
+		}()
+	}
+
+	pid := proc.Pid
+	ws, err := waitProcess(proc, ec.job)
+	if ec.job != nil {
+		ec.job.processExited(pid)
+	}
+	if copier != nil {
+		copier.drain()
+	}
 
 	if err != nil {
 		throw(err)
 	} else {
-		maybeThrow(NewExternalCmdExit(e.Name, state.Sys().(syscall.WaitStatus), proc.Pid))
+		maybeThrow(NewExternalCmdExit(e.Name, ws, pid))
+	}
+}
+
+// ptyCopier copies what a command writes to a pty from the master side.
+type ptyCopier struct {
+	master *os.File
+	// 1 while data read from master is being written; accessed atomically.
+	writing int32
+	// Receives a value when data has been read from master.
+	read chan struct{}
+	// Closed when the copying has ended.
+	done chan struct{}
+}
+
+func startPtyCopier(master *os.File, out io.Writer) *ptyCopier {
+	c := &ptyCopier{master, 0, make(chan struct{}, 1), make(chan struct{})}
+	go c.copy(out)
+	return c
+}
+
+func (c *ptyCopier) copy(out io.Writer) {
+	defer close(c.done)
+	buf := make([]byte, 4096)
+	for {
+		// Reading from the master side fails with EIO once the command and
+		// all its children have exited.
+		n, err := c.master.Read(buf)
+		if n > 0 {
+			atomic.StoreInt32(&c.writing, 1)
+			select {
+			case c.read <- struct{}{}:
+			default:
+			}
+			_, werr := out.Write(buf[:n])
+			atomic.StoreInt32(&c.writing, 0)
+			if werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// drain waits until the copying ends, which is normally soon after the command
+// exits. If the command has left processes that still have the slave side
+// open, it would never end; so drain stops it by closing the master side once
+// no data has been read for ptyDrainTimeout.
+func (c *ptyCopier) drain() {
+	idle := time.NewTimer(ptyDrainTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.read:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(ptyDrainTimeout)
+		case <-idle.C:
+			if atomic.LoadInt32(&c.writing) == 1 {
+				// Still writing what was read; the reader is not idle.
+				idle.Reset(ptyDrainTimeout)
+				continue
+			}
+			c.master.Close()
+			<-c.done
+			return
+		}
+	}
+}
+
+// overrideEnv returns a copy of env, a list of "name=value" entries, with the
+// variables in m set.
+func overrideEnv(env []string, m types.MapLike) []string {
+	overrides := make(map[string]string)
+	m.IteratePair(func(k, v types.Value) bool {
+		name, ok := k.(types.String)
+		if !ok {
+			throwf("environment variable name should be string, got %s", k.Kind())
+		}
+		if !ValidEnvName(string(name)) {
+			throwf("bad environment variable name %s", parse.Quote(string(name)))
+		}
+		overrides[string(name)] = types.ToString(v)
+		return true
+	})
+
+	newEnv := make([]string, 0, len(env)+len(overrides))
+	for _, s := range env {
+		if i := strings.IndexByte(s, '='); i > 0 {
+			if _, ok := overrides[s[:i]]; ok {
+				continue
+			}
+		}
+		newEnv = append(newEnv, s)
+	}
+	for name, value := range overrides {
+		newEnv = append(newEnv, name+"="+value)
 	}
+	return newEnv
 }
 
 // EachExternal calls f for each name that can resolve to an external
-// command.
-// TODO(xiaq): Windows support
+// command. On Windows, the names include the extension.
 func EachExternal(f func(string)) {
 	for _, dir := range searchPaths() {
 		// XXX Ignore error
 		infos, _ := ioutil.ReadDir(dir)
 		for _, info := range infos {
-			if !info.IsDir() && (info.Mode()&0111 != 0) {
+			if util.IsExecutableFile(info) {
 				f(info.Name())
 			}
 		}
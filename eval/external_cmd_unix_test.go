@@ -0,0 +1,35 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExternalCmdPty(t *testing.T) {
+	runTests(t, []Test{
+		// The standard output is a terminal, and its output is copied to the
+		// byte output unchanged.
+		NewTest(`e:sh &pty -c 'test -t 1 && printf "tty\n"'`).
+			WantBytesOutString("tty\n"),
+		NewTest(`e:sh &pty=$false -c 'test -t 1 || echo no'`).
+			WantBytesOutString("no\n"),
+		// The standard input is not changed.
+		NewTest(`echo input | e:sh &pty -c 'test -t 0 || cat'`).
+			WantBytesOutString("input\n"),
+		NewTest(`e:true &pty=yes`).WantAnyErr(),
+	})
+}
+
+func TestExternalCmdPty_ChildHoldsSlave(t *testing.T) {
+	// A process left behind by the command keeps the slave side open; the
+	// command should still return soon after it exits.
+	start := time.Now()
+	runTests(t, []Test{
+		NewTest(`e:sh &pty -c 'sleep 3 & echo a'`).WantBytesOutString("a\n"),
+	})
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("command with &pty took %v to return", d)
+	}
+}
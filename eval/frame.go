@@ -25,6 +25,26 @@ type Frame struct {
 	traceback  *util.SourceRange
 
 	background bool
+
+	// Cleanups to run when the innermost function call or top-level evaluation
+	// finishes. Shared by all frames forked within it.
+	cleanups *cleanups
+
+	// Deadline of the innermost with-timeout, or nil.
+	deadline *deadline
+
+	// The job that the frame is part of, or nil outside pipelines.
+	job *job
+
+	// Whether pipelines update $pipestatus. False in frames of internal
+	// evaluations, like prompts and hooks, so that they don't clobber the
+	// value left by the last command the user ran.
+	recordPipeStatus bool
+
+	// Set on the frames of the forms of a background job. It is called once
+	// the form no longer needs the port files to stay open, which for an
+	// external command is when it has started.
+	releasePorts func()
 }
 
 // NewTopFrame creates a top-level Frame.
@@ -34,6 +54,8 @@ func NewTopFrame(ev *Evaler, src *Source, ports []*Port) *Frame {
 		ev.Global, make(Ns),
 		ports,
 		0, len(src.code), nil, false,
+		&cleanups{}, nil, nil,
+		src.typ != SrcInternal, nil,
 	}
 }
 
@@ -52,24 +74,68 @@ func (ec *Frame) OutputChan() chan<- types.Value {
 	return ec.ports[1].Chan
 }
 
+// Output writes a value to the value output. If there is an interrupt, either
+// before or while it is blocked writing, it throws ErrInterrupted instead, so
+// that producers blocked by slow consumers can be stopped. If the reader of
+// the output has stopped reading, like take after it has taken enough values,
+// it throws errReaderGone, so that producers do not keep producing values that
+// are never read.
+func (ec *Frame) Output(v types.Value) {
+	ec.CheckInterrupts()
+	select {
+	case ec.ports[1].Chan <- v:
+	case <-ec.Interrupts():
+		throw(ErrInterrupted)
+	case <-ec.ports[1].readerGone.done():
+		throw(errReaderGone)
+	}
+}
+
 // OutputFile returns a file onto which output can be written.
 func (ec *Frame) OutputFile() *os.File {
 	return ec.ports[1].File
 }
 
+// ErrorFile returns a file onto which error messages can be written.
+func (ec *Frame) ErrorFile() *os.File {
+	return ec.ports[2].File
+}
+
 // IterateInputs calls the passed function for each input element.
 func (ec *Frame) IterateInputs(f func(types.Value)) {
+	ec.IterateInputsUntil(func(v types.Value) bool {
+		f(v)
+		return true
+	})
+}
+
+// IterateInputsUntil calls the passed function for each input element, until
+// it returns false. When the iteration stops early, the byte input is closed if
+// it is owned by the input port, so that upstream writers do not keep
+// producing output that is never read.
+func (ec *Frame) IterateInputsUntil(f func(types.Value) bool) {
 	var w sync.WaitGroup
 	inputs := make(chan types.Value)
+	done := make(chan struct{})
+	send := func(v types.Value) bool {
+		select {
+		case inputs <- v:
+			return true
+		case <-done:
+			return false
+		}
+	}
 
 	w.Add(2)
 	go func() {
-		linesToChan(ec.ports[0].File, inputs)
+		linesToFunc(ec.ports[0].File, send)
 		w.Done()
 	}()
 	go func() {
 		for v := range ec.ports[0].Chan {
-			inputs <- v
+			if !send(v) {
+				break
+			}
 		}
 		w.Done()
 	}()
@@ -79,16 +145,41 @@ func (ec *Frame) IterateInputs(f func(types.Value)) {
 	}()
 
 	for v := range inputs {
-		f(v)
+		if !f(v) {
+			close(done)
+			ec.ports[0].closeEarly()
+			return
+		}
 	}
 }
 
-func linesToChan(r io.Reader, ch chan<- types.Value) {
+// linesToFunc calls f with each line read from r, until f returns false.
+func linesToFunc(r io.Reader, f func(types.Value) bool) {
+	recordsToFunc(r, "\n", false, f)
+}
+
+// recordsToFunc calls f with each record read from r, until f returns false.
+// Records end with the terminator, which is kept only if keep is true; the
+// last record may also end with EOF.
+func recordsToFunc(r io.Reader, terminator string, keep bool, f func(types.Value) bool) {
 	filein := bufio.NewReader(r)
+	last := terminator[len(terminator)-1]
+	record := ""
 	for {
-		line, err := filein.ReadString('\n')
-		if line != "" {
-			ch <- types.String(strings.TrimSuffix(line, "\n"))
+		chunk, err := filein.ReadString(last)
+		record += chunk
+		if err == nil && !strings.HasSuffix(record, terminator) {
+			// Only the last byte of a longer terminator has been found.
+			continue
+		}
+		if record != "" {
+			if !keep {
+				record = strings.TrimSuffix(record, terminator)
+			}
+			if !f(types.String(record)) {
+				break
+			}
+			record = ""
 		}
 		if err != nil {
 			if err != io.EOF {
@@ -111,6 +202,80 @@ func (ec *Frame) fork(name string) *Frame {
 		ec.local, ec.up,
 		newPorts,
 		ec.begin, ec.end, ec.traceback, ec.background,
+		ec.cleanups, ec.deadline, ec.job,
+		ec.recordPipeStatus, nil,
+	}
+}
+
+// cleanups is a list of functions to be run when a function call or a
+// top-level evaluation finishes, in the reverse order they were added. Running
+// them is deferred until all the background jobs holding them have finished
+// too, since the jobs may still use the temporary files they remove.
+type cleanups struct {
+	mutex    sync.Mutex
+	fns      []func()
+	holds    int
+	finished bool
+	// Forms of background jobs that still need the port files of the
+	// function call or top-level evaluation to stay open.
+	portUsers sync.WaitGroup
+}
+
+// AddCleanup arranges f to be called when the innermost function call or
+// top-level evaluation finishes, even when it throws an exception.
+func (ec *Frame) AddCleanup(f func()) {
+	if ec.cleanups == nil {
+		throwf("cleanup not supported in this context")
+	}
+	ec.cleanups.mutex.Lock()
+	defer ec.cleanups.mutex.Unlock()
+	ec.cleanups.fns = append(ec.cleanups.fns, f)
+}
+
+// hold defers running the cleanups until the returned function is called.
+func (c *cleanups) hold() func() {
+	c.mutex.Lock()
+	c.holds++
+	c.mutex.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mutex.Lock()
+			c.holds--
+			c.runIfDone()
+		})
+	}
+}
+
+// holdPorts makes run wait until the returned function is called, which can
+// be done more than once.
+func (c *cleanups) holdPorts() func() {
+	c.portUsers.Add(1)
+	var once sync.Once
+	return func() { once.Do(c.portUsers.Done) }
+}
+
+// run marks the function call or top-level evaluation as finished, and runs
+// the cleanups unless they are held. It first waits for the forms of
+// background jobs that need the port files; the owner of the ports may close
+// them as soon as it returns.
+func (c *cleanups) run() {
+	c.portUsers.Wait()
+	c.mutex.Lock()
+	c.finished = true
+	c.runIfDone()
+}
+
+// runIfDone must be called with the mutex locked, and unlocks it.
+func (c *cleanups) runIfDone() {
+	var fns []func()
+	if c.finished && c.holds == 0 {
+		fns = c.fns
+		c.fns = nil
+	}
+	c.mutex.Unlock()
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
 	}
 }
 
@@ -162,7 +327,11 @@ func catch(perr *error, ec *Frame) {
 
 // makeException turns an error into an Exception by adding traceback.
 func (ec *Frame) makeException(e error) *Exception {
-	return &Exception{e, ec.addTraceback()}
+	exc := &Exception{e, ec.addTraceback(), nil}
+	if _, ok := e.(Flow); !ok && ec.DebugOnException() != "" {
+		exc.Scope = &DebugScope{ec.local, ec.up}
+	}
+	return exc
 }
 
 func (ec *Frame) addTraceback() *util.SourceRange {
@@ -4,12 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"unicode"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/glob"
 	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
 )
 
 // GlobPattern is en ephemeral Value generated when evaluating tilde and
@@ -24,6 +26,10 @@ type GlobFlag uint
 
 const (
 	NoMatchOK GlobFlag = 1 << iota
+	FollowSymlinks
+	// Sort the results in natural order, so that "file2" comes before
+	// "file10".
+	Natural
 )
 
 func (f GlobFlag) Has(g GlobFlag) bool {
@@ -84,49 +90,74 @@ func (gp GlobPattern) Index(modifiers []types.Value) []types.Value {
 		switch {
 		case modifier == "nomatch-ok":
 			gp.Flags |= NoMatchOK
+		case modifier == "follow-symlinks":
+			gp.Flags |= FollowSymlinks
+		case modifier == "natural":
+			gp.Flags |= Natural
 		case strings.HasPrefix(modifier, "but:"):
 			gp.Buts = append(gp.Buts, modifier[len("but:"):])
 		case modifier == "match-hidden":
 			lastSeg := gp.mustGetLastWildSeg()
-			gp.Segments[len(gp.Segments)-1] = glob.Wild{
-				lastSeg.Type, true, lastSeg.Matchers,
+			lastSeg.MatchHidden = true
+			gp.Segments[len(gp.Segments)-1] = lastSeg
+		case strings.HasPrefix(modifier, "!"):
+			matcher, ok := runeMatcher(modifier[1:])
+			if !ok {
+				throw(fmt.Errorf("unknown modifier %s", modifierv.Repr(types.NoPretty)))
 			}
+			gp.addExcluder(matcher)
 		default:
-			if matcher, ok := runeMatchers[modifier]; ok {
-				gp.addMatcher(matcher)
-			} else if strings.HasPrefix(modifier, "set:") {
-				set := modifier[len("set:"):]
-				gp.addMatcher(func(r rune) bool {
-					return strings.ContainsRune(set, r)
-				})
-			} else if strings.HasPrefix(modifier, "range:") {
-				rangeExpr := modifier[len("range:"):]
-				badRangeExpr := fmt.Errorf("bad range modifier: %s", parse.Quote(rangeExpr))
-				runes := []rune(rangeExpr)
-				if len(runes) != 3 {
-					throw(badRangeExpr)
-				}
-				from, sep, to := runes[0], runes[1], runes[2]
-				switch sep {
-				case '-':
-					gp.addMatcher(func(r rune) bool {
-						return from <= r && r <= to
-					})
-				case '~':
-					gp.addMatcher(func(r rune) bool {
-						return from <= r && r < to
-					})
-				default:
-					throw(badRangeExpr)
-				}
-			} else {
+			matcher, ok := runeMatcher(modifier)
+			if !ok {
 				throw(fmt.Errorf("unknown modifier %s", modifierv.Repr(types.NoPretty)))
 			}
+			gp.addMatcher(matcher)
 		}
 	}
 	return []types.Value{gp}
 }
 
+// runeMatcher returns the function for a modifier that restricts the runes a
+// wildcard matches.
+func runeMatcher(modifier string) (func(rune) bool, bool) {
+	if matcher, ok := runeMatchers[modifier]; ok {
+		return matcher, true
+	} else if strings.HasPrefix(modifier, "set:") {
+		set := modifier[len("set:"):]
+		return func(r rune) bool {
+			return strings.ContainsRune(set, r)
+		}, true
+	} else if strings.HasPrefix(modifier, "range:") {
+		rangeExpr := modifier[len("range:"):]
+		badRangeExpr := fmt.Errorf("bad range modifier: %s", parse.Quote(rangeExpr))
+		runes := []rune(rangeExpr)
+		if len(runes) != 3 {
+			throw(badRangeExpr)
+		}
+		from, sep, to := runes[0], runes[1], runes[2]
+		switch sep {
+		case '-':
+			return func(r rune) bool {
+				return from <= r && r <= to
+			}, true
+		case '~':
+			return func(r rune) bool {
+				return from <= r && r < to
+			}, true
+		default:
+			throw(badRangeExpr)
+		}
+	} else if strings.HasPrefix(modifier, "class:") {
+		classExpr := modifier[len("class:"):]
+		matcher, ok := glob.ParseClass(classExpr)
+		if !ok {
+			throw(fmt.Errorf("bad class modifier: %s", parse.Quote(classExpr)))
+		}
+		return matcher, true
+	}
+	return nil, false
+}
+
 func (gp *GlobPattern) mustGetLastWildSeg() glob.Wild {
 	if len(gp.Segments) == 0 {
 		throw(ErrBadGlobPattern)
@@ -139,10 +170,14 @@ func (gp *GlobPattern) mustGetLastWildSeg() glob.Wild {
 
 func (gp *GlobPattern) addMatcher(matcher func(rune) bool) {
 	lastSeg := gp.mustGetLastWildSeg()
-	gp.Segments[len(gp.Segments)-1] = glob.Wild{
-		lastSeg.Type, lastSeg.MatchHidden,
-		append(lastSeg.Matchers, matcher),
-	}
+	lastSeg.Matchers = append(lastSeg.Matchers, matcher)
+	gp.Segments[len(gp.Segments)-1] = lastSeg
+}
+
+func (gp *GlobPattern) addExcluder(excluder func(rune) bool) {
+	lastSeg := gp.mustGetLastWildSeg()
+	lastSeg.Excluders = append(lastSeg.Excluders, excluder)
+	gp.Segments[len(gp.Segments)-1] = lastSeg
 }
 
 func (gp *GlobPattern) append(segs ...glob.Segment) {
@@ -152,11 +187,11 @@ func (gp *GlobPattern) append(segs ...glob.Segment) {
 func wildcardToSegment(s string) (glob.Segment, error) {
 	switch s {
 	case "*":
-		return glob.Wild{glob.Star, false, nil}, nil
+		return glob.Wild{glob.Star, false, nil, nil}, nil
 	case "**":
-		return glob.Wild{glob.StarStar, false, nil}, nil
+		return glob.Wild{glob.StarStar, false, nil, nil}, nil
 	case "?":
-		return glob.Wild{glob.Question, false, nil}, nil
+		return glob.Wild{glob.Question, false, nil, nil}, nil
 	default:
 		return nil, fmt.Errorf("bad wildcard: %q", s)
 	}
@@ -183,14 +218,15 @@ func stringToSegments(s string) []glob.Segment {
 	return segs
 }
 
-func doGlob(gp GlobPattern, abort <-chan struct{}) []types.Value {
+func doGlob(gp GlobPattern, fs glob.FS, abort <-chan struct{}) []types.Value {
 	but := make(map[string]struct{})
 	for _, s := range gp.Buts {
 		but[s] = struct{}{}
 	}
 
 	vs := make([]types.Value, 0)
-	if !gp.Glob(func(name string) bool {
+	opts := glob.Options{FollowSymlinks: gp.Flags.Has(FollowSymlinks), FS: fs}
+	if !gp.GlobWithOptions(opts, func(name string) bool {
 		select {
 		case <-abort:
 			logger.Println("glob aborted")
@@ -207,5 +243,23 @@ func doGlob(gp GlobPattern, abort <-chan struct{}) []types.Value {
 	if len(vs) == 0 && !gp.Flags.Has(NoMatchOK) {
 		throw(ErrWildcardNoMatch)
 	}
+	if gp.Flags.Has(Natural) {
+		sort.SliceStable(vs, func(i, j int) bool {
+			return compareNaturalPaths(string(vs[i].(types.String)), string(vs[j].(types.String))) < 0
+		})
+	}
 	return vs
 }
+
+// compareNaturalPaths compares two paths in natural order, one path component
+// at a time, so that files are still listed after the directories that
+// contain them.
+func compareNaturalPaths(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := util.NaturalCompare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareFloats(float64(len(as)), float64(len(bs)))
+}
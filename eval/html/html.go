@@ -0,0 +1,228 @@
+// Package html implements the html: module for parsing HTML and XML documents
+// and querying them with CSS selectors.
+package html
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+// Ns returns the namespace of the html: module.
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"parse", parse},
+	{"select", selectFn},
+	{"text", text},
+}
+
+// ErrNotNode is thrown when a value that is not a node is passed to a function
+// expecting a node.
+var ErrNotNode = errors.New("not a node")
+
+var nodeDescriptor = types.NewStructDescriptor("tag", "attrs", "children")
+
+// parse parses the byte input into a document node. The document node has an
+// empty tag, and the top-level elements as its children. Each element node
+// has a tag, a map of attributes, and a list of children, which are either
+// element nodes or strings. Whitespace-only text is dropped.
+//
+// The parser is lenient by default, recovering from unclosed and mismatched
+// tags as commonly found in HTML. When &xml is true, the input must be
+// well-formed XML.
+func parse(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var optXML bool
+	eval.TakeNoArg(args)
+	ec.ScanOpts(opts, eval.OptToScan{"xml", &optXML, types.Bool(false)})
+
+	doc, err := parseDocument(ec.InputFile(), optXML)
+	maybeThrow(err)
+	ec.OutputChan() <- doc
+}
+
+// node is a mutable node used during parsing.
+type node struct {
+	tag      string
+	attrs    types.Map
+	children []interface{}
+}
+
+func parseDocument(r io.Reader, strict bool) (types.Value, error) {
+	decoder := xml.NewDecoder(r)
+	if !strict {
+		decoder.Strict = false
+		decoder.AutoClose = xml.HTMLAutoClose
+		decoder.Entity = xml.HTMLEntity
+	}
+	root := &node{attrs: types.EmptyMap}
+	stack := []*node{root}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			n := &node{tag: token.Name.Local, attrs: types.EmptyMap}
+			for _, attr := range token.Attr {
+				n.attrs = n.attrs.Assoc(
+					types.String(attr.Name.Local), types.String(attr.Value)).(types.Map)
+			}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			// Pop up to the matching element; ignore the end tag if there is
+			// none.
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == token.Name.Local {
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			if s := string(token); strings.TrimSpace(s) != "" {
+				top.children = append(top.children, s)
+			}
+		}
+	}
+	return root.value(), nil
+}
+
+func (n *node) value() types.Value {
+	children := make([]types.Value, len(n.children))
+	for i, child := range n.children {
+		switch child := child.(type) {
+		case string:
+			children[i] = types.String(child)
+		case *node:
+			children[i] = child.value()
+		}
+	}
+	return types.NewStruct(nodeDescriptor, []types.Value{
+		types.String(n.tag), n.attrs, types.MakeList(children...)})
+}
+
+// element is an accessor for a node value.
+type element struct {
+	types.MapLike
+}
+
+func toElement(v types.Value) (element, bool) {
+	m, ok := v.(types.MapLike)
+	if !ok {
+		return element{}, false
+	}
+	for _, key := range []string{"tag", "attrs", "children"} {
+		if !m.HasKey(types.String(key)) {
+			return element{}, false
+		}
+	}
+	return element{m}, true
+}
+
+func (e element) tag() string {
+	return types.ToString(e.IndexOne(types.String("tag")))
+}
+
+func (e element) attr(name string) (string, bool) {
+	attrs, ok := e.IndexOne(types.String("attrs")).(types.MapLike)
+	if !ok || !attrs.HasKey(types.String(name)) {
+		return "", false
+	}
+	return types.ToString(attrs.IndexOne(types.String(name))), true
+}
+
+func (e element) eachChild(f func(types.Value)) {
+	children, ok := e.IndexOne(types.String("children")).(types.Iterator)
+	if !ok {
+		return
+	}
+	children.Iterate(func(v types.Value) bool {
+		f(v)
+		return true
+	})
+}
+
+// selectFn outputs all the descendant elements of a node that match a CSS
+// selector, in document order.
+func selectFn(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		root     types.Value
+		selector string
+	)
+	ec.ScanArgs(args, &root, &selector)
+	eval.TakeNoOpt(opts)
+
+	rootElement, ok := toElement(root)
+	if !ok {
+		throw(ErrNotNode)
+	}
+	sels, err := parseSelectorGroup(selector)
+	maybeThrow(err)
+
+	out := ec.OutputChan()
+	var walk func(path []element)
+	walk = func(path []element) {
+		path[len(path)-1].eachChild(func(v types.Value) {
+			child, ok := toElement(v)
+			if !ok {
+				return
+			}
+			childPath := append(path[:len(path):len(path)], child)
+			for _, sel := range sels {
+				if sel.match(len(sel.parts)-1, childPath) {
+					out <- v
+					break
+				}
+			}
+			walk(childPath)
+		})
+	}
+	walk([]element{rootElement})
+}
+
+// text outputs the text content of a node, which is the concatenation of all
+// descendant strings.
+func text(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var root types.Value
+	ec.ScanArgs(args, &root)
+	eval.TakeNoOpt(opts)
+
+	var buf bytes.Buffer
+	var walk func(v types.Value)
+	walk = func(v types.Value) {
+		if s, ok := v.(types.String); ok {
+			buf.WriteString(string(s))
+		} else if e, ok := toElement(v); ok {
+			e.eachChild(walk)
+		} else {
+			throw(ErrNotNode)
+		}
+	}
+	walk(root)
+	ec.OutputChan() <- types.String(buf.String())
+}
+
+func throw(e error) {
+	util.Throw(e)
+}
+
+func maybeThrow(e error) {
+	if e != nil {
+		util.Throw(e)
+	}
+}
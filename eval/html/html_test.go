@@ -0,0 +1,35 @@
+package html
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+const doc = `echo '<html><body>
+<div class="item first"><a href="/a">A</a></div>
+<div class="item"><p><a href="/b">B<br>b</a></p></div>
+<div id="other"><a href="/c">C</a></div>
+</body></html>'`
+
+var tests = []eval.Test{
+	eval.NewTest(doc + ` | html:text (html:parse)`).WantOutStrings("ABbC"),
+	eval.NewTest(doc+` | html:select (html:parse) 'div.item a' | each [n]{ put $n[attrs][href] }`).WantOutStrings("/a", "/b"),
+	eval.NewTest(doc + ` | html:select (html:parse) 'div.item > a' | each [n]{ put $n[attrs][href] }`).WantOutStrings("/a"),
+	eval.NewTest(doc+` | html:select (html:parse) '#other a, .first a' | each [n]{ put $n[attrs][href] }`).WantOutStrings("/a", "/c"),
+	eval.NewTest(doc + ` | html:select (html:parse) 'a[href="/b"]' | each $html:text~`).WantOutStrings("Bb"),
+	eval.NewTest(doc + ` | html:select (html:parse) 'br' | each [n]{ put $n[tag] }`).WantOutStrings("br"),
+	eval.NewTest(`echo '<a><b>x</b></a>' | put (html:parse &xml)[children][0][tag]`).WantOutStrings("a"),
+	eval.NewTest(`echo '<a><b>x</a>' | html:parse &xml`).WantAnyErr(),
+	eval.NewTest(doc + ` | html:select (html:parse) 'div >'`).WantAnyErr(),
+	eval.NewTest(`html:text [a]`).WantAnyErr(),
+}
+
+func TestHTML(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["html"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
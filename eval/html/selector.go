@@ -0,0 +1,253 @@
+package html
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A subset of CSS selectors is supported:
+//
+// Group    = Complex { ',' Complex }
+// Complex  = Compound { [ '>' ] Compound }
+// Compound = [ Tag | '*' ] { '.' Class | '#' Id | '[' Attr [ '=' Value ] ']' }
+
+// complexSelector is a sequence of compound selectors joined by combinators.
+// combinators[i] joins parts[i] and parts[i+1], and is either ' ' (descendant)
+// or '>' (child).
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+}
+
+type attrSelector struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// match returns whether the last element of path matches parts[:i+1], with
+// the rest of path being its ancestors.
+func (sel *complexSelector) match(i int, path []element) bool {
+	if !sel.parts[i].match(path[len(path)-1]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	ancestors := path[:len(path)-1]
+	if sel.combinators[i-1] == '>' {
+		return len(ancestors) > 0 && sel.match(i-1, ancestors)
+	}
+	for j := len(ancestors); j > 0; j-- {
+		if sel.match(i-1, ancestors[:j]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sel *compoundSelector) match(e element) bool {
+	tag := e.tag()
+	if tag == "" {
+		// The document node is never matched.
+		return false
+	}
+	if sel.tag != "" && sel.tag != "*" && !strings.EqualFold(sel.tag, tag) {
+		return false
+	}
+	if sel.id != "" {
+		if id, _ := e.attr("id"); id != sel.id {
+			return false
+		}
+	}
+	if len(sel.classes) > 0 {
+		class, _ := e.attr("class")
+		classes := strings.Fields(class)
+		for _, want := range sel.classes {
+			if !contains(classes, want) {
+				return false
+			}
+		}
+	}
+	for _, attr := range sel.attrs {
+		value, ok := e.attr(attr.name)
+		if !ok || (attr.hasValue && value != attr.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorParser parses CSS selectors.
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func parseSelectorGroup(src string) (sels []*complexSelector, err error) {
+	p := &selectorParser{src, 0}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(selectorError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	for {
+		sels = append(sels, p.complex())
+		p.skipSpaces()
+		if p.pos == len(p.src) {
+			return sels, nil
+		}
+		p.expect(',')
+	}
+}
+
+type selectorError struct {
+	src string
+	pos int
+	msg string
+}
+
+func (e selectorError) Error() string {
+	return fmt.Sprintf("bad selector %q at %d: %s", e.src, e.pos, e.msg)
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) {
+	panic(selectorError{p.src, p.pos, fmt.Sprintf(format, args...)})
+}
+
+func (p *selectorParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	begin := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n", p.src[p.pos]) != -1 {
+		p.pos++
+	}
+	return p.pos > begin
+}
+
+func (p *selectorParser) expect(b byte) {
+	if p.peek() != b {
+		p.errorf("want %q", b)
+	}
+	p.pos++
+}
+
+func (p *selectorParser) complex() *complexSelector {
+	sel := &complexSelector{}
+	p.skipSpaces()
+	sel.parts = append(sel.parts, p.compound())
+	for {
+		spaced := p.skipSpaces()
+		var combinator byte
+		switch p.peek() {
+		case '>':
+			p.pos++
+			p.skipSpaces()
+			combinator = '>'
+		case ',', 0:
+			return sel
+		default:
+			if !spaced {
+				p.errorf("unexpected %q", p.peek())
+			}
+			combinator = ' '
+		}
+		sel.combinators = append(sel.combinators, combinator)
+		sel.parts = append(sel.parts, p.compound())
+	}
+}
+
+func (p *selectorParser) compound() compoundSelector {
+	var sel compoundSelector
+	begin := p.pos
+	if p.peek() == '*' {
+		p.pos++
+		sel.tag = "*"
+	} else if isIdentByte(p.peek()) {
+		sel.tag = p.ident()
+	}
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			sel.classes = append(sel.classes, p.ident())
+		case '#':
+			p.pos++
+			sel.id = p.ident()
+		case '[':
+			p.pos++
+			p.skipSpaces()
+			attr := attrSelector{name: p.ident()}
+			p.skipSpaces()
+			if p.peek() == '=' {
+				p.pos++
+				p.skipSpaces()
+				attr.value, attr.hasValue = p.attrValue(), true
+				p.skipSpaces()
+			}
+			p.expect(']')
+			sel.attrs = append(sel.attrs, attr)
+		default:
+			if p.pos == begin {
+				p.errorf("want selector")
+			}
+			return sel
+		}
+	}
+}
+
+func (p *selectorParser) ident() string {
+	begin := p.pos
+	for isIdentByte(p.peek()) {
+		p.pos++
+	}
+	if p.pos == begin {
+		p.errorf("want identifier")
+	}
+	return p.src[begin:p.pos]
+}
+
+func (p *selectorParser) attrValue() string {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return p.ident()
+	}
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], quote)
+	if end == -1 {
+		p.errorf("unterminated string")
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return value
+}
+
+func isIdentByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		b == '-' || b == '_' || b >= 0x80
+}
@@ -1,10 +1,41 @@
 package eval
 
-import "errors"
+import (
+	"errors"
+	"sync"
+)
 
-// Interrupts returns a channel that is closed when an interrupt signal comes.
+// Interrupts returns a channel that is closed when an interrupt signal comes,
+// or when the deadline of the innermost with-timeout expires. Background jobs
+// are not interrupted by signals.
 func (ec *Frame) Interrupts() <-chan struct{} {
-	return ec.intCh
+	if ec.deadline != nil {
+		return ec.deadline.interrupts
+	}
+	if ec.background {
+		return nil
+	}
+	return ec.intCh.get()
+}
+
+// interruptChan keeps the channel that is closed when an interrupt signal
+// comes during the current evaluation, which is nil outside evaluations. It is
+// set on the goroutine that evaluates, and read on those of the frames.
+type interruptChan struct {
+	mutex sync.Mutex
+	ch    chan struct{}
+}
+
+func (c *interruptChan) get() chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ch
+}
+
+func (c *interruptChan) set(ch chan struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ch = ch
 }
 
 var ErrInterrupted = errors.New("interrupted")
@@ -18,3 +49,41 @@ func (ec *Frame) CheckInterrupts() {
 	default:
 	}
 }
+
+// deadline is set on frames running within the body of with-timeout.
+type deadline struct {
+	// Closed when the deadline expires or an interrupt signal comes.
+	interrupts chan struct{}
+	// Closed when the deadline, or that of an enclosing with-timeout, expires.
+	expired chan struct{}
+}
+
+// newCancelDeadline returns a deadline that expires when cancel is called or
+// when the deadline of ec expires, and that also takes the interrupts of ec.
+// The returned stop function must be called when the deadline is no longer in
+// use.
+func (ec *Frame) newCancelDeadline() (dl *deadline, cancel, stop func()) {
+	dl = &deadline{make(chan struct{}), make(chan struct{})}
+	interrupts := ec.Interrupts()
+	var expired <-chan struct{}
+	if ec.deadline != nil {
+		expired = ec.deadline.expired
+	}
+	cancelCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-cancelCh:
+			close(dl.expired)
+		case <-expired:
+			close(dl.expired)
+		case <-interrupts:
+		case <-done:
+			return
+		}
+		close(dl.interrupts)
+	}()
+	var cancelOnce sync.Once
+	cancel = func() { cancelOnce.Do(func() { close(cancelCh) }) }
+	return dl, cancel, func() { close(done) }
+}
@@ -0,0 +1,227 @@
+// Package ip implements the ip: module for working with IP addresses, subnets
+// and DNS names.
+package ip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"valid", valid},
+	{"parse", parseIP},
+	{"parse-cidr", parseCIDR},
+	{"contains", contains},
+	{"hosts", hosts},
+	{"resolve", resolve},
+}
+
+var (
+	ipDescriptor   = types.NewStructDescriptor("addr", "version")
+	cidrDescriptor = types.NewStructDescriptor(
+		"network", "prefix", "mask", "version", "first", "last", "size")
+)
+
+func scanIP(s types.String) net.IP {
+	ip := net.ParseIP(string(s))
+	if ip == nil {
+		throwf("bad IP address: %s", parse.Quote(string(s)))
+	}
+	return ip
+}
+
+func scanCIDR(s types.String) *net.IPNet {
+	_, ipnet, err := net.ParseCIDR(string(s))
+	if err != nil {
+		throwf("bad CIDR: %s", parse.Quote(string(s)))
+	}
+	return ipnet
+}
+
+func version(ip net.IP) string {
+	if ip.To4() != nil {
+		return "4"
+	}
+	return "6"
+}
+
+// valid outputs whether a string is an IPv4 or IPv6 address.
+func valid(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Bool(net.ParseIP(string(s)) != nil)
+}
+
+// parseIP outputs an IP address in its canonical form, together with its
+// version, either 4 or 6.
+func parseIP(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	ip := scanIP(s)
+	ec.OutputChan() <- types.NewStruct(ipDescriptor, []types.Value{
+		types.String(ip.String()), types.String(version(ip))})
+}
+
+// parseCIDR outputs information about a subnet in CIDR notation, like
+// "192.168.0.0/24": the network address, the prefix length, the netmask, the
+// IP version, the first and last addresses, and the number of addresses.
+func parseCIDR(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	ipnet := scanCIDR(s)
+	ones, bits := ipnet.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	ec.OutputChan() <- types.NewStruct(cidrDescriptor, []types.Value{
+		types.String(ipnet.IP.String()),
+		types.String(strconv.Itoa(ones)),
+		types.String(net.IP(ipnet.Mask).String()),
+		types.String(version(ipnet.IP)),
+		types.String(ipnet.IP.String()),
+		types.String(lastIP(ipnet).String()),
+		types.String(size.String()),
+	})
+}
+
+func lastIP(ipnet *net.IPNet) net.IP {
+	last := make(net.IP, len(ipnet.IP))
+	for i := range ipnet.IP {
+		last[i] = ipnet.IP[i] | ^ipnet.Mask[i]
+	}
+	return last
+}
+
+// contains outputs whether a subnet in CIDR notation contains an IP address.
+func contains(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var c, s types.String
+	ec.ScanArgs(args, &c, &s)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Bool(scanCIDR(c).Contains(scanIP(s)))
+}
+
+// hosts outputs the addresses of the hosts in a subnet in CIDR notation. For
+// IPv4 subnets with more than two addresses, the network and broadcast
+// addresses are left out.
+func hosts(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var c types.String
+	ec.ScanArgs(args, &c)
+	eval.TakeNoOpt(opts)
+
+	ipnet := scanCIDR(c)
+	first, last := ipnet.IP, lastIP(ipnet)
+	ones, bits := ipnet.Mask.Size()
+	if bits == 32 && bits-ones > 1 {
+		first, last = next(first, 1), next(last, -1)
+	}
+	for ip := first; ; ip = next(ip, 1) {
+		ec.Output(types.String(ip.String()))
+		if ip.Equal(last) {
+			break
+		}
+	}
+}
+
+// next returns the address after or before ip, depending on whether delta is
+// 1 or -1.
+func next(ip net.IP, delta int) net.IP {
+	n := make(net.IP, len(ip))
+	copy(n, ip)
+	for i := len(n) - 1; i >= 0; i-- {
+		n[i] += byte(delta)
+		if (delta > 0 && n[i] != 0) || (delta < 0 && n[i] != 0xff) {
+			break
+		}
+	}
+	return n
+}
+
+type resolveOptions struct {
+	Type    string
+	Timeout float64
+}
+
+var errTimeout = errors.New("timed out")
+
+// resolve looks up a DNS name, and outputs its addresses or records. &type is
+// one of "A", "AAAA", "TXT", or "" (the default) for both A and AAAA records.
+// &timeout is the number of seconds to wait for the answer, 5 by default.
+func resolve(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var name types.String
+	ec.ScanArgs(args, &name)
+	options := resolveOptions{"", 5}
+	ec.ScanOptsToStruct(opts, &options)
+
+	switch options.Type {
+	case "", "A", "AAAA", "TXT":
+	default:
+		throwf("&type must be A, AAAA or TXT, got %s", options.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(options.Timeout*float64(time.Second)))
+	defer cancel()
+	go func() {
+		select {
+		case <-ec.Interrupts():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var results []string
+	if options.Type == "TXT" {
+		txts, err := net.DefaultResolver.LookupTXT(ctx, string(name))
+		maybeThrowResolveError(ctx, err)
+		results = txts
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, string(name))
+		maybeThrowResolveError(ctx, err)
+		for _, addr := range addrs {
+			is4 := addr.IP.To4() != nil
+			if options.Type == "" || is4 == (options.Type == "A") {
+				results = append(results, addr.IP.String())
+			}
+		}
+	}
+
+	out := ec.OutputChan()
+	for _, result := range results {
+		out <- types.String(result)
+	}
+}
+
+func maybeThrowResolveError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		util.Throw(errTimeout)
+	}
+	util.Throw(err)
+}
+
+func throwf(format string, args ...interface{}) {
+	util.Throw(fmt.Errorf(format, args...))
+}
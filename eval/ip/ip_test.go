@@ -0,0 +1,49 @@
+package ip
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`ip:valid 192.168.0.1`).WantOutBools(true),
+	eval.NewTest(`ip:valid 2001:db8::1`).WantOutBools(true),
+	eval.NewTest(`ip:valid 256.0.0.1`).WantOutBools(false),
+
+	eval.NewTest(`put (ip:parse 2001:0db8:0:0::1)[addr version]`).
+		WantOutStrings("2001:db8::1", "6"),
+	eval.NewTest(`ip:parse foo`).WantAnyErr(),
+
+	eval.NewTest(`put (ip:parse-cidr 192.168.1.7/22)[network prefix mask version first last size]`).
+		WantOutStrings("192.168.0.0", "22", "255.255.252.0", "4",
+			"192.168.0.0", "192.168.3.255", "1024"),
+	eval.NewTest(`put (ip:parse-cidr 2001:db8::/64)[last size]`).
+		WantOutStrings("2001:db8::ffff:ffff:ffff:ffff", "18446744073709551616"),
+	eval.NewTest(`ip:parse-cidr 10.0.0.0/33`).WantAnyErr(),
+
+	eval.NewTest(`ip:contains 10.0.0.0/8 10.255.1.2`).WantOutBools(true),
+	eval.NewTest(`ip:contains 10.0.0.0/8 11.0.0.1`).WantOutBools(false),
+	eval.NewTest(`ip:contains 2001:db8::/32 2001:db8:1::1`).WantOutBools(true),
+
+	eval.NewTest(`ip:hosts 192.168.0.0/30`).
+		WantOutStrings("192.168.0.1", "192.168.0.2"),
+	eval.NewTest(`ip:hosts 10.0.0.254/31`).WantOutStrings("10.0.0.254", "10.0.0.255"),
+	eval.NewTest(`ip:hosts 10.0.0.1/32`).WantOutStrings("10.0.0.1"),
+	eval.NewTest(`ip:hosts 10.0.0.0/23 | count`).WantOutStrings("510"),
+	eval.NewTest(`ip:hosts 2001:db8::fe/127`).WantOutStrings("2001:db8::fe", "2001:db8::ff"),
+
+	// IP literals resolve to themselves without any network access.
+	eval.NewTest(`ip:resolve 127.0.0.1`).WantOutStrings("127.0.0.1"),
+	eval.NewTest(`ip:resolve &type=AAAA 127.0.0.1`).WantOutStrings(),
+	eval.NewTest(`ip:resolve &type=MX example.com`).WantAnyErr(),
+}
+
+func TestIP(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["ip"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
@@ -0,0 +1,419 @@
+package eval
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+	"github.com/xiaq/persistent/hash"
+)
+
+var errNoSuchJobField = errors.New("no such field of job")
+
+// Job control.
+//
+// Each top-level pipeline, and each pipeline run in the background, is run as
+// a job. When job control is enabled, which is the case when Elvish is
+// interactive and its standard input is a terminal, the external commands
+// started by a job are put in a process group of their own, which is given the
+// terminal while the job is in the foreground. A foreground job that gets
+// stopped, typically by pressing ^Z, is added to the job table of the Evaler,
+// and Elvish moves on; the job can later be continued with fg or bg. Jobs run
+// in the background are always in the job table.
+//
+// A job can be disowned, after which it is no longer in the job table, and is
+// left alone when Elvish exits because of a hangup. Jobs started with
+// daemonize are disowned from the start, and their external commands are also
+// detached from the session and the terminal of Elvish.
+//
+// Jobs are also values. A pipeline run in the background directly inside an
+// output capture, like in "j = (cmd &)", outputs its job, which can be indexed
+// to inspect it and passed to wait, fg, bg and kill-job. Elsewhere it outputs
+// nothing, so that "cmd &" doesn't print the job at the prompt.
+
+type job struct {
+	src        string
+	background bool // Whether the job was started in the background.
+	control    bool // Whether job control is enabled for the job.
+	// Whether each external command of the job is started in a session of
+	// its own.
+	detached bool
+
+	mutex      sync.Mutex
+	id         int // Assigned when the job is added to the job table.
+	pid        int // The first process started by the job.
+	pgid       int
+	pids       map[int]bool // Processes of the job that are still running.
+	foreground bool
+	stopped    bool
+	waited     bool // Whether wait has been called on the job.
+	disowned   bool
+	// Restores the terminal state of the job when it was stopped.
+	restoreTTY func()
+
+	// Written to when a process of the job stops while the job is in the
+	// foreground.
+	stopCh chan struct{}
+	// Closed when all the forms of the job have finished, after which errors
+	// contains their exceptions.
+	done   chan struct{}
+	errors []*Exception
+	// When the job was started, and when it became done.
+	started, finished time.Time
+}
+
+func newJob(ec *Frame, src string, bg bool) *job {
+	return &job{
+		src: src, background: bg,
+		control:    ec.Editor != nil && jobControlAvailable(),
+		foreground: !bg,
+		pids:       make(map[int]bool),
+		stopCh:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		started:    time.Now(),
+	}
+}
+
+// usesProcessGroup returns whether the external commands of the job are put
+// in a process group of their own.
+func (j *job) usesProcessGroup() bool {
+	return j.background || j.control
+}
+
+// startProcess starts an external command as part of the job. The first
+// process becomes the leader of the process group of the job, and later ones
+// join it.
+func (j *job) startProcess(path string, args []string, attr *os.ProcAttr) (*os.Process, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	group := j.usesProcessGroup() && !j.detached
+	attr.Sys = makeSysProcAttr(group, j.pgid)
+	if j.detached {
+		attr.Sys = makeDetachedSysProcAttr()
+	}
+	proc, err := os.StartProcess(path, args, attr)
+	if err != nil && group && j.pgid != 0 {
+		// The process group disappears when all of its processes have exited
+		// and been waited for, in which case a new one has to be made.
+		attr.Sys = makeSysProcAttr(true, 0)
+		proc, err = os.StartProcess(path, args, attr)
+		if err == nil {
+			j.pgid = 0
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.pids[proc.Pid] = true
+	if j.pid == 0 {
+		j.pid = proc.Pid
+	}
+	if group && j.pgid == 0 {
+		j.pgid = proc.Pid
+		if j.foreground && j.control {
+			giveTerminal(j.pgid)
+		}
+	}
+	return proc, nil
+}
+
+// processExited is called when a process of the job has exited.
+func (j *job) processExited(pid int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	delete(j.pids, pid)
+}
+
+// notifyStopped is called when a process of the job gets stopped.
+func (j *job) notifyStopped() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.stopped = true
+	if j.foreground {
+		select {
+		case j.stopCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (j *job) isForeground() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.foreground
+}
+
+// finish records the exceptions of the forms of the job and marks it as done.
+func (j *job) finish(errors []*Exception) {
+	j.errors = errors
+	j.finished = time.Now()
+	close(j.done)
+}
+
+// waitForeground waits until the job is either done or stopped. When the job
+// has been given the terminal, restoreShellTTY restores the terminal state of
+// Elvish, and is used when taking the terminal back after the job stops;
+// otherwise it is nil. It returns whether the job was stopped, in which case
+// it has been added to the job table and moved to the background.
+func (j *job) waitForeground(ec *Frame, restoreShellTTY func()) bool {
+	select {
+	case <-j.done:
+		j.mutex.Lock()
+		defer j.mutex.Unlock()
+		j.foreground = false
+		if restoreShellTTY != nil && j.pgid != 0 {
+			putSelfInFg()
+		}
+		return false
+	case <-j.stopCh:
+	}
+
+	j.mutex.Lock()
+	j.foreground = false
+	if restoreShellTTY != nil {
+		j.restoreTTY = saveTTY()
+		restoreShellTTY()
+		putSelfInFg()
+	}
+	j.mutex.Unlock()
+
+	added := ec.jobs.add(j)
+	ec.ports[2].File.WriteString("job " + j.describe() + " stopped\n")
+	if added {
+		go j.notifyWhenDone(ec)
+	}
+	ec.EmitEvent(EventJobStateChange, j, types.String("stopped"))
+	return true
+}
+
+// notifyWhenDone waits until the job is done, queues the event of the change
+// of its state, and unless it is in the foreground by then, is being waited
+// for or has been disowned, removes it from the job table and tells the user.
+func (j *job) notifyWhenDone(ec *Frame) {
+	<-j.done
+	ec.QueueEvent(EventJobStateChange, j, types.String("done"))
+	j.mutex.Lock()
+	quiet := j.foreground || j.waited || j.disowned
+	j.mutex.Unlock()
+	if quiet {
+		return
+	}
+	ec.jobs.remove(j)
+	msg := j.doneMessage()
+	if ec.Editor != nil {
+		m := ec.Editor.ActiveMutex()
+		m.Lock()
+		defer m.Unlock()
+
+		if ec.Editor.Active() {
+			ec.Editor.Notify("%s", msg)
+			return
+		}
+	}
+	ec.ports[2].File.WriteString(msg + "\n")
+}
+
+// doneMessage returns the message telling the user that the job has finished,
+// with how long it took and its errors, if any. It must only be called when the
+// job is done.
+func (j *job) doneMessage() string {
+	msg := "job " + j.describe()
+	took := util.FormatDuration(j.finished.Sub(j.started))
+	err := ComposeExceptionsFromPipeline(dropBrokenPipes(j.errors))
+	if err != nil {
+		return msg + " failed after " + took + ": " + err.Error()
+	}
+	return msg + " finished after " + took
+}
+
+// result returns $ok if the job has succeeded, and its exception otherwise. It
+// must only be called when the job is done.
+func (j *job) result() types.Value {
+	switch err := ComposeExceptionsFromPipeline(dropBrokenPipes(j.errors)).(type) {
+	case nil:
+		return OK
+	case *Exception:
+		return err
+	default:
+		return &Exception{Cause: err}
+	}
+}
+
+func (j *job) isDone() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ types.Value = (*job)(nil)
+
+func (*job) Kind() string {
+	return "job"
+}
+
+func (j *job) Equal(rhs interface{}) bool {
+	return j == rhs
+}
+
+func (j *job) Hash() uint32 {
+	return hash.Pointer(unsafe.Pointer(j))
+}
+
+func (j *job) Repr(int) string {
+	return "<job " + j.describe() + ">"
+}
+
+// IndexOne gives the fields of the job: id (0 if it has never been in the job
+// table), src, pid (of the first external command started by the job, or 0),
+// pgid (of its process group, or 0 if it has none), state (running, stopped or
+// done) and status ($ok or the exception of the job once it is done, and an
+// empty string before that).
+func (j *job) IndexOne(idx types.Value) types.Value {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	switch idx {
+	case types.String("id"):
+		return types.String(strconv.Itoa(j.id))
+	case types.String("src"):
+		return types.String(j.src)
+	case types.String("pid"):
+		return types.String(strconv.Itoa(j.pid))
+	case types.String("pgid"):
+		return types.String(strconv.Itoa(j.pgid))
+	case types.String("state"):
+		switch {
+		case j.isDone():
+			return types.String("done")
+		case j.stopped:
+			return types.String("stopped")
+		default:
+			return types.String("running")
+		}
+	case types.String("status"):
+		if j.isDone() {
+			return j.result()
+		}
+		return types.String("")
+	}
+	throw(errNoSuchJobField)
+	panic("unreachable")
+}
+
+// describe returns the job number together with the source of the job.
+func (j *job) describe() string {
+	return "%" + strconv.Itoa(j.getID()) + " " + j.src
+}
+
+func (j *job) getID() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.id
+}
+
+// dropBrokenPipes returns a copy of the exceptions of the forms of a
+// pipeline, with the exceptions of upstream forms that are external commands
+// killed by SIGPIPE, or builtins stopped by errReaderGone, removed. They are
+// not errors; this happens when a downstream command stops reading its input
+// early.
+func dropBrokenPipes(errors []*Exception) []*Exception {
+	dropped := make([]*Exception, len(errors))
+	copy(dropped, errors)
+	for i := 0; i < len(dropped)-1; i++ {
+		if dropped[i] != nil && isBrokenPipe(dropped[i].Cause) {
+			dropped[i] = nil
+		}
+	}
+	return dropped
+}
+
+// HangUpJobs sends SIGHUP to the jobs in the job table, continuing the ones
+// that are stopped so that they can handle it. It is called when Elvish exits
+// because its terminal has been hung up; disowned jobs are left alone.
+func (ev *Evaler) HangUpJobs() {
+	for _, j := range ev.jobs.list() {
+		j.mutex.Lock()
+		j.sendSignal("HUP")
+		if j.stopped {
+			j.continueProcesses()
+		}
+		j.mutex.Unlock()
+	}
+}
+
+// jobTable keeps the jobs that are stopped or in the background.
+type jobTable struct {
+	mutex sync.Mutex
+	jobs  []*job
+}
+
+// add adds a job to the table, assigning it the smallest unused job number.
+// It returns false if the job is already in the table.
+func (jt *jobTable) add(j *job) bool {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+	for _, j2 := range jt.jobs {
+		if j2 == j {
+			return false
+		}
+	}
+	used := make(map[int]bool)
+	for _, j2 := range jt.jobs {
+		used[j2.getID()] = true
+	}
+	id := 1
+	for used[id] {
+		id++
+	}
+	j.mutex.Lock()
+	j.id = id
+	j.mutex.Unlock()
+	jt.jobs = append(jt.jobs, j)
+	return true
+}
+
+func (jt *jobTable) remove(j *job) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+	for i, j2 := range jt.jobs {
+		if j2 == j {
+			jt.jobs = append(jt.jobs[:i], jt.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// get finds a job by its number. The number 0 stands for the job that was most
+// recently added to the table.
+func (jt *jobTable) get(id int) *job {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+	if id == 0 && len(jt.jobs) > 0 {
+		return jt.jobs[len(jt.jobs)-1]
+	}
+	for _, j := range jt.jobs {
+		if j.getID() == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// list returns the jobs in the table, sorted by their numbers.
+func (jt *jobTable) list() []*job {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+	jobs := make([]*job, len(jt.jobs))
+	copy(jobs, jt.jobs)
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].getID() < jobs[k].getID() })
+	return jobs
+}
@@ -0,0 +1,27 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobDoneMessage(t *testing.T) {
+	started := time.Unix(1500000000, 0)
+	for _, test := range []struct {
+		errors []*Exception
+		took   time.Duration
+		want   string
+	}{
+		{[]*Exception{nil}, 1200 * time.Millisecond,
+			"job %1 make & finished after 1.2s"},
+		{[]*Exception{{Cause: errors.New("bad")}}, 75 * time.Second,
+			"job %1 make & failed after 1m15s: bad"},
+	} {
+		j := &job{id: 1, src: "make &", errors: test.errors,
+			started: started, finished: started.Add(test.took)}
+		if got := j.doneMessage(); got != test.want {
+			t.Errorf("doneMessage() => %q, want %q", got, test.want)
+		}
+	}
+}
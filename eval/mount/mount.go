@@ -0,0 +1,140 @@
+// Package mount implements the mount: module, which mounts virtual file
+// systems implemented by Elvish functions. Mounted file systems take part in
+// wildcard expansion and filename completion like directories on disk.
+package mount
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/glob"
+	"github.com/elves/elvish/util"
+)
+
+var (
+	errNotMounted = errors.New("nothing is mounted there")
+	errNoSymlinks = errors.New("virtual file systems have no symbolic links")
+	errBadEntry   = errors.New("entries of virtual directories should be strings")
+)
+
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"mount:add", add},
+	{"mount:remove", remove},
+	{"mount:points", points},
+}
+
+// add mounts a virtual file system on a path. The file system is implemented
+// by a function that lists directories: it is called with the path of a
+// directory relative to the mount point, "" for the mount point itself, and
+// outputs the names of the entries, with a trailing "/" for subdirectories.
+// It should throw an exception if the directory does not exist. A relative
+// mount point is taken relative to the current directory.
+func add(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var (
+		point string
+		list  eval.Callable
+	)
+	ec.ScanArgs(args, &point, &list)
+	eval.TakeNoOpt(opts)
+	point, err := filepath.Abs(point)
+	maybeThrow(err)
+
+	fs := &fnFS{ec.Evaler, point, list}
+	maybeThrow(ec.Mounts.Mount(point, fs))
+}
+
+// remove removes the virtual file system mounted on a path.
+func remove(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var point string
+	ec.ScanArgs(args, &point)
+	eval.TakeNoOpt(opts)
+	point, err := filepath.Abs(point)
+	maybeThrow(err)
+
+	if !ec.Mounts.Unmount(point) {
+		util.Throw(errNotMounted)
+	}
+}
+
+// points outputs the paths that virtual file systems are mounted on.
+func points(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	eval.TakeNoArg(args)
+	eval.TakeNoOpt(opts)
+
+	out := ec.OutputChan()
+	for _, point := range ec.Mounts.Points() {
+		out <- types.String(point)
+	}
+}
+
+// fnFS is a glob.FS whose directories are listed by an Elvish function.
+type fnFS struct {
+	ev    *eval.Evaler
+	point string
+	list  eval.Callable
+}
+
+func (fs *fnFS) Stat(p string) (os.FileInfo, error) {
+	if p == "" {
+		return glob.FileInfo{FileName: path.Base(fs.point), Dir: true}, nil
+	}
+	dir, name := path.Split(strings.TrimSuffix(p, "/"))
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.Name() == name {
+			return info, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (fs *fnFS) ReadDir(dir string) ([]os.FileInfo, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	ports := []*eval.Port{
+		{File: eval.DevNull, Chan: eval.ClosedChan},
+		{File: os.Stdout, Chan: eval.BlackholeChan},
+		{File: os.Stderr, Chan: eval.BlackholeChan},
+	}
+	ec := eval.NewTopFrame(fs.ev, eval.NewInternalSource("[mount "+fs.point+"]"), ports)
+	entries, err := ec.PCaptureOutput(fs.list, []types.Value{types.String(dir)}, eval.NoOpts)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, len(entries))
+	for i, entry := range entries {
+		name, ok := entry.(types.String)
+		if !ok {
+			return nil, errBadEntry
+		}
+		if strings.HasSuffix(string(name), "/") {
+			infos[i] = glob.FileInfo{FileName: string(name[:len(name)-1]), Dir: true}
+		} else {
+			infos[i] = glob.FileInfo{FileName: string(name), Dir: false}
+		}
+	}
+	return infos, nil
+}
+
+func (fs *fnFS) EvalSymlinks(string) (string, error) {
+	return "", errNoSymlinks
+}
+
+func maybeThrow(err error) {
+	if err != nil {
+		util.Throw(err)
+	}
+}
@@ -0,0 +1,40 @@
+package mount
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/util"
+)
+
+// A virtual file system with a directory x and a file y, and a file z in x.
+const mountV = `mount:add v [dir]{
+	if (eq $dir '') { put x/ y } elif (eq $dir x) { put z } else { fail 'no such directory' }
+}; `
+
+var tests = []eval.Test{
+	eval.NewTest(mountV + `eq (mount:points) (path-abs v)`).WantOutBools(true),
+	eval.NewTest(mountV+`put v/*`).WantOutStrings("v/x", "v/y"),
+	eval.NewTest(mountV+`put v/**`).WantOutStrings("v/x/z", "v/x", "v/y"),
+	eval.NewTest(mountV + `put v/*/z`).WantOutStrings("v/x/z"),
+	eval.NewTest(mountV + `put v/y/*`).WantAnyErr(),
+	eval.NewTest(mountV + `mount:remove v; put v/*`).WantAnyErr(),
+	eval.NewTest(`mount:add v [_]{ put [] }; put v/*`).WantAnyErr(),
+	eval.NewTest(`mount:remove v`).WantErr(errNotMounted),
+	// The mount point does not move with the current directory.
+	eval.NewTest(mountV+`mkdir d; cd d; put ../v/*; cd ..`).
+		WantOutStrings("../v/x", "../v/y"),
+	eval.NewTest(mountV + `cd d; try { put v/* } except { put err }; cd ..`).
+		WantOutStrings("err"),
+}
+
+func TestMount(t *testing.T) {
+	util.InTempDir(func(string) {
+		eval.RunTests(t, tests, func() *eval.Evaler {
+			ev := eval.NewEvaler()
+			ev.Builtin["mount"+eval.NsSuffix] = vartypes.NewRo(Ns())
+			return ev
+		})
+	})
+}
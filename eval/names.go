@@ -29,7 +29,7 @@ func (ev *evalerScopes) EachVariableInTop(ns string, f func(s string)) {
 		for name := range ev.Builtin {
 			f(name)
 		}
-	case "e":
+	case "e", "external":
 		EachExternal(func(cmd string) {
 			f(cmd + FnSuffix)
 		})
@@ -74,6 +74,7 @@ func (ev *evalerScopes) EachModInTop(f func(s string)) {
 func (ev *evalerScopes) EachNsInTop(f func(s string)) {
 	f("builtin")
 	f("e")
+	f("external")
 	f("E")
 	f("shared")
 	ev.EachModInTop(f)
@@ -97,7 +98,7 @@ func (ec *Frame) ResolveVar(ns, name string) vartypes.Variable {
 			return v
 		}
 		return ec.Builtin[name]
-	case "e":
+	case "e", "external":
 		if strings.HasSuffix(name, FnSuffix) {
 			return vartypes.NewRo(ExternalCmd{name[:len(name)-len(FnSuffix)]})
 		}
@@ -0,0 +1,59 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+// OpNode describes an op in the tree of ops that a chunk compiles to. Ops are
+// closures and cannot be inspected; the tree records which ops are compiled as
+// part of which, and the part of the source each op covers.
+type OpNode struct {
+	Kind     string    `json:"kind"`
+	Begin    int       `json:"begin"`
+	End      int       `json:"end"`
+	Source   string    `json:"source"`
+	Children []*OpNode `json:"children,omitempty"`
+}
+
+// CompileOpTree is like Compile, but also records the op tree.
+func (ev *Evaler) CompileOpTree(n *parse.Chunk, src *Source) (op Op, tree *OpNode, err error) {
+	root := &OpNode{}
+	cp := &compiler{ev.Builtin.static(), []staticNs{ev.Global.static()},
+		make(staticNs), 0, 0, src, []*OpNode{root}}
+	defer util.Catch(&err)
+	op = cp.chunkOp(n)
+	return op, root.Children[0], nil
+}
+
+// recordOp records an op that is about to be compiled when recording the op
+// tree, and returns a function to call after it has been compiled.
+func (cp *compiler) recordOp(kind string, n parse.Node) func() {
+	if cp.opStack == nil {
+		return func() {}
+	}
+	node := &OpNode{kind, n.Begin(), n.End(), n.SourceText(), nil}
+	parent := cp.opStack[len(cp.opStack)-1]
+	parent.Children = append(parent.Children, node)
+	cp.opStack = append(cp.opStack, node)
+	return func() {
+		cp.opStack = cp.opStack[:len(cp.opStack)-1]
+	}
+}
+
+// Pprint pretty prints the op tree, one op per line.
+func (node *OpNode) Pprint(wr io.Writer) {
+	node.pprint(wr, 0)
+}
+
+func (node *OpNode) pprint(wr io.Writer, indent int) {
+	fmt.Fprintf(wr, "%*s%s %s %d-%d\n", indent, "",
+		node.Kind, strconv.Quote(node.Source), node.Begin, node.End)
+	for _, child := range node.Children {
+		child.pprint(wr, indent+2)
+	}
+}
@@ -0,0 +1,41 @@
+package eval
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elves/elvish/parse"
+)
+
+func TestCompileOpTree(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	code := "echo [a]"
+	n, err := parse.Parse("[test]", code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tree, err := ev.CompileOpTree(n, NewInteractiveSource(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	tree.Pprint(&b)
+	want := `chunk "echo [a]" 0-8
+  pipeline "echo [a]" 0-8
+    form "echo [a]" 0-8
+      compound "[a]" 5-8
+        indexing "[a]" 5-8
+          compound "a" 6-7
+            indexing "a" 6-7
+`
+	if b.String() != want {
+		t.Errorf("got op tree:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	n, _ = parse.Parse("[test]", "echo $nonexistent")
+	_, _, err = ev.CompileOpTree(n, NewInteractiveSource("echo $nonexistent"))
+	if err == nil {
+		t.Errorf("CompileOpTree did not return compilation error")
+	}
+}
@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+// Hashing of external commands.
+//
+// Looking up an external command in $E:PATH costs a stat for each directory
+// searched. To avoid this, the paths found are remembered, keyed by command
+// name. A remembered path is dropped when it no longer refers to an
+// executable, and all of them are forgotten when $E:PATH changes or when
+// "rehash" is called. Names that are not found are not remembered, so that
+// commands installed later are found without calling "rehash".
+
+func init() {
+	addToBuiltinFns([]*BuiltinFn{
+		{"rehash", rehash},
+	})
+}
+
+type pathHash struct {
+	mutex sync.Mutex
+	// The value of $E:PATH when the paths were found.
+	searchPath string
+	paths      map[string]string
+}
+
+// lookPath is like exec.LookPath, but uses and fills the hash.
+func (h *pathHash) lookPath(name string) (string, error) {
+	if util.DontSearch(name) {
+		return exec.LookPath(name)
+	}
+
+	searchPath := os.Getenv("PATH")
+	h.mutex.Lock()
+	if h.paths == nil || h.searchPath != searchPath {
+		h.searchPath = searchPath
+		h.paths = make(map[string]string)
+	}
+	path, ok := h.paths[name]
+	h.mutex.Unlock()
+	if ok && util.IsExecutable(path) {
+		return path, nil
+	}
+
+	path, err := exec.LookPath(name)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err != nil {
+		delete(h.paths, name)
+	} else if h.searchPath == searchPath {
+		h.paths[name] = path
+	}
+	return path, err
+}
+
+func (h *pathHash) clear() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.paths = nil
+}
+
+// rehash forgets the paths of all external commands that have been found.
+func rehash(ec *Frame, args []types.Value, opts map[string]types.Value) {
+	TakeNoArg(args)
+	TakeNoOpt(opts)
+
+	ec.pathHash.clear()
+}
@@ -0,0 +1,77 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elves/elvish/util"
+)
+
+func TestPathHash(t *testing.T) {
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+
+	util.InTempDir(func(tmpDir string) {
+		dir1 := filepath.Join(tmpDir, "1")
+		dir2 := filepath.Join(tmpDir, "2")
+		mustMkdir(dir1)
+		mustMkdir(dir2)
+		mustWriteExecutable(filepath.Join(dir2, "cmd"))
+		os.Setenv("PATH", dir1+":"+dir2)
+
+		var h pathHash
+		wantLookPath(t, &h, "cmd", filepath.Join(dir2, "cmd"))
+
+		// A command that shadows the hashed one is only found after the hash
+		// is cleared.
+		mustWriteExecutable(filepath.Join(dir1, "cmd"))
+		wantLookPath(t, &h, "cmd", filepath.Join(dir2, "cmd"))
+		h.clear()
+		wantLookPath(t, &h, "cmd", filepath.Join(dir1, "cmd"))
+
+		// Changing $E:PATH invalidates the hash.
+		os.Setenv("PATH", dir2)
+		wantLookPath(t, &h, "cmd", filepath.Join(dir2, "cmd"))
+
+		// A hashed path that no longer exists is dropped.
+		os.Setenv("PATH", dir1+":"+dir2)
+		wantLookPath(t, &h, "cmd", filepath.Join(dir1, "cmd"))
+		os.Remove(filepath.Join(dir1, "cmd"))
+		wantLookPath(t, &h, "cmd", filepath.Join(dir2, "cmd"))
+
+		os.Remove(filepath.Join(dir2, "cmd"))
+		if path, err := h.lookPath("cmd"); err == nil {
+			t.Errorf("lookPath(cmd) -> %q, want error", path)
+		}
+	})
+}
+
+func TestRehash(t *testing.T) {
+	runTests(t, []Test{
+		NewTest("rehash").WantOutStrings(),
+		NewTest("rehash foo").WantAnyErr(),
+	})
+}
+
+func wantLookPath(t *testing.T, h *pathHash, name, want string) {
+	path, err := h.lookPath(name)
+	if path != want || err != nil {
+		t.Errorf("lookPath(%q) -> (%q, %v), want (%q, nil)", name, path, err, want)
+	}
+}
+
+func mustMkdir(dir string) {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		panic(err)
+	}
+}
+
+func mustWriteExecutable(path string) {
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		panic(err)
+	}
+}
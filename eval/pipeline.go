@@ -0,0 +1,251 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+// Pipelines built with Go code.
+//
+// Applications that embed Elvish, for instance to use it as a dataflow engine,
+// can build a pipeline out of Callables and external commands with a
+// PipelineBuilder, instead of generating Elvish code and parsing it. The
+// resulting Pipeline can then be run any number of times.
+
+var errEmptyPipeline = errors.New("pipeline has no stages")
+
+// Stage is a stage of a pipeline.
+type Stage struct {
+	Callable Callable
+	Args     []types.Value
+	Opts     map[string]types.Value
+	// Ports of the stage to replace, indexed by file descriptor. They are
+	// applied after the input and output of the stage are connected to its
+	// neighbors, and thus take precedence.
+	Ports map[int]*Port
+}
+
+// PipelineBuilder builds a Pipeline, one stage after another.
+type PipelineBuilder struct {
+	stages []Stage
+}
+
+// NewPipelineBuilder creates a new PipelineBuilder.
+func NewPipelineBuilder() *PipelineBuilder {
+	return &PipelineBuilder{}
+}
+
+// Call adds a stage that calls a Callable with the given arguments.
+func (b *PipelineBuilder) Call(c Callable, args ...types.Value) *PipelineBuilder {
+	return b.Stage(Stage{Callable: c, Args: args, Opts: NoOpts})
+}
+
+// External adds a stage that runs an external command with the given
+// arguments.
+func (b *PipelineBuilder) External(name string, args ...string) *PipelineBuilder {
+	vs := make([]types.Value, len(args))
+	for i, arg := range args {
+		vs[i] = types.String(arg)
+	}
+	return b.Call(ExternalCmd{name}, vs...)
+}
+
+// Stage adds a stage.
+func (b *PipelineBuilder) Stage(s Stage) *PipelineBuilder {
+	b.stages = append(b.stages, s)
+	return b
+}
+
+// Build checks the stages that have been added and builds a Pipeline.
+func (b *PipelineBuilder) Build() (*Pipeline, error) {
+	if len(b.stages) == 0 {
+		return nil, errEmptyPipeline
+	}
+	stages := make([]Stage, len(b.stages))
+	for i, s := range b.stages {
+		if s.Callable == nil {
+			return nil, fmt.Errorf("stage %d has no callable", i)
+		}
+		for fd := range s.Ports {
+			if fd < 0 {
+				return nil, fmt.Errorf("stage %d has port with bad fd %d", i, fd)
+			}
+		}
+		if s.Opts == nil {
+			s.Opts = NoOpts
+		}
+		stages[i] = s
+	}
+	return &Pipeline{stages}, nil
+}
+
+// Pipeline is a pipeline built with a PipelineBuilder. It may be run multiple
+// times, including concurrently.
+type Pipeline struct {
+	stages []Stage
+}
+
+// Run runs the pipeline with the given ports; the input of the first stage and
+// the output of the last stage come from ports[0] and ports[1]. When ports is
+// nil, the standard ports of the Evaler are used. Closing cancel interrupts
+// the pipeline like a timeout of with-timeout does: external commands are
+// killed, and builtins are interrupted like with ^C.
+//
+// Like a pipeline in Elvish code, Run returns nil if all stages succeeded, the
+// exception of the failing stage if only one failed, and a PipelineError
+// otherwise.
+func (p *Pipeline) Run(ev *Evaler, ports []*Port, cancel <-chan struct{}) error {
+	return p.run(ev, ports, cancel, nil)
+}
+
+// RunInstrumented is like Run, but also measures the stages like pipelines
+// are measured when $pipeline-instrument is true, and returns the
+// measurements of each stage.
+func (p *Pipeline) RunInstrumented(ev *Evaler, ports []*Port, cancel <-chan struct{}) ([]StageMetrics, error) {
+	meter := newPipeMeter(len(p.stages))
+	err := p.run(ev, ports, cancel, meter)
+	return meter.result(), err
+}
+
+func (p *Pipeline) run(ev *Evaler, ports []*Port, cancel <-chan struct{}, meter *pipeMeter) error {
+	if ports == nil {
+		ports = ev.ports[:]
+	}
+	ec := NewTopFrame(ev, NewInternalSource("[pipeline]"), ports)
+	defer ec.cleanups.run()
+
+	dl := &deadline{make(chan struct{}), make(chan struct{})}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cancel:
+			close(dl.expired)
+			close(dl.interrupts)
+		case <-done:
+		}
+	}()
+	ec.deadline = dl
+
+	wg, errors, err := startStages(ec, "[pipeline stage]", len(p.stages), meter,
+		func(i int, newEc *Frame) {
+			for fd, port := range p.stages[i].Ports {
+				newEc.growPorts(fd + 1)
+				// Close the replaced port, so that the next stage does not
+				// wait for input forever.
+				newEc.ports[fd].Close()
+				newEc.ports[fd] = port
+			}
+		},
+		func(i int, newEc *Frame) error {
+			s := p.stages[i]
+			return newEc.PCall(s.Callable, s.Args, s.Opts)
+		})
+	if err != nil {
+		return err
+	}
+	wg.Wait()
+	return ComposeExceptionsFromPipeline(dropBrokenPipes(errors))
+}
+
+// startStages runs the n stages of a pipeline, each on its own goroutine and
+// with its own Frame forked from ec. The output of each stage is connected to
+// the input of the next one, measured by meter if it is not nil. The Frame of
+// each stage is passed to prepare before any stage is started, and then to
+// run, which runs the stage.
+//
+// It returns a WaitGroup that is done when all the stages have finished, and
+// the slice that their exceptions are stored in. All the pipes are created
+// before any stage is started, so that no stage is left running if one cannot
+// be created.
+func startStages(ec *Frame, name string, n int, meter *pipeMeter,
+	prepare func(int, *Frame), run func(int, *Frame) error) (*sync.WaitGroup, []*Exception, error) {
+
+	outs, ins, err := makePipes(n-1, ec.valueBufferSize(), meter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	frames := make([]*Frame, n)
+	for i := range frames {
+		newEc := ec.fork(name)
+		if i > 0 {
+			newEc.ports[0] = ins[i-1]
+		}
+		if i < n-1 {
+			newEc.ports[1] = outs[i]
+		}
+		prepare(i, newEc)
+		frames[i] = newEc
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	errors := make([]*Exception, n)
+	for i, newEc := range frames {
+		i, newEc := i, newEc
+		go func() {
+			meter.begin(i)
+			err := run(i, newEc)
+			ClosePorts(newEc.ports)
+			meter.end(i)
+			if err != nil {
+				errors[i] = err.(*Exception)
+			}
+			wg.Done()
+			if i > 0 {
+				// Tell the previous stage that its value output is no longer
+				// read; it stops at its next call to Output. Values written
+				// to the channel directly are discarded, so that writers do
+				// not lock up in erroneous pipelines like "range 100 | cat".
+				in := ins[i-1]
+				in.readerGone.close()
+				for range in.Chan {
+				}
+			}
+		}()
+	}
+	return wg, errors, nil
+}
+
+// makePipes creates the ports of n pipes, measured by meter if it is not nil.
+// The output port of pipe i is outs[i] and its input port is ins[i]. If a pipe
+// cannot be created, the ones already created are closed.
+func makePipes(n, bufferSize int, meter *pipeMeter) (outs, ins []*Port, err error) {
+	outs = make([]*Port, n)
+	ins = make([]*Port, n)
+	for i := 0; i < n; i++ {
+		if meter != nil {
+			outs[i], ins[i], err = meter.pipe(i, bufferSize)
+		} else {
+			outs[i], ins[i], err = makePipe(bufferSize)
+		}
+		if err != nil {
+			ClosePorts(outs[:i])
+			ClosePorts(ins[:i])
+			return nil, nil, fmt.Errorf("failed to create pipe: %s", err)
+		}
+	}
+	return outs, ins, nil
+}
+
+// makePipe creates the ports of a pipe. Each of them consists of a (byte) pipe
+// pair and a channel.
+func makePipe(bufferSize int) (out, in *Port, err error) {
+	// os.Pipe sets O_CLOEXEC, which is what we want.
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan types.Value, bufferSize)
+	gone := newReaderGone()
+	out = &Port{File: writer, Chan: ch, CloseFile: true, CloseChan: true,
+		readerGone: gone}
+	in = &Port{File: reader, Chan: ch, CloseFile: true, CloseChan: false,
+		readerGone: gone}
+	return out, in, nil
+}
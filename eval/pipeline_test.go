@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestPipelineBuilder(t *testing.T) {
+	if _, err := NewPipelineBuilder().Build(); err != errEmptyPipeline {
+		t.Errorf("Build() without stages => %v, want %v", err, errEmptyPipeline)
+	}
+	if _, err := NewPipelineBuilder().Call(nil).Build(); err == nil {
+		t.Errorf("Build() with nil Callable => no error")
+	}
+}
+
+func TestPipelineRun(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	fn := func(name string) Fn { return ev.Builtin[name+FnSuffix].Get().(Fn) }
+	double := &BuiltinFn{"double", func(ec *Frame, args []types.Value, opts map[string]types.Value) {
+		out := ec.OutputChan()
+		for v := range ec.InputChan() {
+			out <- types.String(types.ToString(v) + types.ToString(v))
+		}
+	}}
+
+	// Values flow between Callables, and the output of the last stage goes to
+	// ports[1].
+	p, err := NewPipelineBuilder().
+		Call(fn("put"), types.String("a"), types.String("b")).
+		Call(double).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		ch := make(chan types.Value, 10)
+		ports := []*Port{DevNullClosedChan, {File: DevNull, Chan: ch}, DevNullClosedChan}
+		if err := p.Run(ev, ports, nil); err != nil {
+			t.Errorf("Run() => %v", err)
+		}
+		close(ch)
+		var got []types.Value
+		for v := range ch {
+			got = append(got, v)
+		}
+		want := []types.Value{types.String("aa"), types.String("bb")}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Run() outputs %v, want %v", got, want)
+		}
+	}
+
+	// Bytes flow between external commands, and ports can be wired explicitly.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = NewPipelineBuilder().
+		External("echo", "hello").
+		Stage(Stage{Callable: ExternalCmd{"tr"},
+			Args:  []types.Value{types.String("a-z"), types.String("A-Z")},
+			Ports: map[int]*Port{1: {File: w, Chan: BlackholeChan}}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Run(ev, []*Port{DevNullClosedChan, DevNullClosedChan, DevNullClosedChan}, nil)
+	w.Close()
+	if err != nil {
+		t.Errorf("Run() => %v", err)
+	}
+	if out, _ := ioutil.ReadAll(r); string(out) != "HELLO\n" {
+		t.Errorf("Run() writes %q, want %q", out, "HELLO\n")
+	}
+
+	// Pipelines can be measured.
+	p, _ = NewPipelineBuilder().
+		Call(fn("put"), types.String("a"), types.String("b")).
+		Call(fn("to-lines")).External("cat").Build()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	metrics, err := p.RunInstrumented(ev, []*Port{DevNullClosedChan, {File: devNull, Chan: BlackholeChan}, DevNullClosedChan}, nil)
+	if err != nil {
+		t.Errorf("RunInstrumented() => %v", err)
+	}
+	if len(metrics) != 3 || metrics[0].ValuesOut != 2 || metrics[1].ValuesIn != 2 ||
+		metrics[1].BytesOut != 4 || metrics[2].BytesIn != 4 {
+		t.Errorf("RunInstrumented() measures %+v", metrics)
+	}
+
+	// Failures are reported like for pipelines in Elvish code.
+	p, _ = NewPipelineBuilder().External("false").Build()
+	if err := p.Run(ev, nil, nil); err == nil {
+		t.Errorf("Run() with failing stage => no error")
+	}
+
+	// Closing the cancel channel stops the pipeline.
+	p, _ = NewPipelineBuilder().External("sleep", "10").Call(fn("all")).Build()
+	cancel := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(cancel) })
+	begin := time.Now()
+	if err := p.Run(ev, nil, cancel); err == nil {
+		t.Errorf("Run() that is canceled => no error")
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("Run() that is canceled took %v", d)
+	}
+}
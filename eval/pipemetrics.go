@@ -0,0 +1,235 @@
+package eval
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+// Instrumented pipelines.
+//
+// When $pipeline-instrument is true, each foreground pipeline with more than
+// one form measures how much data its forms pass to each other, and how long
+// they wait for each other. The measurements of the last such pipeline are
+// exposed as $pipemetrics, a list with one struct for each form.
+//
+// The measuring is done by relays that sit between adjacent forms, one for
+// values and one for bytes. The times that forms spend blocked are therefore
+// approximations: a form is taken to be blocked on sending while the relay
+// cannot pass its output on because the buffer of the next form is full, and
+// to be blocked on receiving while the relay waits for input from the
+// previous form with that buffer empty.
+
+// StageMetrics keeps the measurements of one stage of an instrumented
+// pipeline. The input of the first stage and the output of the last one are
+// not measured.
+type StageMetrics struct {
+	ValuesIn, ValuesOut int64
+	BytesIn, BytesOut   int64
+	// How long the stage was blocked on sending its output and on receiving
+	// its input, and how long it ran in total.
+	SendBlocked, RecvBlocked, Duration time.Duration
+}
+
+var stageMetricsDescriptor = types.NewStructDescriptor(
+	"values-in", "values-out", "bytes-in", "bytes-out",
+	"send-blocked", "recv-blocked", "duration")
+
+// toStruct converts the measurements to a struct. Times are in seconds.
+func (m *StageMetrics) toStruct() *types.Struct {
+	itos := func(i int64) types.Value { return types.String(strconv.FormatInt(i, 10)) }
+	return types.NewStruct(stageMetricsDescriptor, []types.Value{
+		itos(m.ValuesIn), itos(m.ValuesOut), itos(m.BytesIn), itos(m.BytesOut),
+		floatToString(m.SendBlocked.Seconds()),
+		floatToString(m.RecvBlocked.Seconds()),
+		floatToString(m.Duration.Seconds())})
+}
+
+// pipeMeter measures the stages of an instrumented pipeline. Its methods do
+// nothing when it is nil, so that uninstrumented pipelines can use the same
+// code.
+type pipeMeter struct {
+	stages []StageMetrics
+	begins []time.Time
+	links  []*pipeLink
+}
+
+func newPipeMeter(n int) *pipeMeter {
+	return &pipeMeter{
+		stages: make([]StageMetrics, n),
+		begins: make([]time.Time, n),
+		links:  make([]*pipeLink, n-1),
+	}
+}
+
+// pipe creates the ports that connect stage i to stage i+1, with relays that
+// measure what passes between them.
+func (m *pipeMeter) pipe(i, bufferSize int) (out, in *Port, err error) {
+	fromReader, fromWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	toReader, toWriter, err := os.Pipe()
+	if err != nil {
+		fromReader.Close()
+		fromWriter.Close()
+		return nil, nil, err
+	}
+	// The channel from the upstream stage is unbuffered, so that the relay
+	// sees every value as soon as it is sent. The buffer sits between the
+	// relay and the downstream stage instead.
+	fromCh := make(chan types.Value)
+	toCh := make(chan types.Value, bufferSize)
+
+	l := &pipeLink{}
+	l.wg.Add(2)
+	go l.relayValues(fromCh, toCh)
+	go l.relayBytes(fromReader, toWriter)
+	m.links[i] = l
+
+	gone := newReaderGone()
+	out = &Port{File: fromWriter, Chan: fromCh, CloseFile: true, CloseChan: true,
+		readerGone: gone}
+	in = &Port{File: toReader, Chan: toCh, CloseFile: true, CloseChan: false,
+		readerGone: gone}
+	return out, in, nil
+}
+
+// begin is called when stage i starts running.
+func (m *pipeMeter) begin(i int) {
+	if m != nil {
+		m.begins[i] = time.Now()
+	}
+}
+
+// end is called when stage i has finished.
+func (m *pipeMeter) end(i int) {
+	if m != nil {
+		m.stages[i].Duration = time.Since(m.begins[i])
+	}
+}
+
+// result waits for the relays to finish and returns the measurements. It must
+// only be called after all the stages have finished.
+func (m *pipeMeter) result() []StageMetrics {
+	if m == nil {
+		return nil
+	}
+	for i, l := range m.links {
+		if l == nil {
+			// Creating the pipe failed.
+			continue
+		}
+		l.wg.Wait()
+		up, down := &m.stages[i], &m.stages[i+1]
+		up.ValuesOut, down.ValuesIn = l.values.count, l.values.count
+		up.BytesOut, down.BytesIn = l.bytes.count, l.bytes.count
+		up.SendBlocked = l.values.sendBlocked + l.bytes.sendBlocked
+		down.RecvBlocked = l.values.recvBlocked + l.bytes.recvBlocked
+	}
+	return m.stages
+}
+
+// pipeLink relays the values and bytes between two adjacent stages.
+type pipeLink struct {
+	wg sync.WaitGroup
+	// Each relay updates its own counters.
+	values, bytes linkCounters
+}
+
+type linkCounters struct {
+	count                    int64
+	sendBlocked, recvBlocked time.Duration
+}
+
+func (l *pipeLink) relayValues(from <-chan types.Value, to chan<- types.Value) {
+	defer l.wg.Done()
+	defer close(to)
+	c := &l.values
+	for {
+		starving := len(to) == 0
+		begin := time.Now()
+		v, ok := <-from
+		if !ok {
+			return
+		}
+		if starving {
+			c.recvBlocked += time.Since(begin)
+		}
+		select {
+		case to <- v:
+		default:
+			begin = time.Now()
+			to <- v
+			c.sendBlocked += time.Since(begin)
+		}
+		c.count++
+	}
+}
+
+func (l *pipeLink) relayBytes(from, to *os.File) {
+	defer l.wg.Done()
+	defer from.Close()
+	defer to.Close()
+	c := &l.bytes
+	buf := make([]byte, 32*1024)
+	for {
+		begin := time.Now()
+		n, err := from.Read(buf)
+		// Reads that return nothing are not counted, so that stages that only
+		// pass values are not taken to be waiting for bytes all the time.
+		if n > 0 {
+			c.recvBlocked += time.Since(begin)
+			begin = time.Now()
+			_, werr := to.Write(buf[:n])
+			c.sendBlocked += time.Since(begin)
+			c.count += int64(n)
+			if werr != nil {
+				// The downstream stage has stopped reading; closing the
+				// reader lets the upstream stage know.
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// pipeMetrics keeps the measurements of the last instrumented pipeline, which
+// are exposed as $pipemetrics.
+type pipeMetrics struct {
+	mutex sync.RWMutex
+	list  types.Value
+}
+
+func (pm *pipeMetrics) variable() vartypes.Variable {
+	return vartypes.NewRoCallback(func() types.Value {
+		pm.mutex.RLock()
+		defer pm.mutex.RUnlock()
+		if pm.list == nil {
+			return types.MakeList()
+		}
+		return pm.list
+	})
+}
+
+func (pm *pipeMetrics) set(stages []StageMetrics) {
+	vs := make([]types.Value, len(stages))
+	for i := range stages {
+		vs[i] = stages[i].toStruct()
+	}
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.list = types.MakeList(vs...)
+}
+
+// pipelineInstrument returns the value of $pipeline-instrument, which
+// determines whether pipelines are instrumented.
+func (ev *Evaler) pipelineInstrument() bool {
+	return types.ToBool(ev.Builtin["pipeline-instrument"].Get())
+}
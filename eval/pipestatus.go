@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"sync"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+// pipeStatus keeps the results of the forms of the last foreground pipeline
+// that has more than one form, which are exposed as $pipestatus. Pipelines
+// with just one form do not update it, so that it can be inspected after a
+// pipeline that throws an exception, like in "try { a | b } except { };
+// put $pipestatus". Pipelines run by internal evaluations, like prompts,
+// hooks and event handlers, do not update it either.
+type pipeStatus struct {
+	mutex sync.RWMutex
+	list  types.Value
+}
+
+func (ps *pipeStatus) variable() vartypes.Variable {
+	return vartypes.NewRoCallback(func() types.Value {
+		ps.mutex.RLock()
+		defer ps.mutex.RUnlock()
+		if ps.list == nil {
+			return types.MakeList()
+		}
+		return ps.list
+	})
+}
+
+// set records the results of a pipeline, with nil results stored as $ok.
+func (ps *pipeStatus) set(excs []*Exception) {
+	vs := make([]types.Value, len(excs))
+	for i, exc := range excs {
+		if exc == nil {
+			vs[i] = OK
+		} else {
+			vs[i] = exc
+		}
+	}
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.list = types.MakeList(vs...)
+}
+
+// pipelineFailFast returns the value of $pipeline-fail-fast, which determines
+// whether the failure of one form of a pipeline cancels the other forms.
+func (ev *Evaler) pipelineFailFast() bool {
+	return types.ToBool(ev.Builtin["pipeline-fail-fast"].Get())
+}
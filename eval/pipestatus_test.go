@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestPipeStatus_NotUpdatedByInternalEvaluations(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	err := ev.SourceText(NewInteractiveSource(
+		"fn h { try { fail bad | nop } except { } }; nop | nop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.MakeList(OK, OK)
+	ev.OnEvent("foo", ev.Global["h"+FnSuffix].Get().(Fn))
+	ev.EmitEvent("foo")
+	if got := ev.Builtin["pipestatus"].Get(); !got.Equal(want) {
+		t.Errorf("$pipestatus is %s after an event handler, want %s",
+			got.Repr(types.NoPretty), want.Repr(types.NoPretty))
+	}
+
+	err = ev.SourceText(NewInteractiveSource("h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ev.Builtin["pipestatus"].Get(); got.Equal(want) {
+		t.Errorf("$pipestatus not updated by an interactive command")
+	}
+}
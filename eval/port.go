@@ -1,7 +1,9 @@
 package eval
 
 import (
+	"errors"
 	"os"
+	"sync"
 
 	"github.com/elves/elvish/eval/types"
 )
@@ -12,11 +14,14 @@ type Port struct {
 	Chan      chan types.Value
 	CloseFile bool
 	CloseChan bool
+	// Signals that the reader of Chan has stopped reading; nil if Chan is not
+	// connected to a reader that can stop early.
+	readerGone *readerGone
 }
 
 // Fork returns a copy of a Port with the Close* flags unset.
 func (p *Port) Fork() *Port {
-	return &Port{p.File, p.Chan, false, false}
+	return &Port{p.File, p.Chan, false, false, p.readerGone}
 }
 
 // Close closes a Port.
@@ -33,6 +38,48 @@ func (p *Port) Close() {
 	}
 }
 
+// closeEarly is called when the reader of an input Port stops reading before
+// the input ends. If the Port owns its byte band, it closes it, and tells the
+// writers of the channel band that no more values will be read.
+func (p *Port) closeEarly() {
+	if p.CloseFile {
+		p.File.Close()
+		p.CloseFile = false
+		p.readerGone.close()
+	}
+}
+
+// errReaderGone is thrown by Frame.Output when the reader of the value output
+// has stopped reading. Like external commands killed by SIGPIPE, forms that
+// stop because of it are not considered to have failed.
+var errReaderGone = errors.New("reader of value output is gone")
+
+// readerGone is closed by the reader of a channel when it stops reading, so
+// that writers can stop writing instead of blocking or filling the channel.
+type readerGone struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+func newReaderGone() *readerGone {
+	return &readerGone{ch: make(chan struct{})}
+}
+
+func (r *readerGone) close() {
+	if r != nil {
+		r.once.Do(func() { close(r.ch) })
+	}
+}
+
+// done returns a channel that is closed when the reader is gone, or nil if r
+// is nil.
+func (r *readerGone) done() <-chan struct{} {
+	if r == nil {
+		return nil
+	}
+	return r.ch
+}
+
 // ClosePorts closes a list of Ports.
 func ClosePorts(ports []*Port) {
 	for _, port := range ports {
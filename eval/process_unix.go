@@ -3,7 +3,10 @@
 package eval
 
 import (
+	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/elves/elvish/sys"
@@ -19,10 +22,144 @@ func unignoreTTOU() {
 	signal.Reset(syscall.SIGTTOU)
 }
 
+// catchTSTP keeps Elvish from being stopped by SIGTSTP, which is sent when ^Z
+// is pressed while Elvish itself is in the foreground. Unlike ignoring the
+// signal, this does not make external commands ignore it too. It returns a
+// function that undoes the effect.
+func catchTSTP() func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTSTP)
+	go func() {
+		for range ch {
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(ch)
+	}
+}
+
 func putSelfInFg() error {
 	return sys.Tcsetpgrp(0, syscall.Getpgrp())
 }
 
-func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
-	return &syscall.SysProcAttr{Setpgid: bg}
+// jobControlAvailable returns whether the standard input is a terminal, which
+// is needed for job control.
+func jobControlAvailable() bool {
+	return sys.IsATTY(os.Stdin)
+}
+
+// giveTerminal puts a process group in the foreground of the terminal.
+func giveTerminal(pgid int) error {
+	return sys.Tcsetpgrp(0, pgid)
+}
+
+// saveTTY saves the state of the terminal, returning a function that restores
+// it.
+func saveTTY() func() {
+	term, err := sys.NewTermiosFromFd(0)
+	if err != nil {
+		return func() {}
+	}
+	return func() { term.ApplyToFd(0) }
+}
+
+// makeSysProcAttr returns the attributes for starting a process. When group is
+// true, the process is put in the given process group, or a new one when pgid
+// is 0.
+func makeSysProcAttr(group bool, pgid int) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: group, Pgid: pgid}
+}
+
+// makeDetachedSysProcAttr returns the attributes for starting a process in a
+// session of its own, without a controlling terminal.
+func makeDetachedSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// waitProcess waits for a process to exit. When the process is part of a job,
+// the job is notified when the process gets stopped.
+func waitProcess(proc *os.Process, j *job) (syscall.WaitStatus, error) {
+	options := 0
+	if j != nil {
+		options = syscall.WUNTRACED
+	}
+	for {
+		var ws syscall.WaitStatus
+		_, err := syscall.Wait4(proc.Pid, &ws, options, nil)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return ws, err
+		}
+		if !ws.Stopped() {
+			// The process has been waited for, so signalling it would affect
+			// whatever process reuses the pid.
+			proc.Release()
+			return ws, nil
+		}
+		if ws.StopSignal() == syscall.SIGTTIN && j.isForeground() {
+			// The process tried to read from the terminal before its process
+			// group was given the terminal.
+			syscall.Kill(proc.Pid, syscall.SIGCONT)
+			continue
+		}
+		j.notifyStopped()
+	}
+}
+
+// continueProcesses sends SIGCONT to the processes of a job. The caller must
+// hold the mutex of the job.
+func (j *job) continueProcesses() error {
+	return j.signal(syscall.SIGCONT)
+}
+
+var signalNames = map[string]syscall.Signal{
+	"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL, "TERM": syscall.SIGTERM, "CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP, "TSTP": syscall.SIGTSTP,
+	"USR1": syscall.SIGUSR1, "USR2": syscall.SIGUSR2, "WINCH": syscall.SIGWINCH,
+}
+
+// signalName returns the name of a signal without the "SIG" prefix, or "" if
+// it has none.
+func signalName(sig os.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return name
+		}
+	}
+	return ""
+}
+
+// sendSignal sends a signal, given by its name with or without the "SIG"
+// prefix or by its number, to the processes of a job. The caller must hold
+// the mutex of the job.
+func (j *job) sendSignal(name string) error {
+	sig, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 0 {
+			return errBadSignal
+		}
+		sig = syscall.Signal(n)
+	}
+	return j.signal(sig)
+}
+
+// signal sends a signal to the process group of a job, or to each of its
+// processes when it has no process group of its own.
+func (j *job) signal(sig syscall.Signal) error {
+	if j.pgid != 0 {
+		return syscall.Kill(-j.pgid, sig)
+	}
+	if len(j.pids) == 0 {
+		return errJobNoProcess
+	}
+	for pid := range j.pids {
+		if err := syscall.Kill(pid, sig); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,18 +1,60 @@
 package eval
 
-import "syscall"
+import (
+	"errors"
+	"os"
+	"syscall"
+)
 
-// Process control functions in Windows. These are all NOPs.
-func ignoreTTOU()        {}
-func unignoreTTOU()      {}
-func putSelfInFg() error { return nil }
+// Process control functions in Windows. These are mostly NOPs, since there is
+// no job control.
+func ignoreTTOU()                 {}
+func unignoreTTOU()               {}
+func catchTSTP() func()           { return func() {} }
+func putSelfInFg() error          { return nil }
+func jobControlAvailable() bool   { return false }
+func giveTerminal(pgid int) error { return nil }
+func saveTTY() func()             { return func() {} }
+
+func (j *job) continueProcesses() error { return nil }
+
+func (j *job) sendSignal(string) error {
+	return errors.New("sending signals is not supported on Windows")
+}
 
 const DETACHED_PROCESS = 0x00000008
 
-func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
+// makeSysProcAttr returns the attributes for starting a process. Processes
+// that would be put in a process group of their own on Unix are detached from
+// the console.
+func makeSysProcAttr(group bool, pgid int) *syscall.SysProcAttr {
 	flags := uint32(0)
-	if bg {
+	if group {
 		flags |= DETACHED_PROCESS
 	}
 	return &syscall.SysProcAttr{CreationFlags: flags}
 }
+
+// signalName returns the name of a signal without the "SIG" prefix, or "" if
+// it has none. Interrupts are the only signals on Windows.
+func signalName(sig os.Signal) string {
+	if sig == os.Interrupt {
+		return "INT"
+	}
+	return ""
+}
+
+// makeDetachedSysProcAttr returns the attributes for starting a process
+// detached from the console.
+func makeDetachedSysProcAttr() *syscall.SysProcAttr {
+	return makeSysProcAttr(true, 0)
+}
+
+// waitProcess waits for a process to exit.
+func waitProcess(proc *os.Process, j *job) (syscall.WaitStatus, error) {
+	state, err := proc.Wait()
+	if err != nil {
+		return syscall.WaitStatus{}, err
+	}
+	return state.Sys().(syscall.WaitStatus), nil
+}
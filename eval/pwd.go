@@ -8,9 +8,10 @@ import (
 )
 
 // PwdVariable is a variable whose value always reflects the current working
-// directory. Setting it changes the current working directory.
+// directory. Setting it changes the current working directory, calling the
+// chdir hooks of the Evaler.
 type PwdVariable struct {
-	store AddDirer
+	ev *Evaler
 }
 
 var _ vartypes.Variable = PwdVariable{}
@@ -26,5 +27,5 @@ func (pwd PwdVariable) Set(v types.Value) error {
 	if !ok {
 		return ErrPathMustBeString
 	}
-	return Chdir(string(path), pwd.store)
+	return pwd.ev.Chdir(string(path))
 }
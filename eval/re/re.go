@@ -32,8 +32,8 @@ func match(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 		argSource  types.String
 		optPOSIX   types.Bool
 	)
-	eval.ScanArgs(args, &argPattern, &argSource)
-	eval.ScanOpts(opts, eval.OptToScan{"posix", &optPOSIX, types.Bool(false)})
+	ec.ScanArgs(args, &argPattern, &argSource)
+	ec.ScanOpts(opts, eval.OptToScan{"posix", &optPOSIX, types.Bool(false)})
 
 	pattern := makePattern(argPattern, optPOSIX, types.Bool(false))
 	matched := pattern.MatchString(string(argSource))
@@ -49,8 +49,8 @@ func find(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 		optLongest types.Bool
 		optMax     int
 	)
-	eval.ScanArgs(args, &argPattern, &argSource)
-	eval.ScanOpts(opts,
+	ec.ScanArgs(args, &argPattern, &argSource)
+	ec.ScanOpts(opts,
 		eval.OptToScan{"posix", &optPOSIX, types.Bool(false)},
 		eval.OptToScan{"longest", &optLongest, types.Bool(false)},
 		eval.OptToScan{"max", &optMax, types.String("-1")})
@@ -86,8 +86,8 @@ func replace(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 		optLongest types.Bool
 		optLiteral types.Bool
 	)
-	eval.ScanArgs(args, &argPattern, &argRepl, &argSource)
-	eval.ScanOpts(opts,
+	ec.ScanArgs(args, &argPattern, &argRepl, &argSource)
+	ec.ScanOpts(opts,
 		eval.OptToScan{"posix", &optPOSIX, types.Bool(false)},
 		eval.OptToScan{"longest", &optLongest, types.Bool(false)},
 		eval.OptToScan{"literal", &optLiteral, types.Bool(false)})
@@ -138,8 +138,8 @@ func split(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
 		optLongest types.Bool
 		optMax     int
 	)
-	eval.ScanArgs(args, &argPattern, &argSource)
-	eval.ScanOpts(opts,
+	ec.ScanArgs(args, &argPattern, &argSource)
+	ec.ScanOpts(opts,
 		eval.OptToScan{"posix", &optPOSIX, types.Bool(false)},
 		eval.OptToScan{"longest", &optLongest, types.Bool(false)},
 		eval.OptToScan{"max", &optMax, types.String("-1")})
@@ -11,23 +11,45 @@ import (
 )
 
 // ScanArgs scans arguments into pointers to supported argument types. If the
-// arguments cannot be scanned, an error is thrown.
+// arguments cannot be scanned, an error is thrown. Numbers are scanned
+// leniently; builtins scan their arguments with the methods of Frame, which
+// honor $strict-numbers.
 func ScanArgs(src []types.Value, dstPtrs ...interface{}) {
+	scanArgs(src, dstPtrs, false)
+}
+
+// ScanArgs is like the ScanArgs function, but int and float64 arguments are
+// scanned strictly when $strict-numbers is true.
+func (ec *Frame) ScanArgs(src []types.Value, dstPtrs ...interface{}) {
+	scanArgs(src, dstPtrs, ec.strictNumbers())
+}
+
+func scanArgs(src []types.Value, dstPtrs []interface{}, strict bool) {
 	if len(src) != len(dstPtrs) {
 		throwf("arity mistmatch: want %d arguments, got %d", len(dstPtrs), len(src))
 	}
 	for i, value := range src {
-		scanValueToGo(value, dstPtrs[i])
+		scanValueToGo(value, dstPtrs[i], strict)
 	}
 }
 
 // ScanArgsVariadic is like ScanArgs, but the last element of args should be a
 // pointer to a slice, and the rest of arguments will be scanned into it.
 func ScanArgsVariadic(src []types.Value, dstPtrs ...interface{}) {
+	scanArgsVariadic(src, dstPtrs, false)
+}
+
+// ScanArgsVariadic is like the ScanArgsVariadic function, but honors
+// $strict-numbers.
+func (ec *Frame) ScanArgsVariadic(src []types.Value, dstPtrs ...interface{}) {
+	scanArgsVariadic(src, dstPtrs, ec.strictNumbers())
+}
+
+func scanArgsVariadic(src []types.Value, dstPtrs []interface{}, strict bool) {
 	if len(src) < len(dstPtrs)-1 {
 		throwf("arity mistmatch: want at least %d arguments, got %d", len(dstPtrs)-1, len(src))
 	}
-	ScanArgs(src[:len(dstPtrs)-1], dstPtrs[:len(dstPtrs)-1]...)
+	scanArgs(src[:len(dstPtrs)-1], dstPtrs[:len(dstPtrs)-1], strict)
 
 	// Scan the rest of arguments into a slice.
 	rest := src[len(dstPtrs)-1:]
@@ -37,7 +59,7 @@ func ScanArgsVariadic(src []types.Value, dstPtrs ...interface{}) {
 	}
 	scanned := reflect.MakeSlice(restDst.Elem().Type(), len(rest), len(rest))
 	for i, value := range rest {
-		scanValueToGo(value, scanned.Index(i).Addr().Interface())
+		scanValueToGo(value, scanned.Index(i).Addr().Interface(), strict)
 	}
 	reflect.Indirect(restDst).Set(scanned)
 }
@@ -47,23 +69,30 @@ func ScanArgsVariadic(src []types.Value, dstPtrs ...interface{}) {
 // return value is a function that iterates the iterable value if it exists, or
 // the input otherwise.
 func ScanArgsOptionalInput(ec *Frame, src []types.Value, dstArgs ...interface{}) func(func(types.Value)) {
+	iterate := ScanArgsOptionalInputUntil(ec, src, dstArgs...)
+	return func(f func(types.Value)) {
+		iterate(func(v types.Value) bool {
+			f(v)
+			return true
+		})
+	}
+}
+
+// ScanArgsOptionalInputUntil is like ScanArgsOptionalInput, but the returned
+// function stops iterating as soon as the passed function returns false.
+func ScanArgsOptionalInputUntil(ec *Frame, src []types.Value, dstArgs ...interface{}) func(func(types.Value) bool) {
 	switch len(src) {
 	case len(dstArgs):
-		ScanArgs(src, dstArgs...)
-		return ec.IterateInputs
+		ec.ScanArgs(src, dstArgs...)
+		return ec.IterateInputsUntil
 	case len(dstArgs) + 1:
-		ScanArgs(src[:len(dstArgs)], dstArgs...)
+		ec.ScanArgs(src[:len(dstArgs)], dstArgs...)
 		value := src[len(dstArgs)]
 		iterable, ok := value.(types.Iterator)
 		if !ok {
 			throwf("need iterable argument, got %s", value.Kind())
 		}
-		return func(f func(types.Value)) {
-			iterable.Iterate(func(v types.Value) bool {
-				f(v)
-				return true
-			})
-		}
+		return iterable.Iterate
 	default:
 		throwf("arity mistmatch: want %d or %d arguments, got %d", len(dstArgs), len(dstArgs)+1, len(src))
 		return nil
@@ -80,6 +109,15 @@ type OptToScan struct {
 
 // ScanOpts scans options from a map.
 func ScanOpts(m map[string]types.Value, opts ...OptToScan) {
+	scanOpts(m, opts, false)
+}
+
+// ScanOpts is like the ScanOpts function, but honors $strict-numbers.
+func (ec *Frame) ScanOpts(m map[string]types.Value, opts ...OptToScan) {
+	scanOpts(m, opts, ec.strictNumbers())
+}
+
+func scanOpts(m map[string]types.Value, opts []OptToScan, strict bool) {
 	scanned := make(map[string]bool)
 	for _, opt := range opts {
 		a := opt.Ptr
@@ -87,7 +125,7 @@ func ScanOpts(m map[string]types.Value, opts ...OptToScan) {
 		if !ok {
 			value = opt.Default
 		}
-		scanValueToGo(value, a)
+		scanValueToGo(value, a, strict)
 		scanned[opt.Name] = true
 	}
 	for key := range m {
@@ -102,6 +140,16 @@ func ScanOpts(m map[string]types.Value, opts ...OptToScan) {
 // named FieldName corresponds to the option named field-name, unless the field
 // has a explicit "name" tag.
 func ScanOptsToStruct(m map[string]types.Value, structPtr interface{}) {
+	scanOptsToStruct(m, structPtr, false)
+}
+
+// ScanOptsToStruct is like the ScanOptsToStruct function, but honors
+// $strict-numbers.
+func (ec *Frame) ScanOptsToStruct(m map[string]types.Value, structPtr interface{}) {
+	scanOptsToStruct(m, structPtr, ec.strictNumbers())
+}
+
+func scanOptsToStruct(m map[string]types.Value, structPtr interface{}, strict bool) {
 	ptrValue := reflect.ValueOf(structPtr)
 	if ptrValue.Kind() != reflect.Ptr || ptrValue.Elem().Kind() != reflect.Struct {
 		throwf("internal bug: need struct ptr for ScanOptsToStruct, got %T", structPtr)
@@ -129,6 +177,6 @@ func ScanOptsToStruct(m map[string]types.Value, structPtr interface{}) {
 		if !ok {
 			throwf("unknown option %s", parse.Quote(k))
 		}
-		scanValueToGo(v, struc.Field(fieldIdx).Addr().Interface())
+		scanValueToGo(v, struc.Field(fieldIdx).Addr().Interface(), strict)
 	}
 }
@@ -200,7 +200,7 @@ var scanArgTestCases = []struct {
 
 func TestScanArg(t *testing.T) {
 	for _, tc := range scanArgTestCases {
-		scanValueToGo(tc.source, tc.destPtr)
+		scanValueToGo(tc.source, tc.destPtr, false)
 		if !equals(indirect(tc.destPtr), tc.want) {
 			t.Errorf("scanArg(%s) got %q, want %v", tc.source,
 				indirect(tc.destPtr), tc.want)
@@ -0,0 +1,196 @@
+package semver
+
+import (
+	"errors"
+	"strings"
+)
+
+// A constraint is a disjunction of ranges separated by "||". Each range is a
+// conjunction of comparators separated by spaces or commas, like
+// ">=1.2.0 <2.0.0". A comparator is a version preceded by an operator:
+//
+//   =, >, >=, <, <=, !=   compare with the version
+//   ~                     allow patch-level changes; ~1.2.3 is >=1.2.3 <1.3.0
+//   ^                     allow changes that do not modify the leftmost
+//                         non-zero part; ^1.2.3 is >=1.2.3 <2.0.0, and
+//                         ^0.2.3 is >=0.2.3 <0.3.0
+//
+// The operator defaults to "=". Trailing parts of the version may be omitted
+// or be "x" or "*", in which case they match anything: "1.2" and "1.2.x" are
+// both >=1.2.0 <1.3.0, and "*" matches all versions.
+
+var errBadConstraint = errors.New("bad constraint")
+
+type constraint [][]comparator
+
+// comparator matches versions v for which v.compare(ver) is one of the
+// allowed results.
+type comparator struct {
+	ver     *version
+	allowed [3]bool // Indexed by compare result + 1.
+}
+
+func (c comparator) match(v *version) bool {
+	return c.allowed[v.compare(c.ver)+1]
+}
+
+var (
+	lt = [3]bool{true, false, false}
+	le = [3]bool{true, true, false}
+	eq = [3]bool{false, true, false}
+	ne = [3]bool{true, false, true}
+	ge = [3]bool{false, true, true}
+	gt = [3]bool{false, false, true}
+)
+
+func parseConstraint(s string) (constraint, error) {
+	var cons constraint
+	for _, rangeSrc := range strings.Split(s, "||") {
+		fields := strings.FieldsFunc(rangeSrc, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		if len(fields) == 0 {
+			return nil, errBadConstraint
+		}
+		// Wildcards expand to no comparators, so comps can be empty.
+		comps := []comparator{}
+		for _, field := range fields {
+			cs, err := parseComparator(field)
+			if err != nil {
+				return nil, err
+			}
+			comps = append(comps, cs...)
+		}
+		cons = append(cons, comps)
+	}
+	return cons, nil
+}
+
+// parseComparator parses one comparator, which can expand to zero (for
+// wildcards), one or two comparators.
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			s = s[len(prefix):]
+			break
+		}
+	}
+	low, n, err := parsePartial(s)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		// A wildcard.
+		switch op {
+		case "", "=", ">=", "<=", "~", "^":
+			return nil, nil
+		default:
+			return []comparator{{low, [3]bool{}}}, nil
+		}
+	}
+
+	if n == 3 {
+		switch op {
+		case "", "=":
+			return []comparator{{low, eq}}, nil
+		case "!=":
+			return []comparator{{low, ne}}, nil
+		case ">":
+			return []comparator{{low, gt}}, nil
+		case ">=":
+			return []comparator{{low, ge}}, nil
+		case "<":
+			return []comparator{{low, lt}}, nil
+		case "<=":
+			return []comparator{{low, le}}, nil
+		}
+	}
+
+	// Compute the lowest version that is excluded from the range.
+	high := &version{major: low.major, minor: low.minor, patch: low.patch}
+	switch {
+	case op == "^" && low.major > 0, n == 1:
+		high.major++
+		high.minor, high.patch = 0, 0
+	case op == "^" && low.minor > 0, n == 2, op == "~":
+		high.minor++
+		high.patch = 0
+	default:
+		high.patch++
+	}
+	// The excluded versions include the prereleases of high.
+	high.prerelease = []string{"0"}
+
+	switch op {
+	case "", "=", "~", "^":
+		return []comparator{{low, ge}, {high, lt}}, nil
+	case "!=":
+		return nil, errBadConstraint
+	case ">":
+		return []comparator{{high, ge}}, nil
+	case ">=":
+		return []comparator{{low, ge}}, nil
+	case "<":
+		return []comparator{{low, lt}}, nil
+	case "<=":
+		return []comparator{{high, lt}}, nil
+	}
+	return nil, errBadConstraint
+}
+
+// parsePartial parses a version whose trailing parts may be omitted or be
+// wildcards, returning the lowest matching version and the number of parts
+// that are given.
+func parsePartial(s string) (*version, int, error) {
+	if s == "" {
+		return nil, 0, errBadConstraint
+	}
+	if strings.ContainsAny(s, "-+") {
+		v, err := parseVersion(s)
+		return v, 3, err
+	}
+	s = strings.TrimPrefix(s, "v")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return nil, 0, errBadConstraint
+	}
+	v := &version{}
+	dsts := []*uint64{&v.major, &v.minor, &v.patch}
+	n := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		num, err := parseNumber(part)
+		if err != nil {
+			return nil, 0, errBadConstraint
+		}
+		*dsts[i] = num
+		n++
+	}
+	// Only wildcards can follow a wildcard.
+	for _, part := range parts[n:] {
+		if part != "x" && part != "X" && part != "*" {
+			return nil, 0, errBadConstraint
+		}
+	}
+	return v, n, nil
+}
+
+func (cons constraint) match(v *version) bool {
+	for _, comps := range cons {
+		ok := true
+		for _, c := range comps {
+			if !c.match(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
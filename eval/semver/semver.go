@@ -0,0 +1,106 @@
+// Package semver implements the semver: module for parsing and comparing
+// semantic versions.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"parse", parseFn},
+	{"valid", valid},
+	{"cmp", cmp},
+	{"less-than", lessThan},
+	{"satisfies", satisfies},
+}
+
+var versionDescriptor = types.NewStructDescriptor(
+	"major", "minor", "patch", "prerelease", "build")
+
+func scanVersion(s types.String) *version {
+	v, err := parseVersion(string(s))
+	if err != nil {
+		throwf("%v: %s", err, parse.Quote(string(s)))
+	}
+	return v
+}
+
+// parseFn outputs the parts of a version as a struct with the fields major,
+// minor, patch, prerelease and build. The prerelease identifiers are joined
+// with dots.
+func parseFn(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	v := scanVersion(s)
+	ec.OutputChan() <- types.NewStruct(versionDescriptor, []types.Value{
+		types.String(strconv.FormatUint(v.major, 10)),
+		types.String(strconv.FormatUint(v.minor, 10)),
+		types.String(strconv.FormatUint(v.patch, 10)),
+		types.String(strings.Join(v.prerelease, ".")),
+		types.String(v.build),
+	})
+}
+
+// valid outputs whether a string is a valid version.
+func valid(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	_, err := parseVersion(string(s))
+	ec.OutputChan() <- types.Bool(err == nil)
+}
+
+// cmp outputs -1, 0 or 1 when the first version has lower, the same or higher
+// precedence than the second.
+func cmp(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var a, b types.String
+	ec.ScanArgs(args, &a, &b)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.String(strconv.Itoa(scanVersion(a).compare(scanVersion(b))))
+}
+
+// lessThan outputs whether the first version has lower precedence than the
+// second. It can be used to sort versions, as in
+// "order &less-than=$semver:less-than~".
+func lessThan(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var a, b types.String
+	ec.ScanArgs(args, &a, &b)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.Bool(scanVersion(a).compare(scanVersion(b)) < 0)
+}
+
+// satisfies outputs whether a version satisfies a constraint, like
+// "^1.2 || >=2.1.0 <3".
+func satisfies(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var c, s types.String
+	ec.ScanArgs(args, &c, &s)
+	eval.TakeNoOpt(opts)
+
+	cons, err := parseConstraint(string(c))
+	if err != nil {
+		throwf("%v: %s", err, parse.Quote(string(c)))
+	}
+	ec.OutputChan() <- types.Bool(cons.match(scanVersion(s)))
+}
+
+func throwf(format string, args ...interface{}) {
+	util.Throw(fmt.Errorf(format, args...))
+}
@@ -0,0 +1,56 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`put (semver:parse v1.2.3-rc.1+build.5)[major minor patch prerelease build]`).
+		WantOutStrings("1", "2", "3", "rc.1", "build.5"),
+	eval.NewTest(`semver:parse 1.2`).WantAnyErr(),
+	eval.NewTest(`semver:parse 01.2.3`).WantAnyErr(),
+
+	eval.NewTest(`semver:valid 1.0.0-alpha`).WantOutBools(true),
+	eval.NewTest(`semver:valid 1.0.0-01`).WantOutBools(false),
+
+	eval.NewTest(`semver:cmp 1.10.0 1.9.0`).WantOutStrings("1"),
+	eval.NewTest(`semver:cmp 1.0.0+a 1.0.0+b`).WantOutStrings("0"),
+	eval.NewTest(`semver:cmp 1.0.0-rc.1 1.0.0`).WantOutStrings("-1"),
+	eval.NewTest(`semver:cmp 1.0.0-alpha.10 1.0.0-alpha.9`).WantOutStrings("1"),
+	eval.NewTest(`semver:cmp 1.0.0-alpha.1 1.0.0-alpha`).WantOutStrings("1"),
+	eval.NewTest(`semver:cmp 1.0.0-1 1.0.0-alpha`).WantOutStrings("-1"),
+
+	eval.NewTest(`put 1.10.0 1.2.0 1.2.0-beta 0.9.1 | order &less-than=$semver:less-than~`).
+		WantOutStrings("0.9.1", "1.2.0-beta", "1.2.0", "1.10.0"),
+
+	eval.NewTest(`semver:satisfies '>=1.2.0 <2' 1.9.9`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '>=1.2.0, <2' 2.0.0`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '<2' 2.0.0-rc.1`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '^1.2.3' 1.9.0`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '^1.2.3' 2.0.0-rc.1`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '^0.2.3' 0.3.0`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '^0.0.3' 0.0.4`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '~1.2.3' 1.2.9`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '~1.2.3' 1.3.0`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies 1.2.x 1.2.7`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies 1 1.9.0`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '>1.2' 1.2.9`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '<=1.2' 1.2.9`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '!=1.2.3' 1.2.3`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '*' 3.1.4`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '<1 || >=2' 1.5.0`).WantOutBools(false),
+	eval.NewTest(`semver:satisfies '<1 || >=2' 2.5.0`).WantOutBools(true),
+	eval.NewTest(`semver:satisfies '1.x.3' 1.2.3`).WantAnyErr(),
+	eval.NewTest(`semver:satisfies '' 1.2.3`).WantAnyErr(),
+}
+
+func TestSemver(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["semver"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
@@ -0,0 +1,151 @@
+package semver
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errBadVersion = errors.New("bad version")
+
+// version is a semantic version, as specified in https://semver.org.
+type version struct {
+	major, minor, patch uint64
+	prerelease          []string
+	build               string
+}
+
+// parseVersion parses a semantic version. A leading "v" is allowed, as is
+// common in the names of tags.
+func parseVersion(s string) (*version, error) {
+	s = strings.TrimPrefix(s, "v")
+	v := &version{}
+	if i := strings.IndexByte(s, '+'); i != -1 {
+		v.build = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.build, false) {
+			return nil, errBadVersion
+		}
+	}
+	if i := strings.IndexByte(s, '-'); i != -1 {
+		pre := s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(pre, true) {
+			return nil, errBadVersion
+		}
+		v.prerelease = strings.Split(pre, ".")
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, errBadVersion
+	}
+	for i, p := range []*uint64{&v.major, &v.minor, &v.patch} {
+		n, err := parseNumber(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		*p = n
+	}
+	return v, nil
+}
+
+// parseNumber parses a numeric part of a version, which must not have leading
+// zeros.
+func parseNumber(s string) (uint64, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, errBadVersion
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errBadVersion
+	}
+	return n, nil
+}
+
+// validIdentifiers checks dot-separated prerelease or build identifiers.
+// Numeric prerelease identifiers must not have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' ||
+				'A' <= r && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// compare compares two versions by their precedence, returning -1, 0 or 1.
+// Build metadata is ignored, and a prerelease version has lower precedence
+// than the corresponding normal version.
+func (v *version) compare(w *version) int {
+	if c := compareUint(v.major, w.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, w.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.patch, w.patch); c != 0 {
+		return c
+	}
+	switch {
+	case len(v.prerelease) == 0 && len(w.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(w.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.prerelease) && i < len(w.prerelease); i++ {
+		if c := compareIdentifiers(v.prerelease[i], w.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.prerelease)), uint64(len(w.prerelease)))
+}
+
+// compareIdentifiers compares prerelease identifiers. Numeric identifiers are
+// compared numerically, and have lower precedence than alphanumeric ones.
+func compareIdentifiers(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,110 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+// Signal hooks.
+//
+// $signal-hooks maps the names of the signals that Elvish code can react to
+// to lists of functions, which are called without arguments when Elvish
+// receives the signal. The hooks run in addition to the usual handling of the
+// signal: INT still interrupts the code that is running, and HUP still makes
+// Elvish exit, after the hooks have run. A hook can call exit to make Elvish
+// exit on other signals.
+//
+// Like other Elvish code, the hooks run on the goroutine that evaluates code:
+// they are queued with QueueSignalHooks when a signal comes, and run after the
+// top-level pipeline that is running, or right away when the editor is waiting
+// for keys. INT hooks do not fire when ^C is pressed while an external command is in the
+// foreground, since the terminal then sends the signal to the command instead
+// of Elvish.
+
+// hookableSignals are the names of the signals that can have hooks.
+var hookableSignals = []string{"HUP", "INT", "TERM", "USR1", "USR2", "WINCH"}
+
+var errSignalHooksShouldBeMap = errors.New("signal hooks should be a map")
+
+func newSignalHooksVariable() vartypes.Variable {
+	m := make(map[types.Value]types.Value)
+	for _, name := range hookableSignals {
+		m[types.String(name)] = types.EmptyList
+	}
+	return vartypes.NewValidatedPtr(types.MakeMap(m), validateSignalHooks)
+}
+
+func validateSignalHooks(v types.Value) error {
+	m, ok := v.(types.Map)
+	if !ok {
+		return errSignalHooksShouldBeMap
+	}
+	var err error
+	m.IteratePair(func(k, v types.Value) bool {
+		if !isHookableSignal(k) {
+			err = fmt.Errorf("signal %s cannot have hooks", k.Repr(types.NoPretty))
+		} else if _, ok := v.(types.List); !ok {
+			err = fmt.Errorf("hooks of signal %s should be a list", k.Repr(types.NoPretty))
+		}
+		return err == nil
+	})
+	return err
+}
+
+func isHookableSignal(v types.Value) bool {
+	for _, name := range hookableSignals {
+		if v == types.String(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// QueueSignalHooks queues the hooks in $signal-hooks for a signal to be called
+// by the next RunQueuedHooks, and returns a channel that is closed when they
+// have been called. It can be called from any goroutine.
+func (ev *Evaler) QueueSignalHooks(sig os.Signal) <-chan struct{} {
+	done := make(chan struct{})
+	ev.queueHooks(func() {
+		defer close(done)
+		ev.RunSignalHooks(sig)
+	})
+	return done
+}
+
+// RunSignalHooks calls the hooks in $signal-hooks for a signal one after
+// another, and returns when they have all finished. Errors are written to the
+// standard error. It must be called on the goroutine that evaluates code.
+func (ev *Evaler) RunSignalHooks(sig os.Signal) {
+	name := types.String(signalName(sig))
+	hooks := ev.Builtin["signal-hooks"].Get().(types.Map)
+	if name == "" || !hooks.HasKey(name) {
+		return
+	}
+	ev.callHooks(hooks.IndexOne(name).(types.List), "signal hook")
+}
+
+// callHooks calls each function in a list of hooks with the given arguments,
+// one after another. Elements that are not functions and errors thrown by the
+// hooks are reported on the standard error; what is used as the description of
+// the hooks in the messages.
+func (ev *Evaler) callHooks(hooks types.List, what string, args ...types.Value) {
+	hooks.Iterate(func(v types.Value) bool {
+		fn, ok := v.(Fn)
+		if !ok {
+			fmt.Fprintf(ev.ports[2].File, "not a function: %s\n", v.Repr(types.NoPretty))
+			return true
+		}
+		ec := NewTopFrame(ev, NewInternalSource("["+what+"]"), ev.ports[:])
+		err := ec.PCall(fn, args, NoOpts)
+		ec.cleanups.run()
+		if err != nil {
+			fmt.Fprintf(ev.ports[2].File, "%s error: %s\n", what, err.Error())
+		}
+		return true
+	})
+}
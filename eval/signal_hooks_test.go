@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"os"
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestSignalHooks(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	err := ev.SourceText(NewInteractiveSource(
+		"n a b c = 0 0 0 0; signal-hooks[INT] = [{ n = (+ $n 1) } { n = (+ $n 2) }]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.RunSignalHooks(os.Interrupt)
+	if n := ev.Global["n"].Get(); n != types.String("3") {
+		t.Errorf("hooks of INT made $n %v, want 3", n)
+	}
+
+	// Queued hooks run when the queue is run.
+	ev.QueueSignalHooks(os.Interrupt)
+	if n := ev.Global["n"].Get(); n != types.String("3") {
+		t.Errorf("queued hooks of INT ran early and made $n %v", n)
+	}
+	select {
+	case <-ev.QueuedHooksReady():
+	default:
+		t.Errorf("QueuedHooksReady not ready after queueing hooks")
+	}
+	ev.RunQueuedHooks()
+	if n := ev.Global["n"].Get(); n != types.String("6") {
+		t.Errorf("queued hooks of INT made $n %v, want 6", n)
+	}
+
+	// Queued hooks run between top-level pipelines, and not inside functions.
+	done := ev.QueueSignalHooks(os.Interrupt)
+	err = ev.SourceText(NewInteractiveSource(
+		"{ a = $n; b = $n }; c = $n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	for name, want := range map[string]string{"a": "6", "b": "6", "c": "9"} {
+		if v := ev.Global[name].Get(); v != types.String(want) {
+			t.Errorf("$%s is %v, want %s", name, v, want)
+		}
+	}
+
+	runTests(t, []Test{
+		NewTest("keys $signal-hooks | count").WantOutStrings("6"),
+		NewTest("signal-hooks[FOO] = []").WantAnyErr(),
+		NewTest("signal-hooks[TERM] = foo").WantAnyErr(),
+		NewTest("signal-hooks = []").WantAnyErr(),
+	})
+}
@@ -3,6 +3,7 @@ package eval
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/parse"
@@ -44,6 +45,24 @@ func (src *Source) describePath() string {
 	return src.path
 }
 
+// describePosition describes a position in the source as its path and line
+// number, like "a.elv:3".
+func (src *Source) describePosition(pos int) string {
+	if pos > len(src.code) {
+		return src.describePath()
+	}
+	return src.describePath() + ":" + strconv.Itoa(src.lineOf(pos))
+}
+
+// lineOf returns the 1-based line number of a position in the source, or 0 if
+// the position is out of range.
+func (src *Source) lineOf(pos int) int {
+	if pos < 0 || pos > len(src.code) {
+		return 0
+	}
+	return strings.Count(src.code[:pos], "\n") + 1
+}
+
 var (
 	_ types.Value      = (*Source)(nil)
 	_ types.IndexOneer = (*Source)(nil)
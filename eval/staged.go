@@ -0,0 +1,324 @@
+package eval
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+// Staged evaluation, used for reloading rc.elv without restarting Elvish.
+//
+// The code is evaluated against staging copies of the global and builtin
+// namespaces and of the loaded modules, in which assignments to existing
+// variables are recorded instead of carried out. When the evaluation succeeds,
+// all the recorded assignments, together with new and deleted variables, are
+// applied to the live namespaces at once. Side effects that do not go through
+// variables, such as setting environment variables, take place during the
+// evaluation as usual.
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+// Possible values of ChangeKind.
+const (
+	Added ChangeKind = iota
+	Modified
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a change applied by SourceStaged. Name is the qualified
+// name of the variable, like "edit:prompt" or "f~", followed by the key in
+// brackets when only one entry of a map, like a binding table, has changed.
+type Change struct {
+	Kind ChangeKind
+	Name string
+}
+
+// SourceStaged evaluates a chunk of Elvish source like SourceText, but only
+// applies its changes to variables when the evaluation succeeds, all at once.
+// It returns the changes, sorted by name.
+func (ev *Evaler) SourceStaged(src *Source) ([]Change, error) {
+	s := &staging{staged: make(map[uintptr]Ns), orig: make(map[uintptr]Ns)}
+	scopes := evalerScopes{
+		Global:  s.stage("", ev.Global),
+		Builtin: s.stage("", ev.Builtin),
+	}
+	modules := make(map[string]Ns, len(ev.modules))
+	for name, mod := range ev.modules {
+		modules[name] = s.stage(name+NsSuffix, mod)
+	}
+	// The scratch Evaler shares everything but the namespaces with ev.
+	scratch := *ev
+	scratch.evalerScopes = scopes
+	scratch.modules = modules
+
+	err := scratch.SourceText(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []stagedChange
+	for _, ns := range s.nss {
+		changes = append(changes, s.diff(ns)...)
+	}
+	for name, mod := range scratch.modules {
+		name, mod := name, mod
+		if _, ok := ev.modules[name]; !ok {
+			changes = append(changes, stagedChange{
+				apply: func() error { ev.modules[name] = mod; return nil },
+				undo:  func() { delete(ev.modules, name) },
+			})
+		}
+	}
+	for i, c := range changes {
+		if err := c.apply(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				changes[j].undo()
+			}
+			return nil, fmt.Errorf("cannot apply change to %s: %v", c.Name, err)
+		}
+	}
+	s.commit()
+
+	var applied []Change
+	for _, c := range changes {
+		if c.Name != "" {
+			applied = append(applied, c.Change)
+		}
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Name < applied[j].Name })
+	return applied, nil
+}
+
+// stagedVariable records assignments to a variable. When the variable holds a
+// namespace, it gives a staging copy of the namespace instead. After the
+// changes are applied, it forwards everything to the variable, since closures
+// defined during the staged evaluation may still refer to it.
+type stagedVariable struct {
+	orig      vartypes.Variable
+	ns        Ns
+	value     types.Value
+	set       bool
+	committed bool
+}
+
+func (v *stagedVariable) Get() types.Value {
+	switch {
+	case v.committed:
+		return v.orig.Get()
+	case v.set:
+		return v.value
+	case v.ns != nil:
+		return v.ns
+	default:
+		return v.orig.Get()
+	}
+}
+
+func (v *stagedVariable) Set(val types.Value) error {
+	if v.committed {
+		return v.orig.Set(val)
+	}
+	v.value, v.set = val, true
+	return nil
+}
+
+type stagedNs struct {
+	prefix       string
+	orig, staged Ns
+}
+
+type stagedChange struct {
+	Change
+	apply func() error
+	undo  func()
+}
+
+// staging keeps the staging copies of namespaces.
+type staging struct {
+	nss []*stagedNs
+	// Maps the addresses of namespaces to their staging copies, and vice
+	// versa.
+	staged map[uintptr]Ns
+	orig   map[uintptr]Ns
+}
+
+// stage returns a staging copy of a namespace, whose variables are named with
+// the given prefix in changes.
+func (s *staging) stage(prefix string, orig Ns) Ns {
+	if ns, ok := s.staged[addrOf(orig)]; ok {
+		return ns
+	}
+	staged := make(Ns, len(orig))
+	s.staged[addrOf(orig)] = staged
+	s.orig[addrOf(staged)] = orig
+	s.nss = append(s.nss, &stagedNs{prefix, orig, staged})
+	for name, variable := range orig {
+		if vartypes.IsBlackhole(variable) {
+			staged[name] = variable
+			continue
+		}
+		sv := &stagedVariable{orig: variable}
+		if strings.HasSuffix(name, NsSuffix) {
+			if ns, ok := variable.Get().(Ns); ok {
+				sv.ns = s.stage(prefix+name, ns)
+			}
+		}
+		staged[name] = sv
+	}
+	return staged
+}
+
+// unstage maps staging copies of namespaces back to the originals.
+func (s *staging) unstage(v types.Value) types.Value {
+	if ns, ok := v.(Ns); ok {
+		if orig, ok := s.orig[addrOf(ns)]; ok {
+			return orig
+		}
+	}
+	return v
+}
+
+// diff finds the changes made to a staged namespace.
+func (s *staging) diff(ns *stagedNs) []stagedChange {
+	var changes []stagedChange
+	for name, variable := range ns.staged {
+		name, variable := name, variable
+		qname := ns.prefix + name
+		orig, exists := ns.orig[name]
+		sv, isStaged := variable.(*stagedVariable)
+		switch {
+		case !exists:
+			changes = append(changes, stagedChange{Change{Added, qname},
+				func() error { ns.orig[name] = variable; return nil },
+				func() { delete(ns.orig, name) }})
+		case !isStaged:
+			if vartypes.IsBlackhole(variable) {
+				continue
+			}
+			// The variable was defined again, like functions are.
+			changes = appendChanges(changes, qname, orig.Get(), variable.Get(),
+				func() error { ns.orig[name] = variable; return nil },
+				func() { ns.orig[name] = orig })
+		case sv.set:
+			old, val := orig.Get(), s.unstage(sv.value)
+			changes = appendChanges(changes, qname, old, val,
+				func() error { return orig.Set(val) },
+				func() { orig.Set(old) })
+		}
+	}
+	for name, orig := range ns.orig {
+		name, orig := name, orig
+		if _, ok := ns.staged[name]; !ok {
+			changes = append(changes, stagedChange{Change{Removed, ns.prefix + name},
+				func() error { delete(ns.orig, name); return nil },
+				func() { ns.orig[name] = orig }})
+		}
+	}
+	return changes
+}
+
+// appendChanges appends the changes between the old and new values of a
+// variable, if there are any. Only the first change carries the functions that
+// apply and undo the assignment.
+func appendChanges(changes []stagedChange, name string, old, new types.Value,
+	apply func() error, undo func()) []stagedChange {
+
+	for i, change := range changedNames(name, old, new) {
+		if i == 0 {
+			changes = append(changes, stagedChange{change, apply, undo})
+		} else {
+			changes = append(changes, stagedChange{change,
+				func() error { return nil }, func() {}})
+		}
+	}
+	return changes
+}
+
+// commit makes all staged variables forward to the original variables.
+func (s *staging) commit() {
+	for _, ns := range s.nss {
+		for _, variable := range ns.staged {
+			if sv, ok := variable.(*stagedVariable); ok {
+				sv.committed = true
+			}
+		}
+	}
+}
+
+// changedNames returns the changes between the old and new values of a
+// variable. When both are maps, each key that has changed is listed.
+func changedNames(name string, old, new types.Value) []Change {
+	oldMap, ok1 := old.(mapLike)
+	newMap, ok2 := new.(mapLike)
+	if !ok1 || !ok2 {
+		if sameValue(old, new) {
+			return nil
+		}
+		return []Change{{Modified, name}}
+	}
+	var changes []Change
+	newMap.IterateKey(func(k types.Value) bool {
+		key := name + "[" + keyName(k) + "]"
+		if !oldMap.HasKey(k) {
+			changes = append(changes, Change{Added, key})
+		} else if !sameValue(oldMap.IndexOne(k), newMap.IndexOne(k)) {
+			changes = append(changes, Change{Modified, key})
+		}
+		return true
+	})
+	oldMap.IterateKey(func(k types.Value) bool {
+		if !newMap.HasKey(k) {
+			changes = append(changes,
+				Change{Removed, name + "[" + keyName(k) + "]"})
+		}
+		return true
+	})
+	return changes
+}
+
+// keyName returns a short name of a map key. Keys with a String method, like
+// those of binding tables, use it, while others use their repr.
+func keyName(k types.Value) string {
+	if s, ok := k.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return k.Repr(types.NoPretty)
+}
+
+type mapLike interface {
+	types.IterateKeyer
+	types.IndexOneer
+	types.HasKeyer
+}
+
+// sameValue is like Equal, except that closures are considered the same when
+// they have the same source code, since evaluating the same definition again
+// makes a new closure.
+func sameValue(a, b types.Value) bool {
+	ca, ok1 := a.(*Closure)
+	cb, ok2 := b.(*Closure)
+	if ok1 && ok2 {
+		return ca.SrcMeta.code[ca.Op.Begin:ca.Op.End] == cb.SrcMeta.code[cb.Op.Begin:cb.Op.End] &&
+			reflect.DeepEqual(ca.ArgNames, cb.ArgNames) && ca.RestArg == cb.RestArg &&
+			reflect.DeepEqual(ca.OptNames, cb.OptNames)
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+func TestSourceStaged(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	mustSource := func(code string) {
+		if err := ev.SourceText(NewInteractiveSource(code)); err != nil {
+			t.Fatalf("SourceText(%q) => %v", code, err)
+		}
+	}
+	get := func(name string) types.Value {
+		if v := ev.Global[name]; v != nil {
+			return v.Get()
+		}
+		return nil
+	}
+	mustSource("x = 1; fn f { put a }; m = [&a=1 &b=2]; y = 1")
+
+	for _, test := range []struct {
+		code string
+		want []Change
+	}{
+		// Redefining a function with the same code is not a change.
+		{"x = 2; fn f { put a }; fn g { }; m[a] = 3; m[c] = 1; del m[b]",
+			[]Change{{Added, "g~"}, {Modified, "m[a]"}, {Removed, "m[b]"},
+				{Added, "m[c]"}, {Modified, "x"}}},
+		{"del y; n = 0; fn inc { n = (+ $n 1) }",
+			[]Change{{Added, "inc~"}, {Added, "n"}, {Removed, "y"}}},
+		{"x = 2", nil},
+	} {
+		changes, err := ev.SourceStaged(NewInteractiveSource(test.code))
+		if err != nil || !reflect.DeepEqual(changes, test.want) {
+			t.Errorf("SourceStaged(%q) => (%v, %v), want (%v, nil)",
+				test.code, changes, err, test.want)
+		}
+	}
+	if x := get("x"); x != types.String("2") {
+		t.Errorf("$x is %v after staged evaluation, want 2", x)
+	}
+	if y := get("y"); y != nil {
+		t.Errorf("$y is %v after being deleted in staged evaluation", y)
+	}
+
+	// Closures defined in staged evaluation work with the live variables.
+	mustSource("inc; inc")
+	if n := get("n"); n != types.String("2") {
+		t.Errorf("$n is %v after calling inc twice, want 2", n)
+	}
+
+	// Nothing is applied when the evaluation fails.
+	_, err := ev.SourceStaged(NewInteractiveSource("x = 3; fn g { put b }; fail bad"))
+	if err == nil {
+		t.Errorf("SourceStaged with failing code => no error")
+	}
+	if x := get("x"); x != types.String("2") {
+		t.Errorf("$x is %v after failed staged evaluation, want 2", x)
+	}
+}
+
+func TestSourceStaged_Glob(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	util.InTempDir(func(string) {
+		if err := ioutil.WriteFile("a", nil, 0644); err != nil {
+			panic(err)
+		}
+		changes, err := ev.SourceStaged(NewInteractiveSource("x = [*]"))
+		if err != nil || !reflect.DeepEqual(changes, []Change{{Added, "x"}}) {
+			t.Errorf("SourceStaged with wildcard => (%v, %v)", changes, err)
+		}
+	})
+	if x := ev.Global["x"]; x == nil || !x.Get().Equal(types.MakeList(types.String("a"))) {
+		t.Errorf("$x is %v after staged evaluation, want [a]", x)
+	}
+}
+
+func TestSourceStaged_KeepsEvalerState(t *testing.T) {
+	ev := NewEvaler()
+	defer ev.Close()
+	_, err := ev.SourceStaged(NewInteractiveSource(
+		"EXPORT_TEST_STAGED = foo; export EXPORT_TEST_STAGED"))
+	if err != nil {
+		t.Fatalf("SourceStaged => %v", err)
+	}
+	defer os.Unsetenv("EXPORT_TEST_STAGED")
+	if _, ok := ev.exports.vars["EXPORT_TEST_STAGED"]; !ok {
+		t.Errorf("variable exported in staged evaluation is not exported")
+	}
+}
@@ -0,0 +1,139 @@
+// Package str implements the str: module for laying out text on the terminal.
+package str
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/util"
+)
+
+// All functions measure text by its width on the terminal, so that CJK
+// characters and emoji count as two columns, never split grapheme clusters,
+// and ignore ANSI escape sequences, so that they work with styled text.
+
+func Ns() eval.Ns {
+	ns := eval.Ns{}
+	eval.AddBuiltinFns(ns, fns...)
+	return ns
+}
+
+var fns = []*eval.BuiltinFn{
+	{"width", width},
+	{"wrap", wrap},
+	{"truncate", truncate},
+	{"pad", pad},
+	{"center", center},
+	{"indent", indent},
+}
+
+// width outputs the width of a string on the terminal.
+func width(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	eval.TakeNoOpt(opts)
+
+	ec.OutputChan() <- types.String(strconv.Itoa(util.StyledWcswidth(string(s))))
+}
+
+type widthOptions struct {
+	Width int
+}
+
+func scanWidth(ec *eval.Frame, opts map[string]types.Value, def int) int {
+	options := widthOptions{def}
+	ec.ScanOptsToStruct(opts, &options)
+	if options.Width < 0 {
+		throwf("&width should be non-negative, got %d", options.Width)
+	}
+	return options.Width
+}
+
+// wrap outputs the lines of a string wrapped to &width, 80 by default.
+func wrap(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	w := scanWidth(ec, opts, 80)
+
+	out := ec.OutputChan()
+	for _, line := range util.WrapWcwidth(string(s), w) {
+		out <- types.String(line)
+	}
+}
+
+type truncateOptions struct {
+	Width    int
+	Ellipsis string
+}
+
+// truncate truncates a string to &width, ending it with &ellipsis if anything
+// is cut off.
+func truncate(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	options := truncateOptions{80, "…"}
+	ec.ScanOptsToStruct(opts, &options)
+	if options.Width < 0 {
+		throwf("&width should be non-negative, got %d", options.Width)
+	}
+
+	ec.OutputChan() <- types.String(
+		util.TruncateWcwidth(string(s), options.Width, options.Ellipsis))
+}
+
+type padOptions struct {
+	Width int
+	Align string
+}
+
+// pad pads a string with spaces to &width. &align is one of "left" (the
+// default), "right" and "center".
+func pad(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	options := padOptions{0, "left"}
+	ec.ScanOptsToStruct(opts, &options)
+
+	var align int
+	switch options.Align {
+	case "left":
+		align = -1
+	case "right":
+		align = 1
+	case "center":
+		align = 0
+	default:
+		throwf("&align must be left, right or center, got %s", options.Align)
+	}
+	ec.OutputChan() <- types.String(util.PadWcwidth(string(s), options.Width, align))
+}
+
+// center centers a string within &width, 80 by default.
+func center(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	w := scanWidth(ec, opts, 80)
+
+	ec.OutputChan() <- types.String(util.PadWcwidth(string(s), w, 0))
+}
+
+type indentOptions struct {
+	Prefix string
+}
+
+// indent prepends &prefix, two spaces by default, to each non-empty line of a
+// string.
+func indent(ec *eval.Frame, args []types.Value, opts map[string]types.Value) {
+	var s types.String
+	ec.ScanArgs(args, &s)
+	options := indentOptions{"  "}
+	ec.ScanOptsToStruct(opts, &options)
+
+	ec.OutputChan() <- types.String(util.IndentLines(string(s), options.Prefix))
+}
+
+func throwf(format string, args ...interface{}) {
+	util.Throw(fmt.Errorf(format, args...))
+}
@@ -0,0 +1,40 @@
+package str
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var tests = []eval.Test{
+	eval.NewTest(`str:width 你好`).WantOutStrings("4"),
+	eval.NewTest(`str:width "\e[31mred\e[m"`).WantOutStrings("3"),
+
+	eval.NewTest(`str:wrap &width=7 'lorem ipsum dolor'`).
+		WantOutStrings("lorem", "ipsum", "dolor"),
+	eval.NewTest(`str:wrap &width=5 你好世界`).WantOutStrings("你好", "世界"),
+	eval.NewTest(`str:wrap &width=-1 x`).WantAnyErr(),
+
+	eval.NewTest(`str:truncate &width=5 你好世界`).WantOutStrings("你好…"),
+	eval.NewTest(`str:truncate &width=5 &ellipsis=... abcdefg`).
+		WantOutStrings("ab..."),
+	eval.NewTest(`str:truncate &width=5 abc`).WantOutStrings("abc"),
+
+	eval.NewTest(`str:pad &width=4 ab`).WantOutStrings("ab  "),
+	eval.NewTest(`str:pad &width=4 &align=right 你`).WantOutStrings("  你"),
+	eval.NewTest(`str:pad &width=4 &align=middle ab`).WantAnyErr(),
+
+	eval.NewTest(`str:center &width=5 ab`).WantOutStrings(" ab  "),
+
+	eval.NewTest(`str:indent "a\n\nb"`).WantOutStrings("  a\n\n  b"),
+	eval.NewTest(`str:indent &prefix='> ' a`).WantOutStrings("> a"),
+}
+
+func TestStr(t *testing.T) {
+	eval.RunTests(t, tests, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["str"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
@@ -0,0 +1,25 @@
+// Package sys implements the sys: module for querying information about the
+// operating system without forking external commands.
+package sys
+
+import (
+	"runtime"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/sys"
+)
+
+// Ns returns the namespace of the sys: module. The kernel information is
+// queried once; if that fails, the kernel-* variables are empty.
+func Ns() eval.Ns {
+	name, release, version, _ := sys.Uname()
+	return eval.Ns{
+		"os":             vartypes.NewRo(types.String(runtime.GOOS)),
+		"arch":           vartypes.NewRo(types.String(runtime.GOARCH)),
+		"kernel-name":    vartypes.NewRo(types.String(name)),
+		"kernel-release": vartypes.NewRo(types.String(release)),
+		"kernel-version": vartypes.NewRo(types.String(version)),
+	}
+}
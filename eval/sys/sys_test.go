@@ -0,0 +1,28 @@
+package sys
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/vartypes"
+	"github.com/elves/elvish/sys"
+)
+
+func TestSys(t *testing.T) {
+	name, release, version, err := sys.Uname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval.RunTests(t, []eval.Test{
+		eval.NewTest(`put $sys:os $sys:arch`).
+			WantOutStrings(runtime.GOOS, runtime.GOARCH),
+		eval.NewTest(`put $sys:kernel-name $sys:kernel-release $sys:kernel-version`).
+			WantOutStrings(name, release, version),
+		eval.NewTest(`sys:os = plan9`).WantAnyErr(),
+	}, func() *eval.Evaler {
+		ev := eval.NewEvaler()
+		ev.Builtin["sys"+eval.NsSuffix] = vartypes.NewRo(Ns())
+		return ev
+	})
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/xiaq/persistent/hash"
+)
+
+// Bytes is a string of bytes that are not necessarily valid UTF-8 text, such
+// as the content of binary files. Unlike String, it is never treated as text:
+// it is written out verbatim, and its repr shows the bytes in hex.
+type Bytes string
+
+var (
+	_ Value    = Bytes("")
+	_ ListLike = Bytes("")
+)
+
+func (Bytes) Kind() string {
+	return "bytes"
+}
+
+func (b Bytes) Repr(int) string {
+	return "(bytes " + hex.EncodeToString([]byte(b)) + ")"
+}
+
+func (b Bytes) Equal(rhs interface{}) bool {
+	return b == rhs
+}
+
+func (b Bytes) Hash() uint32 {
+	return hash.String(string(b))
+}
+
+// String returns the bytes unchanged, so that they are written out verbatim.
+func (b Bytes) String() string {
+	return string(b)
+}
+
+func (b Bytes) Len() int {
+	return len(b)
+}
+
+// IndexOne returns the byte at an index, or the bytes in a slice, as a Bytes.
+func (b Bytes) IndexOne(idx Value) Value {
+	slice, i, j := ParseAndFixListIndex(ToString(idx), len(b))
+	if slice {
+		return b[i:j]
+	}
+	return b[i : i+1]
+}
+
+// Iterate calls f with each byte as a Bytes.
+func (b Bytes) Iterate(f func(v Value) bool) {
+	for i := 0; i < len(b); i++ {
+		if !f(b[i : i+1]) {
+			break
+		}
+	}
+}
+
+// MarshalJSON encodes the bytes as a JSON string of their standard base64
+// encoding, like encoding/json does with []byte.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString([]byte(b)))
+}
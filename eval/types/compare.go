@@ -0,0 +1,177 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Comparer wraps the Compare method.
+type Comparer interface {
+	// Compare compares the receiver to another value. It returns -1, 0 or 1
+	// when the receiver is less than, equal to or greater than the other
+	// value, and an error when the two values cannot be ordered, for instance
+	// because they are of different kinds. Numbers of different types, and
+	// strings that can be parsed as numbers, are compared by value, so they
+	// may compare equal without being Equal.
+	Compare(other Value) (int, error)
+}
+
+// Compare compares two values with the Compare method of the first value. It
+// returns an error if the first value does not implement Comparer, or if the
+// two values cannot be ordered.
+func Compare(a, b Value) (int, error) {
+	if c, ok := a.(Comparer); ok {
+		return c.Compare(b)
+	}
+	return 0, errNotComparable(a, b)
+}
+
+func errNotComparable(a, b Value) error {
+	return fmt.Errorf("cannot compare %s and %s", a.Kind(), b.Kind())
+}
+
+// Compare compares strings that can be parsed as numbers numerically, like
+// the numeric builtins do, and orders them before other strings, which are
+// compared lexicographically. A string can also be compared with a float64 or
+// a rat, in which case strings that are not numbers come after them.
+func (s String) Compare(other Value) (int, error) {
+	f, isNum := parseNumber(s)
+	switch other := other.(type) {
+	case String:
+		g, otherIsNum := parseNumber(other)
+		switch {
+		case isNum && otherIsNum:
+			return compareFloat64s(s, other, f, g)
+		case isNum:
+			return -1, nil
+		case otherIsNum:
+			return 1, nil
+		}
+		return strings.Compare(string(s), string(other)), nil
+	case Float64:
+		if !isNum {
+			return 1, nil
+		}
+		return compareFloat64s(s, other, f, float64(other))
+	case Rat:
+		if !isNum {
+			return 1, nil
+		}
+		return compareFloat64s(s, other, f, other.Float64())
+	}
+	return 0, errNotComparable(s, other)
+}
+
+// parseNumber parses a string as a number in the same way as the numeric
+// builtins.
+func parseNumber(s String) (float64, bool) {
+	if f, err := strconv.ParseFloat(string(s), 64); err == nil {
+		return f, true
+	}
+	if i, err := strconv.ParseInt(string(s), 0, 64); err == nil {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+func (b Bytes) Compare(other Value) (int, error) {
+	if other, ok := other.(Bytes); ok {
+		return strings.Compare(string(b), string(other)), nil
+	}
+	return 0, errNotComparable(b, other)
+}
+
+// Compare orders false before true.
+func (b Bool) Compare(other Value) (int, error) {
+	if other, ok := other.(Bool); ok {
+		switch {
+		case b == other:
+			return 0, nil
+		case !bool(b):
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	return 0, errNotComparable(b, other)
+}
+
+// Compare compares the number with another float64, a rat or a string that
+// can be parsed as a number; other strings come after it. NaN cannot be
+// ordered.
+func (f Float64) Compare(other Value) (int, error) {
+	var g float64
+	switch other := other.(type) {
+	case Float64:
+		g = float64(other)
+	case Rat:
+		g = other.Float64()
+	case String:
+		c, err := other.Compare(f)
+		return -c, err
+	default:
+		return 0, errNotComparable(f, other)
+	}
+	return compareFloat64s(f, other, float64(f), g)
+}
+
+// Compare compares the number with another rat, a float64 or a string like
+// Float64.Compare.
+func (r Rat) Compare(other Value) (int, error) {
+	switch other := other.(type) {
+	case Rat:
+		return r.b.Cmp(other.b), nil
+	case Float64:
+		return compareFloat64s(r, other, r.Float64(), float64(other))
+	case String:
+		c, err := other.Compare(r)
+		return -c, err
+	}
+	return 0, errNotComparable(r, other)
+}
+
+func compareFloat64s(a, b Value, f, g float64) (int, error) {
+	switch {
+	case f < g:
+		return -1, nil
+	case f > g:
+		return 1, nil
+	case f == g:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", a.Repr(NoPretty), b.Repr(NoPretty))
+}
+
+// Compare compares the elements of the two lists in order, with their own
+// Compare methods. When one list is a prefix of the other, the shorter one is
+// less.
+func (l List) Compare(other Value) (int, error) {
+	l2, ok := other.(List)
+	if !ok {
+		return 0, errNotComparable(l, other)
+	}
+	it1, it2 := l.inner.Iterator(), l2.inner.Iterator()
+	for ; it1.HasElem() && it2.HasElem(); it1.Next() {
+		c, err := Compare(it1.Elem().(Value), it2.Elem().(Value))
+		if c != 0 || err != nil {
+			return c, err
+		}
+		it2.Next()
+	}
+	switch {
+	case it1.HasElem():
+		return 1, nil
+	case it2.HasElem():
+		return -1, nil
+	}
+	return 0, nil
+}
+
+// Compare only tells whether the receiver is equal to another nil.
+func (NilValue) Compare(other Value) (int, error) {
+	if other == Nil {
+		return 0, nil
+	}
+	return 0, errNotComparable(Nil, other)
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/tt"
+)
+
+func compareOrError(a, b Value) interface{} {
+	c, err := Compare(a, b)
+	if err != nil {
+		return "error"
+	}
+	return c
+}
+
+func TestCompare(t *testing.T) {
+	tt.Test(t, tt.Fn("compareOrError", compareOrError), tt.Table{
+		Args(String("a"), String("b")).Rets(-1),
+		Args(String("10"), String("9")).Rets(1),
+		Args(String("10"), String("a")).Rets(-1),
+		Args(String("a"), Float64(1)).Rets(1),
+		Args(Float64(10), String("9")).Rets(1),
+		Args(MakeList(String("2")), MakeList(String("10"))).Rets(-1),
+		Args(String("a"), Bytes("a")).Rets("error"),
+		Args(Bytes("b"), Bytes("a")).Rets(1),
+		Args(Bool(true), Bool(true)).Rets(0),
+		Args(Float64(2), Float64(1.5)).Rets(1),
+		Args(Nil, Nil).Rets(0),
+		Args(MakeList(String("a")), MakeList(String("a"), String("b"))).Rets(-1),
+		Args(MakeList(String("b")), MakeList(String("a"), String("b"))).Rets(1),
+		Args(MakeList(String("a")), MakeList(Bool(true))).Rets("error"),
+		Args(EmptyMap, EmptyMap).Rets("error"),
+	})
+}
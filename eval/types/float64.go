@@ -0,0 +1,38 @@
+package types
+
+import (
+	"math"
+	"strconv"
+)
+
+// Float64 is a floating-point number.
+type Float64 float64
+
+var _ Value = Float64(0)
+
+func (Float64) Kind() string {
+	return "float64"
+}
+
+func (f Float64) Equal(a interface{}) bool {
+	return f == a
+}
+
+func (f Float64) Hash() uint32 {
+	if f == 0 {
+		// 0 and -0 are equal, but have different bits.
+		return 0
+	}
+	bits := math.Float64bits(float64(f))
+	return uint32(bits ^ bits>>32)
+}
+
+func (f Float64) Repr(int) string {
+	return "(float64 " + f.String() + ")"
+}
+
+// String formats the number in the shortest way that parses back to it, like
+// "1.5", "1e+21", "+Inf" or "NaN".
+func (f Float64) String() string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 64)
+}
@@ -49,8 +49,10 @@ func (List) Kind() string {
 	return "list"
 }
 
+// Equal returns whether rhs is a List with equal elements.
 func (l List) Equal(rhs interface{}) bool {
-	return eqListLike(l, rhs)
+	l2, ok := rhs.(List)
+	return ok && eqListLike(l, l2)
 }
 
 func (l List) Hash() uint32 {
@@ -100,6 +102,11 @@ func (l List) Iterate(f func(Value) bool) {
 	}
 }
 
+// Cons returns a new List with an additional element at the end.
+func (l List) Cons(v Value) List {
+	return List{l.inner.Cons(v)}
+}
+
 func (l List) IndexOne(idx Value) Value {
 	slice, i, j := ParseAndFixListIndex(ToString(idx), l.Len())
 	if slice {
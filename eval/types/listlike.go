@@ -13,15 +13,22 @@ type ListLike interface {
 	IndexOneer
 }
 
-func eqListLike(lhs ListLike, r interface{}) bool {
-	rhs, ok := r.(ListLike)
-	if !ok {
-		return false
-	}
+func eqListLike(lhs ListLike, rhs ListLike) bool {
 	if lhs.Len() != rhs.Len() {
 		return false
 	}
-	return true
+	var rhsElems []Value
+	rhs.Iterate(func(v Value) bool {
+		rhsElems = append(rhsElems, v)
+		return true
+	})
+	i, eq := 0, true
+	lhs.Iterate(func(v Value) bool {
+		eq = v.Equal(rhsElems[i])
+		i++
+		return eq
+	})
+	return eq
 }
 
 func hashListLike(l ListLike) uint32 {
@@ -0,0 +1,34 @@
+package types
+
+// NilValue is the type of Nil.
+type NilValue struct{}
+
+// Nil is a value that stands for the absence of a value, like null in JSON.
+// It is false and only equal to itself.
+var Nil = NilValue{}
+
+var _ Value = Nil
+
+func (NilValue) Kind() string {
+	return "nil"
+}
+
+func (NilValue) Equal(a interface{}) bool {
+	return a == Nil
+}
+
+func (NilValue) Hash() uint32 {
+	return 0
+}
+
+func (NilValue) Repr(int) string {
+	return "$nil"
+}
+
+func (NilValue) Bool() bool {
+	return false
+}
+
+func (NilValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
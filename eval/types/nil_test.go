@@ -0,0 +1,21 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval"
+)
+
+func TestNil(t *testing.T) {
+	eval.RunTests(t, []eval.Test{
+		eval.NewTest("kind-of $nil").WantOutStrings("nil"),
+		eval.NewTest("eq $nil $nil").WantOutBools(true),
+		eval.NewTest("eq $nil ''").WantOutBools(false),
+		eval.NewTest("repr $nil").WantBytesOutString("$nil\n"),
+		eval.NewTest("bool $nil").WantOutBools(false),
+		eval.NewTest("put [$nil] | to-json").WantBytesOutString("[null]\n"),
+		eval.NewTest("echo '[null, \"\"]' | from-json | each [l]{ eq $l[0] $nil; eq $l[1] $nil }").
+			WantOutBools(true, false),
+		eval.NewTest("nil = foo").WantAnyErr(),
+	}, eval.NewEvaler)
+}
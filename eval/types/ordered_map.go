@@ -0,0 +1,134 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/xiaq/persistent/hashmap"
+	"github.com/xiaq/persistent/vector"
+)
+
+// OrderedMap is a map that remembers the order in which its keys were first
+// added, and iterates over its pairs, prints them and encodes them to JSON in
+// that order. Associating a key that is already in the map keeps its place.
+// An OrderedMap is equal to a Map with the same pairs.
+type OrderedMap struct {
+	inner hashmap.HashMap
+	keys  vector.Vector
+}
+
+var _ MapLike = OrderedMap{}
+
+// EmptyOrderedMap is an empty OrderedMap.
+var EmptyOrderedMap = OrderedMap{hashmap.Empty, vector.Empty}
+
+func (OrderedMap) Kind() string {
+	return "map"
+}
+
+func (m OrderedMap) Equal(a interface{}) bool {
+	return EqMapLike(m, a)
+}
+
+func (m OrderedMap) Hash() uint32 {
+	// Use the hash of the Map with the same pairs, since they are equal.
+	return Map{m.inner}.Hash()
+}
+
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	var err error
+	first := true
+	m.IteratePair(func(k, v Value) bool {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		var kb, vb []byte
+		kb, err = json.Marshal(ToString(k))
+		if err != nil {
+			return false
+		}
+		vb, err = json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m OrderedMap) Repr(indent int) string {
+	var builder MapReprBuilder
+	builder.Indent = indent
+	m.IteratePair(func(k, v Value) bool {
+		builder.WritePair(k.Repr(indent+1), indent+2, v.Repr(indent+2))
+		return true
+	})
+	return builder.String()
+}
+
+func (m OrderedMap) Len() int {
+	return m.inner.Len()
+}
+
+func (m OrderedMap) IndexOne(idx Value) Value {
+	v, ok := m.inner.Get(idx)
+	if !ok {
+		throw(errors.New("no such key: " + idx.Repr(NoPretty)))
+	}
+	return v.(Value)
+}
+
+func (m OrderedMap) Assoc(k, v Value) Value {
+	keys := m.keys
+	if !m.HasKey(k) {
+		keys = keys.Cons(k)
+	}
+	return OrderedMap{m.inner.Assoc(k, v), keys}
+}
+
+func (m OrderedMap) Dissoc(k Value) Value {
+	if !m.HasKey(k) {
+		return m
+	}
+	keys := vector.Empty
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		if key := it.Elem().(Value); !key.Equal(k) {
+			keys = keys.Cons(key)
+		}
+	}
+	return OrderedMap{m.inner.Without(k), keys}
+}
+
+func (m OrderedMap) IterateKey(f func(Value) bool) {
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		if !f(it.Elem().(Value)) {
+			break
+		}
+	}
+}
+
+func (m OrderedMap) IteratePair(f func(Value, Value) bool) {
+	for it := m.keys.Iterator(); it.HasElem(); it.Next() {
+		k := it.Elem().(Value)
+		v, _ := m.inner.Get(k)
+		if !f(k, v.(Value)) {
+			break
+		}
+	}
+}
+
+func (m OrderedMap) HasKey(k Value) bool {
+	_, ok := m.inner.Get(k)
+	return ok
+}
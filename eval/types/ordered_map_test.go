@@ -0,0 +1,33 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/tt"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var m Value = EmptyOrderedMap
+	for _, pair := range [][2]string{{"b", "1"}, {"a", "2"}, {"b", "3"}} {
+		m = m.(OrderedMap).Assoc(String(pair[0]), String(pair[1]))
+	}
+	same := MakeMap(map[Value]Value{String("a"): String("2"), String("b"): String("3")})
+
+	tt.Test(t, tt.Fn("Repr", Value.Repr), tt.Table{
+		Args(m, NoPretty).Rets("[&b=3 &a=2]"),
+		Args(m.(OrderedMap).Dissoc(String("b")), NoPretty).Rets("[&a=2]"),
+		Args(EmptyOrderedMap, NoPretty).Rets("[&]"),
+	})
+
+	if !m.Equal(same) || !same.Equal(m) {
+		t.Errorf("OrderedMap not equal to Map with the same pairs")
+	}
+	if m.Hash() != same.Hash() {
+		t.Errorf("OrderedMap has different hash from Map with the same pairs")
+	}
+
+	json, err := m.(OrderedMap).MarshalJSON()
+	if string(json) != `{"b":"3","a":"2"}` || err != nil {
+		t.Errorf("MarshalJSON -> (%q, %v), want (%q, nil)", json, err, `{"b":"3","a":"2"}`)
+	}
+}
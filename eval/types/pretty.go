@@ -0,0 +1,96 @@
+package types
+
+import (
+	"bytes"
+	"strings"
+)
+
+// PrettyOptions controls how Pretty lays out values.
+type PrettyOptions struct {
+	// Number of spaces by which the elements of a list or the pairs of a map
+	// are indented relative to its brackets. Zero means 1.
+	Indent int
+	// Maximum nesting depth of the lists and maps that are shown; deeper ones
+	// are shown as [...] or [&...]. Zero means no limit.
+	MaxDepth int
+	// Maximum number of elements of a list or pairs of a map that are shown;
+	// the rest are replaced by "...". Zero means no limit.
+	MaxItems int
+}
+
+// Pretty returns the representation of a value laid out on multiple lines,
+// with each element of a list and each pair of a map on a line of its own.
+// Other values are shown like in v.Repr(NoPretty).
+func Pretty(v Value, opts PrettyOptions) string {
+	if opts.Indent <= 0 {
+		opts.Indent = 1
+	}
+	var buf bytes.Buffer
+	writePretty(&buf, v, opts, 0, 1)
+	return buf.String()
+}
+
+func writePretty(buf *bytes.Buffer, v Value, opts PrettyOptions, indent, depth int) {
+	tooDeep := opts.MaxDepth > 0 && depth > opts.MaxDepth
+	inner := indent + opts.Indent
+	n := 0
+	switch v := v.(type) {
+	case List:
+		switch {
+		case v.Len() == 0:
+			buf.WriteString("[]")
+			return
+		case tooDeep:
+			buf.WriteString("[...]")
+			return
+		}
+		buf.WriteByte('[')
+		v.Iterate(func(elem Value) bool {
+			if !startElem(buf, opts, inner, n) {
+				return false
+			}
+			writePretty(buf, elem, opts, inner, depth+1)
+			n++
+			return true
+		})
+	case MapLike:
+		switch {
+		case v.Len() == 0:
+			buf.WriteString("[&]")
+			return
+		case tooDeep:
+			buf.WriteString("[&...]")
+			return
+		}
+		buf.WriteByte('[')
+		v.IteratePair(func(k, val Value) bool {
+			if !startElem(buf, opts, inner, n) {
+				return false
+			}
+			buf.WriteString("&" + k.Repr(NoPretty) + "=")
+			writePretty(buf, val, opts, inner, depth+1)
+			n++
+			return true
+		})
+	default:
+		buf.WriteString(v.Repr(NoPretty))
+		return
+	}
+	writeNewline(buf, indent)
+	buf.WriteByte(']')
+}
+
+// startElem starts the line of the n-th element of a list or map. If the
+// element is beyond opts.MaxItems, it writes "..." instead and returns false.
+func startElem(buf *bytes.Buffer, opts PrettyOptions, indent, n int) bool {
+	writeNewline(buf, indent)
+	if opts.MaxItems > 0 && n == opts.MaxItems {
+		buf.WriteString("...")
+		return false
+	}
+	return true
+}
+
+func writeNewline(buf *bytes.Buffer, indent int) {
+	buf.WriteString("\n" + strings.Repeat(" ", indent))
+}
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/tt"
+)
+
+func TestPretty(t *testing.T) {
+	nested := MakeList(String("a"), MakeList(String("b"), MakeList(String("c"))), String("d"))
+	m := NewStruct(NewStructDescriptor("k", "l"), []Value{MakeList(), MakeList(String("v"))})
+
+	tt.Test(t, tt.Fn("Pretty", Pretty), tt.Table{
+		Args(String("a b"), PrettyOptions{}).Rets("'a b'"),
+		Args(MakeList(), PrettyOptions{}).Rets("[]"),
+		Args(EmptyMap, PrettyOptions{}).Rets("[&]"),
+		Args(nested, PrettyOptions{}).Rets(
+			"[\n a\n [\n  b\n  [\n   c\n  ]\n ]\n d\n]"),
+		Args(nested, PrettyOptions{Indent: 2, MaxDepth: 2}).Rets(
+			"[\n  a\n  [\n    b\n    [...]\n  ]\n  d\n]"),
+		Args(nested, PrettyOptions{MaxItems: 2}).Rets(
+			"[\n a\n [\n  b\n  [\n   c\n  ]\n ]\n ...\n]"),
+		Args(m, PrettyOptions{}).Rets("[\n &k=[]\n &l=[\n  v\n ]\n]"),
+		Args(m, PrettyOptions{MaxDepth: 1}).Rets("[\n &k=[]\n &l=[...]\n]"),
+	})
+}
@@ -48,6 +48,22 @@ func (r Rat) String() string {
 	return r.b.String()
 }
 
+// Float64 returns the float64 value nearest to the rat.
+func (r Rat) Float64() float64 {
+	f, _ := r.b.Float64()
+	return f
+}
+
+// Int returns the value of the rat as an int, and whether it is an integer
+// that fits in one.
+func (r Rat) Int() (int, bool) {
+	if !r.b.IsInt() || !r.b.Num().IsInt64() {
+		return 0, false
+	}
+	i := r.b.Num().Int64()
+	return int(i), int64(int(i)) == i
+}
+
 // ToRat converts a Value to rat. A str can be converted to a rat if it can be
 // parsed. A rat is returned as-is. Other types of values cannot be converted.
 func ToRat(v Value) (Rat, error) {
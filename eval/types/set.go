@@ -0,0 +1,177 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/xiaq/persistent/hashmap"
+)
+
+// Set is an unordered collection of distinct Value's.
+type Set struct {
+	// The elements are the keys; the values are unused.
+	inner hashmap.HashMap
+}
+
+var _ Iterator = Set{}
+
+// EmptySet is an empty Set.
+var EmptySet = Set{hashmap.Empty}
+
+// MakeSet creates a new Set from values, dropping duplicates.
+func MakeSet(vs ...Value) Set {
+	s := EmptySet
+	for _, v := range vs {
+		s = s.Add(v)
+	}
+	return s
+}
+
+func (Set) Kind() string {
+	return "set"
+}
+
+func (s Set) Equal(a interface{}) bool {
+	s2, ok := a.(Set)
+	if !ok || s.Len() != s2.Len() {
+		return false
+	}
+	return s.IsSubset(s2)
+}
+
+func (s Set) Hash() uint32 {
+	// The hash must not depend on the order of elements, so the hashes of
+	// elements are added up instead of being combined in sequence.
+	var h uint32
+	s.Iterate(func(v Value) bool {
+		h += v.Hash()
+		return true
+	})
+	return h
+}
+
+// Repr returns the representation of the set as a call to the set builtin,
+// like "(set [a b])". The elements are sorted by their representations, so
+// that the representation of a set does not depend on how it was built.
+func (s Set) Repr(indent int) string {
+	var b ListReprBuilder
+	b.Indent = indent
+	if indent >= 0 {
+		b.Indent += len("(set ")
+	}
+	for _, repr := range s.sortedReprs(b.Indent + 1) {
+		b.WriteElem(repr)
+	}
+	return "(set " + b.String() + ")"
+}
+
+func (s Set) sortedReprs(indent int) []string {
+	reprs := make([]string, 0, s.Len())
+	s.Iterate(func(v Value) bool {
+		reprs = append(reprs, v.Repr(indent))
+		return true
+	})
+	sort.Strings(reprs)
+	return reprs
+}
+
+// MarshalJSON encodes the set to a JSON array, with elements in the same order
+// as in Repr.
+func (s Set) MarshalJSON() ([]byte, error) {
+	elems := make([]Value, 0, s.Len())
+	s.Iterate(func(v Value) bool {
+		elems = append(elems, v)
+		return true
+	})
+	sort.Slice(elems, func(i, j int) bool {
+		return elems[i].Repr(NoPretty) < elems[j].Repr(NoPretty)
+	})
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, v := range elems {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func (s Set) Len() int {
+	return s.inner.Len()
+}
+
+func (s Set) Iterate(f func(Value) bool) {
+	for it := s.inner.Iterator(); it.HasElem(); it.Next() {
+		v, _ := it.Elem()
+		if !f(v.(Value)) {
+			break
+		}
+	}
+}
+
+// Has returns whether v is an element of the set.
+func (s Set) Has(v Value) bool {
+	_, ok := s.inner.Get(v)
+	return ok
+}
+
+// Add returns a new Set with v added.
+func (s Set) Add(v Value) Set {
+	return Set{s.inner.Assoc(v, true)}
+}
+
+// Remove returns a new Set with v removed.
+func (s Set) Remove(v Value) Set {
+	return Set{s.inner.Without(v)}
+}
+
+// IsSubset returns whether all the elements of the set are also elements of
+// s2.
+func (s Set) IsSubset(s2 Set) bool {
+	subset := true
+	s.Iterate(func(v Value) bool {
+		subset = s2.Has(v)
+		return subset
+	})
+	return subset
+}
+
+// Union returns a new Set with the elements that are in either s or s2.
+func (s Set) Union(s2 Set) Set {
+	if s.Len() < s2.Len() {
+		s, s2 = s2, s
+	}
+	s2.Iterate(func(v Value) bool {
+		s = s.Add(v)
+		return true
+	})
+	return s
+}
+
+// Intersection returns a new Set with the elements that are in both s and s2.
+func (s Set) Intersection(s2 Set) Set {
+	result := EmptySet
+	s.Iterate(func(v Value) bool {
+		if s2.Has(v) {
+			result = result.Add(v)
+		}
+		return true
+	})
+	return result
+}
+
+// Difference returns a new Set with the elements of s that are not in s2.
+func (s Set) Difference(s2 Set) Set {
+	s2.Iterate(func(v Value) bool {
+		s = s.Remove(v)
+		return true
+	})
+	return s
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/tt"
+)
+
+func TestSet(t *testing.T) {
+	s := MakeSet(String("b"), String("a"), String("b"))
+	tt.Test(t, tt.Fn("Repr", Value.Repr), tt.Table{
+		Args(s, NoPretty).Rets("(set [a b])"),
+		Args(EmptySet, NoPretty).Rets("(set [])"),
+		Args(s, 0).Rets("(set [\n      a\n      b\n     ])"),
+	})
+
+	s2 := MakeSet(String("a"), String("b"))
+	if !s.Equal(s2) || s.Hash() != s2.Hash() {
+		t.Errorf("sets with the same elements are not equal or have different hashes")
+	}
+	if !s.Remove(String("b")).IsSubset(s) || s.IsSubset(s.Remove(String("b"))) {
+		t.Errorf("IsSubset gives wrong result")
+	}
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/xiaq/persistent/hash"
+)
+
+// Stream is a lazy sequence of values read from a channel, typically written
+// by a goroutine that produces them on demand. Unlike a List, a Stream can be
+// iterated only once: each value is consumed when it is read, and iterating
+// over the stream again resumes after the last value read. A Stream is only
+// equal to itself.
+//
+// A Stream should be closed when it is no longer needed, so that its producer
+// stops. A Stream that is not closed is closed when it is garbage collected,
+// which may take arbitrarily long.
+type Stream struct {
+	ch        <-chan Value
+	cancel    func()
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Iterator = (*Stream)(nil)
+
+// NewStream creates a new Stream that reads values from ch until it is
+// closed. The cancel function, if not nil, is called when the stream is
+// closed, and should make the producer stop writing and close ch. The
+// producer must not keep a reference to the Stream, or it will never be
+// garbage collected.
+func NewStream(ch <-chan Value, cancel func()) *Stream {
+	s := &Stream{ch: ch, cancel: cancel, closed: make(chan struct{})}
+	runtime.SetFinalizer(s, (*Stream).Close)
+	return s
+}
+
+func (*Stream) Kind() string {
+	return "stream"
+}
+
+func (s *Stream) Equal(rhs interface{}) bool {
+	return s == rhs
+}
+
+func (s *Stream) Hash() uint32 {
+	return hash.Pointer(unsafe.Pointer(s))
+}
+
+// Repr returns an opaque representation "<stream 0x23333333>".
+func (s *Stream) Repr(int) string {
+	return fmt.Sprintf("<stream %p>", s)
+}
+
+// Iterate reads values from the stream until it is exhausted or closed, or f
+// returns false.
+func (s *Stream) Iterate(f func(Value) bool) {
+	for {
+		// The producer may still be able to write a value after the stream is
+		// closed, so check for that first.
+		select {
+		case <-s.closed:
+			return
+		default:
+		}
+		select {
+		case v, ok := <-s.ch:
+			if !ok || !f(v) {
+				return
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Close cancels the producer of the stream; no more values are read from it
+// afterwards. It is safe to call Close more than once.
+func (s *Stream) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		if s.cancel != nil {
+			s.cancel()
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	ch := make(chan Value)
+	done := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for i := 0; ; i++ {
+			select {
+			case ch <- String(fmt.Sprint(i)):
+			case <-done:
+				return
+			}
+		}
+	}()
+	s := NewStream(ch, func() { close(done) })
+
+	if got := takeFromStream(s, 2); got != "0 1" {
+		t.Errorf("first take -> %q, want %q", got, "0 1")
+	}
+	if got := takeFromStream(s, 2); got != "2 3" {
+		t.Errorf("second take -> %q, want %q", got, "2 3")
+	}
+	s.Close()
+	s.Close()
+	if got := takeFromStream(s, 2); got != "" {
+		t.Errorf("take after Close -> %q, want nothing", got)
+	}
+	if !s.Equal(s) || s.Equal(NewStream(ch, nil)) {
+		t.Errorf("a Stream should only be equal to itself")
+	}
+}
+
+func TestStream_ClosedWhenCollected(t *testing.T) {
+	cancelled := make(chan struct{})
+	NewStream(make(chan Value), func() { close(cancelled) })
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-cancelled:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Errorf("a Stream was not closed after being garbage collected")
+}
+
+func takeFromStream(s *Stream, n int) string {
+	var taken []string
+	s.Iterate(func(v Value) bool {
+		taken = append(taken, string(v.(String)))
+		return len(taken) < n
+	})
+	return strings.Join(taken, " ")
+}
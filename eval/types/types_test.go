@@ -19,6 +19,9 @@ func TestKind(t *testing.T) {
 	tt.Test(t, tt.Fn("kind", kind), tt.Table{
 		Args(Bool(true)).Rets("bool"),
 		Args(String("")).Rets("string"),
+		Args(Bytes("\xff")).Rets("bytes"),
+		Args(Float64(1.5)).Rets("float64"),
+		Args(Nil).Rets("nil"),
 		Args(NewList(vector.Empty)).Rets("list"),
 		Args(NewMap(hashmap.Empty)).Rets("map"),
 		Args(NewStruct(NewStructDescriptor(), nil)).Rets("map"),
@@ -0,0 +1,36 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+var errBadUmask = errors.New("umask should be an octal number between 0 and 0777")
+
+// UmaskVariable is a variable whose value always reflects the file mode
+// creation mask of the process, as an octal number like "0022". Setting it to
+// an octal number changes the mask.
+type UmaskVariable struct{}
+
+var _ vartypes.Variable = UmaskVariable{}
+
+func (UmaskVariable) Get() types.Value {
+	return types.String(fmt.Sprintf("%04o", getUmask()))
+}
+
+func (UmaskVariable) Set(v types.Value) error {
+	s, ok := v.(types.String)
+	if !ok {
+		return errBadUmask
+	}
+	mask, err := strconv.ParseUint(strings.TrimPrefix(string(s), "0o"), 8, 0)
+	if err != nil || mask > 0777 {
+		return errBadUmask
+	}
+	return setUmask(int(mask))
+}
@@ -0,0 +1,67 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The umask can only be read by setting it, which would briefly change the
+// mode of files created on other goroutines. It is instead read from
+// /proc/self/status where that is supported, and otherwise remembered from
+// when it was last set. The initial value is read during initialization,
+// before any files are created.
+var (
+	umaskMutex sync.Mutex
+	umask      int
+)
+
+func init() {
+	if mask, ok := readProcUmask(); ok {
+		umask = mask
+	} else {
+		umask = syscall.Umask(0777)
+		syscall.Umask(umask)
+	}
+}
+
+func getUmask() int {
+	if mask, ok := readProcUmask(); ok {
+		return mask
+	}
+	umaskMutex.Lock()
+	defer umaskMutex.Unlock()
+	return umask
+}
+
+func setUmask(mask int) error {
+	umaskMutex.Lock()
+	defer umaskMutex.Unlock()
+	syscall.Umask(mask)
+	umask = mask
+	return nil
+}
+
+// readProcUmask reads the umask from the "Umask:" line of /proc/self/status,
+// which Linux has since 4.7.
+func readProcUmask() (int, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Umask:") {
+			mask, err := strconv.ParseUint(strings.TrimSpace(line[len("Umask:"):]), 8, 0)
+			return int(mask), err == nil
+		}
+	}
+	return 0, false
+}
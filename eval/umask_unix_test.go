@@ -0,0 +1,33 @@
+// +build !windows,!plan9
+
+package eval
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestUmask(t *testing.T) {
+	defer setUmask(getUmask())
+	setUmask(022)
+
+	runTests(t, []Test{
+		NewTest("put $umask").WantOutStrings("0022"),
+		NewTest("umask = 077; put $umask").WantOutStrings("0077"),
+		NewTest("umask = 0o27; put $umask").WantOutStrings("0027"),
+		NewTest("umask = 0; put $umask").WantOutStrings("0000"),
+		NewTest("umask = 1000").WantAnyErr(),
+		NewTest("umask = 9").WantAnyErr(),
+		NewTest("umask = [077]").WantAnyErr(),
+	})
+}
+
+func TestReadProcUmask(t *testing.T) {
+	mask, ok := readProcUmask()
+	if !ok {
+		t.Skip("/proc/self/status has no umask")
+	}
+	if want := syscall.Umask(mask); mask != want {
+		t.Errorf("readProcUmask() -> %04o, want %04o", mask, want)
+	}
+}
@@ -0,0 +1,11 @@
+package eval
+
+import "errors"
+
+// Windows has no umask; the permissions of new files come from the ACLs of
+// their directories.
+func getUmask() int { return 0 }
+
+func setUmask(int) error {
+	return errors.New("setting umask is not supported on Windows")
+}
@@ -107,3 +107,11 @@ func (u ValueUnwrapper) Iterable() types.Iterator {
 	}
 	return it
 }
+
+func (u ValueUnwrapper) IteratePairer() types.IteratePairer {
+	it, ok := u.values[0].(types.IteratePairer)
+	if !ok {
+		u.error("map", "%s", u.values[0].Kind())
+	}
+	return it
+}
@@ -100,3 +100,9 @@ func TakeNoOpt(opts map[string]types.Value) {
 		throw(ErrNoOptAccepted)
 	}
 }
+
+// ValidEnvName returns whether name can be the name of an environment
+// variable. It must be non-empty and must not contain "=" or NUL.
+func ValidEnvName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "=\x00")
+}
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/elves/elvish/eval/types"
@@ -38,8 +39,7 @@ type Fn interface {
 // a Value.
 func FromJSONInterface(v interface{}) types.Value {
 	if v == nil {
-		// TODO Use a more appropriate type
-		return types.String("")
+		return types.Nil
 	}
 	switch v.(type) {
 	case bool:
@@ -66,3 +66,47 @@ func FromJSONInterface(v interface{}) types.Value {
 		return nil // not reached
 	}
 }
+
+// fromJSONTokens reads one JSON value from dec, like Decode followed by
+// FromJSONInterface, except that objects are converted to OrderedMap's that
+// keep the order of their keys.
+func fromJSONTokens(dec *json.Decoder) (types.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return FromJSONInterface(tok), nil
+	}
+	switch delim {
+	case '[':
+		var vs []types.Value
+		for dec.More() {
+			v, err := fromJSONTokens(dec)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+		_, err := dec.Token()
+		return types.MakeList(vs...), err
+	case '{':
+		m := types.EmptyOrderedMap
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := fromJSONTokens(dec)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(types.String(k.(string)), v).(types.OrderedMap)
+		}
+		_, err := dec.Token()
+		return m, err
+	default:
+		return nil, fmt.Errorf("unexpected %v in JSON", delim)
+	}
+}
@@ -0,0 +1,46 @@
+package eval
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+const (
+	// The default value of $value-buffer-size.
+	defaultValueBufferSize = 32
+	// The maximum value of $value-buffer-size. Channels are allocated with
+	// their full buffers, so very large sizes would exhaust memory.
+	maxValueBufferSize = 1 << 20
+)
+
+var errShouldBeBufferSize = errors.New(
+	"should be integer between 0 and " + strconv.Itoa(maxValueBufferSize))
+
+// newValueBufferSizeVariable returns the variable $value-buffer-size, the
+// number of values that the channels between the forms of a pipeline can
+// buffer. Writers block when a channel is full, so larger buffers let
+// producers run further ahead of consumers at the cost of memory; 0 makes each
+// write wait for the reader. It can be set for one pipeline with a temporary
+// assignment, like "value-buffer-size=1024 { producer | consumer }".
+func newValueBufferSizeVariable() vartypes.Variable {
+	return vartypes.NewValidatedPtr(types.String(strconv.Itoa(defaultValueBufferSize)),
+		func(v types.Value) error {
+			s, ok := v.(types.String)
+			if !ok {
+				return errShouldBeBufferSize
+			}
+			if n, err := strconv.Atoi(string(s)); err != nil || n < 0 || n > maxValueBufferSize {
+				return errShouldBeBufferSize
+			}
+			return nil
+		})
+}
+
+// valueBufferSize returns the value of $value-buffer-size.
+func (ev *Evaler) valueBufferSize() int {
+	n, _ := strconv.Atoi(types.ToString(ev.Builtin["value-buffer-size"].Get()))
+	return n
+}
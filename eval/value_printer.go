@@ -0,0 +1,66 @@
+package eval
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/elves/elvish/eval/types"
+	"github.com/elves/elvish/eval/vartypes"
+)
+
+// The value printer.
+//
+// Values written to the value channels of the standard output and error of the
+// Evaler, like the outputs of commands run interactively, are printed after
+// $value-out-indicator. By default, they are printed as their repr. When
+// $value-printer is a function, it is called with each value instead, and
+// what it outputs, values and bytes alike, is printed line by line. For
+// instance, "value-printer = [v]{ pprint &max-items=10 $v }" pretty-prints
+// values and elides long lists and maps.
+//
+// The values are printed on the goroutines that relay the value channels to
+// the files, so the value printer runs concurrently with the code that outputs
+// the values. Calls to it are serialized, so it may keep state in variables of
+// its own, but it should not change variables that other code uses.
+
+var errValuePrinterShouldBeFn = errors.New("value printer should be a function or $nil")
+
+func newValuePrinterVariable() vartypes.Variable {
+	return vartypes.NewValidatedPtr(types.Nil, func(v types.Value) error {
+		if _, ok := v.(Fn); !ok && v != types.Nil {
+			return errValuePrinterShouldBeFn
+		}
+		return nil
+	})
+}
+
+// formatValue returns how a value written to the standard output or error is
+// printed. Errors thrown by the value printer are printed after the repr of
+// the value.
+func (ev *Evaler) formatValue(v types.Value) string {
+	var printer Fn
+	if variable, ok := ev.Builtin["value-printer"]; ok {
+		printer, _ = variable.Get().(Fn)
+	}
+	if printer == nil {
+		return v.Repr(initIndent)
+	}
+	ev.valuePrinterMutex.Lock()
+	defer ev.valuePrinterMutex.Unlock()
+	ports := []*Port{
+		DevNullClosedChan,
+		{File: ev.ports[1].File, Chan: BlackholeChan},
+		{File: ev.ports[2].File, Chan: BlackholeChan},
+	}
+	ec := NewTopFrame(ev, NewInternalSource("[value printer]"), ports)
+	outs, err := ec.PCaptureOutput(printer, []types.Value{v}, NoOpts)
+	ec.cleanups.run()
+	if err != nil {
+		return v.Repr(initIndent) + " (value printer error: " + err.Error() + ")"
+	}
+	lines := make([]string, len(outs))
+	for i, out := range outs {
+		lines[i] = types.ToString(out)
+	}
+	return strings.Join(lines, "\n")
+}
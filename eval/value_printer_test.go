@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/elves/elvish/eval/types"
+)
+
+func TestValuePrinter(t *testing.T) {
+	for _, test := range []struct {
+		printer string
+		want    string
+	}{
+		{"", "[a b]"},
+		{"value-printer = $nil", "[a b]"},
+		{"value-printer = [v]{ put (count $v) items }", "2\nitems"},
+		{"value-printer = [v]{ pprint $v }", "[\n a\n b\n]"},
+		{"value-printer = [v]{ fail bad }", "[a b] (value printer error: bad)"},
+	} {
+		ev := NewEvaler()
+		if test.printer != "" {
+			err := ev.SourceText(NewScriptSource("[test]", "[test]", test.printer))
+			if err != nil {
+				t.Fatalf("%s: %v", test.printer, err)
+			}
+		}
+		got := ev.formatValue(types.MakeList(types.String("a"), types.String("b")))
+		if got != test.want {
+			t.Errorf("with %q, formatValue -> %q, want %q", test.printer, got, test.want)
+		}
+		ev.Close()
+	}
+	// Calls to the value printer are serialized.
+	ev := NewEvaler()
+	defer ev.Close()
+	err := ev.SourceText(NewScriptSource("[test]", "[test]",
+		"n = 0; value-printer = [v]{ n = (+ $n 1); put $n }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const calls = 20
+	results := make(chan string, calls)
+	for i := 0; i < calls; i++ {
+		go func() { results <- ev.formatValue(types.String("a")) }()
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < calls; i++ {
+		r := <-results
+		if seen[r] {
+			t.Errorf("value printer output %s twice", r)
+		}
+		seen[r] = true
+	}
+
+	runTests(t, []Test{
+		NewTest("value-printer = foo").WantAnyErr(),
+	})
+}
@@ -19,16 +19,16 @@ var reprTests = []struct {
 	{types.String("a\x00b"), `"a\x00b"`},
 	{types.Bool(true), "$true"},
 	{types.Bool(false), "$false"},
-	{&Exception{nil, nil}, "$ok"},
-	{&Exception{errors.New("foo bar"), nil}, "?(fail 'foo bar')"},
+	{&Exception{nil, nil, nil}, "$ok"},
+	{&Exception{errors.New("foo bar"), nil, nil}, "?(fail 'foo bar')"},
 	{&Exception{
-		PipelineError{[]*Exception{{nil, nil}, {errors.New("lorem"), nil}}}, nil},
+		PipelineError{[]*Exception{{nil, nil, nil}, {errors.New("lorem"), nil, nil}}}, nil, nil},
 		"?(multi-error $ok ?(fail lorem))"},
-	{&Exception{Return, nil}, "?(return)"},
+	{&Exception{Return, nil, nil}, "?(return)"},
 	{types.EmptyList, "[]"},
 	{types.MakeList(types.String("bash"), types.Bool(false)), "[bash $false]"},
 	{types.MakeMap(map[types.Value]types.Value{}), "[&]"},
-	{types.MakeMap(map[types.Value]types.Value{&Exception{nil, nil}: types.String("elvish")}), "[&$ok=elvish]"},
+	{types.MakeMap(map[types.Value]types.Value{&Exception{nil, nil, nil}: types.String("elvish")}), "[&$ok=elvish]"},
 	// TODO: test maps of more elements
 }
 
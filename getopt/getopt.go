@@ -79,6 +79,8 @@ type ParsedOption struct {
 	Option   *Option
 	Long     bool
 	Argument string
+	// Whether an argument was given, which may be empty, like in "--name=".
+	HasArgument bool
 }
 
 // Context indicates what may come after the supplied argument list.
@@ -140,18 +142,20 @@ func (g *Getopt) parseShort(s string) ([]*ParsedOption, bool) {
 		opt := g.findShort(r)
 		if opt != nil {
 			if opt.HasArg == NoArgument {
-				opts = append(opts, &ParsedOption{opt, false, ""})
+				opts = append(opts, &ParsedOption{opt, false, "", false})
 				continue
 			} else {
-				parsed := &ParsedOption{opt, false, s[i+len(string(r)):]}
+				arg := s[i+len(string(r)):]
+				parsed := &ParsedOption{opt, false, arg, arg != ""}
 				opts = append(opts, parsed)
 				needArg = parsed.Argument == "" && opt.HasArg == RequiredArgument
 				break
 			}
 		}
 		// Unknown option, treat as taking an optional argument
+		arg := s[i+len(string(r)):]
 		parsed := &ParsedOption{
-			&Option{r, "", OptionalArgument}, false, s[i+len(string(r)):]}
+			&Option{r, "", OptionalArgument}, false, arg, arg != ""}
 		opts = append(opts, parsed)
 		break
 	}
@@ -164,16 +168,16 @@ func (g *Getopt) parseLong(s string) (*ParsedOption, bool) {
 	eq := strings.IndexRune(s, '=')
 	for _, opt := range g.Options {
 		if s == opt.Long {
-			return &ParsedOption{opt, true, ""}, opt.HasArg == RequiredArgument
+			return &ParsedOption{opt, true, "", false}, opt.HasArg == RequiredArgument
 		} else if eq != -1 && s[:eq] == opt.Long {
-			return &ParsedOption{opt, true, s[eq+1:]}, false
+			return &ParsedOption{opt, true, s[eq+1:], true}, false
 		}
 	}
 	// Unknown option, treat as taking an optional argument
 	if eq == -1 {
-		return &ParsedOption{&Option{0, s, OptionalArgument}, true, ""}, false
+		return &ParsedOption{&Option{0, s, OptionalArgument}, true, "", false}, false
 	}
-	return &ParsedOption{&Option{0, s[:eq], OptionalArgument}, true, s[eq+1:]}, false
+	return &ParsedOption{&Option{0, s[:eq], OptionalArgument}, true, s[eq+1:], true}, false
 }
 
 // Parse parses an argument list.
@@ -192,7 +196,7 @@ func (g *Getopt) Parse(elems []string) ([]*ParsedOption, []string, *Context) {
 	hasPrefix := func(p string) bool { return strings.HasPrefix(elem, p) }
 	for _, elem = range elems[:len(elems)-1] {
 		if opt != nil {
-			opt.Argument = elem
+			opt.Argument, opt.HasArgument = elem, true
 			opts = append(opts, opt)
 			opt = nil
 		} else if noopt {
@@ -233,7 +237,7 @@ func (g *Getopt) Parse(elems []string) ([]*ParsedOption, []string, *Context) {
 	elem = elems[len(elems)-1]
 	ctx := &Context{}
 	if opt != nil {
-		opt.Argument = elem
+		opt.Argument, opt.HasArgument = elem, true
 		ctx.Type, ctx.Option = OptionArgument, opt
 	} else if noopt {
 		ctx.Type, ctx.Text = Argument, elem
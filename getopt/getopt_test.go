@@ -20,50 +20,54 @@ var cases = []struct {
 }{
 	// NoArgument, short option.
 	{0, []string{"-a", ""},
-		[]*ParsedOption{{options[0], false, ""}},
+		[]*ParsedOption{{options[0], false, "", false}},
 		nil, &Context{Type: NewOptionOrArgument}},
 	// NoArgument, long option.
 	{0, []string{"--all", ""},
-		[]*ParsedOption{{options[0], true, ""}},
+		[]*ParsedOption{{options[0], true, "", false}},
+		nil, &Context{Type: NewOptionOrArgument}},
+	// NoArgument, long option, with an empty argument
+	{0, []string{"--all=", ""},
+		[]*ParsedOption{{options[0], true, "", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 
 	// RequiredArgument, argument following the option directly
 	{0, []string{"-oname=elvish", ""},
-		[]*ParsedOption{{options[1], false, "name=elvish"}},
+		[]*ParsedOption{{options[1], false, "name=elvish", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 	// RequiredArgument, argument in next element
 	{0, []string{"-o", "name=elvish", ""},
-		[]*ParsedOption{{options[1], false, "name=elvish"}},
+		[]*ParsedOption{{options[1], false, "name=elvish", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 	// RequiredArgument, long option, argument following the option directly
 	{0, []string{"--option=name=elvish", ""},
-		[]*ParsedOption{{options[1], true, "name=elvish"}},
+		[]*ParsedOption{{options[1], true, "name=elvish", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 	// RequiredArgument, long option, argument in next element
 	{0, []string{"--option", "name=elvish", ""},
-		[]*ParsedOption{{options[1], true, "name=elvish"}},
+		[]*ParsedOption{{options[1], true, "name=elvish", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 
 	// OptionalArgument, with argument
 	{0, []string{"-n1", ""},
-		[]*ParsedOption{{options[2], false, "1"}},
+		[]*ParsedOption{{options[2], false, "1", true}},
 		nil, &Context{Type: NewOptionOrArgument}},
 	// OptionalArgument, without argument
 	{0, []string{"-n", ""},
-		[]*ParsedOption{{options[2], false, ""}},
+		[]*ParsedOption{{options[2], false, "", false}},
 		nil, &Context{Type: NewOptionOrArgument}},
 
 	// DoubleDashTerminatesOptions
 	{DoubleDashTerminatesOptions, []string{"-a", "--", "-o", ""},
-		[]*ParsedOption{{options[0], false, ""}},
+		[]*ParsedOption{{options[0], false, "", false}},
 		[]string{"-o"}, &Context{Type: Argument}},
 	// FirstArgTerminatesOptions
 	{FirstArgTerminatesOptions, []string{"-a", "x", "-o", ""},
-		[]*ParsedOption{{options[0], false, ""}},
+		[]*ParsedOption{{options[0], false, "", false}},
 		[]string{"x", "-o"}, &Context{Type: Argument}},
 	// LongOnly
 	{LongOnly, []string{"-all", ""},
-		[]*ParsedOption{{options[0], true, ""}},
+		[]*ParsedOption{{options[0], true, "", false}},
 		nil, &Context{Type: NewOptionOrArgument}},
 
 	// NewOption
@@ -78,32 +82,32 @@ var cases = []struct {
 		&Context{Type: LongOption, Text: "all"}},
 	// ChainShortOption
 	{0, []string{"-a"},
-		[]*ParsedOption{{options[0], false, ""}}, nil,
+		[]*ParsedOption{{options[0], false, "", false}}, nil,
 		&Context{Type: ChainShortOption}},
 	// OptionArgument, short option, same element
 	{0, []string{"-o"}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], false, ""}}},
+			Option: &ParsedOption{options[1], false, "", false}}},
 	// OptionArgument, short option, separate element
 	{0, []string{"-o", ""}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], false, ""}}},
+			Option: &ParsedOption{options[1], false, "", true}}},
 	// OptionArgument, long option, same element
 	{0, []string{"--option="}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], true, ""}}},
+			Option: &ParsedOption{options[1], true, "", true}}},
 	// OptionArgument, long option, separate element
 	{0, []string{"--option", ""}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], true, ""}}},
+			Option: &ParsedOption{options[1], true, "", true}}},
 	// OptionArgument, long only, same element
 	{LongOnly, []string{"-option="}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], true, ""}}},
+			Option: &ParsedOption{options[1], true, "", true}}},
 	// OptionArgument, long only, separate element
 	{LongOnly, []string{"-option", ""}, nil, nil,
 		&Context{Type: OptionArgument,
-			Option: &ParsedOption{options[1], true, ""}}},
+			Option: &ParsedOption{options[1], true, "", true}}},
 	// Argument
 	{0, []string{"x"}, nil, nil,
 		&Context{Type: Argument, Text: "x"}},
@@ -113,37 +117,37 @@ var cases = []struct {
 		&Context{
 			Type: OptionArgument,
 			Option: &ParsedOption{
-				&Option{'x', "", OptionalArgument}, false, ""}}},
+				&Option{'x', "", OptionalArgument}, false, "", false}}},
 	// Unknown short option, separate element
 	{0, []string{"-x", ""},
 		[]*ParsedOption{{
-			&Option{'x', "", OptionalArgument}, false, ""}},
+			&Option{'x', "", OptionalArgument}, false, "", false}},
 		nil,
 		&Context{Type: NewOptionOrArgument}},
 
 	// Unknown long option
 	{0, []string{"--unknown", ""},
 		[]*ParsedOption{{
-			&Option{0, "unknown", OptionalArgument}, true, ""}},
+			&Option{0, "unknown", OptionalArgument}, true, "", false}},
 		nil,
 		&Context{Type: NewOptionOrArgument}},
 	// Unknown long option, with argument
 	{0, []string{"--unknown=value", ""},
 		[]*ParsedOption{{
-			&Option{0, "unknown", OptionalArgument}, true, "value"}},
+			&Option{0, "unknown", OptionalArgument}, true, "value", true}},
 		nil,
 		&Context{Type: NewOptionOrArgument}},
 
 	// Unknown long option, LongOnly
 	{LongOnly, []string{"-unknown", ""},
 		[]*ParsedOption{{
-			&Option{0, "unknown", OptionalArgument}, true, ""}},
+			&Option{0, "unknown", OptionalArgument}, true, "", false}},
 		nil,
 		&Context{Type: NewOptionOrArgument}},
 	// Unknown long option, with argument
 	{LongOnly, []string{"-unknown=value", ""},
 		[]*ParsedOption{{
-			&Option{0, "unknown", OptionalArgument}, true, "value"}},
+			&Option{0, "unknown", OptionalArgument}, true, "value", true}},
 		nil,
 		&Context{Type: NewOptionOrArgument}},
 }
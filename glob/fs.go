@@ -0,0 +1,176 @@
+package glob
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is a file system that can be globbed. Paths are separated by "/", and the
+// empty path refers to the working directory.
+type FS interface {
+	// Stat returns information about a file, following symbolic links.
+	Stat(path string) (os.FileInfo, error)
+	// ReadDir returns information about the entries of a directory, without
+	// following symbolic links.
+	ReadDir(dir string) ([]os.FileInfo, error)
+	// EvalSymlinks returns the path after evaluating any symbolic links. It is
+	// only called on paths that ReadDir reports as symbolic links.
+	EvalSymlinks(path string) (string, error)
+}
+
+// OSFS is the file system of the operating system.
+var OSFS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Stat(path string) (os.FileInfo, error) {
+	if path == "" {
+		path = "."
+	}
+	return os.Stat(path)
+}
+
+func (osFS) ReadDir(dir string) ([]os.FileInfo, error) {
+	if dir == "" {
+		dir = "."
+	}
+	return ioutil.ReadDir(dir)
+}
+
+func (osFS) EvalSymlinks(path string) (string, error) {
+	return filepath.EvalSymlinks(path)
+}
+
+// FileInfo is a minimal os.FileInfo for the files of virtual file systems.
+type FileInfo struct {
+	FileName string
+	Dir      bool
+}
+
+var _ os.FileInfo = FileInfo{}
+
+func (fi FileInfo) Name() string       { return fi.FileName }
+func (fi FileInfo) Size() int64        { return 0 }
+func (fi FileInfo) ModTime() time.Time { return time.Time{} }
+func (fi FileInfo) IsDir() bool        { return fi.Dir }
+func (fi FileInfo) Sys() interface{}   { return nil }
+
+func (fi FileInfo) Mode() os.FileMode {
+	if fi.Dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+var errBadMountPoint = errors.New("mount point must be an absolute path other than the root")
+
+// Mounts is an FS that serves the paths under mount points from the file
+// systems mounted on them, and all other paths from a fallback FS. A mounted
+// FS sees paths relative to its mount point, with "" being the mount point
+// itself. It is safe to use concurrently.
+type Mounts struct {
+	fallback FS
+	mutex    sync.RWMutex
+	points   map[string]FS
+}
+
+// NewMounts creates a Mounts with no mount points.
+func NewMounts(fallback FS) *Mounts {
+	return &Mounts{fallback: fallback, points: make(map[string]FS)}
+}
+
+// Mount mounts a file system on an absolute path, replacing any file system
+// already mounted there. Relative paths passed to the methods of FS are looked
+// up relative to the working directory at the time of the call, so that the
+// mount point does not move when the working directory changes.
+func (m *Mounts) Mount(point string, fs FS) error {
+	if !filepath.IsAbs(point) {
+		return errBadMountPoint
+	}
+	point = path.Clean(point)
+	if point == "/" {
+		return errBadMountPoint
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.points[point] = fs
+	return nil
+}
+
+// Unmount removes the file system mounted on a path, and returns whether there
+// was one.
+func (m *Mounts) Unmount(point string) bool {
+	point = path.Clean(point)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, ok := m.points[point]
+	delete(m.points, point)
+	return ok
+}
+
+// Points returns all the mount points, sorted.
+func (m *Mounts) Points() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	points := make([]string, 0, len(m.points))
+	for point := range m.points {
+		points = append(points, point)
+	}
+	sort.Strings(points)
+	return points
+}
+
+// find finds the FS that serves a path, and the path relative to it. When
+// mount points are nested, the innermost one wins.
+func (m *Mounts) find(p string) (FS, string, string) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if len(m.points) > 0 && p != "" {
+		abs := p
+		if !filepath.IsAbs(abs) {
+			wd, err := os.Getwd()
+			if err != nil {
+				return m.fallback, "", p
+			}
+			abs = path.Join(filepath.ToSlash(wd), p)
+		}
+		for dir, rel := path.Clean(abs), ""; ; {
+			if fs, ok := m.points[dir]; ok {
+				return fs, dir, rel
+			}
+			i := strings.LastIndexByte(dir, '/')
+			if i <= 0 {
+				break
+			}
+			rel = path.Join(dir[i+1:], rel)
+			dir = dir[:i]
+		}
+	}
+	return m.fallback, "", p
+}
+
+func (m *Mounts) Stat(path string) (os.FileInfo, error) {
+	fs, _, rel := m.find(path)
+	return fs.Stat(rel)
+}
+
+func (m *Mounts) ReadDir(dir string) ([]os.FileInfo, error) {
+	fs, _, rel := m.find(dir)
+	return fs.ReadDir(rel)
+}
+
+func (m *Mounts) EvalSymlinks(p string) (string, error) {
+	fs, point, rel := m.find(p)
+	real, err := fs.EvalSymlinks(rel)
+	if err != nil || point == "" {
+		return real, err
+	}
+	return path.Join(point, real), nil
+}
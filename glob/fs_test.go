@@ -0,0 +1,107 @@
+package glob
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/elves/elvish/util"
+)
+
+// mapFS is a virtual FS whose directories are given by a map from directory
+// paths to entries. Entries ending in "/" are directories.
+type mapFS map[string][]string
+
+func (fs mapFS) Stat(p string) (os.FileInfo, error) {
+	if p == "" {
+		return FileInfo{"", true}, nil
+	}
+	dir, name := path.Split(p)
+	infos, err := fs.ReadDir(path.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.Name() == name {
+			return info, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (fs mapFS) ReadDir(dir string) ([]os.FileInfo, error) {
+	if dir == "." {
+		dir = ""
+	}
+	entries, ok := fs[path.Clean("/" + dir)[1:]]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	var infos []os.FileInfo
+	for _, entry := range entries {
+		if entry[len(entry)-1] == '/' {
+			infos = append(infos, FileInfo{entry[:len(entry)-1], true})
+		} else {
+			infos = append(infos, FileInfo{entry, false})
+		}
+	}
+	return infos, nil
+}
+
+func (mapFS) EvalSymlinks(p string) (string, error) {
+	return "", os.ErrInvalid
+}
+
+var testFS = mapFS{"": {"x/", "y"}, "x": {"z"}}
+
+func TestMounts(t *testing.T) {
+	util.InTempDir(func(string) {
+		if err := os.Mkdir("d", 0755); err != nil {
+			panic(err)
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			panic(err)
+		}
+		mounts := NewMounts(OSFS)
+		point := path.Join(wd, "d/v")
+		if err := mounts.Mount(point+"/", testFS); err != nil {
+			t.Fatal(err)
+		}
+		for _, bad := range []string{"/", "d/w"} {
+			if err := mounts.Mount(bad, testFS); err != errBadMountPoint {
+				t.Errorf("Mount on %s returns %v, want errBadMountPoint", bad, err)
+			}
+		}
+		if points := mounts.Points(); !reflect.DeepEqual(points, []string{point}) {
+			t.Errorf("Points() => %v", points)
+		}
+
+		for _, tc := range []globCase{
+			{"d/*", []string{}},
+			{"d/v", []string{"d/v"}},
+			{"d/v/*", []string{"d/v/x", "d/v/y"}},
+			{"d/v/**", []string{"d/v/x", "d/v/x/z", "d/v/y"}},
+			{"d/v/x/z", []string{"d/v/x/z"}},
+			{"d/v/*/z", []string{"d/v/x/z"}},
+			{"d/v/y/*", []string{}},
+			{point + "/*", []string{point + "/x", point + "/y"}},
+		} {
+			names := []string{}
+			Parse(tc.pattern).GlobWithOptions(Options{FS: mounts}, func(name string) bool {
+				names = append(names, name)
+				return true
+			})
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("glob %q with mounts => %v, want %v", tc.pattern, names, tc.want)
+			}
+		}
+
+		if !mounts.Unmount(point) || mounts.Unmount(point) {
+			t.Errorf("Unmount does not report whether there was a mount point")
+		}
+	})
+}
@@ -2,7 +2,6 @@
 package glob
 
 import (
-	"io/ioutil"
 	"os"
 	"runtime"
 	"unicode/utf8"
@@ -15,8 +14,24 @@ func Glob(p string, cb func(string) bool) bool {
 	return Parse(p).Glob(cb)
 }
 
+// Options controls how a Pattern is matched against the file system.
+type Options struct {
+	// Whether to descend into symbolic links to directories when matching
+	// wildcards. Symbolic links that lead to a directory being visited are
+	// never descended into, to avoid cycles.
+	FollowSymlinks bool
+	// The file system to match against. If nil, the file system of the
+	// operating system is used.
+	FS FS
+}
+
 // Glob returns a list of file names satisfying the Pattern.
 func (p Pattern) Glob(cb func(string) bool) bool {
+	return p.GlobWithOptions(Options{}, cb)
+}
+
+// GlobWithOptions is like Glob, but uses the given Options.
+func (p Pattern) GlobWithOptions(opts Options, cb func(string) bool) bool {
 	segs := p.Segments
 	dir := ""
 
@@ -38,18 +53,31 @@ func (p Pattern) Glob(cb func(string) bool) bool {
 		}
 	}
 
-	return glob(segs, dir, cb)
+	fs := opts.FS
+	if fs == nil {
+		fs = OSFS
+	}
+	g := &globber{opts, fs, make(map[string]bool)}
+	return g.glob(segs, dir, cb)
+}
+
+// globber keeps the state of one globbing.
+type globber struct {
+	opts Options
+	fs   FS
+	// Real paths of the symbolic links being descended into.
+	following map[string]bool
 }
 
 func isDrive(s string) bool {
 	return len(s) == 2 && s[1] == ':' &&
-		(('a' <= s[0] && s[1] <= 'z') || ('A' <= s[0] && s[0] <= 'Z'))
+		(('a' <= s[0] && s[0] <= 'z') || ('A' <= s[0] && s[0] <= 'Z'))
 }
 
 // glob finds all filenames matching the given Segments in the given dir, and
 // calls the callback on all of them. If the callback returns false, globbing is
 // interrupted, and glob returns false. Otherwise it returns true.
-func glob(segs []Segment, dir string, cb func(string) bool) bool {
+func (g *globber) glob(segs []Segment, dir string, cb func(string) bool) bool {
 	// Consume non-wildcard path elements simply by following the path. This may
 	// seem like an optimization, but is actually required for "." and ".." to
 	// be used as path elements, as they do not appear in the result of ReadDir.
@@ -57,7 +85,7 @@ func glob(segs []Segment, dir string, cb func(string) bool) bool {
 		elem := segs[0].(Literal).Data
 		segs = segs[2:]
 		dir += elem + "/"
-		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		if info, err := g.fs.Stat(dir); err != nil || !info.IsDir() {
 			return true
 		}
 	}
@@ -66,13 +94,13 @@ func glob(segs []Segment, dir string, cb func(string) bool) bool {
 		return cb(dir)
 	} else if len(segs) == 1 && IsLiteral(segs[0]) {
 		path := dir + segs[0].(Literal).Data
-		if _, err := os.Stat(path); err == nil {
+		if _, err := g.fs.Stat(path); err == nil {
 			return cb(path)
 		}
 		return true
 	}
 
-	infos, err := readDir(dir)
+	infos, err := g.fs.ReadDir(dir)
 	if err != nil {
 		// XXX Silently drop the error
 		return true
@@ -123,8 +151,15 @@ func glob(segs []Segment, dir string, cb func(string) bool) bool {
 
 		for _, info := range infos {
 			name := info.Name()
-			if matchElement(first, name) && info.IsDir() {
-				if !glob(rest, dir+name+"/", cb) {
+			if !matchElement(first, name) {
+				continue
+			}
+			if info.IsDir() {
+				if !g.glob(rest, dir+name+"/", cb) {
+					return false
+				}
+			} else if info.Mode()&os.ModeSymlink != 0 && g.opts.FollowSymlinks {
+				if !g.globSymlink(rest, dir+name, cb) {
 					return false
 				}
 			}
@@ -150,13 +185,19 @@ func glob(segs []Segment, dir string, cb func(string) bool) bool {
 	return true
 }
 
-// readDir is just like ioutil.ReadDir except that it treats an argument of ""
-// as ".".
-func readDir(dir string) ([]os.FileInfo, error) {
-	if dir == "" {
-		dir = "."
+// globSymlink descends into a symbolic link if it leads to a directory that is
+// not already being visited through another symbolic link.
+func (g *globber) globSymlink(segs []Segment, path string, cb func(string) bool) bool {
+	real, err := g.fs.EvalSymlinks(path)
+	if err != nil || g.following[real] {
+		return true
+	}
+	if info, err := g.fs.Stat(real); err != nil || !info.IsDir() {
+		return true
 	}
-	return ioutil.ReadDir(dir)
+	g.following[real] = true
+	defer delete(g.following, real)
+	return g.glob(segs, path+"/", cb)
 }
 
 // matchElement matches a path element against segments, which may not contain
@@ -46,6 +46,14 @@ var globCases = []globCase{
 	{"?", []string{"a", "b", "c"}},
 	{"??", []string{"d1", "d2", "dX"}},
 
+	// Character classes.
+	{"[ab]", []string{"a", "b"}},
+	{"[a-b]/X", []string{"a/X", "b/X"}},
+	{"d[0-9]", []string{"d1", "d2"}},
+	{"d[!0-9]", []string{"dX"}},
+	{"[^a-d]*", []string{"lorem", "ipsum"}},
+	{"[]", []string{}},
+
 	// Nonexistent paths.
 	{"xxxx", []string{}},
 	{"xxxx/*", []string{}},
@@ -97,3 +105,41 @@ func TestGlob(t *testing.T) {
 		}
 	})
 }
+
+func TestGlobWithOptions(t *testing.T) {
+	util.InTempDir(func(string) {
+		for _, dir := range []string{"d", "d/e"} {
+			if err := os.Mkdir(dir, 0755); err != nil {
+				panic(err)
+			}
+		}
+		for _, link := range [][2]string{{"d", "l"}, {"..", "d/e/up"}} {
+			if err := os.Symlink(link[0], link[1]); err != nil {
+				t.Skip("cannot create symlink:", err)
+			}
+		}
+		for _, tc := range []struct {
+			pattern string
+			opts    Options
+			want    []string
+		}{
+			{"*/e", Options{}, []string{"d/e"}},
+			{"*/e", Options{FollowSymlinks: true}, []string{"d/e", "l/e"}},
+			{"**/up", Options{}, []string{"d/e/up"}},
+			// Cycles through symlinks are not followed.
+			{"**/up", Options{FollowSymlinks: true},
+				[]string{"d/e/up", "d/e/up/e/up", "l/e/up"}},
+		} {
+			names := []string{}
+			Parse(tc.pattern).GlobWithOptions(tc.opts, func(name string) bool {
+				names = append(names, name)
+				return true
+			})
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("GlobWithOptions(%q, %v) => %v, want %v",
+					tc.pattern, tc.opts, names, tc.want)
+			}
+		}
+	})
+}
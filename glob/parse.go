@@ -2,10 +2,15 @@ package glob
 
 import (
 	"bytes"
+	"os"
 	"unicode/utf8"
 )
 
-// Parse parses a pattern.
+// Parse parses a pattern. Besides the wildcards ?, * and **, it supports
+// character classes like [abc] and [a-z], which match one rune; a class that
+// starts with ! or ^ matches runes not in it. A [ that does not start a
+// well-formed class is literal. On Windows, \ separates path elements like /
+// instead of escaping the next rune.
 func Parse(s string) Pattern {
 	segments := []Segment{}
 	add := func(seg Segment) {
@@ -16,11 +21,14 @@ func Parse(s string) Pattern {
 rune:
 	for {
 		r := p.next()
+		if isSeparator(r) {
+			r = '/'
+		}
 		switch r {
 		case eof:
 			break rune
 		case '?':
-			add(Wild{Question, false, nil})
+			add(Wild{Question, false, nil, nil})
 		case '*':
 			n := 1
 			for p.next() == '*' {
@@ -28,22 +36,39 @@ rune:
 			}
 			p.backup()
 			if n == 1 {
-				add(Wild{Star, false, nil})
+				add(Wild{Star, false, nil, nil})
 			} else {
-				add(Wild{StarStar, false, nil})
+				add(Wild{StarStar, false, nil, nil})
 			}
 		case '/':
-			for p.next() == '/' {
+			for isSeparator(p.next()) {
 			}
 			p.backup()
 			add(Slash{})
 		default:
+			if r == '[' {
+				if class, n, ok := parseClass(s[p.pos:]); ok {
+					p.pos += n
+					add(Wild{Question, false, []func(rune) bool{class}, nil})
+					continue
+				}
+			}
 			var literal bytes.Buffer
 		literal:
 			for {
+				if isSeparator(r) {
+					break literal
+				}
 				switch r {
-				case '?', '*', '/', eof:
+				case '?', '*', eof:
 					break literal
+				case '[':
+					if literal.Len() > 0 {
+						if _, _, ok := parseClass(s[p.pos:]); ok {
+							break literal
+						}
+					}
+					literal.WriteRune(r)
 				case '\\':
 					r = p.next()
 					if r == eof {
@@ -62,6 +87,58 @@ rune:
 	return Pattern{segments, ""}
 }
 
+func isSeparator(r rune) bool {
+	return r == '/' || r == os.PathSeparator
+}
+
+// ParseClass parses the content of a character class, like "a-z" or "!abc",
+// and returns a function that matches runes in the class.
+func ParseClass(s string) (func(rune) bool, bool) {
+	class, n, ok := parseClass(s + "]")
+	if !ok || n != len(s)+1 {
+		return nil, false
+	}
+	return class, true
+}
+
+// parseClass parses a character class whose opening [ has been consumed. It
+// returns a function that matches runes in the class, and the number of bytes
+// consumed including the closing ].
+func parseClass(s string) (func(rune) bool, int, bool) {
+	i := 0
+	negate := false
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		negate = true
+		i++
+	}
+	var ranges [][2]rune
+	for first := true; i < len(s); first = false {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r == ']' && !first {
+			return func(r rune) bool {
+				for _, rg := range ranges {
+					if rg[0] <= r && r <= rg[1] {
+						return !negate
+					}
+				}
+				return negate
+			}, i, true
+		}
+		if r == '\\' && i < len(s) {
+			r, size = utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		lo, hi := r, r
+		if i+1 < len(s) && s[i] == '-' && s[i+1] != ']' {
+			hi, size = utf8.DecodeRuneInString(s[i+1:])
+			i += 1 + size
+		}
+		ranges = append(ranges, [2]rune{lo, hi})
+	}
+	return nil, 0, false
+}
+
 // XXX Contains duplicate code with parse/parser.go.
 
 type parser struct {
@@ -12,25 +12,18 @@ var parseCases = []struct {
 	{``, []Segment{}},
 	{`foo`, []Segment{Literal{"foo"}}},
 	{`*foo*bar`, []Segment{
-		Wild{Star, false, nil}, Literal{"foo"},
-		Wild{Star, false, nil}, Literal{"bar"}}},
+		Wild{Star, false, nil, nil}, Literal{"foo"},
+		Wild{Star, false, nil, nil}, Literal{"bar"}}},
 	{`foo**bar`, []Segment{
-		Literal{"foo"}, Wild{StarStar, false, nil}, Literal{"bar"}}},
+		Literal{"foo"}, Wild{StarStar, false, nil, nil}, Literal{"bar"}}},
 	{`/usr/a**b/c`, []Segment{
 		Slash{}, Literal{"usr"}, Slash{}, Literal{"a"},
-		Wild{StarStar, false, nil}, Literal{"b"}, Slash{}, Literal{"c"}}},
+		Wild{StarStar, false, nil, nil}, Literal{"b"}, Slash{}, Literal{"c"}}},
 	{`??b`, []Segment{
-		Wild{Question, false, nil}, Wild{Question, false, nil}, Literal{"b"}}},
+		Wild{Question, false, nil, nil}, Wild{Question, false, nil, nil}, Literal{"b"}}},
 	// Multiple slashes should be parsed as one.
 	{`//a//b`, []Segment{
 		Slash{}, Literal{"a"}, Slash{}, Literal{"b"}}},
-	// Escaping.
-	{`\*\?b`, []Segment{
-		Literal{"*?b"},
-	}},
-	{`abc\`, []Segment{
-		Literal{"abc"},
-	}},
 }
 
 func TestParse(t *testing.T) {
@@ -41,3 +34,42 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+var classCases = []struct {
+	class   string
+	matches string
+	misses  string
+}{
+	{"abc", "abc", "dA-"},
+	{"a-c", "abc", "dA-"},
+	{"!a-c", "dA-", "abc"},
+	{"^a-cx", "dA-", "abcx"},
+	{"]a", "]a", "b"},
+	{"a-", "a-", "b"},
+	{`\]\-`, "]-", "a"},
+}
+
+func TestParseClass(t *testing.T) {
+	for _, tc := range classCases {
+		match, ok := ParseClass(tc.class)
+		if !ok {
+			t.Errorf("ParseClass(%q) failed", tc.class)
+			continue
+		}
+		for _, r := range tc.matches {
+			if !match(r) {
+				t.Errorf("class %q does not match %q", tc.class, r)
+			}
+		}
+		for _, r := range tc.misses {
+			if match(r) {
+				t.Errorf("class %q matches %q", tc.class, r)
+			}
+		}
+	}
+	for _, bad := range []string{"", "!", "a]b"} {
+		if _, ok := ParseClass(bad); ok {
+			t.Errorf("ParseClass(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// +build !windows,!plan9
+
+package glob
+
+func init() {
+	parseCases = append(parseCases, []struct {
+		src  string
+		want []Segment
+	}{
+		// Escaping.
+		{`\*\?b`, []Segment{
+			Literal{"*?b"},
+		}},
+		{`abc\`, []Segment{
+			Literal{"abc"},
+		}},
+	}...)
+}
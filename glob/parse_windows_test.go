@@ -0,0 +1,14 @@
+package glob
+
+func init() {
+	parseCases = append(parseCases, []struct {
+		src  string
+		want []Segment
+	}{
+		// Backslashes separate path elements.
+		{`C:\a\\b/c`, []Segment{
+			Literal{"C:"}, Slash{}, Literal{"a"}, Slash{}, Literal{"b"}, Slash{}, Literal{"c"}}},
+		{`\*`, []Segment{
+			Slash{}, Wild{Star, false, nil, nil}}},
+	}...)
+}
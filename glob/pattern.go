@@ -25,6 +25,9 @@ type Wild struct {
 	Type        WildType
 	MatchHidden bool
 	Matchers    []func(rune) bool
+	// Runes matched by any of Excluders are not matched, regardless of
+	// Matchers.
+	Excluders []func(rune) bool
 }
 
 // WildType is the type of a Wild.
@@ -39,6 +42,11 @@ const (
 
 // Match returns whether a rune is within the match set.
 func (w Wild) Match(r rune) bool {
+	for _, e := range w.Excluders {
+		if e(r) {
+			return false
+		}
+	}
 	if len(w.Matchers) == 0 {
 		return true
 	}
@@ -7,22 +7,24 @@ import (
 	"github.com/elves/elvish/util"
 )
 
-// ErrorEntry represents one parse error.
+// ErrorEntry represents one parse error. Expected describes what the parser
+// expected at the position of the error, like "')'" or "variable name".
 type ErrorEntry struct {
-	Message string
-	Context util.SourceRange
+	Message  string
+	Context  util.SourceRange
+	Expected []string
 }
 
-// Error stores multiple ErrorEntry's and can pretty print them.
-type Error struct {
+// MultiError stores multiple ErrorEntry's and can pretty print them.
+type MultiError struct {
 	Entries []*ErrorEntry
 }
 
-func (pe *Error) Add(msg string, ctx *util.SourceRange) {
-	pe.Entries = append(pe.Entries, &ErrorEntry{msg, *ctx})
+func (pe *MultiError) Add(msg string, ctx *util.SourceRange, expected ...string) {
+	pe.Entries = append(pe.Entries, &ErrorEntry{msg, *ctx, expected})
 }
 
-func (pe *Error) Error() string {
+func (pe *MultiError) Error() string {
 	switch len(pe.Entries) {
 	case 0:
 		return "no parse error"
@@ -44,7 +46,7 @@ func (pe *Error) Error() string {
 	}
 }
 
-func (pe *Error) Pprint(indent string) string {
+func (pe *MultiError) Pprint(indent string) string {
 	buf := new(bytes.Buffer)
 
 	switch len(pe.Entries) {
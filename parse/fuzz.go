@@ -0,0 +1,35 @@
+package parse
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Fuzz is an entry point for fuzzers like go-fuzz. It parses data, and panics
+// if the parser violates one of its guarantees for arbitrary input: Parse never
+// panics, and each error it returns has a valid position within the source and
+// says what the parser expected. It returns 1 if data parses without errors,
+// and 0 otherwise.
+func Fuzz(data []byte) int {
+	if !utf8.Valid(data) {
+		return 0
+	}
+	src := string(data)
+	_, err := Parse("[fuzz]", src)
+	if err == nil {
+		return 1
+	}
+	for _, e := range err.(*MultiError).Entries {
+		begin, end := e.Context.Begin, e.Context.End
+		if begin < 0 || begin > end || end > len(src) {
+			panic(fmt.Sprintf("error %q has bad position %d-%d", e.Message, begin, end))
+		}
+		if !utf8.ValidString(src[begin:end]) {
+			panic(fmt.Sprintf("error %q splits a rune at %d-%d", e.Message, begin, end))
+		}
+		if len(e.Expected) == 0 {
+			panic(fmt.Sprintf("error %q does not say what is expected", e.Message))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package parse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFuzz(t *testing.T) {
+	var srcs []string
+	for _, tc := range goodCases {
+		srcs = append(srcs, tc.src)
+	}
+	for _, tc := range badCases {
+		srcs = append(srcs, tc.src)
+	}
+	srcs = append(srcs, "a $é", "a [&é", "\"\\é", "a << é",
+		// Used to take exponential time.
+		strings.Repeat("(", 100)+"?", strings.Repeat("{(", 100)+"?")
+	// Prefixes of valid code exercise most of the error paths.
+	for _, src := range srcs {
+		for i := range src {
+			Fuzz([]byte(src[:i]))
+		}
+		Fuzz([]byte(src))
+	}
+}
+
+var expectedCases = []struct {
+	src      string
+	expected []string
+}{
+	{"a (", []string{"')'"}},
+	{"a >", []string{"a composite term representing filename"}},
+	{"a 'b", []string{"closing quote"}},
+	{"a b)", []string{"end of code"}},
+}
+
+func TestErrorExpected(t *testing.T) {
+	for _, tc := range expectedCases {
+		_, err := Parse("[test]", tc.src)
+		if err == nil {
+			t.Errorf("Parse(%q) returns no error", tc.src)
+			continue
+		}
+		expected := err.(*MultiError).Entries[0].Expected
+		if !reflect.DeepEqual(expected, tc.expected) {
+			t.Errorf("Parse(%q) expects %q, want %q", tc.src, expected, tc.expected)
+		}
+	}
+}
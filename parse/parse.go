@@ -6,13 +6,13 @@ package parse
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
+	"strings"
 	"unicode"
 )
 
 // Parse parses Elvish source. If the error is not nil, it always has type
-// ParseError.
+// *MultiError.
 func Parse(srcname, src string) (*Chunk, error) {
 	ps := NewParser(srcname, src)
 	n := ParseChunk(ps)
@@ -23,15 +23,17 @@ func Parse(srcname, src string) (*Chunk, error) {
 // Errors.
 var (
 	errShouldBeForm         = newError("", "form")
-	errBadLHS               = errors.New("bad assignment LHS")
-	errDuplicateExitusRedir = newError("duplicate exitus redir")
-	errBadRedirSign         = newError("bad redir sign", "'<'", "'>'", "'>>'", "'<>'")
+	errBadLHS               = newErrorHint("bad assignment LHS", "variable name")
+	errDuplicateExitusRedir = newErrorHint("duplicate exitus redir", "at most one exitus redir")
+	errBadRedirSign         = newError("bad redir sign", "'<'", "'>'", "'>>'", "'<>'", "'>>>'", "'<<'", "'<<<'")
+	errShouldBeDelimiter    = newError("", "a bareword or quoted string as delimiter")
+	errHeredocUnterminated  = newErrorHint("here-document not terminated", "line with the delimiter")
 	errShouldBeFD           = newError("", "a composite term representing fd")
 	errShouldBeFilename     = newError("", "a composite term representing filename")
 	errShouldBeArray        = newError("", "spaced")
-	errStringUnterminated   = newError("string not terminated")
-	errChainedAssignment    = newError("chained assignment not yet supported")
-	errInvalidEscape        = newError("invalid escape sequence")
+	errStringUnterminated   = newErrorHint("string not terminated", "closing quote")
+	errChainedAssignment    = newErrorHint("chained assignment not yet supported", "space")
+	errInvalidEscape        = newErrorHint("invalid escape sequence", "valid escape sequence")
 	errInvalidEscapeOct     = newError("invalid escape sequence", "octal digit")
 	errInvalidEscapeHex     = newError("invalid escape sequence", "hex digit")
 	errInvalidEscapeControl = newError("invalid control sequence", "a rune between @ (0x40) and _(0x5F)")
@@ -44,7 +46,7 @@ var (
 	errShouldBeRParen             = newError("", "')'")
 	errShouldBeCompound           = newError("", "compound")
 	errShouldBeEqual              = newError("", "'='")
-	errBothElementsAndPairs       = newError("cannot contain both list elements and map pairs")
+	errBothElementsAndPairs       = newErrorHint("cannot contain both list elements and map pairs", "list elements", "map pairs")
 	errShouldBeNewline            = newError("", "newline")
 )
 
@@ -77,6 +79,9 @@ func (bn *Chunk) parseSeps(ps *Parser) int {
 		if isPipelineSep(r) {
 			// parse as a Sep
 			parseSep(bn, ps, r)
+			if r == '\n' {
+				parseHeredocBodies(bn, ps)
+			}
 			nseps++
 		} else if IsSpace(r) {
 			// parse a run of spaces as a Sep
@@ -156,7 +161,7 @@ func (fn *Form) parse(ps *Parser) {
 			return
 		}
 		// Bad form.
-		ps.error(fmt.Errorf("bad rune at form head: %q", ps.peek()))
+		ps.error(newErrorHint(fmt.Sprintf("bad rune at form head: %q", ps.peek()), "compound"))
 	}
 	fn.setHead(ParseCompound(ps, CmdExpr))
 	parseSpaces(fn, ps)
@@ -226,7 +231,7 @@ func (fn *Form) parse(ps *Parser) {
 // assignment to fn.Assignments and returns true. Otherwise it rewinds the
 // parser and returns false.
 func (fn *Form) tryAssignment(ps *Parser) bool {
-	if !startsIndexing(ps.peek(), LHSExpr) {
+	if !startsIndexing(ps.peek(), LHSExpr) || ps.notAssignments[ps.pos] {
 		return false
 	}
 
@@ -237,6 +242,7 @@ func (fn *Form) tryAssignment(ps *Parser) bool {
 	if len(ps.errors.Entries) > len(errorEntries) {
 		ps.errors.Entries = errorEntries
 		ps.pos = pos
+		ps.notAssignments[pos] = true
 		return false
 	}
 	fn.addToAssignments(an)
@@ -280,7 +286,7 @@ func checkVariableInAssignment(p *Primary, ps *Parser) bool {
 	}
 	for _, r := range p.Value {
 		// XXX special case '&' and '@'.
-		if !allowedInVariableName(r) && r != '&' && r != '@' {
+		if !AllowedInVariableName(r) && r != '&' && r != '@' {
 			return false
 		}
 	}
@@ -301,13 +307,21 @@ func (ern *ExitusRedir) parse(ps *Parser) {
 	ern.setDest(ParseCompound(ps, NormalExpr))
 }
 
-// Redir = { Compound } { '<'|'>'|'<>'|'>>' } { Space } ( '&'? Compound )
+// Redir = { Compound } { '<'|'>'|'<>'|'>>'|'>>>'|'<<'|'<<<' } { Space } ( '&'? Compound )
+//
+// For the here-document redirection '<<', Right is the delimiter, and the body
+// consists of the lines after the next newline up to a line that consists of
+// the delimiter only.
 type Redir struct {
 	node
 	Left      *Compound
 	Mode      RedirMode
 	RightIsFd bool
 	Right     *Compound
+	// The body of a here-document, and whether its delimiter is quoted, in
+	// which case the body is not subject to variable interpolation.
+	Heredoc        string
+	HeredocLiteral bool
 }
 
 func (rn *Redir) parse(ps *Parser, dest *Compound) {
@@ -331,12 +345,19 @@ func (rn *Redir) parse(ps *Parser, dest *Compound) {
 		rn.Mode = Append
 	case "<>":
 		rn.Mode = ReadWrite
+	case ">>>":
+		rn.Mode = Capture
+	case "<<":
+		rn.Mode = Heredoc
+	case "<<<":
+		rn.Mode = HereString
 	default:
 		ps.error(errBadRedirSign)
 	}
 	addSep(rn, ps)
 	parseSpaces(rn, ps)
-	if parseSep(rn, ps, '&') {
+	if rn.Mode != Capture && rn.Mode != Heredoc && rn.Mode != HereString &&
+		parseSep(rn, ps, '&') {
 		rn.RightIsFd = true
 	}
 	rn.setRight(ParseCompound(ps, NormalExpr))
@@ -348,6 +369,43 @@ func (rn *Redir) parse(ps *Parser, dest *Compound) {
 		}
 		return
 	}
+	if rn.Mode == Heredoc {
+		delim := rn.Right.Indexings[0].Head
+		if len(rn.Right.Indexings) != 1 || len(rn.Right.Indexings[0].Indicies) > 0 ||
+			(delim.Type != Bareword && delim.Type != SingleQuoted && delim.Type != DoubleQuoted) {
+			ps.errorp(rn.Right.Begin(), rn.Right.End(), errShouldBeDelimiter)
+			return
+		}
+		rn.HeredocLiteral = delim.Type != Bareword
+		ps.heredocs = append(ps.heredocs, rn)
+	}
+}
+
+// parseHeredocBodies parses the bodies of pending here-documents, which start
+// right after a newline, and adds them to n as a Sep.
+func parseHeredocBodies(n Node, ps *Parser) {
+	for _, rn := range ps.heredocs {
+		delim := rn.Right.Indexings[0].Head.Value
+		var body bytes.Buffer
+		for {
+			if ps.pos == len(ps.src) {
+				ps.error(errHeredocUnterminated)
+				break
+			}
+			line := ps.src[ps.pos:]
+			if i := strings.IndexByte(line, '\n'); i != -1 {
+				line = line[:i+1]
+			}
+			ps.pos += len(line)
+			if strings.TrimSuffix(line, "\n") == delim {
+				break
+			}
+			body.WriteString(line)
+		}
+		rn.Heredoc = body.String()
+	}
+	ps.heredocs = nil
+	addSep(n, ps)
 }
 
 func isRedirSign(r rune) bool {
@@ -364,6 +422,11 @@ const (
 	Write
 	ReadWrite
 	Append
+	// Capture stores the output in a variable instead of a file.
+	Capture
+	// Heredoc and HereString feed a literal string as the input.
+	Heredoc
+	HereString
 )
 
 // Compound = { Indexing }
@@ -693,7 +756,7 @@ func (pn *Primary) variable(ps *Parser) {
 		ps.error(errShouldBeVariableName)
 		ps.next()
 	}
-	for allowedInVariableName(ps.peek()) {
+	for AllowedInVariableName(ps.peek()) {
 		ps.next()
 	}
 }
@@ -702,7 +765,7 @@ func (pn *Primary) variable(ps *Parser) {
 // * Anything beyond ASCII that is printable
 // * Letters and numbers
 // * The symbols "-_:~"
-func allowedInVariableName(r rune) bool {
+func AllowedInVariableName(r rune) bool {
 	return (r >= 0x80 && unicode.IsPrint(r)) ||
 		('0' <= r && r <= '9') ||
 		('a' <= r && r <= 'z') ||
@@ -862,7 +925,7 @@ func (pn *Primary) bareword(ps *Parser, ctx ExprCtx) {
 // The seemingly weird inclusion of \ is for easier path manipulation in
 // Windows.
 func allowedInBareword(r rune, ctx ExprCtx) bool {
-	return allowedInVariableName(r) || r == '.' || r == '/' || r == '\\' ||
+	return AllowedInVariableName(r) || r == '.' || r == '/' || r == '\\' ||
 		r == '@' || r == '%' || r == '+' || r == '!' ||
 		(ctx != LHSExpr && ctx != strictExpr && r == '=') ||
 		(ctx != BracedElemExpr && ctx != strictExpr && r == ',') ||
@@ -945,6 +1008,10 @@ spaces:
 		switch {
 		case isSpace(r):
 			ps.next()
+			if r == '\n' && len(ps.heredocs) > 0 {
+				addSep(n, ps)
+				parseHeredocBodies(n, ps)
+			}
 		case r == '`': // line continuation
 			ps.next()
 			switch ps.peek() {
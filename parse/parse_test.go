@@ -69,6 +69,41 @@ var goodCases = []struct {
 			{"Redir", fs{"Left": "6", "Mode": ReadWrite, "Right": "d"}},
 		},
 	}}},
+	// Capture redirections
+	{"a >>>out 2>>> err", ast{"Chunk/Pipeline/Form", fs{
+		"Head": "a",
+		"Redirs": []ast{
+			{"Redir", fs{"Mode": Capture, "Right": "out"}},
+			{"Redir", fs{"Left": "2", "Mode": Capture, "Right": "err"}},
+		},
+	}}},
+	// Here-strings and here-documents
+	{"a <<< $x", ast{"Chunk/Pipeline/Form", fs{
+		"Head":   "a",
+		"Redirs": []ast{{"Redir", fs{"Mode": HereString, "Right": "$x"}}},
+	}}},
+	{"a << EOF; b\nfoo $x\n EOF\nEOF\nc", ast{"Chunk", fs{
+		"Pipelines": []ast{
+			{"Pipeline/Form", fs{
+				"Head": "a",
+				"Redirs": []ast{{"Redir", fs{
+					"Mode": Heredoc, "Right": "EOF", "Heredoc": "foo $x\n EOF\n"}}},
+			}},
+			{"Pipeline/Form", fs{"Head": "b"}},
+			{"Pipeline/Form", fs{"Head": "c"}},
+		},
+	}}},
+	{"a << 'EOF' 3<< x\n$y\nEOF\nEOF\nx", ast{"Chunk", fs{
+		"Pipelines": []ast{{"Pipeline/Form", fs{
+			"Head": "a",
+			"Redirs": []ast{
+				{"Redir", fs{"Mode": Heredoc, "Right": "'EOF'",
+					"Heredoc": "$y\n", "HeredocLiteral": true}},
+				{"Redir", fs{"Left": "3", "Mode": Heredoc, "Right": "x",
+					"Heredoc": "EOF\n"}},
+			},
+		}}},
+	}}},
 	// Exitus redirection
 	{"a ?>$e", ast{"Chunk/Pipeline/Form", fs{
 		"Head":        "a",
@@ -308,6 +343,10 @@ var badCases = []struct {
 	{"a (", 3}, {"a [", 3}, {"a {", 3},
 	// Bogus ampersand.
 	{"a & &", 4}, {"a [&", 4},
+	// Unterminated here-documents.
+	{"a << EOF", 8}, {"a << EOF\nfoo\n", 13},
+	// Bad here-document delimiter.
+	{"a << $x\nfoo", 5},
 }
 
 func TestParseError(t *testing.T) {
@@ -317,7 +356,7 @@ func TestParseError(t *testing.T) {
 			t.Errorf("Parse(%q) returns no error", tc.src)
 			continue
 		}
-		posErr0 := err.(*Error).Entries[0]
+		posErr0 := err.(*MultiError).Entries[0]
 		if posErr0.Context.Begin != tc.pos {
 			t.Errorf("Parse(%q) first error begins at %d, want %d. Errors are:%s\n", tc.src, posErr0.Context.Begin, tc.pos, err)
 		}
@@ -2,7 +2,6 @@ package parse
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"strings"
 	"unicode/utf8"
@@ -19,24 +18,34 @@ type Parser struct {
 	pos     int
 	overEOF int
 	cutsets []map[rune]int
-	errors  Error
+	errors  MultiError
+	// Here-document redirections whose bodies start after the next newline.
+	heredocs []*Redir
+	// Positions where an assignment has failed to parse. Forms try to parse
+	// an assignment first, and without remembering the failures, code with
+	// nested output captures like "((((a" would take exponential time.
+	notAssignments map[int]bool
 }
 
 // NewParser creates a new parser from a piece of source text and its name.
 func NewParser(srcname, src string) *Parser {
-	return &Parser{srcname, src, 0, 0, []map[rune]int{{}}, Error{}}
+	return &Parser{srcname, src, 0, 0, []map[rune]int{{}}, MultiError{}, nil, make(map[int]bool)}
 }
 
 // Done tells the parser that parsing has completed.
 func (ps *Parser) Done() {
+	if len(ps.heredocs) > 0 {
+		ps.error(errHeredocUnterminated)
+		ps.heredocs = nil
+	}
 	if ps.pos != len(ps.src) {
 		r, _ := utf8.DecodeRuneInString(ps.src[ps.pos:])
-		ps.error(fmt.Errorf("unexpected rune %q", r))
+		ps.error(newErrorHint(fmt.Sprintf("unexpected rune %q", r), "end of code"))
 	}
 }
 
 // Errors gets the parsing errors after calling one of the parse* functions. If
-// the return value is not nil, it is always of type Error.
+// the return value is not nil, it is always of type *MultiError.
 func (ps *Parser) Errors() error {
 	if len(ps.errors.Entries) > 0 {
 		return &ps.errors
@@ -83,20 +92,36 @@ func (ps *Parser) backup() {
 }
 
 func (ps *Parser) errorp(begin, end int, e error) {
-	ps.errors.Add(e.Error(), util.NewSourceRange(ps.srcName, ps.src, begin, end, nil))
+	var expected []string
+	if pe, ok := e.(*parseError); ok {
+		expected = pe.expected
+	}
+	ps.errors.Add(e.Error(),
+		util.NewSourceRange(ps.srcName, ps.src, begin, end, nil), expected...)
 }
 
+// error records an error at the current position, spanning the next rune if
+// there is one.
 func (ps *Parser) error(e error) {
-	end := ps.pos
-	if end < len(ps.src) {
-		end++
-	}
-	ps.errorp(ps.pos, end, e)
+	_, size := utf8.DecodeRuneInString(ps.src[ps.pos:])
+	ps.errorp(ps.pos, ps.pos+size, e)
+}
+
+// parseError is an error with a description of what the parser expected.
+type parseError struct {
+	message  string
+	expected []string
 }
 
+func (e *parseError) Error() string {
+	return e.message
+}
+
+// newError creates an error whose message ends with what the parser expected,
+// like "bad redir sign, should be '<' or '>'".
 func newError(text string, shouldbe ...string) error {
 	if len(shouldbe) == 0 {
-		return errors.New(text)
+		return &parseError{text, nil}
 	}
 	var buf bytes.Buffer
 	if len(text) > 0 {
@@ -111,5 +136,11 @@ func newError(text string, shouldbe ...string) error {
 		}
 		buf.WriteString(opt)
 	}
-	return errors.New(buf.String())
+	return &parseError{buf.String(), shouldbe}
+}
+
+// newErrorHint creates an error like newError, but its message does not
+// mention what the parser expected.
+func newErrorHint(text string, expected ...string) error {
+	return &parseError{text, expected}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 )
 
@@ -106,6 +107,58 @@ func pprintAST(n Node, wr io.Writer, indent int, leading string) {
 	}
 }
 
+// ASTNode is the AST part of a Node in a form that can be encoded as JSON.
+// Properties are the fields of the Node that are not children, formatted as
+// strings.
+type ASTNode struct {
+	Type       string            `json:"type"`
+	Begin      int               `json:"begin"`
+	End        int               `json:"end"`
+	Source     string            `json:"source"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Children   []*ASTNode        `json:"children,omitempty"`
+}
+
+// DumpAST converts the AST part of a Node to an ASTNode. Unlike PprintAST, it
+// does not coalesce nodes with only one child.
+func DumpAST(n Node) *ASTNode {
+	nodeType := reflect.TypeOf((*Node)(nil)).Elem()
+	nt := reflect.TypeOf(n).Elem()
+	nv := reflect.ValueOf(n).Elem()
+	dumped := &ASTNode{Type: nt.Name(),
+		Begin: n.Begin(), End: n.End(), Source: n.SourceText()}
+
+	for i := 0; i < nt.NumField(); i++ {
+		f := nt.Field(i)
+		if f.Anonymous {
+			// embedded node struct, skip
+			continue
+		}
+		fv := nv.Field(i)
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Implements(nodeType) {
+			for j := 0; j < fv.Len(); j++ {
+				dumped.Children = append(dumped.Children,
+					DumpAST(fv.Index(j).Interface().(Node)))
+			}
+		} else if child, ok := fv.Interface().(Node); ok {
+			if reflect.Indirect(fv) != zeroValue {
+				dumped.Children = append(dumped.Children, DumpAST(child))
+			}
+		} else {
+			if dumped.Properties == nil {
+				dumped.Properties = make(map[string]string)
+			}
+			dumped.Properties[f.Name] = fmt.Sprint(fv.Interface())
+		}
+	}
+	// Children from different fields are put in the order they appear in the
+	// source.
+	sort.SliceStable(dumped.Children, func(i, j int) bool {
+		return dumped.Children[i].Begin < dumped.Children[j].Begin
+	})
+	return dumped
+}
+
 // PprintParseTree pretty prints the parse tree part of a Node.
 func PprintParseTree(n Node, wr io.Writer) {
 	pprintParseTree(n, wr, 0)
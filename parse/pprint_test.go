@@ -63,3 +63,23 @@ func TestPprint(t *testing.T) {
 		}
 	}
 }
+
+func TestDumpAST(t *testing.T) {
+	n, err := Parse("[test]", "echo $x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast := DumpAST(n)
+	if ast.Type != "Chunk" || ast.Begin != 0 || ast.End != 7 || ast.Source != "echo $x" {
+		t.Errorf("DumpAST: got root %v", ast)
+	}
+	form := ast.Children[0].Children[0]
+	if form.Type != "Form" || len(form.Children) != 2 {
+		t.Fatalf("DumpAST: got form %v", form)
+	}
+	primary := form.Children[1].Children[0].Children[0]
+	if primary.Type != "Primary" || primary.Begin != 5 ||
+		primary.Properties["Type"] != "Variable" || primary.Properties["Value"] != "x" {
+		t.Errorf("DumpAST: got primary %v", primary)
+	}
+}
@@ -15,9 +15,9 @@ func (i PrimaryType) String() string {
 	return _PrimaryType_name[_PrimaryType_index[i]:_PrimaryType_index[i+1]]
 }
 
-const _RedirMode_name = "BadRedirModeReadWriteReadWriteAppend"
+const _RedirMode_name = "BadRedirModeReadWriteReadWriteAppendCaptureHeredocHereString"
 
-var _RedirMode_index = [...]uint8{0, 12, 16, 21, 30, 36}
+var _RedirMode_index = [...]uint8{0, 12, 16, 21, 30, 36, 43, 50, 60}
 
 func (i RedirMode) String() string {
 	if i < 0 || i >= RedirMode(len(_RedirMode_index)-1) {
@@ -24,23 +24,28 @@ type Daemon struct {
 	// LogPathPrefix is used to derive the name of the log file by adding the
 	// pid.
 	LogPathPrefix string
+	// MetricsAddr is the address on which the daemon serves runtime metrics
+	// over HTTP. This field is optional; metrics are not served if it is
+	// empty.
+	MetricsAddr string
 }
 
 // Main is the entry point of the daemon sub-program. It simply sets the umask
 // (if relevant) and runs serve. It always return a nil error, since any errors
 // encountered is logged in the serve function.
-func (d *Daemon) Main(serve func(string, string)) error {
+func (d *Daemon) Main(serve func(string, string, string)) error {
 	setUmask()
-	serve(d.SockPath, d.DbPath)
+	serve(d.SockPath, d.DbPath, d.MetricsAddr)
 	return nil
 }
 
 // Spawn spawns a daemon process in the background by invoking BinPath, passing
 // DbPath, SockPath and LogPathPrefix as command-line arguments after resolving
-// them to absolute paths. A suitable ProcAttr is chosen depending on the OS and
-// makes sure that the daemon is detached from the current terminal (so that it
-// is not affected by I/O or signals in the current terminal), and keeps running
-// after the current process quits.
+// them to absolute paths, as well as MetricsAddr if it is not empty. A
+// suitable ProcAttr is chosen depending on the OS and makes sure that the
+// daemon is detached from the current terminal (so that it is not affected by
+// I/O or signals in the current terminal), and keeps running after the current
+// process quits.
 func (d *Daemon) Spawn() error {
 	binPath := d.BinPath
 	// Determine binPath.
@@ -83,6 +88,9 @@ func (d *Daemon) Spawn() error {
 		"-sock", sockPath,
 		"-logprefix", logPathPrefix,
 	}
+	if d.MetricsAddr != "" {
+		args = append(args, "-metrics", d.MetricsAddr)
+	}
 
 	// TODO Redirect daemon stdout and stderr
 
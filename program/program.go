@@ -36,13 +36,17 @@ type flagSet struct {
 
 	CodeInArg, CompileOnly bool
 
+	DumpAST, DumpOps bool
+
 	Web  bool
 	Port int
 
 	Daemon bool
 	Forked int
 
-	Bin, DB, Sock string
+	StoreFsck, Repair bool
+
+	Bin, DB, Sock, Metrics string
 }
 
 func newFlagSet() *flagSet {
@@ -64,14 +68,21 @@ func newFlagSet() *flagSet {
 	f.BoolVar(&f.CodeInArg, "c", false, "take first argument as code to execute")
 	f.BoolVar(&f.CompileOnly, "compileonly", false, "Parse/Compile but do not execute")
 
+	f.BoolVar(&f.DumpAST, "dump-ast", false, "parse the script and show its AST. Useful with -json.")
+	f.BoolVar(&f.DumpOps, "dump-ops", false, "compile the script and show its ops. Useful with -json.")
+
 	f.BoolVar(&f.Web, "web", false, "run backend of web interface")
 	f.IntVar(&f.Port, "port", defaultWebPort, "the port of the web backend")
 
 	f.BoolVar(&f.Daemon, "daemon", false, "run daemon instead of shell")
 
+	f.BoolVar(&f.StoreFsck, "store-fsck", false, "check the database and quit")
+	f.BoolVar(&f.Repair, "repair", false, "repair the database if problems are found. Useful with -store-fsck.")
+
 	f.StringVar(&f.Bin, "bin", "", "path to the elvish binary")
 	f.StringVar(&f.DB, "db", "", "path to the database")
 	f.StringVar(&f.Sock, "sock", "", "path to the daemon socket")
+	f.StringVar(&f.Metrics, "metrics", "", "address to serve daemon metrics on, like localhost:3172")
 
 	return &f
 }
@@ -144,7 +155,21 @@ func FindProgram(flag *flagSet) Program {
 			DbPath:        flag.DB,
 			SockPath:      flag.Sock,
 			LogPathPrefix: flag.LogPrefix,
+			MetricsAddr:   flag.Metrics,
 		}}
+	case flag.StoreFsck:
+		if len(flag.Args()) > 0 {
+			return ShowCorrectUsage{"arguments are not allowed with -store-fsck", flag}
+		}
+		return StoreFsck{flag.DB, flag.Repair}
+	case flag.DumpAST || flag.DumpOps:
+		if len(flag.Args()) != 1 {
+			return ShowCorrectUsage{"-dump-ast and -dump-ops need exactly one script", flag}
+		}
+		if flag.DumpAST && flag.DumpOps {
+			return ShowCorrectUsage{"-dump-ast cannot be used together with -dump-ops", flag}
+		}
+		return shell.Dump{Ops: flag.DumpOps, JSON: flag.JSON, CodeInArg: flag.CodeInArg}
 	case flag.Web:
 		if len(flag.Args()) > 0 {
 			return ShowCorrectUsage{"arguments are not allowed with -web", flag}
@@ -33,6 +33,19 @@ var findProgramTests = []struct {
 	}},
 	{[]string{"-daemon"}, isDaemon},
 	{[]string{"-daemon", "x"}, isShowCorrectUsage},
+	{[]string{"-store-fsck"}, isStoreFsck},
+	{[]string{"-store-fsck", "x"}, isShowCorrectUsage},
+	{[]string{"-store-fsck", "-repair", "-db", "/db"}, func(p Program) bool {
+		return p.(StoreFsck).Repair && p.(StoreFsck).DbPath == "/db"
+	}},
+	{[]string{"-dump-ast", "a.elv"}, func(p Program) bool {
+		return !p.(shell.Dump).Ops
+	}},
+	{[]string{"-dump-ops", "-json", "-c", "echo"}, func(p Program) bool {
+		return p.(shell.Dump) == shell.Dump{Ops: true, JSON: true, CodeInArg: true}
+	}},
+	{[]string{"-dump-ast"}, isShowCorrectUsage},
+	{[]string{"-dump-ast", "-dump-ops", "a.elv"}, isShowCorrectUsage},
 
 	{[]string{"-bin", "/elvish"}, func(p Program) bool {
 		return p.(*shell.Shell).BinPath == "/elvish"
@@ -70,6 +83,7 @@ func isShowCorrectUsage(p Program) bool { _, ok := p.(ShowCorrectUsage); return
 func isShowVersion(p Program) bool      { _, ok := p.(ShowVersion); return ok }
 func isShowBuildInfo(p Program) bool    { _, ok := p.(ShowBuildInfo); return ok }
 func isDaemon(p Program) bool           { _, ok := p.(Daemon); return ok }
+func isStoreFsck(p Program) bool        { _, ok := p.(StoreFsck); return ok }
 func isWeb(p Program) bool              { _, ok := p.(*web.Web); return ok }
 func isShell(p Program) bool            { _, ok := p.(*shell.Shell); return ok }
 
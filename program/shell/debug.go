@@ -0,0 +1,44 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/util"
+)
+
+// debugException opens a debug REPL in the scope where an uncaught exception
+// was raised, if enabled by $debug-on-exception. The REPL reads lines with the
+// same editor until EOF.
+func debugException(ev *eval.Evaler, ed editor, err error) {
+	mode := ev.DebugOnException()
+	if mode == "" {
+		return
+	}
+	exc, ok := err.(*eval.Exception)
+	if !ok {
+		return
+	}
+	ns := exc.DebugNs(ev, mode == eval.DebugReadWrite)
+	if ns == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Entering %s debug REPL in the scope of the exception; press ^D to leave\n", mode)
+	for {
+		line, err := ed.ReadLine()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "Editor error:", err)
+			break
+		}
+		err = ev.SourceTextInNs(eval.NewInteractiveSource(line), ns)
+		if err != nil {
+			util.PprintError(err)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Leaving debug REPL")
+}
@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/parse"
+	"github.com/elves/elvish/util"
+)
+
+// Dump parses a script and writes its AST, or compiles it and writes its op
+// tree, without executing it. It is meant for debugging Elvish itself and for
+// external tools.
+type Dump struct {
+	Ops       bool
+	JSON      bool
+	CodeInArg bool
+}
+
+func (d Dump) Main(args []string) int {
+	return d.dump(args, os.Stdout)
+}
+
+func (d Dump) dump(args []string, out io.Writer) int {
+	name, path, code := "code from -c", "", args[0]
+	if !d.CodeInArg {
+		name = args[0]
+		var err error
+		path, err = filepath.Abs(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get full path of script %q: %v\n", name, err)
+			return 2
+		}
+		code, err = readFileUTF8(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read script %q: %v\n", name, err)
+			return 2
+		}
+	}
+
+	n, err := parse.Parse(name, code)
+	if err != nil {
+		util.PprintError(err)
+		return 2
+	}
+	if !d.Ops {
+		if d.JSON {
+			return encodeJSON(out, parse.DumpAST(n))
+		}
+		parse.PprintParseTree(n, out)
+		return 0
+	}
+
+	ev := eval.NewEvaler()
+	defer ev.Close()
+	_, tree, err := ev.CompileOpTree(n, eval.NewScriptSource(name, path, code))
+	if err != nil {
+		util.PprintError(err)
+		return 2
+	}
+	if d.JSON {
+		return encodeJSON(out, tree)
+	}
+	tree.Pprint(out)
+	return 0
+}
+
+func encodeJSON(out io.Writer, v interface{}) int {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot encode JSON:", err)
+		return 2
+	}
+	return 0
+}
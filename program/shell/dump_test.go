@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/elves/elvish/eval"
+)
+
+func TestDump(t *testing.T) {
+	var b bytes.Buffer
+	if ret := (Dump{CodeInArg: true}).dump([]string{"echo"}, &b); ret != 0 {
+		t.Errorf("dump of AST returned %d", ret)
+	}
+	if want := "Chunk/Pipeline/Form/Compound/Indexing/Primary \"echo\" 0-4\n"; b.String() != want {
+		t.Errorf("dump of AST is %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if ret := (Dump{JSON: true, CodeInArg: true}).dump([]string{"echo"}, &b); ret != 0 {
+		t.Errorf("JSON dump of AST returned %d", ret)
+	}
+	var ast map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &ast); err != nil || ast["type"] != "Chunk" {
+		t.Errorf("JSON dump of AST is %q", b.String())
+	}
+
+	b.Reset()
+	if ret := (Dump{Ops: true, JSON: true, CodeInArg: true}).dump([]string{"echo"}, &b); ret != 0 {
+		t.Errorf("JSON dump of ops returned %d", ret)
+	}
+	var ops eval.OpNode
+	if err := json.Unmarshal(b.Bytes(), &ops); err != nil || ops.Kind != "chunk" || ops.End != 4 {
+		t.Errorf("JSON dump of ops is %q", b.String())
+	}
+
+	if ret := (Dump{Ops: true, CodeInArg: true}).dump([]string{"echo $x"}, &b); ret != 2 {
+		t.Errorf("dump of ops with compilation error returned %d", ret)
+	}
+}
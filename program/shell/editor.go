@@ -6,10 +6,12 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 )
 
 type editor interface {
 	ReadLine() (string, error)
+	AfterCommand(src string, duration time.Duration, err error)
 	Close()
 }
 
@@ -34,5 +36,8 @@ func (ed *minEditor) ReadLine() (string, error) {
 	return line, err
 }
 
+func (ed *minEditor) AfterCommand(string, time.Duration, error) {
+}
+
 func (editor *minEditor) Close() {
 }
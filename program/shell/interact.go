@@ -7,11 +7,14 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/elves/elvish/edit"
 	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/types"
 	"github.com/elves/elvish/sys"
 	"github.com/elves/elvish/util"
 )
@@ -21,7 +24,8 @@ func interact(ev *eval.Evaler, dataDir string) {
 	var ed editor
 	if sys.IsATTY(os.Stdin) {
 		sigch := make(chan os.Signal)
-		signal.Notify(sigch, syscall.SIGHUP, syscall.SIGINT, sys.SIGWINCH)
+		// HUP is left to handleSignal, which runs its hooks before exiting.
+		signal.Notify(sigch, syscall.SIGINT, sys.SIGWINCH)
 		ed = edit.NewEditor(os.Stdin, os.Stderr, sigch, ev)
 	} else {
 		ed = newMinEditor(os.Stdin, os.Stderr)
@@ -44,8 +48,15 @@ func interact(ev *eval.Evaler, dataDir string) {
 	cooldown := time.Second
 	usingBasic := false
 	cmdNum := 0
+	// Whether the daemon failed to record the last command, so that
+	// store-degraded is published only when it stops working. It is set from
+	// the goroutines of ReportEval, so it is accessed atomically.
+	var storeDown int32
 
 	for {
+		// Deliver events and signals that came while the last command ran or
+		// the prompt was shown, like background jobs finishing.
+		ev.RunQueuedHooks()
 		cmdNum++
 
 		line, err := readLine()
@@ -72,11 +83,44 @@ func interact(ev *eval.Evaler, dataDir string) {
 		// No error; reset cooldown.
 		cooldown = time.Second
 
+		ev.EmitEvent(eval.EventCommandStart, types.String(line))
+		begin := time.Now()
 		err = ev.SourceText(eval.NewInteractiveSource(line))
+		duration := time.Since(begin)
+		if ev.DaemonClient != nil {
+			ev.DaemonClient.ReportEval(duration, func(err error) {
+				if err == nil {
+					atomic.StoreInt32(&storeDown, 0)
+				} else if atomic.SwapInt32(&storeDown, 1) == 0 {
+					ev.QueueEvent(eval.EventStoreDegraded, types.String(err.Error()))
+				}
+			})
+		}
 		if err != nil {
 			util.PprintError(err)
+			debugException(ev, ed, err)
+		}
+		ed.AfterCommand(line, duration, err)
+		ev.EmitEvent(eval.EventCommandEnd, commandEndInfo(line, duration, err))
+	}
+}
+
+// commandEndInfo builds the argument of the command-end event.
+func commandEndInfo(src string, duration time.Duration, err error) types.Value {
+	var exc types.Value = eval.OK
+	if err != nil {
+		if e, ok := err.(*eval.Exception); ok {
+			exc = e
+		} else {
+			exc = &eval.Exception{Cause: err}
 		}
 	}
+	return types.MakeMap(map[types.Value]types.Value{
+		types.String("src"): types.String(src),
+		types.String("duration"): types.String(
+			strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)),
+		types.String("error"): exc,
+	})
 }
 
 func sourceRC(ev *eval.Evaler, dataDir string) error {
@@ -7,6 +7,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/runtime"
 	"github.com/elves/elvish/sys"
 	"github.com/elves/elvish/util"
@@ -35,7 +36,7 @@ func (sh *Shell) Main(args []string) int {
 	ev, dataDir := runtime.InitRuntime(sh.BinPath, sh.SockPath, sh.DbPath)
 	defer runtime.CleanupRuntime(ev)
 
-	handleSignals()
+	handleSignals(ev)
 
 	if len(args) > 0 {
 		err := script(ev, args, sh.Cmd, sh.CompileOnly)
@@ -62,13 +63,13 @@ func rescue() {
 	}
 }
 
-func handleSignals() {
+func handleSignals(ev *eval.Evaler) {
 	sigs := make(chan os.Signal)
 	signal.Notify(sigs)
 	go func() {
 		for sig := range sigs {
 			logger.Println("signal", sig)
-			handleSignal(sig)
+			handleSignal(sig, ev)
 		}
 	}()
 }
@@ -6,13 +6,24 @@ import (
 	"fmt"
 	"os"
 	"syscall"
+	"time"
 
+	"github.com/elves/elvish/eval"
 	"github.com/elves/elvish/sys"
 )
 
-func handleSignal(sig os.Signal) {
+// hangUpHooksTimeout is how long the hooks of HUP can take before Elvish exits.
+const hangUpHooksTimeout = time.Second
+
+func handleSignal(sig os.Signal, ev *eval.Evaler) {
+	hooksDone := ev.QueueSignalHooks(sig)
 	switch sig {
 	case syscall.SIGHUP:
+		select {
+		case <-hooksDone:
+		case <-time.After(hangUpHooksTimeout):
+		}
+		ev.HangUpJobs()
 		syscall.Kill(0, syscall.SIGHUP)
 		os.Exit(0)
 	case syscall.SIGUSR1:
@@ -2,7 +2,10 @@ package shell
 
 import (
 	"os"
+
+	"github.com/elves/elvish/eval"
 )
 
-func handleSignal(_ os.Signal) {
+func handleSignal(sig os.Signal, ev *eval.Evaler) {
+	ev.QueueSignalHooks(sig)
 }
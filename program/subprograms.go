@@ -3,10 +3,14 @@ package program
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/elves/elvish/build"
 	daemonsvc "github.com/elves/elvish/daemon"
 	"github.com/elves/elvish/program/daemon"
+	"github.com/elves/elvish/store"
+	"github.com/elves/elvish/store/storedefs"
 )
 
 // ShowHelp shows help message.
@@ -67,3 +71,51 @@ func (d Daemon) Main([]string) int {
 	}
 	return 0
 }
+
+// StoreFsck checks, and optionally repairs, the database.
+type StoreFsck struct {
+	DbPath string
+	Repair bool
+}
+
+func (s StoreFsck) Main([]string) int {
+	dbpath := s.DbPath
+	if dbpath == "" {
+		dataDir, err := storedefs.EnsureDataDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cannot find data directory:", err)
+			return 2
+		}
+		dbpath = filepath.Join(dataDir, "db")
+	}
+
+	report, err := store.Fsck(dbpath, s.Repair)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot check database:", err)
+		return 2
+	}
+	if len(report.Problems) == 0 {
+		fmt.Println("no problems found in", dbpath)
+		return 0
+	}
+	fmt.Println("problems found in", dbpath+":")
+	for _, problem := range report.Problems {
+		fmt.Println("  " + problem)
+	}
+	if !report.Repaired {
+		fmt.Println("run with -repair to repair the database")
+		return 1
+	}
+
+	fmt.Println("repaired; the original database was moved to", report.BackupPath)
+	var buckets []string
+	for bucket := range report.Recovered {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+	for _, bucket := range buckets {
+		fmt.Printf("  %s: %d entries recovered, %d lost\n",
+			bucket, report.Recovered[bucket], report.Lost[bucket])
+	}
+	return 0
+}
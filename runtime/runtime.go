@@ -12,8 +12,18 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/elves/elvish/daemon"
 	"github.com/elves/elvish/eval"
+	"github.com/elves/elvish/eval/color"
 	daemonmod "github.com/elves/elvish/eval/daemon"
+	"github.com/elves/elvish/eval/direnv"
+	"github.com/elves/elvish/eval/env"
+	"github.com/elves/elvish/eval/event"
+	"github.com/elves/elvish/eval/html"
+	"github.com/elves/elvish/eval/ip"
+	"github.com/elves/elvish/eval/mount"
 	"github.com/elves/elvish/eval/re"
+	"github.com/elves/elvish/eval/semver"
+	"github.com/elves/elvish/eval/str"
+	sysmod "github.com/elves/elvish/eval/sys"
 	daemonp "github.com/elves/elvish/program/daemon"
 	"github.com/elves/elvish/store/storedefs"
 	"github.com/elves/elvish/util"
@@ -43,6 +53,10 @@ const (
 	connectionShutdownFmt = "Socket file %s exists but is not responding to request. This is likely due to abnormal shutdown of the daemon. Going to remove socket file and re-spawn a daemon.\n"
 )
 
+// daemonMetricsEnv is the name of the environment variable that, when set,
+// specifies the address on which a spawned daemon serves runtime metrics.
+const daemonMetricsEnv = "ELVISH_DAEMON_METRICS"
+
 var errInvalidDB = errors.New("daemon reported that database is invalid. If you upgraded Elvish from a pre-0.10 version, you need to upgrade your database by following instructions in https://github.com/elves/upgrade-db-for-0.10/")
 
 // InitRuntime initializes the runtime. The caller is responsible for calling
@@ -74,12 +88,23 @@ func InitRuntime(binpath, sockpath, dbpath string) (*eval.Evaler, string) {
 	ev := eval.NewEvaler()
 	ev.SetLibDir(filepath.Join(dataDir, "lib"))
 	ev.InstallModule("re", re.Ns())
+	ev.InstallModule("env", env.Ns())
+	ev.InstallModule("html", html.Ns())
+	ev.InstallModule("color", color.Ns())
+	ev.InstallModule("str", str.Ns())
+	ev.InstallModule("semver", semver.Ns())
+	ev.InstallModule("ip", ip.Ns())
+	ev.InstallModule("mount", mount.Ns())
+	ev.InstallModule("sys", sysmod.Ns())
+	ev.InstallModule("direnv", direnv.Ns())
+	ev.InstallModule("event", event.Ns())
 	if sockpath != "" && dbpath != "" {
 		spawner := &daemonp.Daemon{
 			BinPath:       binpath,
 			DbPath:        dbpath,
 			SockPath:      sockpath,
 			LogPathPrefix: filepath.Join(runDir, "daemon.log-"),
+			MetricsAddr:   os.Getenv(daemonMetricsEnv),
 		}
 		// TODO(xiaq): Connect to daemon and install daemon module
 		// asynchronously.
@@ -52,7 +52,10 @@ func (s *Store) AddCmd(cmd string) (int, error) {
 func (s *Store) RemoveCmd(seq int) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(BucketCmd))
-		return b.Delete(marshalSeq(uint64(seq)))
+		if err := b.Delete(marshalSeq(uint64(seq))); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(BucketCmdInfo)).Delete(marshalSeq(uint64(seq)))
 	})
 }
 
@@ -168,6 +171,27 @@ func (s *Store) PrevCmd(upto int, prefix string) (int, string, error) {
 	return seq, cmd, err
 }
 
+// PrevCmds returns at most n commands before the given sequence number
+// (exclusive), the latest first. Only the Seq and Text fields of the commands
+// are set.
+func (s *Store) PrevCmds(upto, n int) ([]storedefs.Cmd, error) {
+	var cmds []storedefs.Cmd
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(BucketCmd)).Cursor()
+		k, v := c.Seek(marshalSeq(uint64(upto)))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		for ; k != nil && len(cmds) < n; k, v = c.Prev() {
+			cmds = append(cmds, storedefs.Cmd{Seq: int(unmarshalSeq(k)), Text: string(v)})
+		}
+		return nil
+	})
+	return cmds, err
+}
+
 func marshalSeq(seq uint64) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, uint64(seq))
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/elves/elvish/store/storedefs"
+)
+
+func init() {
+	initDB["initialize command information table"] = func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(BucketCmdInfo))
+			return err
+		})
+	}
+}
+
+// BucketCmdInfo keeps information about how the commands in the command
+// history were run, keyed by their sequence numbers.
+const BucketCmdInfo = "cmdinfo"
+
+// SetCmdInfo records information about how the command with the given sequence
+// number was run.
+func (s *Store) SetCmdInfo(seq int, info storedefs.CmdInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketCmdInfo))
+		return b.Put(marshalSeq(uint64(seq)), data)
+	})
+}
+
+// CmdsWithInfo returns all commands within the specified range, together with
+// the information about how they were run.
+func (s *Store) CmdsWithInfo(from, upto int) ([]storedefs.Cmd, error) {
+	var cmds []storedefs.Cmd
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketCmd))
+		infos := tx.Bucket([]byte(BucketCmdInfo))
+		c := b.Cursor()
+		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil && unmarshalSeq(k) < uint64(upto); k, v = c.Next() {
+			cmd := storedefs.Cmd{Seq: int(unmarshalSeq(k)), Text: string(v)}
+			cmd.Status = -1
+			if data := infos.Get(k); data != nil {
+				if err := json.Unmarshal(data, &cmd.CmdInfo); err != nil {
+					return err
+				}
+			}
+			cmds = append(cmds, cmd)
+		}
+		return nil
+	})
+	return cmds, err
+}
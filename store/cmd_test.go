@@ -1,7 +1,9 @@
 package store
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/elves/elvish/store/storedefs"
 )
@@ -73,6 +75,30 @@ func TestCmd(t *testing.T) {
 		}
 	}
 
+	for _, tt := range []struct {
+		upto, n  int
+		wantSeqs []int
+	}{
+		{5, 2, []int{4, 3}},
+		{3, 10, []int{2, 1}},
+		{100, 1, []int{4}},
+		{1, 10, nil},
+	} {
+		got, err := tStore.PrevCmds(tt.upto, tt.n)
+		var seqs []int
+		for _, cmd := range got {
+			seqs = append(seqs, cmd.Seq)
+			if cmd.Text != cmds[cmd.Seq-startSeq] {
+				t.Errorf("tStore.PrevCmds(%v, %v) has wrong text %q for %v",
+					tt.upto, tt.n, cmd.Text, cmd.Seq)
+			}
+		}
+		if !reflect.DeepEqual(seqs, tt.wantSeqs) || err != nil {
+			t.Errorf("tStore.PrevCmds(%v, %v) => seqs %v, error %v, want %v, nil",
+				tt.upto, tt.n, seqs, err, tt.wantSeqs)
+		}
+	}
+
 	if err := tStore.RemoveCmd(1); err != nil {
 		t.Error("Failed to remove cmd")
 	}
@@ -81,3 +107,34 @@ func TestCmd(t *testing.T) {
 			seq, err, "", storedefs.ErrNoMatchingCmd)
 	}
 }
+
+func TestCmdInfo(t *testing.T) {
+	from, _ := tStore.NextCmdSeq()
+	tStore.AddCmd("echo foo")
+	tStore.AddCmd("put bar")
+	info := storedefs.CmdInfo{
+		Dir: "/tmp", Time: time.Unix(1500000000, 0), Duration: time.Second, Status: 2}
+	if err := tStore.SetCmdInfo(from+1, info); err != nil {
+		t.Errorf("tStore.SetCmdInfo(%v, %v) => %v, want <nil>", from+1, info, err)
+	}
+
+	cmds, err := tStore.CmdsWithInfo(from, from+2)
+	if err != nil || len(cmds) != 2 {
+		t.Fatalf("tStore.CmdsWithInfo(%v, %v) => (%v, %v), want 2 commands",
+			from, from+2, cmds, err)
+	}
+	if cmds[0].Seq != from || cmds[0].Text != "echo foo" || cmds[0].Status != -1 {
+		t.Errorf("got %v for a command without information", cmds[0])
+	}
+	got := cmds[1]
+	if got.Seq != from+1 || got.Text != "put bar" || got.Dir != info.Dir ||
+		!got.Time.Equal(info.Time) || got.Duration != info.Duration ||
+		got.Status != info.Status {
+		t.Errorf("got %v, want command with %v", got, info)
+	}
+
+	tStore.RemoveCmd(from + 1)
+	if cmds, _ := tStore.CmdsWithInfo(from+1, from+2); len(cmds) != 0 {
+		t.Errorf("tStore.CmdsWithInfo returns removed command %v", cmds)
+	}
+}
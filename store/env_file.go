@@ -0,0 +1,44 @@
+package store
+
+import "github.com/boltdb/bolt"
+
+const BucketEnvFile = "env_file"
+
+func init() {
+	initDB["initialize env file table"] = func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(BucketEnvFile))
+			return err
+		})
+	}
+}
+
+// EnvFileHash gets the hash of the content of an allowed per-directory
+// environment file. It returns an empty string if the file is not allowed.
+func (s *Store) EnvFileHash(path string) (string, error) {
+	var hash string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketEnvFile))
+		hash = string(b.Get([]byte(path)))
+		return nil
+	})
+	return hash, err
+}
+
+// AllowEnvFile allows a per-directory environment file to be loaded as long as
+// the hash of its content stays the same.
+func (s *Store) AllowEnvFile(path, hash string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketEnvFile))
+		return b.Put([]byte(path), []byte(hash))
+	})
+}
+
+// DisallowEnvFile removes a per-directory environment file from the allowed
+// ones.
+func (s *Store) DisallowEnvFile(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketEnvFile))
+		return b.Delete([]byte(path))
+	})
+}
@@ -0,0 +1,31 @@
+package store
+
+import "testing"
+
+func TestEnvFile(t *testing.T) {
+	path := "/home/elf/project/.elvish-env"
+
+	// A file that has not been allowed has no hash.
+	hash, err := tStore.EnvFileHash(path)
+	if hash != "" || err != nil {
+		t.Errorf("want empty hash and no error, got %q and %v", hash, err)
+	}
+
+	err = tStore.AllowEnvFile(path, "abc")
+	if err != nil {
+		t.Error("want no error, got", err)
+	}
+	hash, err = tStore.EnvFileHash(path)
+	if hash != "abc" || err != nil {
+		t.Errorf("want %q and no error, got %q and %v", "abc", hash, err)
+	}
+
+	err = tStore.DisallowEnvFile(path)
+	if err != nil {
+		t.Error("want no error, got", err)
+	}
+	hash, err = tStore.EnvFileHash(path)
+	if hash != "" || err != nil {
+		t.Errorf("want empty hash and no error, got %q and %v", hash, err)
+	}
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/boltdb/bolt"
+)
+
+// FsckReport describes the problems that Fsck has found in a database, and
+// what repairing has recovered and lost.
+type FsckReport struct {
+	// Problems found in the database.
+	Problems []string
+	// Whether the database was repaired.
+	Repaired bool
+	// When repaired, the number of entries of each bucket that were copied to
+	// the new database, and the number of entries that were lost.
+	Recovered map[string]int
+	Lost      map[string]int
+	// When repaired, the path that the original database was moved to.
+	BackupPath string
+}
+
+func (r *FsckReport) addProblem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// fsckBuckets lists the buckets that a database should have, and functions
+// that check whether an entry of each bucket is valid.
+var fsckBuckets = []struct {
+	name  string
+	valid func(k, v []byte) bool
+}{
+	{BucketSchema, func(k, v []byte) bool { return true }},
+	{BucketCmd, func(k, v []byte) bool { return len(k) == 8 && utf8.Valid(v) }},
+	{BucketCmdInfo, func(k, v []byte) bool {
+		return len(k) == 8 && json.Valid(v)
+	}},
+	{BucketDir, func(k, v []byte) bool {
+		_, err := strconv.ParseFloat(string(v), 64)
+		return utf8.Valid(k) && err == nil
+	}},
+	{BucketSharedVar, func(k, v []byte) bool { return utf8.Valid(k) }},
+	{BucketEnvFile, func(k, v []byte) bool { return utf8.Valid(k) }},
+}
+
+// Fsck checks the integrity of the database at the given path. When repair is
+// true and problems are found, all valid entries that can still be read are
+// copied into a fresh database with rebuilt indices, which then replaces the
+// original; the original is kept with a ".corrupt" suffix.
+//
+// The database must not be in use, for instance by a running daemon.
+func Fsck(dbname string, repair bool) (*FsckReport, error) {
+	report := &FsckReport{}
+	db, err := openFsckDB(dbname)
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("database %s is in use; stop the daemon first", dbname)
+	} else if err != nil {
+		report.addProblem("cannot open database: %v", err)
+		if repair {
+			err = replaceDB(dbname, nil, report)
+		}
+		return report, err
+	}
+
+	checkDB(db, report)
+	if !repair || len(report.Problems) == 0 {
+		return report, db.Close()
+	}
+	return report, replaceDB(dbname, db, report)
+}
+
+// openFsckDB opens the database, recovering from panics caused by corrupted
+// meta or freelist pages.
+func openFsckDB(dbname string) (db *bolt.DB, err error) {
+	err = protect(func() error {
+		var err error
+		db, err = DefaultDB(dbname)
+		return err
+	})
+	return db, err
+}
+
+// protect calls f, turning panics into errors; Bolt panics when it finds some
+// kinds of corrupted pages. Faults caused by page numbers that point outside
+// the database file are turned into panics first.
+func protect(f func() error) (err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+// checkDB checks the entries of all buckets and the consistency of the
+// database file, adding problems found to the report.
+func checkDB(db *bolt.DB, report *FsckReport) {
+	readable := true
+	for _, bucket := range fsckBuckets {
+		var maxSeq, seq uint64
+		found := false
+		bad, err := iterateBucket(db, bucket.name, func(k, v []byte) bool {
+			found = true
+			if !bucket.valid(k, v) {
+				return false
+			}
+			if bucket.name == BucketCmd && unmarshalSeq(k) > maxSeq {
+				maxSeq = unmarshalSeq(k)
+			}
+			return true
+		}, &seq)
+		switch {
+		case err == ErrInvalidBucket:
+			report.addProblem("bucket %s is missing", bucket.name)
+			continue
+		case err != nil:
+			readable = false
+			report.addProblem("bucket %s cannot be read fully: %v", bucket.name, err)
+		}
+		if bad > 0 {
+			report.addProblem("bucket %s has %d invalid entries", bucket.name, bad)
+		}
+		if bucket.name == BucketCmd && seq < maxSeq {
+			report.addProblem("bucket %s has sequence %d, behind the last entry %d",
+				bucket.name, seq, maxSeq)
+		}
+		if bucket.name == BucketSchema && !found {
+			report.addProblem("bucket %s has no schema version", bucket.name)
+		}
+	}
+
+	// Bolt checks the consistency in a goroutine of its own, where panics
+	// caused by corrupted pages would crash the process. Walk all the pages
+	// here first, which panics in the same way, and only check the
+	// consistency when that succeeds.
+	if !readable {
+		return
+	}
+	err := protect(func() error {
+		return db.View(func(tx *bolt.Tx) error {
+			// The bucket of the cursor of a transaction is the root bucket.
+			tx.Cursor().Bucket().Stats()
+			return nil
+		})
+	})
+	if err != nil {
+		report.addProblem("database has corrupted pages: %v", err)
+		return
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			report.addProblem("inconsistent database: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		report.addProblem("cannot check database: %v", err)
+	}
+}
+
+// iterateBucket calls f with all entries of a bucket, recovering from panics
+// caused by corrupted pages. It returns the number of entries for which f
+// returned false. If seq is not nil, the sequence of the bucket is stored in
+// it.
+func iterateBucket(db *bolt.DB, name string, f func(k, v []byte) bool, seq *uint64) (bad int, err error) {
+	err = protect(func() error {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				return ErrInvalidBucket
+			}
+			if seq != nil {
+				*seq = b.Sequence()
+			}
+			c := b.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if !f(k, v) {
+					bad++
+				}
+			}
+			return nil
+		})
+	})
+	return bad, err
+}
+
+// replaceDB copies the valid entries of db, which may be nil if it cannot be
+// opened at all, into a fresh database, and replaces the database at dbname
+// with it. It closes db.
+func replaceDB(dbname string, db *bolt.DB, report *FsckReport) error {
+	if db != nil {
+		defer db.Close()
+	}
+	newname := dbname + ".fsck-new"
+	os.Remove(newname)
+	newdb, err := DefaultDB(newname)
+	if err != nil {
+		return err
+	}
+	defer newdb.Close()
+	if _, err := NewStoreDB(newdb); err != nil {
+		return err
+	}
+
+	report.Recovered = make(map[string]int)
+	report.Lost = make(map[string]int)
+	if db != nil {
+		for _, bucket := range fsckBuckets {
+			if bucket.name == BucketSchema {
+				// Written by NewStoreDB.
+				continue
+			}
+			err := copyBucket(db, newdb, bucket.name, bucket.valid, report)
+			if err != nil {
+				return err
+			}
+		}
+		db.Close()
+	}
+	newdb.Close()
+
+	report.BackupPath = dbname + ".corrupt"
+	if err := os.Rename(dbname, report.BackupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(newname, dbname); err != nil {
+		return err
+	}
+	report.Repaired = true
+	return nil
+}
+
+// copyBucket copies the valid entries of a bucket from db to newdb, counting
+// them in the report. The sequence of the bucket is rebuilt from the entries.
+func copyBucket(db, newdb *bolt.DB, name string, valid func(k, v []byte) bool, report *FsckReport) error {
+	type entry struct{ k, v []byte }
+	var entries []entry
+	var seq uint64
+	bad, err := iterateBucket(db, name, func(k, v []byte) bool {
+		if !valid(k, v) {
+			return false
+		}
+		// Keys and values are only valid during the transaction.
+		entries = append(entries, entry{append([]byte(nil), k...), append([]byte(nil), v...)})
+		return true
+	}, &seq)
+	report.Lost[name] = bad
+	if err != nil && err != ErrInvalidBucket {
+		report.addProblem("lost the rest of bucket %s after %d entries", name, len(entries)+bad)
+	}
+
+	return newdb.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(name))
+		for _, e := range entries {
+			if err := b.Put(e.k, e.v); err != nil {
+				return err
+			}
+			if name == BucketCmd && unmarshalSeq(e.k) > seq {
+				seq = unmarshalSeq(e.k)
+			}
+		}
+		report.Recovered[name] = len(entries)
+		return b.SetSequence(seq)
+	})
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// newFsckDB creates a database in a temporary directory with some commands and
+// directories, calls f to tamper with it, and returns its path.
+func newFsckDB(t *testing.T, f func(tx *bolt.Tx) error) (string, func()) {
+	dir, err := ioutil.TempDir("", "elvish.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	dbname := filepath.Join(dir, "db")
+	db, err := DefaultDB(dbname)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s, err := NewStoreDB(db)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	for _, cmd := range []string{"echo a", "echo b", "echo c"} {
+		s.AddCmd(cmd)
+	}
+	s.AddDir("/usr", 1)
+	if f != nil {
+		if err := db.Update(f); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return dbname, cleanup
+}
+
+func TestFsckClean(t *testing.T) {
+	dbname, cleanup := newFsckDB(t, nil)
+	defer cleanup()
+
+	report, err := Fsck(dbname, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 || report.Repaired {
+		t.Errorf("Fsck reports problems %v on a clean database", report.Problems)
+	}
+}
+
+func TestFsckRepair(t *testing.T) {
+	dbname, cleanup := newFsckDB(t, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketCmd))
+		if err := b.SetSequence(1); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(BucketDir)).Put([]byte("/bad"), []byte("bad score"))
+	})
+	defer cleanup()
+
+	report, err := Fsck(dbname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 2 || report.Repaired {
+		t.Errorf("Fsck without repairing reports %v, repaired %v",
+			report.Problems, report.Repaired)
+	}
+
+	report, err = Fsck(dbname, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Repaired {
+		t.Fatalf("Fsck did not repair")
+	}
+	if report.Recovered[BucketCmd] != 3 || report.Lost[BucketCmd] != 0 {
+		t.Errorf("Fsck recovered %d and lost %d commands, want 3 and 0",
+			report.Recovered[BucketCmd], report.Lost[BucketCmd])
+	}
+	if report.Recovered[BucketDir] != 1 || report.Lost[BucketDir] != 1 {
+		t.Errorf("Fsck recovered %d and lost %d dirs, want 1 and 1",
+			report.Recovered[BucketDir], report.Lost[BucketDir])
+	}
+	if _, err := os.Stat(report.BackupPath); err != nil {
+		t.Errorf("backup not kept: %v", err)
+	}
+
+	report, err = Fsck(dbname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("Fsck reports problems %v after repairing", report.Problems)
+	}
+
+	db, err := DefaultDB(dbname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s, err := NewStoreDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, err := s.AddCmd("echo d"); err != nil || seq != 4 {
+		t.Errorf("AddCmd after repairing -> (%d, %v), want (4, nil)", seq, err)
+	}
+}
+
+func TestFsckCorruptPage(t *testing.T) {
+	dbname, cleanup := newFsckDB(t, nil)
+	defer cleanup()
+
+	// Find the leaf page of the root bucket, which holds all the buckets
+	// inline in such a small database, and give it an invalid page type.
+	db, err := DefaultDB(dbname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageSize := db.Info().PageSize
+	var leaf int
+	err = db.View(func(tx *bolt.Tx) error {
+		for id := 2; ; id++ {
+			p, err := tx.Page(id)
+			if err != nil || p == nil {
+				return err
+			}
+			if p.Type == "leaf" {
+				leaf = id
+			}
+		}
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf == 0 {
+		t.Fatal("no leaf page found")
+	}
+	f, err := os.OpenFile(dbname, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The flags of a page are the 2 bytes after its 8-byte ID.
+	_, err = f.WriteAt([]byte{0xff, 0xff}, int64(leaf*pageSize+8))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Fsck(dbname, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) == 0 {
+		t.Errorf("Fsck reports no problems on a corrupt database")
+	}
+
+	report, err = Fsck(dbname, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Repaired {
+		t.Errorf("Fsck did not repair")
+	}
+	report, err = Fsck(dbname, false)
+	if err != nil || len(report.Problems) != 0 {
+		t.Errorf("Fsck after repairing -> (%v, %v), want no problems",
+			report.Problems, err)
+	}
+}
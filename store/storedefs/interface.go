@@ -8,6 +8,9 @@ type Store interface {
 	Cmds(from, upto int) ([]string, error)
 	NextCmd(from int, prefix string) (int, string, error)
 	PrevCmd(upto int, prefix string) (int, string, error)
+	PrevCmds(upto, n int) ([]Cmd, error)
+	SetCmdInfo(seq int, info CmdInfo) error
+	CmdsWithInfo(from, upto int) ([]Cmd, error)
 
 	AddDir(dir string, incFactor float64) error
 	Dirs(blacklist map[string]struct{}) ([]Dir, error)
@@ -15,4 +18,8 @@ type Store interface {
 	SharedVar(name string) (string, error)
 	SetSharedVar(name, value string) error
 	DelSharedVar(name string) error
+
+	EnvFileHash(path string) (string, error)
+	AllowEnvFile(path, hash string) error
+	DisallowEnvFile(path string) error
 }
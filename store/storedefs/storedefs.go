@@ -1,7 +1,10 @@
 // Package storedefs contains definitions used by the store package.
 package storedefs
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // NoBlacklist is an empty blacklist, to be used in GetDirs.
 var NoBlacklist = map[string]struct{}{}
@@ -15,3 +18,26 @@ type Dir struct {
 	Path  string
 	Score float64
 }
+
+// CmdInfo is information about how a command in the command history was run.
+type CmdInfo struct {
+	// The working directory when the command was run.
+	Dir string
+	// When the command was started, and how long it took.
+	Time     time.Time
+	Duration time.Duration
+	// The exit status of the command: 0 if it succeeded, the status of the
+	// external command that caused it to fail, 128 plus the signal number if
+	// the external command was killed by a signal, and 1 for other failures.
+	// It is -1 if it is not known.
+	Status int
+}
+
+// Cmd is an entry in the command history, together with information about how
+// it was run. The information is only available for commands run since Elvish
+// started keeping it; for others, CmdInfo is empty except for Status being -1.
+type Cmd struct {
+	Seq  int
+	Text string
+	CmdInfo
+}
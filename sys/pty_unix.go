@@ -0,0 +1,58 @@
+// +build !windows,!plan9
+
+package sys
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/kr/pty"
+	"golang.org/x/sys/unix"
+)
+
+// OpenPty allocates a pseudo-terminal of the given size, returning its master
+// and slave sides. Output processing is turned off on the slave side, so that
+// what is written to it can be read unchanged from the master side.
+func OpenPty(row, col int) (master, slave *os.File, err error) {
+	master, slave, err = pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	term, err := NewTermiosFromFd(int(slave.Fd()))
+	if err == nil {
+		setFlag(&term.Oflag, unix.OPOST, false)
+		err = term.ApplyToFd(int(slave.Fd()))
+	}
+	if err == nil && row > 0 && col > 0 {
+		ws := winSize{uint16(row), uint16(col), 0, 0}
+		err = Ioctl(int(slave.Fd()), unix.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+	}
+	if err == nil {
+		master, err = pollable(master)
+	}
+	if err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// pollable returns a non-blocking copy of f and closes f. Reads from the
+// copy are done through the runtime poller, so closing it interrupts a
+// pending read; this is not the case for f, which pty.Open has turned into
+// blocking mode.
+func pollable(f *os.File) (*os.File, error) {
+	fd, err := unix.Dup(int(f.Fd()))
+	if err != nil {
+		return f, err
+	}
+	err = unix.SetNonblock(fd, true)
+	if err != nil {
+		unix.Close(fd)
+		return f, err
+	}
+	f.Close()
+	return os.NewFile(uintptr(fd), f.Name()), nil
+}
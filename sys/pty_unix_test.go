@@ -0,0 +1,30 @@
+// +build !windows,!plan9
+
+package sys
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestOpenPty(t *testing.T) {
+	master, slave, err := OpenPty(30, 100)
+	if err != nil {
+		t.Fatal("OpenPty errors:", err)
+	}
+	defer master.Close()
+
+	if !IsATTY(slave) {
+		t.Error("Want slave to be a terminal")
+	}
+	if row, col := GetWinsize(slave); row != 30 || col != 100 {
+		t.Errorf("GetWinsize(slave) -> (%d, %d), want (30, 100)", row, col)
+	}
+	slave.WriteString("a\nb\n")
+	slave.Close()
+	// Reading fails with EIO after the slave side is closed.
+	got, _ := ioutil.ReadAll(master)
+	if string(got) != "a\nb\n" {
+		t.Errorf("Read %q from master, want %q", got, "a\nb\n")
+	}
+}
@@ -0,0 +1,15 @@
+package sys
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrPtyNotSupported is returned by OpenPty on Windows, where allocating
+// pseudo-terminals is not supported yet.
+var ErrPtyNotSupported = errors.New("pseudo-terminals are not supported on this platform")
+
+// OpenPty allocates a pseudo-terminal. It is not supported on Windows.
+func OpenPty(row, col int) (master, slave *os.File, err error) {
+	return nil, nil, ErrPtyNotSupported
+}
@@ -0,0 +1,26 @@
+// +build !windows,!plan9
+
+package sys
+
+import "syscall"
+
+// RlimInfinity is the value of a resource limit that means no limit.
+const RlimInfinity = ^uint64(0)
+
+// The value of RLIM_INFINITY, which is -1 on some platforms.
+var rlimInfinity int64 = syscall.RLIM_INFINITY
+
+// GetRlimit returns the soft and hard limits of a resource. Limits that are
+// infinity are returned as RlimInfinity.
+func GetRlimit(resource int) (soft, hard uint64, err error) {
+	var rlim syscall.Rlimit
+	err = syscall.Getrlimit(resource, &rlim)
+	return fromRlim(rlim.Cur), fromRlim(rlim.Max), err
+}
+
+// SetRlimit sets the soft and hard limits of a resource. RlimInfinity means no
+// limit.
+func SetRlimit(resource int, soft, hard uint64) error {
+	rlim := syscall.Rlimit{Cur: toRlim(soft), Max: toRlim(hard)}
+	return syscall.Setrlimit(resource, &rlim)
+}
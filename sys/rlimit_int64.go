@@ -0,0 +1,20 @@
+// +build dragonfly freebsd
+
+package sys
+
+// The type of the fields of Rlimit is different on different platforms.
+// This file is for those where they are int64.
+
+func fromRlim(v int64) uint64 {
+	if v == rlimInfinity {
+		return RlimInfinity
+	}
+	return uint64(v)
+}
+
+func toRlim(v uint64) int64 {
+	if v == RlimInfinity {
+		return rlimInfinity
+	}
+	return int64(v)
+}
@@ -0,0 +1,20 @@
+// +build !windows,!plan9,!dragonfly,!freebsd
+
+package sys
+
+// The type of the fields of Rlimit is different on different platforms.
+// This file is for those where they are uint64.
+
+func fromRlim(v uint64) uint64 {
+	if v == uint64(rlimInfinity) {
+		return RlimInfinity
+	}
+	return v
+}
+
+func toRlim(v uint64) uint64 {
+	if v == RlimInfinity {
+		return uint64(rlimInfinity)
+	}
+	return v
+}
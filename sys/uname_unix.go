@@ -0,0 +1,28 @@
+// +build !windows,!plan9
+
+package sys
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// Uname returns the name, release and version of the kernel, as reported by
+// uname(2).
+func Uname() (name, release, version string, err error) {
+	var u unix.Utsname
+	err = unix.Uname(&u)
+	if err != nil {
+		return "", "", "", err
+	}
+	return cString(u.Sysname[:]), cString(u.Release[:]), cString(u.Version[:]), nil
+}
+
+// cString converts a NUL-terminated byte slice to a string.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,18 @@
+package sys
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// Uname returns the name, release and version of the kernel. The release is
+// the major and minor version of Windows, and the version is the build number.
+func Uname() (name, release, version string, err error) {
+	v, err := windows.GetVersion()
+	if err != nil {
+		return "", "", "", err
+	}
+	major, minor, build := byte(v), byte(v>>8), uint16(v>>16)
+	return "Windows_NT", fmt.Sprintf("%d.%d", major, minor), fmt.Sprint(build), nil
+}
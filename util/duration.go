@@ -0,0 +1,15 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatDuration formats a duration for humans, with a precision of 0.1
+// seconds when it is shorter than a minute, and 1 second otherwise.
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return (d / time.Second * time.Second).String()
+}
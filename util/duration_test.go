@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	for _, c := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0.0s"},
+		{5240 * time.Millisecond, "5.2s"},
+		{63500 * time.Millisecond, "1m3s"},
+		{2*time.Hour + 500*time.Millisecond, "2h0m0s"},
+	} {
+		if got := FormatDuration(c.d); got != c.want {
+			t.Errorf("FormatDuration(%v) => %q, want %q", c.d, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package util
+
+import "strings"
+
+// NaturalCompare compares two strings in natural order, returning -1, 0 or 1.
+// Runs of ASCII digits are compared by their numeric values, so that "file2"
+// comes before "file10"; everything else is compared byte by byte. Strings
+// that only differ in leading zeros are ordered lexicographically.
+func NaturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			i0, j0 := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			if c := compareDigits(a[i0:i], b[j0:j]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	switch {
+	case i < len(a):
+		return 1
+	case j < len(b):
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareDigits compares two runs of digits by their numeric values.
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
@@ -0,0 +1,28 @@
+package util
+
+import "testing"
+
+var naturalCompareTests = []struct {
+	a, b string
+	want int
+}{
+	{"file2", "file10", -1},
+	{"file10", "file2", 1},
+	{"file10", "file10", 0},
+	{"a", "b", -1},
+	{"a1b2", "a1b10", -1},
+	{"x9", "x9a", -1},
+	{"1.10", "1.9", 1},
+	{"007", "7", -1},
+	{"7", "007", 1},
+	{"10", "9a", 1},
+	{"", "0", -1},
+}
+
+func TestNaturalCompare(t *testing.T) {
+	for _, test := range naturalCompareTests {
+		if got := NaturalCompare(test.a, test.b); got != test.want {
+			t.Errorf("NaturalCompare(%q, %q) => %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -9,7 +9,8 @@ import (
 // DontSearch determines whether the path to an external command should be
 // taken literally and not searched.
 func DontSearch(exe string) bool {
-	return exe == ".." || strings.ContainsRune(exe, filepath.Separator)
+	return exe == ".." || strings.ContainsRune(exe, '/') ||
+		strings.ContainsRune(exe, filepath.Separator) || filepath.VolumeName(exe) != ""
 }
 
 // IsExecutable determines whether path refers to an executable file.
@@ -18,6 +19,22 @@ func IsExecutable(path string) bool {
 	if err != nil {
 		return false
 	}
-	fm := fi.Mode()
-	return !fm.IsDir() && (fm&0111 != 0)
+	return IsExecutableFile(fi)
+}
+
+// FindExecutable finds the executable file that the command name refers to in
+// dir, or relative to the current directory if dir is empty. On Windows, the
+// extensions in $E:PATHEXT are tried in turn if name does not already have
+// one of them. It returns the path of the file, or "" if there is none.
+func FindExecutable(dir, name string) string {
+	for _, candidate := range executableCandidates(name) {
+		path := candidate
+		if dir != "" {
+			path = filepath.Join(dir, candidate)
+		}
+		if IsExecutable(path) {
+			return path
+		}
+	}
+	return ""
 }
@@ -0,0 +1,22 @@
+package util
+
+import "testing"
+
+var dontSearchTests = []struct {
+	exe  string
+	want bool
+}{
+	{"ls", false},
+	{"..", true},
+	{"./ls", true},
+	{"/bin/ls", true},
+	{"a/b", true},
+}
+
+func TestDontSearch(t *testing.T) {
+	for _, tt := range dontSearchTests {
+		if got := DontSearch(tt.exe); got != tt.want {
+			t.Errorf("DontSearch(%q) => %v, want %v", tt.exe, got, tt.want)
+		}
+	}
+}
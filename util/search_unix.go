@@ -0,0 +1,16 @@
+// +build !windows,!plan9
+
+package util
+
+import "os"
+
+// IsExecutableFile determines whether the file described by fi is executable,
+// namely whether it is not a directory and has any of the executable bits set.
+func IsExecutableFile(fi os.FileInfo) bool {
+	fm := fi.Mode()
+	return !fm.IsDir() && (fm&0111 != 0)
+}
+
+func executableCandidates(name string) []string {
+	return []string{name}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The value of $E:PATHEXT used when it is not set.
+const defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+// IsExecutableFile determines whether the file described by fi is executable,
+// namely whether it is not a directory and its extension is in $E:PATHEXT.
+func IsExecutableFile(fi os.FileInfo) bool {
+	return !fi.IsDir() && hasExecutableExt(fi.Name())
+}
+
+func executableCandidates(name string) []string {
+	if hasExecutableExt(name) {
+		return []string{name}
+	}
+	exts := pathExts()
+	candidates := make([]string, len(exts))
+	for i, ext := range exts {
+		candidates[i] = name + ext
+	}
+	return candidates
+}
+
+func hasExecutableExt(name string) bool {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return false
+	}
+	for _, e := range pathExts() {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathExts returns the extensions of executable files, in lower case.
+func pathExts() []string {
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	var exts []string
+	for _, ext := range strings.Split(strings.ToLower(pathExt), ";") {
+		if ext == "" {
+			continue
+		}
+		if ext[0] != '.' {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDontSearch_Windows(t *testing.T) {
+	for _, exe := range []string{`a\b`, `C:foo`, `C:\foo`} {
+		if !DontSearch(exe) {
+			t.Errorf("DontSearch(%q) => false, want true", exe)
+		}
+	}
+}
+
+func TestFindExecutable_Windows(t *testing.T) {
+	oldPathExt := os.Getenv("PATHEXT")
+	defer os.Setenv("PATHEXT", oldPathExt)
+	os.Setenv("PATHEXT", ".COM;.BAT")
+
+	InTempDir(func(dir string) {
+		for _, name := range []string{"a.bat", "a.exe", "b.exe", "c"} {
+			err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+		for _, test := range []struct{ name, want string }{
+			{"a", filepath.Join(dir, "a.bat")},
+			{"A.BAT", filepath.Join(dir, "A.BAT")},
+			{"b", ""},
+			{"b.exe", ""},
+			{"c", ""},
+		} {
+			if got := FindExecutable(dir, test.name); got != test.want {
+				t.Errorf("FindExecutable(%q) => %q, want %q", test.name, got, test.want)
+			}
+		}
+	})
+}
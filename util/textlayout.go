@@ -0,0 +1,206 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Functions for laying out text on the terminal. They work on grapheme
+// clusters, so that combining marks and zero-width-joined emoji sequences are
+// never split, and treat ANSI escape sequences such as "\033[31m" as having no
+// width, so that they can be used with styled text.
+
+const zwj = 0x200d
+
+// nextCluster returns the length in bytes of the first grapheme cluster or
+// escape sequence of s, its width, and whether it is an escape sequence.
+func nextCluster(s string) (n int, width int, escape bool) {
+	if strings.HasPrefix(s, "\033[") {
+		for i := 2; i < len(s); i++ {
+			if 0x40 <= s[i] && s[i] <= 0x7e {
+				return i + 1, 0, true
+			}
+		}
+		return len(s), 0, true
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	width = Wcwidth(r)
+	if r == '\n' || r == '\t' {
+		return n, width, false
+	}
+	joined := false
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !joined && !isCombining(r) {
+			break
+		}
+		joined = r == zwj
+		n += size
+	}
+	return n, width, false
+}
+
+// StyledWcswidth is like Wcswidth, but does not count escape sequences, and
+// counts each grapheme cluster by the width of its first rune.
+func StyledWcswidth(s string) int {
+	w := 0
+	for len(s) > 0 {
+		n, cw, _ := nextCluster(s)
+		w += cw
+		s = s[n:]
+	}
+	return w
+}
+
+// TruncateWcwidth truncates s so that it is no wider than the given width,
+// ending it with the ellipsis if anything is cut off. Escape sequences before
+// the cut are kept, and when there are any, a sequence resetting the style is
+// appended.
+func TruncateWcwidth(s string, width int, ellipsis string) string {
+	if StyledWcswidth(s) <= width {
+		return s
+	}
+	ew := StyledWcswidth(ellipsis)
+	if ew > width {
+		return TruncateWcwidth(ellipsis, width, "")
+	}
+	var b bytes.Buffer
+	styled := false
+	w := 0
+	for len(s) > 0 {
+		n, cw, escape := nextCluster(s)
+		if escape {
+			styled = true
+		} else if w+cw > width-ew {
+			break
+		}
+		b.WriteString(s[:n])
+		w += cw
+		s = s[n:]
+	}
+	b.WriteString(ellipsis)
+	if styled {
+		b.WriteString("\033[m")
+	}
+	return b.String()
+}
+
+// AbbrPathWcwidth abbreviates a slash-separated path so that it is no wider
+// than the given width, by replacing components in the middle with "…". The
+// first component and as many trailing components as fit are kept, so that
+// "~/src/elvish/edit/file" may become "~/…/edit/file". When this is not
+// possible, the path is truncated like TruncateWcwidth.
+func AbbrPathWcwidth(p string, width int) string {
+	if StyledWcswidth(p) <= width {
+		return p
+	}
+	parts := strings.Split(p, "/")
+	if len(parts) > 2 {
+		head := parts[0] + "/…"
+		tail := ""
+		for i := len(parts) - 1; i > 1; i-- {
+			t := "/" + parts[i] + tail
+			if StyledWcswidth(head+t) > width {
+				break
+			}
+			tail = t
+		}
+		if tail != "" && tail != "/" {
+			return head + tail
+		}
+	}
+	return TruncateWcwidth(p, width, "…")
+}
+
+// PadWcwidth pads s with spaces to the given width. The padding goes to the
+// right when align is negative, to the left when it is positive, and to both
+// sides when it is zero, with the extra space going to the right. Strings that
+// are already wide enough are returned unchanged.
+func PadWcwidth(s string, width int, align int) string {
+	pad := width - StyledWcswidth(s)
+	if pad <= 0 {
+		return s
+	}
+	switch {
+	case align < 0:
+		return s + strings.Repeat(" ", pad)
+	case align > 0:
+		return strings.Repeat(" ", pad) + s
+	default:
+		return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+	}
+}
+
+// WrapWcwidth breaks each line of s into lines no wider than the given width,
+// breaking at spaces where possible. Runs of spaces at the breaks are dropped,
+// while leading spaces of each original line are kept. Words wider than the
+// width are broken between grapheme clusters.
+func WrapWcwidth(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		lines = append(lines, wrapLine(line, width)...)
+	}
+	return lines
+}
+
+func wrapLine(line string, width int) []string {
+	var lines []string
+	var current bytes.Buffer
+	w := 0
+	flush := func() {
+		lines = append(lines, current.String())
+		current.Reset()
+		w = 0
+	}
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	if len(indent) < width {
+		current.WriteString(indent)
+		w = len(indent)
+	}
+	for i, word := range strings.Fields(line) {
+		ww := StyledWcswidth(word)
+		if i > 0 {
+			if w+1+ww <= width {
+				current.WriteByte(' ')
+				w++
+			} else {
+				flush()
+			}
+		}
+		for w+ww > width {
+			// The word does not fit even on a line of its own; break it.
+			for len(word) > 0 {
+				n, cw, _ := nextCluster(word)
+				if w+cw > width && w > 0 {
+					break
+				}
+				current.WriteString(word[:n])
+				w += cw
+				ww -= cw
+				word = word[n:]
+			}
+			flush()
+		}
+		current.WriteString(word)
+		w += ww
+	}
+	if w > 0 || current.Len() > 0 || len(lines) == 0 {
+		flush()
+	}
+	return lines
+}
+
+// IndentLines prepends the prefix to each line of s that is not empty.
+func IndentLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
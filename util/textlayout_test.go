@@ -0,0 +1,114 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStyledWcswidth(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"你好", 4},
+		{"\033[31mred\033[m", 3},
+		{"e\u0301", 1},
+		{"\U0001F469\u200d\U0001F4BB", 2}, // Woman technologist
+	} {
+		if got := StyledWcswidth(c.s); got != c.want {
+			t.Errorf("StyledWcswidth(%q) => %d, want %d", c.s, got, c.want)
+		}
+	}
+}
+
+func TestTruncateWcwidth(t *testing.T) {
+	for _, c := range []struct {
+		s        string
+		width    int
+		ellipsis string
+		want     string
+	}{
+		{"abc", 3, "…", "abc"},
+		{"abcd", 3, "…", "ab…"},
+		{"你好世界", 5, "…", "你好…"},
+		{"e\u0301e\u0301e\u0301", 2, "…", "e\u0301…"},
+		{"\033[31mabcd\033[m", 3, "…", "\033[31mab…\033[m"},
+		{"abcd", 2, "...", ".."},
+		{"abcd", 0, "…", ""},
+	} {
+		if got := TruncateWcwidth(c.s, c.width, c.ellipsis); got != c.want {
+			t.Errorf("TruncateWcwidth(%q, %d, %q) => %q, want %q",
+				c.s, c.width, c.ellipsis, got, c.want)
+		}
+	}
+}
+
+func TestAbbrPathWcwidth(t *testing.T) {
+	for _, c := range []struct {
+		p     string
+		width int
+		want  string
+	}{
+		{"/usr/local/file", 15, "/usr/local/file"},
+		{"/usr/local/share/doc/file", 15, "/…/doc/file"},
+		{"~/projects/elvish/edit/candidate.go", 25, "~/…/edit/candidate.go"},
+		{"a/b/c/d/e", 7, "a/…/d/e"},
+		{"dir/very-long-file-name", 10, "dir/very-…"},
+		{"long-file-name", 5, "long…"},
+	} {
+		if got := AbbrPathWcwidth(c.p, c.width); got != c.want {
+			t.Errorf("AbbrPathWcwidth(%q, %d) => %q, want %q",
+				c.p, c.width, got, c.want)
+		}
+	}
+}
+
+func TestPadWcwidth(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		width int
+		align int
+		want  string
+	}{
+		{"ab", 4, -1, "ab  "},
+		{"ab", 4, 1, "  ab"},
+		{"ab", 5, 0, " ab  "},
+		{"你", 4, 0, " 你 "},
+		{"\033[1mab\033[m", 4, 0, " \033[1mab\033[m "},
+		{"abc", 2, 0, "abc"},
+	} {
+		if got := PadWcwidth(c.s, c.width, c.align); got != c.want {
+			t.Errorf("PadWcwidth(%q, %d, %d) => %q, want %q",
+				c.s, c.width, c.align, got, c.want)
+		}
+	}
+}
+
+func TestWrapWcwidth(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"", 5, []string{""}},
+		{"a b c d", 3, []string{"a b", "c d"}},
+		{"aa  bb\ncc", 10, []string{"aa bb", "cc"}},
+		{"  aa bb cc", 7, []string{"  aa bb", "cc"}},
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+		{"x abcdefg", 3, []string{"x", "abc", "def", "g"}},
+		{"你好世界", 5, []string{"你好", "世界"}},
+		{"e\u0301e\u0301e\u0301", 2, []string{"e\u0301e\u0301", "e\u0301"}},
+		{"\033[31mred\033[m text", 4, []string{"\033[31mred\033[m", "text"}},
+	} {
+		if got := WrapWcwidth(c.s, c.width); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("WrapWcwidth(%q, %d) => %q, want %q", c.s, c.width, got, c.want)
+		}
+	}
+}
+
+func TestIndentLines(t *testing.T) {
+	if got := IndentLines("a\n\nb", "  "); got != "  a\n\n  b" {
+		t.Errorf("IndentLines => %q", got)
+	}
+}